@@ -31,7 +31,6 @@ import (
 	"knative.dev/pkg/injection/clients/kubeclient"
 	endpointsinformer "knative.dev/pkg/injection/informers/kubeinformers/corev1/endpoints"
 	serviceinformer "knative.dev/pkg/injection/informers/kubeinformers/corev1/service"
-	sksinformer "knative.dev/serving/pkg/client/injection/informers/networking/v1alpha1/serverlessservice"
 	revisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1alpha1/revision"
 
 	"github.com/kelseyhightower/envconfig"
@@ -55,13 +54,17 @@ import (
 	"knative.dev/serving/pkg/activator"
 	activatorconfig "knative.dev/serving/pkg/activator/config"
 	activatorhandler "knative.dev/serving/pkg/activator/handler"
+	activatornet "knative.dev/serving/pkg/activator/net"
 	"knative.dev/serving/pkg/apis/networking"
 	"knative.dev/serving/pkg/autoscaler"
 	"knative.dev/serving/pkg/goversion"
 	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/logging"
+	servingmetrics "knative.dev/serving/pkg/metrics"
 	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/otlp"
 	"knative.dev/serving/pkg/queue"
+	"knative.dev/serving/pkg/tracecontext"
 )
 
 // Fail if using unsupported go version.
@@ -146,7 +149,6 @@ func main() {
 	endpointInformer := endpointsinformer.Get(ctx)
 	serviceInformer := serviceinformer.Get(ctx)
 	revisionInformer := revisioninformer.Get(ctx)
-	sksInformer := sksinformer.Get(ctx)
 
 	// Run informers instead of starting them from the factory to prevent the sync hanging because of empty handler.
 	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
@@ -176,8 +178,15 @@ func main() {
 	reqCh := make(chan activatorhandler.ReqEvent, requestCountingQueueLength)
 	defer close(reqCh)
 
+	// Start probing the revision backends and feed the healthy dests to the throttler.
+	updateCh := make(chan *activatornet.RevisionDestsUpdate)
+	defer close(updateCh)
+	activatornet.NewRevisionBackendsManager(updateCh, network.NewProberTransport(),
+		revisionInformer.Lister(), serviceInformer.Lister(), endpointInformer, logger)
+
 	params := queue.BreakerParams{QueueDepth: breakerQueueDepth, MaxConcurrency: breakerMaxConcurrency, InitialCapacity: 0}
-	throttler := activator.NewThrottler(params, endpointInformer, sksInformer.Lister(), revisionInformer.Lister(), logger)
+	throttler := activatornet.NewThrottler(params, revisionInformer, endpointInformer, logger)
+	go throttler.Run(updateCh)
 
 	oct := tracing.NewOpenCensusTracer(tracing.WithExporter(networking.ActivatorServiceName, logger))
 
@@ -187,6 +196,7 @@ func main() {
 			logger.Errorw("Unable to apply open census tracer config", zap.Error(err))
 			return
 		}
+		otlp.ApplyTracingConfig(cfg)
 	})
 
 	// Set up our config store
@@ -219,11 +229,9 @@ func main() {
 		reporter,
 		throttler,
 		revisionInformer.Lister(),
-		serviceInformer.Lister(),
-		sksInformer.Lister(),
 	)
 	ah = activatorhandler.NewRequestEventHandler(reqCh, ah)
-	ah = tracing.HTTPSpanMiddleware(ah)
+	ah = tracecontext.HTTPSpanMiddleware(ah)
 	ah = configStore.HTTPMiddleware(ah)
 	reqLogHandler, err := pkghttp.NewRequestLogHandler(ah, logging.NewSyncFileWriter(os.Stdout), "",
 		requestLogTemplateInputGetter(revisionInformer.Lister()))
@@ -244,6 +252,7 @@ func main() {
 	// Watch the observability config map
 	configMapWatcher.Watch(metrics.ConfigMapName(),
 		metrics.UpdateExporterFromConfigMap(component, logger),
+		servingmetrics.UpdateOTLPExporterFromConfigMap(component, logger),
 		updateRequestLogFromConfigMap(logger, reqLogHandler),
 		profilingHandler.UpdateFromConfigMap)
 
@@ -283,4 +292,5 @@ func flush(logger *zap.SugaredLogger) {
 	os.Stdout.Sync()
 	os.Stderr.Sync()
 	metrics.FlushExporter()
+	otlp.Flush()
 }
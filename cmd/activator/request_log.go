@@ -25,15 +25,20 @@ import (
 	"knative.dev/serving/pkg/apis/serving"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1alpha1"
 	pkghttp "knative.dev/serving/pkg/http"
+	"knative.dev/serving/pkg/metrics"
 )
 
 func updateRequestLogFromConfigMap(logger *zap.SugaredLogger, h *pkghttp.RequestLogHandler) func(configMap *corev1.ConfigMap) {
 	return func(configMap *corev1.ConfigMap) {
 		newTemplate := configMap.Data["logging.request-log-template"]
-		if err := h.SetTemplate(newTemplate); err != nil {
+		oc, err := metrics.NewObservabilityConfigFromConfigMap(configMap)
+		if err == nil {
+			err = h.SetConfig(oc.RequestLogConfig())
+		}
+		if err != nil {
 			logger.Errorw("Failed to update the request log template.", zap.Error(err), "template", newTemplate)
 		} else {
-			logger.Infow("Updated the request log template.", "template", newTemplate)
+			logger.Infow("Updated the request log template.", "template", newTemplate, "format", oc.RequestLogFormat)
 		}
 	}
 }
@@ -58,6 +58,8 @@ func TestUpdateRequestLogFromConfigMap(t *testing.T) {
 		url      string
 		body     string
 		template string
+		format   string
+		fields   string
 		want     string
 	}{{
 		name:     "empty template",
@@ -83,6 +85,13 @@ func TestUpdateRequestLogFromConfigMap(t *testing.T) {
 		body:     "test",
 		template: "{{.Revision.Name}}, {{.Revision.Namespace}}, {{.Revision.Service}}, {{.Revision.Configuration}}, {{.Revision.PodName}}, {{.Revision.PodIP}}",
 		want:     "testRevision, testNs, testSvc, testConfig, , \n",
+	}, {
+		name:   "json",
+		url:    "http://example.com/testpage",
+		body:   "test",
+		format: "json",
+		fields: "method, path, status, revision, namespace",
+		want:   `{"method":"POST","path":"/testpage","status":200,"revision":"testRevision","namespace":"testNs"}` + "\n",
 	}, {
 		name:     "empty template 2",
 		url:      "http://example.com/testpage",
@@ -95,7 +104,11 @@ func TestUpdateRequestLogFromConfigMap(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			buf.Reset()
 			cm := &corev1.ConfigMap{}
-			cm.Data = map[string]string{"logging.request-log-template": test.template}
+			cm.Data = map[string]string{
+				"logging.request-log-template": test.template,
+				"logging.request-log-format":   test.format,
+				"logging.request-log-fields":   test.fields,
+			}
 			(updateRequestLogFromConfigMap(testing2.TestLogger(t), handler))(cm)
 			resp := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodPost, test.url, bytes.NewBufferString(test.body))
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cluster models the pods of the simulated revision. New pods become ready
+// after the startup delay, while removed pods go away right away. The ready
+// pods are published as the Endpoints of the revision's service.
+type cluster struct {
+	namespace    string
+	service      string
+	startupDelay time.Duration
+	indexer      cache.Indexer
+
+	ready int
+	// starting are the times the starting pods become ready, ascending.
+	starting []time.Time
+}
+
+func newCluster(namespace, service string, initialPods int, startupDelay time.Duration) *cluster {
+	c := &cluster{
+		namespace:    namespace,
+		service:      service,
+		startupDelay: startupDelay,
+		indexer:      cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}),
+		ready:        initialPods,
+	}
+	c.publish()
+	return c
+}
+
+// lister returns a lister of the Endpoints of the cluster.
+func (c *cluster) lister() corev1listers.EndpointsLister {
+	return corev1listers.NewEndpointsLister(c.indexer)
+}
+
+// readyCount returns the number of ready pods.
+func (c *cluster) readyCount() int {
+	return c.ready
+}
+
+// scale starts or removes pods to end up with the desired number. The
+// starting pods are removed before the ready ones.
+func (c *cluster) scale(desired int, now time.Time) {
+	if total := c.ready + len(c.starting); desired > total {
+		for i := total; i < desired; i++ {
+			c.starting = append(c.starting, now.Add(c.startupDelay))
+		}
+		return
+	}
+	if desired < c.ready {
+		c.starting = nil
+		c.ready = desired
+		c.publish()
+		return
+	}
+	// Keep the pods that started first.
+	c.starting = c.starting[:desired-c.ready]
+}
+
+// advance makes the pods that finished starting by now ready.
+func (c *cluster) advance(now time.Time) {
+	i := 0
+	for i < len(c.starting) && !c.starting[i].After(now) {
+		i++
+	}
+	if i == 0 {
+		return
+	}
+	c.ready += i
+	c.starting = c.starting[i:]
+	c.publish()
+}
+
+func (c *cluster) publish() {
+	addresses := make([]corev1.EndpointAddress, c.ready)
+	for i := range addresses {
+		addresses[i].IP = fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+	}
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: c.namespace,
+			Name:      c.service,
+		},
+	}
+	if len(addresses) > 0 {
+		ep.Subsets = []corev1.EndpointSubset{{Addresses: addresses}}
+	}
+	// Update is infallible with the key func of the indexer.
+	c.indexer.Update(ep)
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Autoscaler simulator executable. It replays a recorded or synthetic
+// traffic trace against the KPA autoscaler in virtual time, to tune the
+// autoscaling configuration offline.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/logging"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/autoscaler"
+)
+
+const (
+	simNamespace = "default"
+	simRevision  = "sim"
+	simService   = "sim-private"
+
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// keyValues is a flag collecting key=value pairs.
+type keyValues map[string]string
+
+func (kv keyValues) String() string {
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (kv keyValues) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("%q is not of the form key=value", s)
+	}
+	kv[parts[0]] = parts[1]
+	return nil
+}
+
+var (
+	input    = flag.String("input", "", "A CSV file of the traffic to replay, with the offset in seconds, the concurrency and optionally the RPS per line. - reads standard input. Overrides -pattern.")
+	pattern  = flag.String("pattern", patternSpike, "The synthetic traffic pattern to replay without -input: constant, step, ramp, spike or sine.")
+	duration = flag.Duration("duration", 10*time.Minute, "The duration of the synthetic traffic.")
+	base     = flag.Float64("base", 10, "The base concurrency of the synthetic traffic.")
+	peak     = flag.Float64("peak", 100, "The peak concurrency of the synthetic traffic.")
+
+	containerConcurrency = flag.Int64("container-concurrency", 0, "The container concurrency of the revision.")
+	initialPods          = flag.Int("initial-pods", 1, "The number of ready pods at the start.")
+	startupDelay         = flag.Duration("startup-delay", 10*time.Second, "How long new pods take to become ready.")
+
+	format  = flag.String("format", formatCSV, "The output format: csv or json.")
+	output  = flag.String("output", "", "The file to write the output to. Defaults to standard output.")
+	verbose = flag.Bool("verbose", false, "Log the decisions of the autoscaler to standard error.")
+
+	configOverrides = keyValues{}
+	annotations     = keyValues{}
+)
+
+func main() {
+	flag.Var(configOverrides, "config", "A key=value of the config-autoscaler ConfigMap, e.g. stable-window=30s. Can be repeated.")
+	flag.Var(annotations, "annotation", "A key=value annotation of the revision, e.g. autoscaling.knative.dev/target=50. Can be repeated.")
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	if *format != formatCSV && *format != formatJSON {
+		return fmt.Errorf("format = %q, must be %s or %s", *format, formatCSV, formatJSON)
+	}
+	if *initialPods < 0 {
+		return fmt.Errorf("initial-pods = %d, must not be negative", *initialPods)
+	}
+	t, err := loadTrace()
+	if err != nil {
+		return err
+	}
+	config, err := autoscaler.NewConfigFromMap(configOverrides)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	if *verbose {
+		l, err := zap.NewDevelopment()
+		if err != nil {
+			return err
+		}
+		logger = l.Sugar()
+	}
+	ctx := logging.WithLogger(context.Background(), logger)
+
+	sim := &simulator{
+		trace:   t,
+		pa:      newPodAutoscaler(),
+		config:  config,
+		cluster: newCluster(simNamespace, simService, *initialPods, *startupDelay),
+	}
+	res, err := sim.run(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	return writeCSV(w, res)
+}
+
+func loadTrace() (trace, error) {
+	switch *input {
+	case "":
+		return syntheticTrace(*pattern, *duration, *base, *peak)
+	case "-":
+		return readTrace(os.Stdin)
+	default:
+		f, err := os.Open(*input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readTrace(f)
+	}
+}
+
+func newPodAutoscaler() *av1alpha1.PodAutoscaler {
+	a := map[string]string{
+		autoscaling.ClassAnnotationKey: autoscaling.KPA,
+	}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	return &av1alpha1.PodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   simNamespace,
+			Name:        simRevision,
+			Annotations: a,
+		},
+		Spec: av1alpha1.PodAutoscalerSpec{
+			ContainerConcurrency: *containerConcurrency,
+		},
+	}
+}
+
+// writeCSV writes a line per point of the result. The panic transitions
+// show as the changes of the panicking column.
+func writeCSV(w io.Writer, res *result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "concurrency", "rps", "readyPods", "desiredScale", "appliedScale",
+		"panicking", "observedStableValue", "observedPanicValue", "excessBurstCapacity"})
+	for _, p := range res.Points {
+		cw.Write([]string{
+			strconv.FormatFloat(p.Time, 'f', -1, 64),
+			strconv.FormatFloat(p.Concurrency, 'f', 3, 64),
+			strconv.FormatFloat(p.RPS, 'f', 3, 64),
+			strconv.Itoa(p.ReadyPods),
+			strconv.Itoa(int(p.DesiredScale)),
+			strconv.Itoa(int(p.AppliedScale)),
+			strconv.FormatBool(p.Panicking),
+			strconv.FormatFloat(p.ObservedStableValue, 'f', 3, 64),
+			strconv.FormatFloat(p.ObservedPanicValue, 'f', 3, 64),
+			strconv.Itoa(int(p.ExcessBurstCapacity)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
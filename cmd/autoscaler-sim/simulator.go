@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/autoscaler"
+	kparesources "knative.dev/serving/pkg/reconciler/autoscaling/kpa/resources"
+	aresources "knative.dev/serving/pkg/reconciler/autoscaling/resources"
+)
+
+// statInterval is how often the pods report their stats, as the queue
+// proxies do.
+const statInterval = time.Second
+
+// point is the state of the simulation at a tick of the autoscaler.
+type point struct {
+	// Time is the offset from the start of the simulation in seconds.
+	Time        float64 `json:"time"`
+	Concurrency float64 `json:"concurrency"`
+	RPS         float64 `json:"rps"`
+	ReadyPods   int     `json:"readyPods"`
+	// DesiredScale is the scale recommended by the autoscaler, and
+	// AppliedScale the one applied after the scale bounds and the scale to
+	// zero grace period.
+	DesiredScale        int32   `json:"desiredScale"`
+	AppliedScale        int32   `json:"appliedScale"`
+	Panicking           bool    `json:"panicking"`
+	ObservedStableValue float64 `json:"observedStableValue"`
+	ObservedPanicValue  float64 `json:"observedPanicValue"`
+	ExcessBurstCapacity int32   `json:"excessBurstCapacity"`
+}
+
+// panicTransition is a time the autoscaler entered or left panic mode.
+type panicTransition struct {
+	Time      float64 `json:"time"`
+	Panicking bool    `json:"panicking"`
+}
+
+// result is the outcome of a simulation.
+type result struct {
+	Points           []point           `json:"points"`
+	PanicTransitions []panicTransition `json:"panicTransitions"`
+}
+
+// simulator replays a trace against the autoscaler of the revision the
+// PodAutoscaler stands for, in virtual time.
+type simulator struct {
+	trace   trace
+	pa      *av1alpha1.PodAutoscaler
+	config  *autoscaler.Config
+	cluster *cluster
+
+	// zeroSince is when the autoscaler started to desire zero pods, if it
+	// does.
+	zeroSince *time.Time
+}
+
+// noopScraper scrapes nothing, the simulator records the stats itself.
+type noopScraper struct{}
+
+func (noopScraper) Scrape() ([]*autoscaler.StatMessage, error) {
+	return nil, nil
+}
+
+func (s *simulator) run(ctx context.Context) (*result, error) {
+	logger := logging.FromContext(ctx)
+	key := types.NamespacedName{Namespace: s.pa.Namespace, Name: s.pa.Name}
+	collector := autoscaler.NewMetricCollector(func(*av1alpha1.Metric) (autoscaler.StatsScraper, error) {
+		return noopScraper{}, nil
+	}, logger)
+	metric := aresources.MakeMetric(ctx, s.pa, s.cluster.service, s.config)
+	if err := collector.CreateOrUpdate(metric); err != nil {
+		return nil, err
+	}
+	defer collector.Delete(key.Namespace, key.Name)
+
+	decider := kparesources.MakeDecider(ctx, s.pa, s.config, s.cluster.service)
+	algorithm, err := autoscaler.NewScalingAlgorithm(decider.Spec.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	a, err := autoscaler.New(key.Namespace, key.Name, collector, s.cluster.lister(), &decider.Spec, algorithm, nopReporter{})
+	if err != nil {
+		return nil, err
+	}
+	min, max := s.pa.ScaleBounds()
+
+	// New relies on the wall clock, so the virtual time starts now.
+	start := time.Now()
+	res := &result{Points: []point{}, PanicTransitions: []panicTransition{}}
+	panicking := false
+	nextTick := start
+	for offset := time.Duration(0); offset <= s.trace.duration(); offset += statInterval {
+		now := start.Add(offset)
+		s.cluster.advance(now)
+		traffic := s.trace.at(offset)
+		for _, stat := range s.stats(traffic, now) {
+			collector.Record(key, stat)
+		}
+
+		if now.Before(nextTick) {
+			continue
+		}
+		nextTick = nextTick.Add(decider.Spec.TickInterval)
+		desired, ebc, ok := a.Scale(ctx, now)
+		if !ok {
+			continue
+		}
+		applied := s.apply(desired, min, max, now)
+		s.cluster.scale(int(applied), now)
+
+		p := point{
+			Time:                offset.Seconds(),
+			Concurrency:         traffic.concurrency,
+			RPS:                 traffic.rps,
+			ReadyPods:           s.cluster.readyCount(),
+			DesiredScale:        desired,
+			AppliedScale:        applied,
+			ExcessBurstCapacity: ebc,
+		}
+		snapshot, observation := a.Inspect()
+		p.Panicking = snapshot.PanicTime != nil
+		if observation != nil {
+			p.ObservedStableValue = observation.ObservedStableValue
+			p.ObservedPanicValue = observation.ObservedPanicValue
+		}
+		if p.Panicking != panicking {
+			panicking = p.Panicking
+			res.PanicTransitions = append(res.PanicTransitions, panicTransition{Time: p.Time, Panicking: panicking})
+		}
+		res.Points = append(res.Points, p)
+	}
+	logger.Infof("Simulated %v of traffic in %d ticks", s.trace.duration(), len(res.Points))
+	return res, nil
+}
+
+// stats returns the stats the revision reports at the given time. The
+// traffic is spread evenly across the ready pods. Without ready pods the
+// activator reports it all.
+func (s *simulator) stats(traffic sample, now time.Time) []autoscaler.Stat {
+	ready := s.cluster.readyCount()
+	if ready == 0 {
+		if traffic.concurrency == 0 && traffic.rps == 0 {
+			return nil
+		}
+		return []autoscaler.Stat{{
+			Time:                      ptr.Time(now),
+			PodName:                   "activator",
+			AverageConcurrentRequests: traffic.concurrency,
+			RequestCount:              traffic.rps * statInterval.Seconds(),
+		}}
+	}
+	stats := make([]autoscaler.Stat, ready)
+	for i := range stats {
+		stats[i] = autoscaler.Stat{
+			Time:                      ptr.Time(now),
+			PodName:                   fmt.Sprintf("pod-%d", i),
+			AverageConcurrentRequests: traffic.concurrency / float64(ready),
+			RequestCount:              traffic.rps * statInterval.Seconds() / float64(ready),
+		}
+	}
+	return stats
+}
+
+// apply returns the scale the PodAutoscaler reconciler would apply for the
+// desired one. It approximates the scale to zero by only doing so once zero
+// has been desired for the grace period.
+func (s *simulator) apply(desired, min, max int32, now time.Time) int32 {
+	if desired != 0 {
+		s.zeroSince = nil
+	} else if !s.config.EnableScaleToZero {
+		desired = 1
+	} else {
+		if s.zeroSince == nil {
+			s.zeroSince = ptr.Time(now)
+		}
+		if now.Sub(*s.zeroSince) < s.config.ScaleToZeroGracePeriod {
+			desired = 1
+		}
+	}
+	if desired < min {
+		return min
+	}
+	if max != 0 && desired > max {
+		return max
+	}
+	return desired
+}
+
+// nopReporter discards the metrics of the autoscaler.
+type nopReporter struct{}
+
+func (nopReporter) ReportDesiredPodCount(int64) error            { return nil }
+func (nopReporter) ReportRequestedPodCount(int64) error          { return nil }
+func (nopReporter) ReportActualPodCount(int64) error             { return nil }
+func (nopReporter) ReportStableRequestConcurrency(float64) error { return nil }
+func (nopReporter) ReportPanicRequestConcurrency(float64) error  { return nil }
+func (nopReporter) ReportTargetRequestConcurrency(float64) error { return nil }
+func (nopReporter) ReportStableRPS(float64) error                { return nil }
+func (nopReporter) ReportPanicRPS(float64) error                 { return nil }
+func (nopReporter) ReportTargetRPS(float64) error                { return nil }
+func (nopReporter) ReportExcessBurstCapacity(float64) error      { return nil }
+func (nopReporter) ReportPanic(int64) error                      { return nil }
+
+var _ autoscaler.StatsReporter = nopReporter{}
@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/logging"
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/autoscaler"
+	"knative.dev/serving/pkg/resources"
+)
+
+func TestCluster(t *testing.T) {
+	now := time.Now()
+	c := newCluster(simNamespace, simService, 1, 10*time.Second)
+	counter := resources.NewScopedEndpointsCounter(c.lister(), simNamespace, simService)
+	expectReady := func(want int) {
+		t.Helper()
+		if got, err := counter.ReadyCount(); err != nil || got != want {
+			t.Errorf("ReadyCount() = %d, %v, want: %d", got, err, want)
+		}
+	}
+	expectReady(1)
+
+	c.scale(3, now)
+	c.scale(4, now.Add(5*time.Second))
+	c.advance(now.Add(9 * time.Second))
+	expectReady(1)
+	c.advance(now.Add(10 * time.Second))
+	expectReady(3)
+
+	// Scaling down removes the starting pod first.
+	c.scale(3, now.Add(11*time.Second))
+	c.advance(now.Add(time.Minute))
+	expectReady(3)
+
+	c.scale(0, now.Add(time.Minute))
+	expectReady(0)
+}
+
+func newTestSimulator(t *testing.T, tr trace, config map[string]string, annotations map[string]string) *simulator {
+	t.Helper()
+	cfg, err := autoscaler.NewConfigFromMap(config)
+	if err != nil {
+		t.Fatalf("NewConfigFromMap() = %v", err)
+	}
+	a := map[string]string{autoscaling.ClassAnnotationKey: autoscaling.KPA}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	return &simulator{
+		trace: tr,
+		pa: &av1alpha1.PodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   simNamespace,
+				Name:        simRevision,
+				Annotations: a,
+			},
+		},
+		config:  cfg,
+		cluster: newCluster(simNamespace, simService, 1, 5*time.Second),
+	}
+}
+
+func TestSimulatorPanics(t *testing.T) {
+	tr, err := syntheticTrace(patternSpike, 6*time.Minute, 10, 1000)
+	if err != nil {
+		t.Fatalf("syntheticTrace() = %v", err)
+	}
+	sim := newTestSimulator(t, tr, nil, nil)
+	res, err := sim.run(logging.WithLogger(context.Background(), TestLogger(t)))
+	if err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+
+	// A tick every 2 seconds.
+	if got, want := len(res.Points), 181; got != want {
+		t.Errorf("len(Points) = %d, want: %d", got, want)
+	}
+	if len(res.PanicTransitions) != 2 || !res.PanicTransitions[0].Panicking || res.PanicTransitions[1].Panicking {
+		t.Fatalf("PanicTransitions = %+v, want to panic and calm down", res.PanicTransitions)
+	}
+	// The spike starts after 2 minutes.
+	if got, want := res.PanicTransitions[0].Time, 120.0; got != want {
+		t.Errorf("Started panicking at %v, want: %v", got, want)
+	}
+	maxReady := 0
+	for _, p := range res.Points {
+		if p.ReadyPods > maxReady {
+			maxReady = p.ReadyPods
+		}
+	}
+	if maxReady <= 10 {
+		t.Errorf("max(ReadyPods) = %d, want more than 10 pods to serve the spike", maxReady)
+	}
+	if last := res.Points[len(res.Points)-1]; last.AppliedScale != 1 {
+		t.Errorf("AppliedScale = %d, want to scale back down to 1", last.AppliedScale)
+	}
+}
+
+func TestSimulatorScaleToZero(t *testing.T) {
+	tr := trace{
+		{offset: 0, concurrency: 10, rps: 10},
+		{offset: 10 * time.Second, concurrency: 0, rps: 0},
+		{offset: 3 * time.Minute, concurrency: 0, rps: 0},
+	}
+	tests := []struct {
+		name        string
+		config      map[string]string
+		annotations map[string]string
+		want        int32
+	}{{
+		name: "scale to zero",
+		config: map[string]string{
+			"stable-window":              "30s",
+			"scale-to-zero-grace-period": "30s",
+		},
+		want: 0,
+	}, {
+		name: "scale to zero disabled",
+		config: map[string]string{
+			"stable-window":        "30s",
+			"enable-scale-to-zero": "false",
+		},
+		want: 1,
+	}, {
+		name:        "min scale",
+		config:      map[string]string{"stable-window": "30s"},
+		annotations: map[string]string{autoscaling.MinScaleAnnotationKey: "2"},
+		want:        2,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sim := newTestSimulator(t, tr, test.config, test.annotations)
+			res, err := sim.run(logging.WithLogger(context.Background(), TestLogger(t)))
+			if err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			last := res.Points[len(res.Points)-1]
+			if last.AppliedScale != test.want || last.ReadyPods != int(test.want) {
+				t.Errorf("AppliedScale = %d, ReadyPods = %d, want: %d", last.AppliedScale, last.ReadyPods, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	res := &result{Points: []point{{
+		Time:                2,
+		Concurrency:         10,
+		RPS:                 5,
+		ReadyPods:           1,
+		DesiredScale:        2,
+		AppliedScale:        2,
+		Panicking:           true,
+		ObservedStableValue: 9.5,
+		ObservedPanicValue:  10,
+		ExcessBurstCapacity: -190,
+	}}}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, res); err != nil {
+		t.Fatalf("writeCSV() = %v", err)
+	}
+	want := strings.Join([]string{
+		"time,concurrency,rps,readyPods,desiredScale,appliedScale,panicking,observedStableValue,observedPanicValue,excessBurstCapacity",
+		"2,10.000,5.000,1,2,2,true,9.500,10.000,-190",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("writeCSV() = %q, want: %q", got, want)
+	}
+}
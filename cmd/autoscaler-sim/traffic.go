@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sample is the traffic the revision receives from a point in time on.
+type sample struct {
+	offset      time.Duration
+	concurrency float64
+	rps         float64
+}
+
+// trace is a traffic time series, sorted by offset.
+type trace []sample
+
+// at returns the traffic at the given offset, i.e. the last sample at or
+// before it.
+func (t trace) at(offset time.Duration) sample {
+	i := sort.Search(len(t), func(i int) bool { return t[i].offset > offset })
+	if i == 0 {
+		return sample{offset: offset}
+	}
+	return t[i-1]
+}
+
+// duration returns the offset of the last sample.
+func (t trace) duration() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].offset
+}
+
+// readTrace reads a trace from CSV records of the offset in seconds, the
+// concurrency and optionally the requests per second. If the requests per
+// second are missing they are presumed to equal the concurrency, i.e. the
+// requests take a second. A header line is skipped.
+func readTrace(r io.Reader) (trace, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var t trace
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("line %d: want 2 or 3 fields, got %d", line, len(record))
+		}
+		values := make([]float64, len(record))
+		for i, field := range record {
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if line == 1 {
+				// The header.
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		s := sample{
+			offset:      time.Duration(values[0] * float64(time.Second)),
+			concurrency: values[1],
+			rps:         values[1],
+		}
+		if len(values) == 3 {
+			s.rps = values[2]
+		}
+		if s.offset < 0 || s.concurrency < 0 || s.rps < 0 {
+			return nil, fmt.Errorf("line %d: values must not be negative", line)
+		}
+		t = append(t, s)
+	}
+	if len(t) == 0 {
+		return nil, fmt.Errorf("the trace is empty")
+	}
+	sort.SliceStable(t, func(i, j int) bool { return t[i].offset < t[j].offset })
+	return t, nil
+}
+
+// Synthetic traffic patterns.
+const (
+	patternConstant = "constant"
+	patternStep     = "step"
+	patternRamp     = "ramp"
+	patternSpike    = "spike"
+	patternSine     = "sine"
+)
+
+// syntheticTrace generates a trace of the given pattern with a sample per
+// second, moving between the base and the peak concurrency:
+//   - constant stays at the base;
+//   - step jumps from the base to the peak after a quarter of the duration;
+//   - ramp rises linearly from the base to the peak;
+//   - spike goes to the peak between a third and half of the duration;
+//   - sine oscillates between the base and the peak twice.
+func syntheticTrace(pattern string, duration time.Duration, base, peak float64) (trace, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration = %v, must be positive", duration)
+	}
+	if base < 0 || peak < 0 {
+		return nil, fmt.Errorf("base = %v and peak = %v must not be negative", base, peak)
+	}
+	var f func(progress float64) float64
+	switch pattern {
+	case patternConstant:
+		f = func(float64) float64 { return base }
+	case patternStep:
+		f = func(p float64) float64 {
+			if p < 0.25 {
+				return base
+			}
+			return peak
+		}
+	case patternRamp:
+		f = func(p float64) float64 { return base + (peak-base)*p }
+	case patternSpike:
+		f = func(p float64) float64 {
+			if p >= 1.0/3 && p < 0.5 {
+				return peak
+			}
+			return base
+		}
+	case patternSine:
+		f = func(p float64) float64 { return base + (peak-base)*(1-math.Cos(4*math.Pi*p))/2 }
+	default:
+		return nil, fmt.Errorf("unknown pattern %q", pattern)
+	}
+
+	t := make(trace, 0, int(duration/time.Second)+1)
+	for offset := time.Duration(0); offset <= duration; offset += time.Second {
+		c := f(float64(offset) / float64(duration))
+		t = append(t, sample{offset: offset, concurrency: c, rps: c})
+	}
+	return t, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    trace
+		wantErr bool
+	}{{
+		name:  "with header and rps",
+		input: "time,concurrency,rps\n0,1,2\n1.5,3,4\n",
+		want: trace{
+			{offset: 0, concurrency: 1, rps: 2},
+			{offset: 1500 * time.Millisecond, concurrency: 3, rps: 4},
+		},
+	}, {
+		name:  "without rps, unsorted",
+		input: "# recorded traffic\n10, 5\n0, 2\n",
+		want: trace{
+			{offset: 0, concurrency: 2, rps: 2},
+			{offset: 10 * time.Second, concurrency: 5, rps: 5},
+		},
+	}, {
+		name:    "not a number",
+		input:   "0,1\n1,x\n",
+		wantErr: true,
+	}, {
+		name:    "too few fields",
+		input:   "0\n",
+		wantErr: true,
+	}, {
+		name:    "negative",
+		input:   "0,-1\n",
+		wantErr: true,
+	}, {
+		name:    "empty",
+		input:   "time,concurrency\n",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := readTrace(strings.NewReader(test.input))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("readTrace() = %v, wantErr = %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want, cmp.AllowUnexported(sample{})) {
+				t.Errorf("readTrace() diff(-want,+got): %s", cmp.Diff(test.want, got, cmp.AllowUnexported(sample{})))
+			}
+		})
+	}
+}
+
+func TestTraceAt(t *testing.T) {
+	tr := trace{
+		{offset: time.Second, concurrency: 1},
+		{offset: 3 * time.Second, concurrency: 3},
+	}
+	for offset, want := range map[time.Duration]float64{
+		0:                       0,
+		time.Second:             1,
+		2 * time.Second:         1,
+		3 * time.Second:         3,
+		time.Minute:             3,
+		500 * time.Millisecond:  0,
+		2500 * time.Millisecond: 1,
+	} {
+		if got := tr.at(offset).concurrency; got != want {
+			t.Errorf("at(%v) = %v, want: %v", offset, got, want)
+		}
+	}
+	if got, want := tr.duration(), 3*time.Second; got != want {
+		t.Errorf("duration() = %v, want: %v", got, want)
+	}
+}
+
+func TestSyntheticTrace(t *testing.T) {
+	const duration = 12 * time.Second
+	tests := []struct {
+		pattern string
+		// want are the concurrencies at 0, 3, 4, 6 and 12 seconds.
+		want []float64
+	}{{
+		pattern: patternConstant,
+		want:    []float64{10, 10, 10, 10, 10},
+	}, {
+		pattern: patternStep,
+		want:    []float64{10, 100, 100, 100, 100},
+	}, {
+		pattern: patternRamp,
+		want:    []float64{10, 32.5, 40, 55, 100},
+	}, {
+		pattern: patternSpike,
+		want:    []float64{10, 10, 100, 10, 10},
+	}, {
+		pattern: patternSine,
+		want:    []float64{10, 100, 77.5, 10, 10},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			tr, err := syntheticTrace(test.pattern, duration, 10, 100)
+			if err != nil {
+				t.Fatalf("syntheticTrace() = %v", err)
+			}
+			if got, want := len(tr), 13; got != want {
+				t.Errorf("len(trace) = %d, want: %d", got, want)
+			}
+			var got []float64
+			for _, s := range []int{0, 3, 4, 6, 12} {
+				got = append(got, tr.at(time.Duration(s)*time.Second).concurrency)
+			}
+			if !cmp.Equal(got, test.want, cmp.Comparer(func(a, b float64) bool { return a-b < 1e-9 && b-a < 1e-9 })) {
+				t.Errorf("concurrencies = %v, want: %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := syntheticTrace("square", duration, 10, 100); err == nil {
+		t.Error("syntheticTrace() = nil, want an error for an unknown pattern")
+	}
+}
@@ -18,10 +18,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -39,10 +42,20 @@ import (
 	"knative.dev/pkg/profiling"
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/system"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler"
+	"knative.dev/serving/pkg/autoscaler/bucket"
+	"knative.dev/serving/pkg/autoscaler/introspection"
+	"knative.dev/serving/pkg/autoscaler/statforwarder"
 	"knative.dev/serving/pkg/autoscaler/statserver"
+	metricinformer "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/metric"
+	painformer "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/podautoscaler"
+	servingmetrics "knative.dev/serving/pkg/metrics"
+	"knative.dev/serving/pkg/otlp"
+	"knative.dev/serving/pkg/reconciler"
+	asconfig "knative.dev/serving/pkg/reconciler/autoscaling/config"
 	"knative.dev/serving/pkg/reconciler/autoscaling/kpa"
 	"knative.dev/serving/pkg/reconciler/metric"
 	"knative.dev/serving/pkg/resources"
@@ -51,13 +64,18 @@ import (
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
 	statsServerAddr = ":8080"
+	statsServerPort = 8080
 	statsBufferLen  = 1000
 	component       = "autoscaler"
 	controllerNum   = 2
+
+	// snapshotConfigMapName is the ConfigMap the configmap snapshot store writes to.
+	snapshotConfigMapName = "autoscaler-snapshot"
 )
 
 var (
@@ -111,20 +129,38 @@ func main() {
 	// Watch the observability config map
 	cmw.Watch(metrics.ConfigMapName(),
 		metrics.UpdateExporterFromConfigMap(component, logger),
+		servingmetrics.UpdateOTLPExporterFromConfigMap(component, logger),
 		profilingHandler.UpdateFromConfigMap)
 
 	endpointsInformer := endpointsinformer.Get(ctx)
 
-	collector := autoscaler.NewMetricCollector(statsScraperFactoryFunc(endpointsInformer.Lister()), logger)
+	// The scrapers are only created once the configs are watched, so the
+	// store is populated by then.
+	configStore := asconfig.NewStore(logger.Named("config-store"))
+	configStore.WatchConfigs(cmw)
+
+	collector := autoscaler.NewMetricCollector(statsScraperFactoryFunc(endpointsInformer.Lister(), configStore), logger)
 	customMetricsAdapter.WithCustomMetrics(autoscaler.NewMetricProvider(collector))
 
 	// Set up scalers.
 	// uniScalerFactory depends endpointsInformer to be set.
 	multiScaler := autoscaler.NewMultiScaler(ctx.Done(), uniScalerFactoryFunc(endpointsInformer, collector), logger)
 
-	controllers := []*controller.Impl{
-		kpa.NewController(ctx, cmw, multiScaler),
-		metric.NewController(ctx, cmw, collector),
+	kpaImpl := kpa.NewController(ctx, cmw, multiScaler)
+	metricImpl := metric.NewController(ctx, cmw, collector)
+	controllers := []*controller.Impl{kpaImpl, metricImpl}
+
+	// Shard the revisions among the replicas, if configured to. The metric
+	// history of a bucket is restored when this replica starts leading it,
+	// before its revisions are resynced.
+	var checkpointer *autoscaler.Checkpointer
+	elector, err := newElector(ctx, kpaImpl, metricImpl, multiScaler, collector, func(b string) {
+		if checkpointer != nil {
+			restore(checkpointer, b, configStore.Load().Autoscaler, logger)
+		}
+	}, logger)
+	if err != nil {
+		logger.Fatalw("Failed to set up the bucket elector", zap.Error(err))
 	}
 
 	// Set up a statserver.
@@ -135,6 +171,10 @@ func main() {
 		logger.Fatalw("Failed to start watching configs", zap.Error(err))
 	}
 
+	// Restore the metric history before the controllers create the collections
+	// and deciders, which pick it up.
+	checkpointer = newCheckpointer(ctx, configStore.Load().Autoscaler, collector, multiScaler, elector, logger)
+
 	// Start all of the informers and wait for them to sync.
 	if err := controller.StartInformers(ctx.Done(), informers...); err != nil {
 		logger.Fatalw("Failed to start informers", err)
@@ -142,14 +182,31 @@ func main() {
 
 	go controller.StartAll(ctx.Done(), controllers...)
 
+	var forwarder *statforwarder.Forwarder
+	if elector != nil {
+		forwarder = statforwarder.New(elector.Owner, statsServerPort, logger.Named("stat-forwarder"))
+		defer forwarder.Shutdown()
+	}
+
 	go func() {
 		for sm := range statsCh {
+			// Stats of revisions owned by other replicas are sent on to them.
+			if elector != nil && !elector.Owns(sm.Key.String()) {
+				if err := forwarder.Forward(sm); err != nil {
+					logger.Debugw("Failed to forward stat of "+sm.Key.String(), zap.Error(err))
+				}
+				continue
+			}
 			collector.Record(sm.Key, sm.Stat)
 			multiScaler.Poke(sm.Key, sm.Stat)
 		}
 	}()
 
-	profilingServer := profiling.NewServer(profilingHandler)
+	// Serve the state of the deciders next to the profiling data.
+	debugMux := http.NewServeMux()
+	debugMux.Handle("/", profilingHandler)
+	debugMux.Handle(introspection.Path, introspection.NewHandler(multiScaler, collector, logger.Named("introspection")))
+	profilingServer := profiling.NewServer(debugMux)
 
 	eg, egCtx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
@@ -157,11 +214,27 @@ func main() {
 	})
 	eg.Go(statsServer.ListenAndServe)
 	eg.Go(profilingServer.ListenAndServe)
+	// The buckets are only released after the final checkpoint, so that no
+	// other replica restores them before their history was saved.
+	stopElector := make(chan struct{})
+	if elector != nil {
+		eg.Go(func() error {
+			elector.Run(stopElector)
+			return nil
+		})
+	}
 
 	// This will block until either a signal arrives or one of the grouped functions
 	// returns an error.
 	<-egCtx.Done()
 
+	if checkpointer != nil {
+		if err := checkpointer.Checkpoint(time.Now()); err != nil {
+			logger.Errorw("Failed to checkpoint", zap.Error(err))
+		}
+	}
+	close(stopElector)
+
 	statsServer.Shutdown(5 * time.Second)
 	profilingServer.Shutdown(context.Background())
 	// Don't forward ErrServerClosed as that indicates we're already shutting down.
@@ -179,6 +252,13 @@ func uniScalerFactoryFunc(endpointsInformer corev1informers.EndpointsInformer, m
 			return nil, fmt.Errorf("%s decider has empty ServiceName", decider.Name)
 		}
 
+		// The webhook only validates the format of the algorithm name, so
+		// unknown algorithms are rejected here.
+		algorithm, err := autoscaler.NewScalingAlgorithm(decider.Spec.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+
 		serviceName := decider.Labels[serving.ServiceLabelKey] // This can be empty.
 		configName := decider.Labels[serving.ConfigurationLabelKey]
 
@@ -188,18 +268,116 @@ func uniScalerFactoryFunc(endpointsInformer corev1informers.EndpointsInformer, m
 			return nil, err
 		}
 
-		return autoscaler.New(decider.Namespace, decider.Name, metricClient, endpointsInformer.Lister(), &decider.Spec, reporter)
+		return autoscaler.New(decider.Namespace, decider.Name, metricClient, endpointsInformer.Lister(), &decider.Spec, algorithm, reporter)
 	}
 }
 
-func statsScraperFactoryFunc(endpointsLister corev1listers.EndpointsLister) func(metric *av1alpha1.Metric) (autoscaler.StatsScraper, error) {
+// newElector sets up the sharding of the revisions among the replicas into
+// AUTOSCALER_BUCKETS buckets, with a leader election per bucket. Each
+// replica only runs the deciders and collections of the revisions in the
+// buckets it leads. onAcquired is called when this replica starts leading a
+// bucket, before its revisions are reconciled. It returns nil if sharding is
+// not configured.
+func newElector(ctx context.Context, kpaImpl, metricImpl *controller.Impl, multiScaler *autoscaler.MultiScaler,
+	collector *autoscaler.MetricCollector, onAcquired func(bucket string), logger *zap.SugaredLogger) (*bucket.Elector, error) {
+	raw := os.Getenv("AUTOSCALER_BUCKETS")
+	if raw == "" {
+		return nil, nil
+	}
+	buckets, err := strconv.Atoi(raw)
+	if err != nil || buckets < 1 {
+		return nil, fmt.Errorf("AUTOSCALER_BUCKETS = %q, must be a positive integer", raw)
+	}
+	podIP := os.Getenv("POD_IP")
+	if podIP == "" {
+		return nil, errors.New("POD_IP must be set when AUTOSCALER_BUCKETS is")
+	}
+
+	set := bucket.NewSet(buckets)
+	paInformer := painformer.Get(ctx).Informer()
+	metricInformer := metricinformer.Get(ctx).Informer()
+	onlyKpaClass := reconciler.AnnotationFilterFunc(autoscaling.ClassAnnotationKey, autoscaling.KPA, false)
+	// Reconcile the revisions of a bucket whenever it changes hands, which
+	// creates or drops their deciders and collections.
+	resync := func(b string) {
+		inBucket := func(obj interface{}) bool {
+			key, err := cache.MetaNamespaceKeyFunc(obj)
+			return err == nil && set.Bucket(key) == b
+		}
+		kpaImpl.FilteredGlobalResync(func(obj interface{}) bool {
+			return onlyKpaClass(obj) && inBucket(obj)
+		}, paInformer)
+		metricImpl.FilteredGlobalResync(inBucket, metricInformer)
+	}
+	elector := bucket.NewElector(kubeclient.Get(ctx).CoordinationV1beta1(), system.Namespace(), podIP, set,
+		func(b string) {
+			onAcquired(b)
+			resync(b)
+		}, resync, logger.Named("elector"))
+
+	kpaImpl.Reconciler = bucket.NewReconciler(kpaImpl.Reconciler, elector.Owns, multiScaler.Delete)
+	metricImpl.Reconciler = bucket.NewReconciler(metricImpl.Reconciler, elector.Owns,
+		func(_ context.Context, namespace, name string) error {
+			return collector.Delete(namespace, name)
+		})
+	return elector, nil
+}
+
+// newCheckpointer restores the last snapshot and starts checkpointing
+// periodically, if a snapshot store is configured. If the autoscaler is
+// sharded, i.e. the elector isn't nil, the snapshot of each bucket is
+// restored when this replica starts leading it instead.
+func newCheckpointer(ctx context.Context, cfg *autoscaler.Config, collector *autoscaler.MetricCollector,
+	multiScaler *autoscaler.MultiScaler, elector *bucket.Elector, logger *zap.SugaredLogger) *autoscaler.Checkpointer {
+	var store autoscaler.SnapshotStore
+	switch cfg.MetricSnapshotStore {
+	case autoscaler.SnapshotStoreConfigMap:
+		store = autoscaler.NewConfigMapSnapshotStore(kubeclient.Get(ctx), system.Namespace(), snapshotConfigMapName)
+	case autoscaler.SnapshotStoreFile:
+		store = autoscaler.NewFileSnapshotStore(cfg.MetricSnapshotPath)
+	default:
+		return nil
+	}
+
+	var checkpointer *autoscaler.Checkpointer
+	if elector != nil {
+		checkpointer = autoscaler.NewCheckpointer(store, collector, multiScaler, elector, logger.Named("checkpointer"))
+	} else {
+		checkpointer = autoscaler.NewCheckpointer(store, collector, multiScaler, nil, logger.Named("checkpointer"))
+		restore(checkpointer, "", cfg, logger)
+	}
+	go checkpointer.Run(ctx.Done(), cfg.MetricSnapshotInterval)
+	return checkpointer
+}
+
+// restore restores the metric history of the given partition.
+func restore(checkpointer *autoscaler.Checkpointer, partition string, cfg *autoscaler.Config, logger *zap.SugaredLogger) {
+	// Snapshots older than the stable window carry no useful history.
+	if err := checkpointer.Restore(partition, time.Now(), cfg.StableWindow); err != nil {
+		logger.Errorw("Failed to restore the metric history", zap.Error(err))
+	}
+}
+
+func statsScraperFactoryFunc(endpointsLister corev1listers.EndpointsLister, configStore *asconfig.Store) func(metric *av1alpha1.Metric) (autoscaler.StatsScraper, error) {
 	return func(metric *av1alpha1.Metric) (autoscaler.StatsScraper, error) {
+		// The scraping mode is read on every scrape, so that changes to it
+		// apply to the existing Metrics too.
 		podCounter := resources.NewScopedEndpointsCounter(endpointsLister, metric.Namespace, metric.Spec.ScrapeTarget)
-		return autoscaler.NewServiceScraper(metric, podCounter)
+		scraper, err := autoscaler.NewScrapeModeScraper(metric, endpointsLister, podCounter, func() *autoscaler.Config {
+			return configStore.Load().Autoscaler
+		})
+		if err != nil {
+			return nil, err
+		}
+		if metric.Annotations[autoscaling.MetricAnnotationKey] == autoscaling.Custom {
+			return autoscaler.NewCustomMetricScraper(metric, endpointsLister, scraper)
+		}
+		return scraper, nil
 	}
 }
 
 func flush(logger *zap.SugaredLogger) {
 	logger.Sync()
 	metrics.FlushExporter()
+	otlp.Flush()
 }
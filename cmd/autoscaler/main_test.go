@@ -105,6 +105,18 @@ func TestUniscalerFactoryFailures(t *testing.T) {
 	if got, want := err.Error(), "decider has empty ServiceName"; !strings.Contains(got, want) {
 		t.Errorf("Error = %q, want to contain = %q", got, want)
 	}
+
+	// Now give a service name, but an algorithm that is not registered.
+	decider.Spec.ServiceName = "wholesome-service"
+	decider.Spec.Algorithm = "magic"
+
+	_, err = uniScalerFactory(decider)
+	if err == nil {
+		t.Fatal("No error was returned")
+	}
+	if got, want := err.Error(), `unknown scaling algorithm "magic"`; !strings.Contains(got, want) {
+		t.Errorf("Error = %q, want to contain = %q", got, want)
+	}
 }
 
 func endpoints(ns, n string) {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -32,7 +33,6 @@ import (
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/pkg/errors"
-	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
@@ -42,19 +42,24 @@ import (
 	"knative.dev/pkg/logging/logkey"
 	"knative.dev/pkg/metrics"
 	"knative.dev/pkg/signals"
+	"knative.dev/pkg/system"
 	"knative.dev/pkg/tracing"
 	tracingconfig "knative.dev/pkg/tracing/config"
 	"knative.dev/serving/pkg/activator"
 	activatorutil "knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler"
 	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/logging"
 	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/otlp"
 	"knative.dev/serving/pkg/queue"
+	"knative.dev/serving/pkg/queue/auth"
 	"knative.dev/serving/pkg/queue/health"
 	"knative.dev/serving/pkg/queue/readiness"
 	queuestats "knative.dev/serving/pkg/queue/stats"
+	"knative.dev/serving/pkg/tracecontext"
 )
 
 const (
@@ -80,6 +85,7 @@ const (
 	appRequestCountN       = "app_request_count"
 	appResponseTimeInMsecN = "app_request_latencies"
 	queueDepthN            = "queue_depth"
+	connDurationN          = "upgraded_connection_durations"
 
 	// requestQueueHealthPath specifies the path for health checks for
 	// queue-proxy.
@@ -93,6 +99,9 @@ const (
 	aggressivePollInterval = 25 * time.Millisecond
 	// reportingPeriod is the interval of time between reporting stats by queue proxy.
 	reportingPeriod = 1 * time.Second
+	// maxAdaptiveConcurrency bounds the adaptive concurrency limit of containers
+	// with unlimited concurrency.
+	maxAdaptiveConcurrency = 1000
 )
 
 var (
@@ -119,20 +128,39 @@ var (
 		queueDepthN,
 		"The current number of items in the serving and waiting queue, or not reported if unlimited concurrency.",
 		stats.UnitDimensionless)
+	connDurationM = stats.Float64(
+		connDurationN,
+		"The duration of upgraded connections, e.g. WebSockets, in millisecond",
+		stats.UnitMilliseconds)
 
 	readinessProbeTimeout = flag.Int("probe-period", -1, "run readiness probe with given timeout")
 )
 
 type config struct {
 	ContainerConcurrency              int                       `split_words:"true" required:"true"`
+	ContainerConcurrencyAdaptive      bool                      `split_words:"true"` // optional
+	RateLimit                         float64                   `split_words:"true"` // optional
+	RateLimitBurst                    int                       `split_words:"true"` // optional
+	RateLimitKeyHeader                string                    `split_words:"true"` // optional
+	RateLimitMaxPods                  int                       `split_words:"true"` // optional
+	AuthJWKSPath                      string                    `split_words:"true"` // optional
+	AuthIssuer                        string                    `split_words:"true"` // optional
+	AuthAudience                      string                    `split_words:"true"` // optional
+	AuthRequiredClaims                string                    `split_words:"true"` // optional
+	AuthClaimHeaders                  string                    `split_words:"true"` // optional
 	QueueServingPort                  int                       `split_words:"true" required:"true"`
 	RevisionTimeoutSeconds            int                       `split_words:"true" required:"true"`
+	RevisionIdleTimeoutSeconds        int                       `split_words:"true"` // optional
+	RevisionMaxDurationSeconds        int                       `split_words:"true"` // optional
 	UserPort                          int                       `split_words:"true" required:"true"`
+	UserSocketPath                    string                    `split_words:"true"` // optional
+	UserLivenessProbePath             string                    `split_words:"true"` // optional
 	EnableVarLogCollection            bool                      `split_words:"true"` // optional
 	ServingConfiguration              string                    `split_words:"true" required:"true"`
 	ServingNamespace                  string                    `split_words:"true" required:"true"`
 	ServingPodIP                      string                    `split_words:"true" required:"true"`
 	ServingPod                        string                    `split_words:"true" required:"true"`
+	ServingPodUID                     string                    `split_words:"true"` // optional
 	ServingRevision                   string                    `split_words:"true" required:"true"`
 	ServingService                    string                    `split_words:"true"` // optional
 	UserContainerName                 string                    `split_words:"true" required:"true"`
@@ -141,13 +169,22 @@ type config struct {
 	ServingLoggingConfig              string                    `split_words:"true" required:"true"`
 	ServingLoggingLevel               string                    `split_words:"true" required:"true"`
 	ServingRequestMetricsBackend      string                    `split_words:"true" required:"true"`
+	OTLPEndpoint                      string                    `split_words:"true"` // optional
+	OTLPProtocol                      string                    `split_words:"true"` // optional
+	RouteTemplates                    string                    `split_words:"true"` // optional
 	ServingRequestLogTemplate         string                    `split_words:"true" required:"true"`
+	ServingRequestLogFormat           string                    `split_words:"true"` // optional
+	ServingRequestLogFields           string                    `split_words:"true"` // optional
+	ServingRequestLogSampleRate       float64                   `split_words:"true" default:"1"`
+	ServingRequestLogAlwaysLogErrors  bool                      `split_words:"true"` // optional
+	ServingRequestLogSlowThreshold    time.Duration             `split_words:"true"` // optional
 	ServingReadinessProbe             string                    `split_words:"true" required:"true"`
 	TracingConfigDebug                bool                      `split_words:"true"` // optional
 	TracingConfigBackend              tracingconfig.BackendType `split_words:"true"` // optional
 	TracingConfigSampleRate           float64                   `split_words:"true"` // optional
 	TracingConfigZipkinEndpoint       string                    `split_words:"true"` // optional
 	TracingConfigStackdriverProjectID string                    `split_words:"true"` // optional
+	CgroupRoot                        string                    `split_words:"true"` // optional
 }
 
 // Make handler a closure for testing.
@@ -177,6 +214,9 @@ func handler(reqChan chan queue.ReqEvent, breaker *queue.Breaker, handler http.H
 		defer func() {
 			reqChan <- queue.ReqEvent{Time: time.Now(), EventType: out}
 		}()
+		// Upgraded connections are counted separately while they are open.
+		w, closeUpgrade := queue.TrackUpgrade(w, r, reqChan)
+		defer closeUpgrade()
 		network.RewriteHostOut(r)
 
 		// Enforce queuing and concurrency limits.
@@ -295,6 +335,11 @@ func main() {
 		logger.Fatal(err.Error())
 	}
 
+	// Export the request metrics and the spans to the OTLP endpoint too.
+	if err := otlp.SetEndpoint("queue-proxy", env.OTLPEndpoint, otlp.Protocol(env.OTLPProtocol), logger); err != nil {
+		logger.Errorw("Failed to set up the OTLP exporter", zap.Error(err))
+	}
+
 	// Setup reporters and processes to handle stat reporting.
 	promStatReporter, err := queue.NewPrometheusStatsReporter(env.ServingNamespace, env.ServingConfiguration, env.ServingRevision, env.ServingPod, reportingPeriod)
 	if err != nil {
@@ -317,19 +362,37 @@ func main() {
 	reportTicker := time.NewTicker(reportingPeriod)
 	defer reportTicker.Stop()
 
+	// The CPU and memory usage is only reported if the cgroup hierarchy of
+	// the node is mounted, which is the case for revisions scaling on them.
+	var usage queue.UsageReader
+	if env.CgroupRoot != "" {
+		if usage, err = queue.NewPodCgroupReader(env.CgroupRoot, env.ServingPodUID); err != nil {
+			logger.Errorw("Failed to find the cgroup of the pod, not reporting CPU and memory usage", zap.Error(err))
+		}
+	}
+	breaker, limiter := buildBreaker(env)
+	var concurrencyLimiter queue.ConcurrencyLimiter
+	if limiter != nil {
+		concurrencyLimiter = limiter
+	}
 	queue.NewStats(env.ServingPod, queue.Channels{
 		ReqChan:    reqChan,
 		ReportChan: reportTicker.C,
 		StatChan:   statChan,
-	}, time.Now())
+	}, time.Now(), usage, concurrencyLimiter)
 
 	// Setup probe to run for checking user-application healthiness.
-	probe := buildProbe(env.ServingReadinessProbe)
+	probe := buildProbe(env.ServingReadinessProbe, env.UserSocketPath)
 	healthState := &health.State{}
 
-	server := buildServer(env, probe, reqChan, logger)
+	var rateLimiter *queue.RateLimiter
+	if env.RateLimit > 0 {
+		rateLimiter = queue.NewRateLimiter(buildRateLimitParams(env), system.RealClock{})
+	}
+
+	server := buildServer(env, probe, reqChan, breaker, limiter, rateLimiter, logger)
 	adminServer := buildAdminServer(healthState, probe, logger)
-	metricsServer := buildMetricsServer(promStatReporter)
+	metricsServer := buildMetricsServer(promStatReporter, rateLimiter)
 
 	servers := map[string]*http.Server{
 		"main":    server,
@@ -399,15 +462,18 @@ func validateEnv(env config) error {
 	return nil
 }
 
-func buildProbe(probeJSON string) *readiness.Probe {
+func buildProbe(probeJSON, socketPath string) *readiness.Probe {
 	coreProbe, err := readiness.DecodeProbe(probeJSON)
 	if err != nil {
 		logger.Fatalw("Queue container failed to parse readiness probe", zap.Error(err))
 	}
-	return readiness.NewProbe(coreProbe)
+	probe := readiness.NewProbe(coreProbe)
+	probe.SocketPath = socketPath
+	return probe
 }
 
-func buildServer(env config, rp *readiness.Probe, reqChan chan queue.ReqEvent, logger *zap.SugaredLogger) *http.Server {
+func buildServer(env config, rp *readiness.Probe, reqChan chan queue.ReqEvent, breaker *queue.Breaker,
+	limiter *queue.AdaptiveLimiter, rateLimiter *queue.RateLimiter, logger *zap.SugaredLogger) *http.Server {
 	target := &url.URL{
 		Scheme: "http",
 		Host:   net.JoinHostPort("127.0.0.1", strconv.Itoa(env.UserPort)),
@@ -423,7 +489,6 @@ func buildServer(env config, rp *readiness.Probe, reqChan chan queue.ReqEvent, l
 	httpProxy.FlushInterval = -1
 	activatorutil.SetupHeaderPruning(httpProxy)
 
-	breaker := buildBreaker(env)
 	metricsSupported := supportsMetrics(env, logger)
 
 	// Create queue handler chain.
@@ -431,46 +496,73 @@ func buildServer(env config, rp *readiness.Probe, reqChan chan queue.ReqEvent, l
 	var composedHandler http.Handler = httpProxy
 	if metricsSupported {
 		composedHandler = pushRequestMetricHandler(httpProxy, appRequestCountM, appResponseTimeInMsecM,
-			queueDepthM, breaker, env)
+			queueDepthM, connDurationM, breaker, env)
+	}
+	if limiter != nil {
+		composedHandler = queue.AdaptiveLimitHandler(composedHandler, limiter)
 	}
 	composedHandler = http.HandlerFunc(handler(reqChan, breaker, composedHandler, rp.ProbeContainer))
+	if env.AuthJWKSPath != "" {
+		// Rejected requests neither reach the breaker nor count towards concurrency.
+		composedHandler = auth.Handler(composedHandler, buildVerifier(env), env.UserLivenessProbePath)
+	}
+	if rateLimiter != nil {
+		// Rejected requests neither reach the breaker nor count towards concurrency,
+		// but do show up in the request metrics.
+		composedHandler = queue.RateLimitHandler(composedHandler, rateLimiter, env.UserLivenessProbePath)
+	}
 	composedHandler = queue.ForwardedShimHandler(composedHandler)
-	composedHandler = queue.TimeToFirstByteTimeoutHandler(composedHandler,
-		time.Duration(env.RevisionTimeoutSeconds)*time.Second, "request timeout")
+	composedHandler = queue.TimeoutHandler(composedHandler, queue.TimeoutParams{
+		FirstByte: time.Duration(env.RevisionTimeoutSeconds) * time.Second,
+		Idle:      time.Duration(env.RevisionIdleTimeoutSeconds) * time.Second,
+		Max:       time.Duration(env.RevisionMaxDurationSeconds) * time.Second,
+	}, "request timeout")
 	composedHandler = pushRequestLogHandler(composedHandler, env)
 
 	if metricsSupported {
+		if env.RouteTemplates != "" {
+			composedHandler = pushRouteMetricHandler(composedHandler, env)
+		}
 		composedHandler = pushRequestMetricHandler(composedHandler, requestCountM, responseTimeInMsecM,
-			nil /*queueDepthM*/, nil /*breaker*/, env)
+			nil /*queueDepthM*/, nil /*connDurationM*/, nil /*breaker*/, env)
 	}
-	composedHandler = tracing.HTTPSpanMiddleware(composedHandler)
+	composedHandler = tracecontext.HTTPSpanMiddleware(composedHandler)
 	composedHandler = network.NewProbeHandler(composedHandler)
 
 	return network.NewServer(":"+strconv.Itoa(env.QueueServingPort), composedHandler)
 }
 
 func buildTransport(env config, logger *zap.SugaredLogger) http.RoundTripper {
-	if env.TracingConfigBackend == tracingconfig.None {
-		return network.AutoTransport
+	transport := network.AutoTransport
+	if env.UserSocketPath != "" {
+		// The user container listens on a Unix domain socket, so the target's
+		// host is only kept for the requests' Host header.
+		transport = network.NewUnixSocketTransport(env.UserSocketPath)
+	}
+	if env.TracingConfigBackend == tracingconfig.None && env.OTLPEndpoint == "" {
+		return transport
 	}
 
 	oct := tracing.NewOpenCensusTracer(tracing.WithExporter(env.ServingPod, logger))
-	oct.ApplyConfig(&tracingconfig.Config{
+	cfg := &tracingconfig.Config{
 		Backend:              env.TracingConfigBackend,
 		Debug:                env.TracingConfigDebug,
 		ZipkinEndpoint:       env.TracingConfigZipkinEndpoint,
 		StackdriverProjectID: env.TracingConfigStackdriverProjectID,
 		SampleRate:           env.TracingConfigSampleRate,
-	})
-
-	return &ochttp.Transport{
-		Base: network.AutoTransport,
 	}
+	oct.ApplyConfig(cfg)
+	otlp.ApplyTracingConfig(cfg)
+
+	return tracecontext.NewTransport(transport)
 }
 
-func buildBreaker(env config) *queue.Breaker {
+func buildBreaker(env config) (*queue.Breaker, *queue.AdaptiveLimiter) {
+	if env.ContainerConcurrencyAdaptive {
+		return buildAdaptiveBreaker(env)
+	}
 	if env.ContainerConcurrency < 1 {
-		return nil
+		return nil, nil
 	}
 
 	// We set the queue depth to be equal to the container concurrency * 10 to
@@ -479,13 +571,63 @@ func buildBreaker(env config) *queue.Breaker {
 	params := queue.BreakerParams{QueueDepth: queueDepth, MaxConcurrency: env.ContainerConcurrency, InitialCapacity: env.ContainerConcurrency}
 	logger.Infof("Queue container is starting with %#v", params)
 
-	return queue.NewBreaker(params)
+	return queue.NewBreaker(params), nil
+}
+
+// buildAdaptiveBreaker builds a breaker whose concurrency limit is discovered at
+// runtime, up to the container concurrency, if any.
+func buildAdaptiveBreaker(env config) (*queue.Breaker, *queue.AdaptiveLimiter) {
+	maxConcurrency := env.ContainerConcurrency
+	if maxConcurrency < 1 {
+		maxConcurrency = maxAdaptiveConcurrency
+	}
+	limiterParams := queue.DefaultAdaptiveLimiterParams(maxConcurrency)
+	params := queue.BreakerParams{QueueDepth: maxConcurrency * 10, MaxConcurrency: maxConcurrency, InitialCapacity: limiterParams.InitialLimit}
+	logger.Infof("Queue container is starting with %#v and %#v", params, limiterParams)
+
+	breaker := queue.NewBreaker(params)
+	return breaker, queue.NewAdaptiveLimiter(limiterParams, system.RealClock{}, breaker.UpdateConcurrency)
+}
+
+func buildRateLimitParams(env config) queue.RateLimitParams {
+	burst := env.RateLimitBurst
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(env.RateLimit)))
+	}
+	params := queue.RateLimitParams{Rate: env.RateLimit, Burst: burst, KeyHeader: env.RateLimitKeyHeader,
+		MaxPods: env.RateLimitMaxPods}
+	logger.Infof("Queue container is rate limiting with %#v", params)
+	return params
+}
+
+func buildVerifier(env config) *auth.Verifier {
+	requiredClaims, err := serving.ParseAuthRequiredClaims(env.AuthRequiredClaims)
+	if err != nil {
+		logger.Fatalw("Failed to parse AUTH_REQUIRED_CLAIMS", zap.Error(err))
+	}
+	claimHeaders, err := serving.ParseAuthClaimHeaders(env.AuthClaimHeaders)
+	if err != nil {
+		logger.Fatalw("Failed to parse AUTH_CLAIM_HEADERS", zap.Error(err))
+	}
+	verifier, err := auth.NewVerifier(auth.Params{
+		JWKSPath:       env.AuthJWKSPath,
+		Issuer:         env.AuthIssuer,
+		Audience:       env.AuthAudience,
+		RequiredClaims: requiredClaims,
+		ClaimHeaders:   claimHeaders,
+	})
+	if err != nil {
+		logger.Fatalw("Failed to load the keys to authenticate requests with", zap.Error(err))
+	}
+	logger.Infof("Queue container is authenticating requests with the keys in %s", env.AuthJWKSPath)
+	return verifier
 }
 
 func supportsMetrics(env config, logger *zap.SugaredLogger) bool {
 	// Setup request metrics reporting for end-user metrics.
 	if env.ServingRequestMetricsBackend == "" {
-		return false
+		// The OTLP exporter is set up already.
+		return env.OTLPEndpoint != ""
 	}
 
 	if err := setupMetricsExporter(env.ServingRequestMetricsBackend); err != nil {
@@ -513,15 +655,30 @@ func buildAdminServer(healthState *health.State, probe *readiness.Probe, logger
 	}
 }
 
-func buildMetricsServer(promStatReporter *queue.PrometheusStatsReporter) *http.Server {
+func buildMetricsServer(promStatReporter *queue.PrometheusStatsReporter, rateLimiter *queue.RateLimiter) *http.Server {
 	metricsMux := http.NewServeMux()
-	metricsMux.Handle("/metrics", promStatReporter.Handler())
+	metricsMux.Handle("/metrics", readyPodsHandler(promStatReporter.Handler(), rateLimiter))
 	return &http.Server{
 		Addr:    ":" + strconv.Itoa(networking.AutoscalingQueueMetricsPort),
 		Handler: metricsMux,
 	}
 }
 
+// readyPodsHandler splits the rate limit across the ready pods of the revision,
+// whose number the autoscaler passes along when scraping the metrics. Pods that
+// are not scraped split it across the revision's maxScale instead.
+func readyPodsHandler(h http.Handler, rateLimiter *queue.RateLimiter) http.Handler {
+	if rateLimiter == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pods, err := strconv.Atoi(r.URL.Query().Get(autoscaler.ReadyPodsQueryParam)); err == nil {
+			rateLimiter.SetPods(pods)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
 // createVarLogLink creates a symlink allowing the fluentd daemon set to capture the
 // logs from the user container /var/log. See fluentd config for more details.
 func createVarLogLink(env config) {
@@ -534,7 +691,7 @@ func createVarLogLink(env config) {
 }
 
 func pushRequestLogHandler(currentHandler http.Handler, env config) http.Handler {
-	if env.ServingRequestLogTemplate == "" {
+	if env.ServingRequestLogTemplate == "" && env.ServingRequestLogFormat != pkghttp.RequestLogFormatJSON {
 		return currentHandler
 	}
 
@@ -546,8 +703,11 @@ func pushRequestLogHandler(currentHandler http.Handler, env config) http.Handler
 		PodName:       env.ServingPod,
 		PodIP:         env.ServingPodIP,
 	}
-	handler, err := pkghttp.NewRequestLogHandler(currentHandler, logging.NewSyncFileWriter(os.Stdout), env.ServingRequestLogTemplate,
+	handler, err := pkghttp.NewRequestLogHandler(currentHandler, logging.NewSyncFileWriter(os.Stdout), "",
 		pkghttp.RequestLogTemplateInputGetterFromRevision(revInfo))
+	if err == nil {
+		err = handler.SetConfig(buildRequestLogConfig(env))
+	}
 
 	if err != nil {
 		logger.Errorw("Error setting up request logger. Request logs will be unavailable.", zap.Error(err))
@@ -556,9 +716,28 @@ func pushRequestLogHandler(currentHandler http.Handler, env config) http.Handler
 	return handler
 }
 
+func buildRequestLogConfig(env config) pkghttp.RequestLogConfig {
+	var fields []string
+	for _, field := range strings.Split(env.ServingRequestLogFields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return pkghttp.RequestLogConfig{
+		Format:          env.ServingRequestLogFormat,
+		Template:        env.ServingRequestLogTemplate,
+		Fields:          fields,
+		SampleRate:      env.ServingRequestLogSampleRate,
+		AlwaysLogErrors: env.ServingRequestLogAlwaysLogErrors,
+		SlowThreshold:   env.ServingRequestLogSlowThreshold,
+	}
+}
+
 func pushRequestMetricHandler(currentHandler http.Handler, countMetric *stats.Int64Measure,
-	latencyMetric *stats.Float64Measure, queueDepthMetric *stats.Int64Measure, breaker *queue.Breaker, env config) http.Handler {
-	r, err := queuestats.NewStatsReporter(env.ServingNamespace, env.ServingService, env.ServingConfiguration, env.ServingRevision, countMetric, latencyMetric, queueDepthMetric)
+	latencyMetric *stats.Float64Measure, queueDepthMetric *stats.Int64Measure, connDurationMetric *stats.Float64Measure,
+	breaker *queue.Breaker, env config) http.Handler {
+	r, err := queuestats.NewStatsReporter(env.ServingNamespace, env.ServingService, env.ServingConfiguration, env.ServingRevision,
+		countMetric, latencyMetric, queueDepthMetric, connDurationMetric)
 	if err != nil {
 		logger.Errorw("Error setting up request metrics reporter. Request metrics will be unavailable.", zap.Error(err))
 		return currentHandler
@@ -572,6 +751,26 @@ func pushRequestMetricHandler(currentHandler http.Handler, countMetric *stats.In
 	return handler
 }
 
+func pushRouteMetricHandler(currentHandler http.Handler, env config) http.Handler {
+	templates, err := serving.ParseRouteTemplates(env.RouteTemplates)
+	if err != nil {
+		logger.Errorw("Error parsing ROUTE_TEMPLATES. Route metrics will be unavailable.", zap.Error(err))
+		return currentHandler
+	}
+	r, err := queuestats.NewRouteStatsReporter(env.ServingNamespace, env.ServingService, env.ServingConfiguration, env.ServingRevision)
+	if err != nil {
+		logger.Errorw("Error setting up route metrics reporter. Route metrics will be unavailable.", zap.Error(err))
+		return currentHandler
+	}
+
+	handler, err := queue.NewRouteMetricHandler(currentHandler, r, queue.NewRouteMatcher(templates))
+	if err != nil {
+		logger.Errorw("Error setting up route metrics handler. Route metrics will be unavailable.", zap.Error(err))
+		return currentHandler
+	}
+	return handler
+}
+
 func setupMetricsExporter(backend string) error {
 	// Set up OpenCensus exporter.
 	// NOTE: We use revision as the component instead of queue because queue is
@@ -595,4 +794,5 @@ func flush(logger *zap.SugaredLogger) {
 	os.Stdout.Sync()
 	os.Stderr.Sync()
 	metrics.FlushExporter()
+	otlp.Flush()
 }
@@ -33,10 +33,12 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"go.opencensus.io/plugin/ochttp"
 	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
 	"knative.dev/pkg/tracing"
 	tracingconfig "knative.dev/pkg/tracing/config"
 	tracetesting "knative.dev/pkg/tracing/testing"
 	"knative.dev/serving/pkg/activator"
+	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/network"
 	"knative.dev/serving/pkg/queue"
 )
@@ -174,6 +176,51 @@ func TestCreateVarLogLink(t *testing.T) {
 	}
 }
 
+func TestBuildRequestLogConfig(t *testing.T) {
+	env := config{
+		ServingRequestLogFormat:          pkghttp.RequestLogFormatJSON,
+		ServingRequestLogFields:          "method, status,,latency",
+		ServingRequestLogSampleRate:      0.1,
+		ServingRequestLogAlwaysLogErrors: true,
+		ServingRequestLogSlowThreshold:   time.Second,
+	}
+	want := pkghttp.RequestLogConfig{
+		Format:          pkghttp.RequestLogFormatJSON,
+		Fields:          []string{"method", "status", "latency"},
+		SampleRate:      0.1,
+		AlwaysLogErrors: true,
+		SlowThreshold:   time.Second,
+	}
+	if got := buildRequestLogConfig(env); !cmp.Equal(got, want) {
+		t.Errorf("buildRequestLogConfig (-want, +got) = %s", cmp.Diff(want, got))
+	}
+}
+
+func TestReadyPodsHandler(t *testing.T) {
+	rateLimiter := queue.NewRateLimiter(queue.RateLimitParams{Rate: 0.2, Burst: 4, KeyHeader: "X-Client", MaxPods: 4},
+		system.RealClock{})
+	metrics := readyPodsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), rateLimiter)
+	h := queue.RateLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), rateLimiter, "")
+	check := func(key string, want []int) {
+		t.Helper()
+		for i, want := range want {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Client", key)
+			h.ServeHTTP(rec, req)
+			if got := rec.Code; got != want {
+				t.Errorf("Request %d of %s: status = %d, want: %d", i, key, got, want)
+			}
+		}
+	}
+
+	// Until the autoscaler reports the ready pods, the limit is split across the 4 max pods.
+	check("a", []int{http.StatusOK, http.StatusTooManyRequests})
+	// Each of the 2 ready pods lets through 2 requests at once.
+	metrics.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics?readyPods=2", nil))
+	check("b", []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests})
+}
+
 func TestProbeQueueConnectionFailure(t *testing.T) {
 	port := 12345 // some random port (that's not listening)
 
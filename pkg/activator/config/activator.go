@@ -0,0 +1,45 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Activator is the configuration of the config-activator ConfigMap.
+type Activator struct {
+	LoadBalancing *LoadBalancing
+	Queueing      *Queueing
+	Retry         *Retry
+}
+
+// NewActivatorFromConfigMap creates an Activator from the supplied ConfigMap.
+func NewActivatorFromConfigMap(configMap *corev1.ConfigMap) (*Activator, error) {
+	lb, err := NewLoadBalancingFromConfigMap(configMap)
+	if err != nil {
+		return nil, err
+	}
+	q, err := NewQueueingFromConfigMap(configMap)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRetryFromConfigMap(configMap)
+	if err != nil {
+		return nil, err
+	}
+	return &Activator{LoadBalancing: lb, Queueing: q, Retry: r}, nil
+}
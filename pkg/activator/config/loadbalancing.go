@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+const (
+	// ConfigName is the name of the ConfigMap of the activator.
+	ConfigName = "config-activator"
+
+	loadBalancingPolicyKey     = "load-balancing-policy"
+	loadBalancingHashHeaderKey = "load-balancing-hash-header"
+	loadBalancingHashCookieKey = "load-balancing-hash-cookie"
+)
+
+// LoadBalancing is how the activator spreads the requests across the pods
+// of a revision.
+type LoadBalancing struct {
+	Policy serving.LoadBalancingPolicy
+
+	// HashHeader and HashCookie are the request header and cookie the
+	// consistent-hash policy routes on. The header takes precedence.
+	HashHeader string
+	HashCookie string
+}
+
+// NewLoadBalancingFromConfigMap creates a LoadBalancing from the supplied
+// ConfigMap.
+func NewLoadBalancingFromConfigMap(configMap *corev1.ConfigMap) (*LoadBalancing, error) {
+	lb := &LoadBalancing{
+		Policy:     serving.LeastConnectionsPolicy,
+		HashHeader: configMap.Data[loadBalancingHashHeaderKey],
+		HashCookie: configMap.Data[loadBalancingHashCookieKey],
+	}
+	if raw, ok := configMap.Data[loadBalancingPolicyKey]; ok {
+		policy, err := serving.ParseLoadBalancingPolicy(raw)
+		if err != nil {
+			return nil, err
+		}
+		lb.Policy = policy
+	}
+	return lb, nil
+}
+
+// ForRevision returns the LoadBalancing of a revision with the given
+// annotations, which override the defaults in lb. Invalid policies are
+// ignored.
+func (lb *LoadBalancing) ForRevision(annotations map[string]string) *LoadBalancing {
+	out := *lb
+	if policy, err := serving.ParseLoadBalancingPolicy(annotations[serving.LoadBalancingPolicyAnnotationKey]); err == nil {
+		out.Policy = policy
+	}
+	if header, ok := annotations[serving.LoadBalancingHashHeaderAnnotationKey]; ok {
+		out.HashHeader = header
+	}
+	if cookie, ok := annotations[serving.LoadBalancingHashCookieAnnotationKey]; ok {
+		out.HashCookie = cookie
+	}
+	return &out
+}
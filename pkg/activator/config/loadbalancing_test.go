@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	. "knative.dev/pkg/configmap/testing"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+func TestLoadBalancingConfig(t *testing.T) {
+	actual, example := ConfigMapsFromTestFile(t, ConfigName)
+	for _, tt := range []struct {
+		name    string
+		data    *corev1.ConfigMap
+		want    *LoadBalancing
+		wantErr bool
+	}{{
+		name: "actual config",
+		data: actual,
+		want: &LoadBalancing{Policy: serving.LeastConnectionsPolicy},
+	}, {
+		name: "example config",
+		data: example,
+		want: &LoadBalancing{Policy: serving.LeastConnectionsPolicy},
+	}, {
+		name: "consistent hash",
+		data: &corev1.ConfigMap{
+			Data: map[string]string{
+				"load-balancing-policy":      "consistent-hash",
+				"load-balancing-hash-header": "X-User",
+				"load-balancing-hash-cookie": "session",
+			},
+		},
+		want: &LoadBalancing{
+			Policy:     serving.ConsistentHashPolicy,
+			HashHeader: "X-User",
+			HashCookie: "session",
+		},
+	}, {
+		name: "invalid policy",
+		data: &corev1.ConfigMap{
+			Data: map[string]string{
+				"load-balancing-policy": "random",
+			},
+		},
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLoadBalancingFromConfigMap(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLoadBalancingFromConfigMap() = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("NewLoadBalancingFromConfigMap() diff(-want,+got): %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestLoadBalancingForRevision(t *testing.T) {
+	defaults := &LoadBalancing{
+		Policy:     serving.RoundRobinPolicy,
+		HashHeader: "X-User",
+	}
+	for _, tt := range []struct {
+		name        string
+		annotations map[string]string
+		want        *LoadBalancing
+	}{{
+		name: "defaults",
+		want: defaults,
+	}, {
+		name: "overrides",
+		annotations: map[string]string{
+			serving.LoadBalancingPolicyAnnotationKey:     "consistent-hash",
+			serving.LoadBalancingHashHeaderAnnotationKey: "",
+			serving.LoadBalancingHashCookieAnnotationKey: "session",
+		},
+		want: &LoadBalancing{
+			Policy:     serving.ConsistentHashPolicy,
+			HashCookie: "session",
+		},
+	}, {
+		name: "invalid policy",
+		annotations: map[string]string{
+			serving.LoadBalancingPolicyAnnotationKey: "random",
+		},
+		want: defaults,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaults.ForRevision(tt.annotations); !cmp.Equal(got, tt.want) {
+				t.Errorf("ForRevision() diff(-want,+got): %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+	if defaults.Policy != serving.RoundRobinPolicy || defaults.HashHeader != "X-User" {
+		t.Errorf("ForRevision() modified the defaults: %+v", defaults)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	queueDepthKey          = "queue-depth"
+	namespaceQueueDepthKey = "namespace-queue-depth"
+	revisionQueueDepthKey  = "revision-queue-depth"
+	namespaceWeightsKey    = "namespace-weights"
+	retryAfterKey          = "retry-after"
+)
+
+// Queueing is how the activator shares its request queue between the
+// namespaces and revisions it buffers requests for.
+type Queueing struct {
+	// QueueDepth is the number of requests queued across all the revisions
+	// the active namespaces share in proportion to their weights. A namespace
+	// under its share is admitted even when the queue is full.
+	QueueDepth int
+
+	// NamespaceQueueDepth and RevisionQueueDepth cap the requests queued for
+	// a single namespace and revision. Zero means no cap other than the fair
+	// share of QueueDepth.
+	NamespaceQueueDepth int
+	RevisionQueueDepth  int
+
+	// NamespaceWeights are the weights of the namespaces in the fair share
+	// of QueueDepth. The namespaces not listed have a weight of 1.
+	NamespaceWeights map[string]int
+
+	// RetryAfter is how long the clients whose requests are rejected for
+	// being over budget are asked to wait before retrying.
+	RetryAfter time.Duration
+}
+
+// NewQueueingFromConfigMap creates a Queueing from the supplied ConfigMap.
+func NewQueueingFromConfigMap(configMap *corev1.ConfigMap) (*Queueing, error) {
+	q := &Queueing{}
+
+	for _, i := range []struct {
+		key          string
+		field        *int
+		defaultValue int
+	}{{
+		key:          queueDepthKey,
+		field:        &q.QueueDepth,
+		defaultValue: 10000,
+	}, {
+		key:          namespaceQueueDepthKey,
+		field:        &q.NamespaceQueueDepth,
+		defaultValue: 0,
+	}, {
+		key:          revisionQueueDepthKey,
+		field:        &q.RevisionQueueDepth,
+		defaultValue: 0,
+	}} {
+		if raw, ok := configMap.Data[i.key]; !ok {
+			*i.field = i.defaultValue
+		} else if val, err := strconv.Atoi(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", i.key, err)
+		} else if val < 0 {
+			return nil, fmt.Errorf("%s = %d, must not be negative", i.key, val)
+		} else {
+			*i.field = val
+		}
+	}
+	if q.QueueDepth == 0 {
+		return nil, fmt.Errorf("%s must be positive", queueDepthKey)
+	}
+
+	q.RetryAfter = time.Second
+	if raw, ok := configMap.Data[retryAfterKey]; ok {
+		val, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", retryAfterKey, err)
+		}
+		if val < time.Second {
+			return nil, fmt.Errorf("%s = %v, must be at least 1s", retryAfterKey, val)
+		}
+		q.RetryAfter = val
+	}
+
+	weights, err := parseNamespaceWeights(configMap.Data[namespaceWeightsKey])
+	if err != nil {
+		return nil, err
+	}
+	q.NamespaceWeights = weights
+	return q, nil
+}
+
+// parseNamespaceWeights parses a comma separated list of namespace=weight
+// pairs.
+func parseNamespaceWeights(raw string) (map[string]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	weights := map[string]int{}
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%s: %q is not of the form namespace=weight", namespaceWeightsKey, pair)
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 1 {
+			return nil, fmt.Errorf("%s: the weight of %q must be a positive integer, was %q", namespaceWeightsKey, parts[0], parts[1])
+		}
+		weights[parts[0]] = weight
+	}
+	return weights, nil
+}
+
+// WeightOf returns the weight of the namespace in the fair share of the
+// queue.
+func (q *Queueing) WeightOf(namespace string) int {
+	if w, ok := q.NamespaceWeights[namespace]; ok {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	. "knative.dev/pkg/configmap/testing"
+)
+
+func TestQueueingConfig(t *testing.T) {
+	defaults := &Queueing{
+		QueueDepth: 10000,
+		RetryAfter: time.Second,
+	}
+	actual, example := ConfigMapsFromTestFile(t, ConfigName)
+	for _, tt := range []struct {
+		name    string
+		data    map[string]string
+		cm      *corev1.ConfigMap
+		want    *Queueing
+		wantErr bool
+	}{{
+		name: "actual config",
+		cm:   actual,
+		want: defaults,
+	}, {
+		name: "example config",
+		cm:   example,
+		want: defaults,
+	}, {
+		name: "budgets and weights",
+		data: map[string]string{
+			"queue-depth":           "100",
+			"namespace-queue-depth": "50",
+			"revision-queue-depth":  "10",
+			"namespace-weights":     "gold=3, silver=2",
+			"retry-after":           "5s",
+		},
+		want: &Queueing{
+			QueueDepth:          100,
+			NamespaceQueueDepth: 50,
+			RevisionQueueDepth:  10,
+			NamespaceWeights:    map[string]int{"gold": 3, "silver": 2},
+			RetryAfter:          5 * time.Second,
+		},
+	}, {
+		name:    "zero queue depth",
+		data:    map[string]string{"queue-depth": "0"},
+		wantErr: true,
+	}, {
+		name:    "negative revision queue depth",
+		data:    map[string]string{"revision-queue-depth": "-1"},
+		wantErr: true,
+	}, {
+		name:    "invalid queue depth",
+		data:    map[string]string{"queue-depth": "many"},
+		wantErr: true,
+	}, {
+		name:    "invalid weight",
+		data:    map[string]string{"namespace-weights": "gold=0"},
+		wantErr: true,
+	}, {
+		name:    "malformed weights",
+		data:    map[string]string{"namespace-weights": "gold"},
+		wantErr: true,
+	}, {
+		name:    "invalid retry after",
+		data:    map[string]string{"retry-after": "soon"},
+		wantErr: true,
+	}, {
+		name:    "retry after under a second",
+		data:    map[string]string{"retry-after": "100ms"},
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := tt.cm
+			if cm == nil {
+				cm = &corev1.ConfigMap{Data: tt.data}
+			}
+			got, err := NewQueueingFromConfigMap(cm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewQueueingFromConfigMap() = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("NewQueueingFromConfigMap() diff(-want,+got): %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestQueueingWeightOf(t *testing.T) {
+	q := &Queueing{NamespaceWeights: map[string]int{"gold": 3}}
+	if got, want := q.WeightOf("gold"), 3; got != want {
+		t.Errorf("WeightOf(gold) = %d, want: %d", got, want)
+	}
+	if got, want := q.WeightOf("default"), 1; got != want {
+		t.Errorf("WeightOf(default) = %d, want: %d", got, want)
+	}
+}
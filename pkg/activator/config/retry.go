@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	retryAttemptsKey     = "retry-attempts"
+	retryMaxBodyBytesKey = "retry-max-body-bytes"
+	retryStatusCodesKey  = "retry-status-codes"
+)
+
+// Retry is when the activator retries the requests that fail to reach a pod.
+// Only the requests with idempotent methods are retried.
+type Retry struct {
+	// Attempts is the most times a request is retried. Zero disables the
+	// retries.
+	Attempts int
+
+	// MaxBodyBytes is the largest request body the activator buffers to be
+	// able to send it again. The requests with larger bodies are not retried.
+	MaxBodyBytes int64
+
+	// StatusCodes are the response codes the requests are retried on, besides
+	// the connection errors.
+	StatusCodes []int
+}
+
+// NewRetryFromConfigMap creates a Retry from the supplied ConfigMap.
+func NewRetryFromConfigMap(configMap *corev1.ConfigMap) (*Retry, error) {
+	r := &Retry{
+		MaxBodyBytes: 64 * 1024,
+		StatusCodes:  []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+	}
+
+	if raw, ok := configMap.Data[retryAttemptsKey]; ok {
+		val, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", retryAttemptsKey, err)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf("%s = %d, must not be negative", retryAttemptsKey, val)
+		}
+		r.Attempts = val
+	}
+
+	if raw, ok := configMap.Data[retryMaxBodyBytesKey]; ok {
+		val, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", retryMaxBodyBytesKey, err)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf("%s = %d, must not be negative", retryMaxBodyBytesKey, val)
+		}
+		r.MaxBodyBytes = val
+	}
+
+	if raw, ok := configMap.Data[retryStatusCodesKey]; ok {
+		r.StatusCodes = nil
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			code, err := strconv.Atoi(s)
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("%s: %q is not an HTTP status code", retryStatusCodesKey, s)
+			}
+			r.StatusCodes = append(r.StatusCodes, code)
+		}
+	}
+	return r, nil
+}
+
+// RetriesMethod reports whether the requests with the given method are
+// retried, which are the idempotent ones as defined by RFC 7231.
+func (r *Retry) RetriesMethod(method string) bool {
+	if r.Attempts == 0 {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetriesStatus reports whether the requests are retried on the given
+// response code.
+func (r *Retry) RetriesStatus(code int) bool {
+	for _, c := range r.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	. "knative.dev/pkg/configmap/testing"
+)
+
+func TestRetryConfig(t *testing.T) {
+	defaults := &Retry{
+		MaxBodyBytes: 65536,
+		StatusCodes:  []int{502, 503},
+	}
+	actual, example := ConfigMapsFromTestFile(t, ConfigName)
+	for _, tt := range []struct {
+		name    string
+		data    map[string]string
+		cm      *corev1.ConfigMap
+		want    *Retry
+		wantErr bool
+	}{{
+		name: "actual config",
+		cm:   actual,
+		want: defaults,
+	}, {
+		name: "example config",
+		cm:   example,
+		want: defaults,
+	}, {
+		name: "enabled",
+		data: map[string]string{
+			"retry-attempts":       "2",
+			"retry-max-body-bytes": "1024",
+			"retry-status-codes":   "502, 504",
+		},
+		want: &Retry{
+			Attempts:     2,
+			MaxBodyBytes: 1024,
+			StatusCodes:  []int{502, 504},
+		},
+	}, {
+		name: "no status codes",
+		data: map[string]string{"retry-status-codes": ""},
+		want: &Retry{MaxBodyBytes: 65536},
+	}, {
+		name:    "negative attempts",
+		data:    map[string]string{"retry-attempts": "-1"},
+		wantErr: true,
+	}, {
+		name:    "invalid max body bytes",
+		data:    map[string]string{"retry-max-body-bytes": "1Ki"},
+		wantErr: true,
+	}, {
+		name:    "invalid status code",
+		data:    map[string]string{"retry-status-codes": "502,5xx"},
+		wantErr: true,
+	}, {
+		name:    "status code out of range",
+		data:    map[string]string{"retry-status-codes": "999"},
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := tt.cm
+			if cm == nil {
+				cm = &corev1.ConfigMap{Data: tt.data}
+			}
+			got, err := NewRetryFromConfigMap(cm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRetryFromConfigMap() = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("NewRetryFromConfigMap() diff(-want,+got): %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	r := &Retry{Attempts: 1, StatusCodes: []int{http.StatusBadGateway}}
+	for method, want := range map[string]bool{
+		http.MethodGet:    true,
+		http.MethodPut:    true,
+		http.MethodDelete: true,
+		http.MethodPost:   false,
+		http.MethodPatch:  false,
+	} {
+		if got := r.RetriesMethod(method); got != want {
+			t.Errorf("RetriesMethod(%s) = %v, want: %v", method, got, want)
+		}
+	}
+	if (&Retry{}).RetriesMethod(http.MethodGet) {
+		t.Error("RetriesMethod(GET) = true, want false without attempts")
+	}
+	if !r.RetriesStatus(http.StatusBadGateway) || r.RetriesStatus(http.StatusServiceUnavailable) {
+		t.Errorf("RetriesStatus() does not match the status codes %v", r.StatusCodes)
+	}
+}
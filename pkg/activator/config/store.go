@@ -28,7 +28,10 @@ type cfgKey struct{}
 
 // Config is a configuration for the activator
 type Config struct {
-	Tracing *tracingconfig.Config
+	Tracing       *tracingconfig.Config
+	LoadBalancing *LoadBalancing
+	Queueing      *Queueing
+	Retry         *Retry
 }
 
 // FromContext obtains a Config injected into the passed context
@@ -54,6 +57,7 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 			logger,
 			configmap.Constructors{
 				tracingconfig.ConfigName: tracingconfig.NewTracingConfigFromConfigMap,
+				ConfigName:               NewActivatorFromConfigMap,
 			},
 			onAfterStore...,
 		),
@@ -67,8 +71,12 @@ func (s *Store) ToContext(ctx context.Context) context.Context {
 
 // Load creates a Config for this store
 func (s *Store) Load() *Config {
+	activator := s.UntypedLoad(ConfigName).(*Activator)
 	return &Config{
-		Tracing: s.UntypedLoad(tracingconfig.ConfigName).(*tracingconfig.Config).DeepCopy(),
+		Tracing:       s.UntypedLoad(tracingconfig.ConfigName).(*tracingconfig.Config).DeepCopy(),
+		LoadBalancing: activator.LoadBalancing.DeepCopy(),
+		Queueing:      activator.Queueing.DeepCopy(),
+		Retry:         activator.Retry.DeepCopy(),
 	}
 }
 
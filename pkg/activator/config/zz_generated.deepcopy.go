@@ -24,6 +24,37 @@ import (
 	tracingconfig "knative.dev/pkg/tracing/config"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Activator) DeepCopyInto(out *Activator) {
+	*out = *in
+	if in.LoadBalancing != nil {
+		in, out := &in.LoadBalancing, &out.LoadBalancing
+		*out = new(LoadBalancing)
+		**out = **in
+	}
+	if in.Queueing != nil {
+		in, out := &in.Queueing, &out.Queueing
+		*out = new(Queueing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(Retry)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Activator.
+func (in *Activator) DeepCopy() *Activator {
+	if in == nil {
+		return nil
+	}
+	out := new(Activator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -32,6 +63,21 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(tracingconfig.Config)
 		**out = **in
 	}
+	if in.LoadBalancing != nil {
+		in, out := &in.LoadBalancing, &out.LoadBalancing
+		*out = new(LoadBalancing)
+		**out = **in
+	}
+	if in.Queueing != nil {
+		in, out := &in.Queueing, &out.Queueing
+		*out = new(Queueing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(Retry)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -44,3 +90,63 @@ func (in *Config) DeepCopy() *Config {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancing) DeepCopyInto(out *LoadBalancing) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancing.
+func (in *LoadBalancing) DeepCopy() *LoadBalancing {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Queueing) DeepCopyInto(out *Queueing) {
+	*out = *in
+	if in.NamespaceWeights != nil {
+		in, out := &in.NamespaceWeights, &out.NamespaceWeights
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Queueing.
+func (in *Queueing) DeepCopy() *Queueing {
+	if in == nil {
+		return nil
+	}
+	out := new(Queueing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Retry) DeepCopyInto(out *Retry) {
+	*out = *in
+	if in.StatusCodes != nil {
+		in, out := &in.StatusCodes, &out.StatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Retry.
+func (in *Retry) DeepCopy() *Retry {
+	if in == nil {
+		return nil
+	}
+	out := new(Retry)
+	in.DeepCopyInto(out)
+	return out
+}
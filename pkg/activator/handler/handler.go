@@ -16,17 +16,18 @@ limitations under the License.
 package handler
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strconv"
 	"time"
 
-	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	activatorconfig "knative.dev/serving/pkg/activator/config"
@@ -34,20 +35,19 @@ import (
 	"knative.dev/pkg/logging/logkey"
 	tracingconfig "knative.dev/pkg/tracing/config"
 	"knative.dev/serving/pkg/activator"
-	revnet "knative.dev/serving/pkg/activator/net"
+	activatornet "knative.dev/serving/pkg/activator/net"
 	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
-	netlisters "knative.dev/serving/pkg/client/listers/networking/v1alpha1"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1alpha1"
 	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/network"
-	"knative.dev/serving/pkg/network/prober"
+	"knative.dev/serving/pkg/otlp"
 	"knative.dev/serving/pkg/queue"
+	"knative.dev/serving/pkg/tracecontext"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/util/wait"
-	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // activationHandler will wait for an active endpoint for a revision
@@ -56,24 +56,19 @@ type activationHandler struct {
 	logger    *zap.SugaredLogger
 	transport http.RoundTripper
 	reporter  activator.StatsReporter
-	throttler *activator.Throttler
+	throttler *activatornet.Throttler
 
-	probeTimeout    time.Duration
-	probeTransport  http.RoundTripper
 	endpointTimeout time.Duration
 
 	revisionLister servinglisters.RevisionLister
-	serviceLister  corev1listers.ServiceLister
-	sksLister      netlisters.ServerlessServiceLister
 }
 
-// The default time we'll try to probe the revision for activation.
+// The default time we'll wait for the revision to have capacity.
 const defaulTimeout = 2 * time.Minute
 
 // New constructs a new http.Handler that deals with revision activation.
-func New(l *zap.SugaredLogger, r activator.StatsReporter, t *activator.Throttler,
-	rl servinglisters.RevisionLister, sl corev1listers.ServiceLister,
-	sksL netlisters.ServerlessServiceLister) http.Handler {
+func New(l *zap.SugaredLogger, r activator.StatsReporter, t *activatornet.Throttler,
+	rl servinglisters.RevisionLister) http.Handler {
 
 	return &activationHandler{
 		logger:          l,
@@ -81,72 +76,14 @@ func New(l *zap.SugaredLogger, r activator.StatsReporter, t *activator.Throttler
 		reporter:        r,
 		throttler:       t,
 		revisionLister:  rl,
-		sksLister:       sksL,
-		serviceLister:   sl,
-		probeTimeout:    defaulTimeout,
-		probeTransport:  network.NewProberTransport(),
 		endpointTimeout: defaulTimeout,
 	}
 }
 
-func withOrigProto(or *http.Request) prober.Preparer {
-	return func(r *http.Request) *http.Request {
-		r.Proto = or.Proto
-		r.ProtoMajor = or.ProtoMajor
-		r.ProtoMinor = or.ProtoMinor
-		return r
-	}
-}
-
-func (a *activationHandler) probeEndpoint(logger *zap.SugaredLogger, r *http.Request, target *url.URL, revID activator.RevisionID) (bool, int) {
-	var (
-		attempts int
-		st       = time.Now()
-		url      = target.String()
-	)
-
-	logger.Debugf("Actually will be probing %s", url)
-	config := activatorconfig.FromContext(r.Context())
-	if config.Tracing.Backend != tracingconfig.None {
-		// When we collect metrics, we're wrapping the RoundTripper
-		// the prober would use inside an annotating transport.
-		a.probeTransport = &ochttp.Transport{
-			Base: network.NewProberTransport(),
-		}
-	}
-
-	err := wait.PollImmediate(100*time.Millisecond, a.probeTimeout, func() (bool, error) {
-		attempts++
-		ret, err := prober.Do(
-			r.Context(),
-			a.probeTransport,
-			url,
-			prober.WithHeader(network.ProbeHeaderName, queue.Name),
-			prober.ExpectsBody(queue.Name),
-			prober.ExpectsStatusCodes([]int{http.StatusOK}),
-			withOrigProto(r))
-		if err != nil {
-			logger.Warnw("Pod probe failed", zap.Error(err))
-			return false, nil
-		}
-		if !ret {
-			logger.Warn("Pod probe unsuccessful")
-			return false, nil
-		}
-
-		// Cache probe success.
-		a.throttler.MarkProbe(revID)
-		return true, nil
-	})
-
-	logger.Debugf("Probing %s took %d attempts and %v time", target.String(), attempts, time.Since(st))
-	return (err == nil), attempts
-}
-
 func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	namespace := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderNamespace)
 	name := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderName)
-	revID := activator.RevisionID{Namespace: namespace, Name: name}
+	revID := types.NamespacedName{Namespace: namespace, Name: name}
 	logger := a.logger.With(zap.String(logkey.Key, revID.String()))
 
 	revision, err := a.revisionLister.Revisions(namespace).Get(name)
@@ -156,24 +93,22 @@ func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// SKS name matches that of revision.
-	sks, err := a.sksLister.ServerlessServices(namespace).Get(name)
-	if err != nil {
-		logger.Errorw("Error while getting SKS", zap.Error(err))
-		sendError(err, w)
-		return
-	}
-	host, err := a.serviceHostName(revision, sks.Status.PrivateServiceName)
-	if err != nil {
-		logger.Errorw("Error while getting hostname", zap.Error(err))
-		sendError(err, w)
+	// The queue-proxy enforces the time to first byte, but the idle and max
+	// duration timeouts must also free the activator's slot of the request.
+	if params := streamingTimeouts(revision); params.Idle > 0 || params.Max > 0 {
+		queue.TimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.serveRevision(logger, w, r, revision)
+		}), params, "request timeout").ServeHTTP(w, r)
 		return
 	}
+	a.serveRevision(logger, w, r, revision)
+}
 
-	target := &url.URL{
-		Scheme: "http",
-		Host:   host,
-	}
+// serveRevision waits for capacity of revision and proxies r to it.
+func (a *activationHandler) serveRevision(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request,
+	revision *v1alpha1.Revision) {
+	namespace, name := revision.Namespace, revision.Name
+	revID := types.NamespacedName{Namespace: namespace, Name: name}
 
 	tryContext, trySpan := trace.StartSpan(r.Context(), "throttler_try")
 	if a.endpointTimeout > 0 {
@@ -181,34 +116,56 @@ func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		tryContext, cancel = context.WithTimeout(tryContext, a.endpointTimeout)
 		defer cancel()
 	}
+	config := activatorconfig.FromContext(r.Context())
+	lb := config.LoadBalancing.ForRevision(revision.Annotations)
+	tryContext = activatornet.WithLoadBalancing(tryContext, lb.Policy, hashKey(r, lb))
+	tryContext = activatornet.WithQueueBudget(tryContext, queueBudget(config.Queueing, namespace))
+
+	configurationName := revision.Labels[serving.ConfigurationLabelKey]
+	serviceName := revision.Labels[serving.ServiceLabelKey]
+	// Report the queue depth as requests enter it too, so that it is up to date
+	// while they wait for the first pod of a cold start.
+	tryContext = activatornet.WithQueueObserver(tryContext, func(queued int) {
+		a.reporter.ReportQueuedRequests(namespace, serviceName, configurationName, name, int64(queued))
+	})
 
-	err = a.throttler.Try(tryContext, revID, func() {
+	retry, body, err := bufferBody(r, config.Retry)
+	if err != nil {
 		trySpan.End()
+		logger.Errorw("Error reading the request body", zap.Error(err))
+		http.Error(w, "error reading the request body", http.StatusBadRequest)
+		return
+	}
 
-		// This opportunistically caches the probes, so
-		// a few concurrent requests might result in concurrent probes
-		// but requests coming after won't. When cached 0 attempts were required.
-		success, attempts := true, 0
-		if a.throttler.ShouldProbe(revID) {
-			probeCtx, probeSpan := trace.StartSpan(r.Context(), "probe")
-			success, attempts = a.probeEndpoint(logger, r.WithContext(probeCtx), target, revID)
-			probeSpan.End()
+	// The throttler only hands out dests that have been probed as healthy by the
+	// revision backends manager, so we can send the request right away.
+	attempts := 0
+	err = a.throttler.Try(tryContext, revID, func(dest string) error {
+		if attempts == 0 {
+			trySpan.End()
 		}
-		var httpStatus int
-		if success {
-			// Once we see a successful probe, send traffic.
-			attempts++
-			proxyCtx, proxySpan := trace.StartSpan(r.Context(), "proxy")
-			httpStatus = a.proxyRequest(logger, w, r.WithContext(proxyCtx), target)
-			proxySpan.End()
-		} else {
-			httpStatus = http.StatusInternalServerError
-			w.WriteHeader(httpStatus)
+		attempts++
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		attemptRetry := retry
+		if retry != nil && attempts > retry.Attempts {
+			attemptRetry = nil
+		}
+
+		target := &url.URL{
+			Scheme: "http",
+			Host:   dest,
+		}
+		proxyCtx, proxySpan := trace.StartSpan(r.Context(), "proxy")
+		httpStatus, retried := a.proxyRequest(logger, w, r.WithContext(proxyCtx), target, attemptRetry)
+		proxySpan.End()
+		if retried {
+			return activatornet.ErrRetry
 		}
 
-		configurationName := revision.Labels[serving.ConfigurationLabelKey]
-		serviceName := revision.Labels[serving.ServiceLabelKey]
 		a.reporter.ReportRequestCount(namespace, serviceName, configurationName, name, httpStatus, attempts)
+		return nil
 	})
 	if err != nil {
 		// Set error on our capacity waiting span and end it
@@ -217,33 +174,56 @@ func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}, "ThrottlerTry")
 		trySpan.End()
 
-		if err == activator.ErrActivatorOverload {
-			http.Error(w, activator.ErrActivatorOverload.Error(), http.StatusServiceUnavailable)
-		} else {
+		switch err {
+		case activatornet.ErrRequestThrottled:
+			// The request never made it into the queue, so the queueing state is unchanged.
+			w.Header().Set("Retry-After", retryAfter(config.Queueing.RetryAfter))
+			http.Error(w, activatornet.ErrRequestThrottled.Error(), http.StatusTooManyRequests)
+			a.reporter.ReportRequestCount(namespace, serviceName, configurationName, name, http.StatusTooManyRequests, 0)
+		case activatornet.ErrActivatorOverload:
+			http.Error(w, activatornet.ErrActivatorOverload.Error(), http.StatusServiceUnavailable)
+		default:
 			w.WriteHeader(http.StatusInternalServerError)
 			logger.Errorw("Error processing request in the activator", zap.Error(err))
 		}
 	}
 }
 
-func (a *activationHandler) proxyRequest(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request, target *url.URL) int {
+// proxyRequest proxies r to target and returns the response code. When retry is not nil
+// and the attempt fails in a way the policy retries on, it writes nothing and reports
+// that the request is to be retried instead.
+func (a *activationHandler) proxyRequest(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request,
+	target *url.URL, retry *activatorconfig.Retry) (int, bool) {
 	network.RewriteHostIn(r)
 
 	// Setup the reverse proxy.
 	proxy := httputil.NewSingleHostReverseProxy(target)
 	proxy.Transport = a.transport
-	if config := activatorconfig.FromContext(r.Context()); config.Tracing.Backend != tracingconfig.None {
+	if config := activatorconfig.FromContext(r.Context()); config.Tracing.Backend != tracingconfig.None || otlp.Enabled() {
 		// When we collect metrics, we're wrapping the RoundTripper
 		// the proxy would use inside an annotating transport.
-		proxy.Transport = &ochttp.Transport{
-			Base: a.transport,
-		}
+		proxy.Transport = tracecontext.NewTransport(a.transport)
 	}
 	proxy.FlushInterval = -1
+	retried := false
 	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		// Don't retry when the client is gone or the request timed out.
+		if retry != nil && (err == errRetryStatus || req.Context().Err() == nil) {
+			logger.Debugf("Retrying request after: %v", err)
+			retried = true
+			return
+		}
 		logger.Infof("error reverse proxying request: %v", err)
 		http.Error(w, err.Error(), http.StatusBadGateway)
 	}
+	if retry != nil {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			if retry.RetriesStatus(resp.StatusCode) {
+				return errRetryStatus
+			}
+			return nil
+		}
+	}
 
 	r.Header.Set(network.ProxyHeaderName, activator.Name)
 
@@ -251,26 +231,92 @@ func (a *activationHandler) proxyRequest(logger *zap.SugaredLogger, w http.Respo
 
 	recorder := pkghttp.NewResponseRecorder(w, http.StatusOK)
 	proxy.ServeHTTP(recorder, r)
-	return recorder.ResponseCode
+	return recorder.ResponseCode, retried
 }
 
-// serviceHostName obtains the hostname of the underlying service and the correct
-// port to send requests to.
-func (a *activationHandler) serviceHostName(rev *v1alpha1.Revision, serviceName string) (string, error) {
-	svc, err := a.serviceLister.Services(rev.Namespace).Get(serviceName)
+// streamingTimeouts returns the idle and max duration timeouts of the revision.
+func streamingTimeouts(revision *v1alpha1.Revision) queue.TimeoutParams {
+	var params queue.TimeoutParams
+	if revision.Spec.IdleTimeoutSeconds != nil {
+		params.Idle = time.Duration(*revision.Spec.IdleTimeoutSeconds) * time.Second
+	}
+	if revision.Spec.MaxDurationSeconds != nil {
+		params.Max = time.Duration(*revision.Spec.MaxDurationSeconds) * time.Second
+	}
+	return params
+}
+
+// errRetryStatus makes the proxy drop a response whose code is retried on.
+var errRetryStatus = errors.New("response status to retry on")
+
+// bufferBody returns the retry policy of r, or nil if it is not to be retried.
+// To be able to send them again, it buffers the bodies of the requests to be
+// retried, and returns them. The requests with bodies larger than the policy
+// allows are not retried.
+func bufferBody(r *http.Request, retry *activatorconfig.Retry) (*activatorconfig.Retry, []byte, error) {
+	if !retry.RetriesMethod(r.Method) {
+		return nil, nil, nil
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return retry, nil, nil
+	}
+	if r.ContentLength > retry.MaxBodyBytes {
+		return nil, nil, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, retry.MaxBodyBytes+1))
 	if err != nil {
-		return "", err
+		return nil, nil, err
+	}
+	if int64(len(body)) > retry.MaxBodyBytes {
+		// Too large to buffer, send what was read followed by the rest.
+		r.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(body), r.Body),
+			Closer: r.Body,
+		}
+		return nil, nil, nil
 	}
+	r.Body.Close()
+	return retry, body, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
 
-	// Search for the appropriate port.
-	port, ok := revnet.GetServicePort(rev.GetProtocol(), svc)
-	if !ok {
-		return "", errors.New("revision needs external HTTP port")
+// hashKey returns the value of the request the consistent-hash policy routes on.
+func hashKey(r *http.Request, lb *activatorconfig.LoadBalancing) string {
+	if lb.Policy != serving.ConsistentHashPolicy {
+		return ""
 	}
+	if lb.HashHeader != "" {
+		if v := r.Header.Get(lb.HashHeader); v != "" {
+			return v
+		}
+	}
+	if lb.HashCookie != "" {
+		if c, err := r.Cookie(lb.HashCookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// queueBudget returns the share of the activator queue the requests to the
+// namespace may use.
+func queueBudget(q *activatorconfig.Queueing, namespace string) activatornet.QueueBudget {
+	return activatornet.QueueBudget{
+		Total:     q.QueueDepth,
+		Namespace: q.NamespaceQueueDepth,
+		Revision:  q.RevisionQueueDepth,
+		Weight:    q.WeightOf(namespace),
+	}
+}
 
-	// Use the ClusterIP directly to elide DNS lookup, which both adds latency
-	// and hurts reliability when routing through the activator.
-	return net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(port)), nil
+// retryAfter formats d as the seconds of a Retry-After header, rounding up.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
 }
 
 func sendError(err error, w http.ResponseWriter) {
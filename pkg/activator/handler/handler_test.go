@@ -16,6 +16,7 @@ limitations under the License.
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -31,8 +32,6 @@ import (
 
 	activatorconfig "knative.dev/serving/pkg/activator/config"
 
-	"knative.dev/pkg/test/helpers"
-
 	"github.com/google/go-cmp/cmp"
 
 	. "knative.dev/pkg/logging/testing"
@@ -41,24 +40,22 @@ import (
 	tracingconfig "knative.dev/pkg/tracing/config"
 	tracetesting "knative.dev/pkg/tracing/testing"
 	"knative.dev/serving/pkg/activator"
+	activatornet "knative.dev/serving/pkg/activator/net"
 	activatortest "knative.dev/serving/pkg/activator/testing"
-	nv1a1 "knative.dev/serving/pkg/apis/networking/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving/v1beta1"
 	servingfake "knative.dev/serving/pkg/client/clientset/versioned/fake"
 	servinginformers "knative.dev/serving/pkg/client/informers/externalversions"
-	netlisters "knative.dev/serving/pkg/client/listers/networking/v1alpha1"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1alpha1"
 	"knative.dev/serving/pkg/network"
 	"knative.dev/serving/pkg/queue"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	kubeinformers "k8s.io/client-go/informers"
-	corev1informers "k8s.io/client-go/informers/core/v1"
 	kubefake "k8s.io/client-go/kubernetes/fake"
-	corev1listers "k8s.io/client-go/listers/core/v1"
 	. "knative.dev/pkg/configmap/testing"
 )
 
@@ -73,193 +70,111 @@ func TestActivationHandler(t *testing.T) {
 	defer ClearAll()
 
 	tests := []struct {
-		label             string
-		namespace         string
-		name              string
-		wantBody          string
-		wantCode          int
-		wantErr           error
-		probeErr          error
-		probeCode         int
-		probeResp         []string
-		probeTimeout      time.Duration
-		endpointsInformer corev1informers.EndpointsInformer
-		sksLister         netlisters.ServerlessServiceLister
-		svcLister         corev1listers.ServiceLister
-		reporterCalls     []reporterCall
+		label           string
+		namespace       string
+		name            string
+		wantBody        string
+		wantCode        int
+		wantErr         error
+		dests           []string
+		endpointTimeout time.Duration
+		reporterCalls   []reporterCall
 	}{{
-		label:             "active endpoint",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          "everything good!",
-		wantCode:          http.StatusOK,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		reporterCalls: []reporterCall{{
-			Op:         "ReportRequestCount",
-			Namespace:  testNamespace,
-			Revision:   testRevName,
-			Service:    "service-real-name",
-			Config:     "config-real-name",
-			StatusCode: http.StatusOK,
-			Attempts:   2, // probe + request
-			Value:      1,
-		}},
-		probeTimeout: 100 * time.Millisecond,
-	}, {
-		label:             "slowly active endpoint",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          "everything good!",
-		wantCode:          http.StatusOK,
-		wantErr:           nil,
-		probeResp:         []string{activator.Name, queue.Name},
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		reporterCalls: []reporterCall{{
-			Op:         "ReportRequestCount",
-			Namespace:  testNamespace,
-			Revision:   testRevName,
-			Service:    "service-real-name",
-			Config:     "config-real-name",
-			StatusCode: http.StatusOK,
-			Attempts:   3, // probe + probe + request
-			Value:      1,
-		}},
-		probeTimeout: 201 * time.Millisecond,
-	}, {
-		label:             "active endpoint with missing count header",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          "everything good!",
-		wantCode:          http.StatusOK,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
+		label:     "active endpoint",
+		namespace: testNamespace,
+		name:      testRevName,
+		wantBody:  wantBody,
+		wantCode:  http.StatusOK,
+		wantErr:   nil,
+		dests:     podDests(1),
 		reporterCalls: []reporterCall{{
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
+			Value:     1,
+		}, {
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
+		}, {
 			Op:         "ReportRequestCount",
 			Namespace:  testNamespace,
 			Revision:   testRevName,
 			Service:    "service-real-name",
 			Config:     "config-real-name",
 			StatusCode: http.StatusOK,
-			Attempts:   2, // one probe call, one proxy call.
+			Attempts:   1,
 			Value:      1,
 		}},
 	}, {
-		label:             "no active endpoint",
-		namespace:         "fake-namespace",
-		name:              "fake-name",
-		wantBody:          errMsg("revision.serving.knative.dev \"fake-name\" not found"),
-		wantCode:          http.StatusNotFound,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		reporterCalls:     nil,
-	}, {
-		label:             "active endpoint (probe failure)",
-		namespace:         testNamespace,
-		name:              testRevName,
-		probeErr:          errors.New("probe error"),
-		wantCode:          http.StatusInternalServerError,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		probeTimeout:      1 * time.Millisecond,
-		reporterCalls: []reporterCall{{
-			Op:         "ReportRequestCount",
-			Namespace:  testNamespace,
-			Revision:   testRevName,
-			Service:    "service-real-name",
-			Config:     "config-real-name",
-			StatusCode: http.StatusInternalServerError,
-			Attempts:   2, // On failed probe we'll always try twice.
-			Value:      1,
-		}},
+		label:     "no active endpoint",
+		namespace: "fake-namespace",
+		name:      "fake-name",
+		wantBody:  errMsg("revision.serving.knative.dev \"fake-name\" not found"),
+		wantCode:  http.StatusNotFound,
+		wantErr:   nil,
+		dests:     podDests(1),
 	}, {
-		label:             "active endpoint (probe 500)",
-		namespace:         testNamespace,
-		name:              testRevName,
-		probeCode:         http.StatusServiceUnavailable,
-		wantCode:          http.StatusInternalServerError,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		probeTimeout:      10 * time.Millisecond,
+		label:           "no healthy pods",
+		namespace:       testNamespace,
+		name:            testRevName,
+		wantBody:        activatornet.ErrActivatorOverload.Error() + "\n",
+		wantCode:        http.StatusServiceUnavailable,
+		endpointTimeout: 10 * time.Millisecond,
 		reporterCalls: []reporterCall{{
-			Op:         "ReportRequestCount",
-			Namespace:  testNamespace,
-			Revision:   testRevName,
-			Service:    "service-real-name",
-			Config:     "config-real-name",
-			StatusCode: http.StatusInternalServerError,
-			Attempts:   2,
-			Value:      1,
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
+			Value:     1,
+		}, {
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
 		}},
 	}, {
-		label:             "request error",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          "request error\n",
-		wantCode:          http.StatusBadGateway,
-		wantErr:           errors.New("request error"),
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
+		label:     "request error",
+		namespace: testNamespace,
+		name:      testRevName,
+		wantBody:  "request error\n",
+		wantCode:  http.StatusBadGateway,
+		wantErr:   errors.New("request error"),
+		dests:     podDests(1),
 		reporterCalls: []reporterCall{{
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
+			Value:     1,
+		}, {
+			Op:        "ReportQueuedRequests",
+			Namespace: testNamespace,
+			Revision:  testRevName,
+			Service:   "service-real-name",
+			Config:    "config-real-name",
+		}, {
 			Op:         "ReportRequestCount",
 			Namespace:  testNamespace,
 			Revision:   testRevName,
 			Service:    "service-real-name",
 			Config:     "config-real-name",
 			StatusCode: http.StatusBadGateway,
-			Attempts:   2, // probe + actual request.
+			Attempts:   1,
 			Value:      1,
 		}},
-	}, {
-		label:             "broken get SKS",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          errMsg("serverlessservice.networking.internal.knative.dev \"real-name\" not found"),
-		wantCode:          http.StatusNotFound,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		sksLister:         sksLister(sks("bogus-namespace", testRevName)),
-		reporterCalls:     nil,
-	}, {
-		label:             "k8s svc incorrectly spec'd",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          errMsg("revision needs external HTTP port"),
-		wantCode:          http.StatusInternalServerError,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints(testNamespace, testRevName, 1000)),
-		svcLister:         serviceLister(service(testNamespace, testRevName, "bogus")),
-		reporterCalls:     nil,
-	}, {
-		label:             "broken get k8s svc",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          errMsg("service \"real-name\" not found"),
-		wantCode:          http.StatusNotFound,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints("bogus-namespace", testRevName, 1000)),
-		svcLister:         serviceLister(service("bogus-namespace", testRevName, "http")),
-		reporterCalls:     nil,
-	}, {
-		label:             "broken get endpoints",
-		namespace:         testNamespace,
-		name:              testRevName,
-		wantBody:          "",
-		wantCode:          http.StatusInternalServerError,
-		wantErr:           nil,
-		endpointsInformer: endpointsInformer(endpoints("bogus-namespace", testRevName, 1000)),
-		reporterCalls:     nil,
 	}}
 	for _, test := range tests {
 		t.Run(test.label, func(t *testing.T) {
-			probeResponses := make([]activatortest.FakeResponse, len(test.probeResp))
-			for i := 0; i < len(test.probeResp); i++ {
-				probeResponses[i] = activatortest.FakeResponse{
-					Err:  test.probeErr,
-					Code: test.probeCode,
-					Body: test.probeResp[i],
-				}
-			}
 			fakeRt := activatortest.FakeRoundTripper{
-				ExpectHost:     "test-host",
-				ProbeResponses: probeResponses,
+				ExpectHost: "test-host",
 				RequestResponse: &activatortest.FakeResponse{
 					Err:  test.wantErr,
 					Code: test.wantCode,
@@ -268,31 +183,17 @@ func TestActivationHandler(t *testing.T) {
 			}
 			reporter := &fakeReporter{}
 			params := queue.BreakerParams{QueueDepth: 1000, MaxConcurrency: 1000, InitialCapacity: 0}
-			throttler := activator.NewThrottler(
-				params,
-				test.endpointsInformer,
-				sksLister(sks(testNamespace, testRevName)),
-				revisionLister(revision(testNamespace, testRevName)),
-				TestLogger(t))
+			throttler := newTestThrottler(t, params, map[string][]string{testRevName: test.dests})
 
 			handler := (New(TestLogger(t), reporter, throttler,
 				revisionLister(revision(testNamespace, testRevName)),
-				serviceLister(service(testNamespace, testRevName, "http")),
-				sksLister(sks(testNamespace, testRevName)),
 			)).(*activationHandler)
-			handler.probeTimeout = test.probeTimeout
+			if test.endpointTimeout != 0 {
+				handler.endpointTimeout = test.endpointTimeout
+			}
 
 			// Setup transports.
-			rt := network.RoundTripperFunc(fakeRt.RT)
-			handler.transport = rt
-			handler.probeTransport = rt
-
-			if test.sksLister != nil {
-				handler.sksLister = test.sksLister
-			}
-			if test.svcLister != nil {
-				handler.serviceLister = test.svcLister
-			}
+			handler.transport = network.RoundTripperFunc(fakeRt.RT)
 
 			resp := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
@@ -322,58 +223,333 @@ func TestActivationHandler(t *testing.T) {
 	}
 }
 
-func TestActivationHandlerProbeCaching(t *testing.T) {
-	namespace := testNamespace
-	revName := testRevName
-	revID := activator.RevisionID{Namespace: namespace, Name: revName}
-
-	fakeRT := activatortest.FakeRoundTripper{}
-	rt := network.RoundTripperFunc(fakeRT.RT)
+// Make sure concurrent requests are sent to different pods.
+func TestActivationHandlerPodSpread(t *testing.T) {
+	const requests = 3
+	namespace, revName := testNamespace, testRevName
 
-	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
-	reporter := &fakeReporter{}
+	hostCh := make(chan string, requests)
+	releaseCh := make(chan struct{})
+	rt := network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		hostCh <- r.URL.Host
+		<-releaseCh
+		return httptest.NewRecorder().Result(), nil
+	})
 
-	throttler := activator.NewThrottler(
-		breakerParams,
-		endpointsInformer(endpoints(namespace, revName, breakerParams.InitialCapacity)),
-		sksLister(sks(namespace, revName)),
-		revisionLister(revision(namespace, revName)),
-		TestLogger(t))
+	// Each pod handles a single request at a time.
+	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 0}
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{revName: podDests(requests)})
 
-	handler := (New(TestLogger(t), reporter, throttler,
+	handler := (New(TestLogger(t), &fakeReporter{}, throttler,
 		revisionLister(revision(namespace, revName)),
-		serviceLister(service(namespace, revName, "http")),
-		sksLister(sks(namespace, revName)),
 	)).(*activationHandler)
-
-	// Setup transports.
 	handler.transport = rt
-	handler.probeTransport = rt
 
-	// set up config store to populate context
 	configStore := setupConfigStore(t)
+	respCh := make(chan *httptest.ResponseRecorder, requests)
+	sendRequests(requests, namespace, revName, respCh, handler, configStore)
 
-	sendRequest(namespace, revName, handler, configStore)
-	if fakeRT.NumProbes != 1 {
-		t.Errorf("NumProbes = %d, want: %d", fakeRT.NumProbes, 1)
+	got := make(map[string]bool, requests)
+	for i := 0; i < requests; i++ {
+		select {
+		case host := <-hostCh:
+			got[host] = true
+		case <-time.After(3 * time.Second):
+			t.Fatal("Timed out waiting for a request to be intercepted")
+		}
+	}
+	close(releaseCh)
+	for i := 0; i < requests; i++ {
+		<-respCh
 	}
 
-	sendRequest(namespace, revName, handler, configStore)
-	// Assert that we didn't reprobe
-	if fakeRT.NumProbes != 1 {
-		t.Errorf("NumProbes = %d, want: %d", fakeRT.NumProbes, 1)
+	want := make(map[string]bool, requests)
+	for _, dest := range podDests(requests) {
+		want[dest] = true
 	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Requested pods = %v, want: %v", got, want)
+	}
+}
+
+func TestActivationHandlerConsistentHash(t *testing.T) {
+	namespace, revName := testNamespace, testRevName
 
-	// Dropping the capacity to 0 causes the next request to probe again.
-	throttler.UpdateCapacity(revID, 0)
-	time.AfterFunc(100*time.Millisecond, func() {
-		// Asynchronously updating the capacity to let the request through.
-		throttler.UpdateCapacity(revID, 1)
+	hostCh := make(chan string, 1)
+	rt := network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		hostCh <- r.URL.Host
+		return httptest.NewRecorder().Result(), nil
 	})
 
-	sendRequest(namespace, revName, handler, configStore)
-	if fakeRT.NumProbes != 2 {
-		t.Errorf("NumProbes = %d, want: %d", fakeRT.NumProbes, 2)
+	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 0}
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{revName: podDests(5)})
+
+	rev := revision(namespace, revName)
+	rev.Annotations = map[string]string{
+		serving.LoadBalancingPolicyAnnotationKey:     string(serving.ConsistentHashPolicy),
+		serving.LoadBalancingHashHeaderAnnotationKey: "X-User",
+		serving.LoadBalancingHashCookieAnnotationKey: "session",
+	}
+	handler := (New(TestLogger(t), &fakeReporter{}, throttler, revisionLister(rev))).(*activationHandler)
+	handler.transport = rt
+	configStore := setupConfigStore(t)
+
+	send := func(header, cookie string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+		req.Header.Set(activator.RevisionHeaderNamespace, namespace)
+		req.Header.Set(activator.RevisionHeaderName, revName)
+		if header != "" {
+			req.Header.Set("X-User", header)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(configStore.ToContext(req.Context())))
+		select {
+		case host := <-hostCh:
+			return host
+		case <-time.After(3 * time.Second):
+			t.Fatal("Timed out waiting for a request to be intercepted")
+			return ""
+		}
+	}
+
+	pods := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		byHeader := send(user, "")
+		if got := send(user, ""); got != byHeader {
+			t.Errorf("Requests of %s went to %s and %s, want the same pod", user, byHeader, got)
+		}
+		if got := send("", user); got != byHeader {
+			t.Errorf("Request with cookie %s went to %s, want: %s", user, got, byHeader)
+		}
+		pods[byHeader] = true
+	}
+	if len(pods) < 2 {
+		t.Errorf("All the users went to %v, want them spread across the pods", pods)
+	}
+}
+
+func TestActivationHandlerQueueBudget(t *testing.T) {
+	revID := types.NamespacedName{Namespace: testNamespace, Name: testRevName}
+	reporter := &fakeReporter{}
+	params := queue.BreakerParams{QueueDepth: 1000, MaxConcurrency: 1000, InitialCapacity: 0}
+	// Without healthy pods the requests stay queued until they time out.
+	throttler := newTestThrottler(t, params, map[string][]string{testRevName: nil})
+	handler := (New(TestLogger(t), reporter, throttler,
+		revisionLister(revision(testNamespace, testRevName)),
+	)).(*activationHandler)
+	handler.endpointTimeout = time.Second
+
+	configStore := setupConfigStore(t)
+	configStore.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: activatorconfig.ConfigName,
+		},
+		Data: map[string]string{
+			"revision-queue-depth": "1",
+			"retry-after":          "2500ms",
+		},
+	})
+
+	queuedCh := make(chan *httptest.ResponseRecorder)
+	go func() {
+		queuedCh <- sendRequest(testNamespace, testRevName, handler, configStore)
+	}()
+	for throttler.QueuedRequests(revID) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	resp := sendRequest(testNamespace, testRevName, handler, configStore)
+	if got, want := resp.Code, http.StatusTooManyRequests; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	if got, want := resp.Header().Get("Retry-After"), "3"; got != want {
+		t.Errorf("Retry-After = %q, want: %q", got, want)
+	}
+	// The queued request was reported as it entered the queue, the throttled one never did.
+	wantCalls := []reporterCall{{
+		Op:        "ReportQueuedRequests",
+		Namespace: testNamespace,
+		Revision:  testRevName,
+		Service:   "service-real-name",
+		Config:    "config-real-name",
+		Value:     1,
+	}, {
+		Op:         "ReportRequestCount",
+		Namespace:  testNamespace,
+		Revision:   testRevName,
+		Service:    "service-real-name",
+		Config:     "config-real-name",
+		StatusCode: http.StatusTooManyRequests,
+		Value:      1,
+	}}
+	reporter.mux.Lock()
+	if diff := cmp.Diff(wantCalls, reporter.calls); diff != "" {
+		t.Errorf("Reporting calls are different (-want, +got) = %v", diff)
+	}
+	reporter.mux.Unlock()
+
+	if got, want := (<-queuedCh).Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("StatusCode = %d, want: %d for the queued request", got, want)
+	}
+}
+
+func TestActivationHandlerRetry(t *testing.T) {
+	type attempt struct {
+		host string
+		body string
+	}
+	for _, tc := range []struct {
+		name         string
+		config       map[string]string
+		method       string
+		body         string
+		responses    []activatortest.FakeResponse
+		wantCode     int
+		wantAttempts int
+	}{{
+		name:   "retries a 502 on another pod",
+		config: map[string]string{"retry-attempts": "1"},
+		method: http.MethodPut,
+		body:   "payload",
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusBadGateway},
+			{Code: http.StatusOK, Body: wantBody},
+		},
+		wantCode:     http.StatusOK,
+		wantAttempts: 2,
+	}, {
+		name:   "retries a connection error",
+		config: map[string]string{"retry-attempts": "1"},
+		method: http.MethodGet,
+		responses: []activatortest.FakeResponse{
+			{Err: errors.New("connection reset by peer")},
+			{Code: http.StatusOK, Body: wantBody},
+		},
+		wantCode:     http.StatusOK,
+		wantAttempts: 2,
+	}, {
+		name:   "gives up after the attempts",
+		config: map[string]string{"retry-attempts": "2"},
+		method: http.MethodGet,
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusServiceUnavailable},
+			{Code: http.StatusServiceUnavailable},
+			{Code: http.StatusServiceUnavailable},
+		},
+		wantCode:     http.StatusServiceUnavailable,
+		wantAttempts: 3,
+	}, {
+		name:   "status code not retried",
+		config: map[string]string{"retry-attempts": "1", "retry-status-codes": "502"},
+		method: http.MethodGet,
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusServiceUnavailable},
+		},
+		wantCode:     http.StatusServiceUnavailable,
+		wantAttempts: 1,
+	}, {
+		name:   "retries disabled",
+		method: http.MethodGet,
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusBadGateway},
+		},
+		wantCode:     http.StatusBadGateway,
+		wantAttempts: 1,
+	}, {
+		name:   "method not idempotent",
+		config: map[string]string{"retry-attempts": "1"},
+		method: http.MethodPost,
+		body:   "payload",
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusBadGateway},
+		},
+		wantCode:     http.StatusBadGateway,
+		wantAttempts: 1,
+	}, {
+		name:   "body too large to buffer",
+		config: map[string]string{"retry-attempts": "1", "retry-max-body-bytes": "4"},
+		method: http.MethodPut,
+		body:   "payload",
+		responses: []activatortest.FakeResponse{
+			{Code: http.StatusBadGateway},
+		},
+		wantCode:     http.StatusBadGateway,
+		wantAttempts: 1,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mux      sync.Mutex
+				attempts []attempt
+			)
+			rt := network.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				mux.Lock()
+				defer mux.Unlock()
+				var body []byte
+				if req.Body != nil {
+					body, _ = ioutil.ReadAll(req.Body)
+				}
+				attempts = append(attempts, attempt{host: req.URL.Host, body: string(body)})
+				resp := tc.responses[len(attempts)-1]
+				if resp.Err != nil {
+					return nil, resp.Err
+				}
+				rec := httptest.NewRecorder()
+				rec.WriteHeader(resp.Code)
+				rec.WriteString(resp.Body)
+				return rec.Result(), nil
+			})
+
+			reporter := &fakeReporter{}
+			params := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
+			throttler := newTestThrottler(t, params, map[string][]string{testRevName: podDests(2)})
+			handler := (New(TestLogger(t), reporter, throttler,
+				revisionLister(revision(testNamespace, testRevName)),
+			)).(*activationHandler)
+			handler.transport = rt
+
+			configStore := setupConfigStore(t)
+			configStore.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: activatorconfig.ConfigName,
+				},
+				Data: tc.config,
+			})
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "http://example.com", strings.NewReader(tc.body))
+			req.Header.Set(activator.RevisionHeaderNamespace, testNamespace)
+			req.Header.Set(activator.RevisionHeaderName, testRevName)
+			handler.ServeHTTP(resp, req.WithContext(configStore.ToContext(req.Context())))
+
+			if got, want := resp.Code, tc.wantCode; got != want {
+				t.Errorf("StatusCode = %d, want: %d", got, want)
+			}
+			if got, want := len(attempts), tc.wantAttempts; got != want {
+				t.Fatalf("Attempts = %d, want: %d", got, want)
+			}
+			for i, a := range attempts {
+				if a.body != tc.body {
+					t.Errorf("Body of attempt %d = %q, want: %q", i+1, a.body, tc.body)
+				}
+			}
+			if len(attempts) > 1 && attempts[0].host == attempts[1].host {
+				t.Errorf("Retried on the same pod %s, want another one", attempts[0].host)
+			}
+
+			var counts []reporterCall
+			for _, call := range reporter.calls {
+				if call.Op == "ReportRequestCount" {
+					counts = append(counts, call)
+				}
+			}
+			if len(counts) != 1 || counts[0].Attempts != tc.wantAttempts || counts[0].StatusCode != tc.wantCode {
+				t.Errorf("ReportRequestCount calls = %+v, want one with %d attempts and code %d", counts, tc.wantAttempts, tc.wantCode)
+			}
+		})
 	}
 }
 
@@ -401,22 +577,14 @@ func TestActivationHandlerOverflow(t *testing.T) {
 	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
 	reporter := &fakeReporter{}
 
-	throttler := activator.NewThrottler(
-		breakerParams,
-		endpointsInformer(endpoints(namespace, revName, breakerParams.InitialCapacity)),
-		sksLister(sks(namespace, revName)),
-		revisionLister(revision(namespace, revName)),
-		TestLogger(t))
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{revName: podDests(breakerParams.InitialCapacity)})
 
 	handler := (New(TestLogger(t), reporter, throttler,
 		revisionLister(revision(namespace, revName)),
-		serviceLister(service(namespace, revName, "http")),
-		sksLister(sks(namespace, revName)),
 	)).(*activationHandler)
 
 	// Setup transports.
 	handler.transport = rt
-	handler.probeTransport = rt
 
 	// set up config store to populate context
 	configStore := setupConfigStore(t)
@@ -439,15 +607,15 @@ func TestActivationHandlerOverflowSeveralRevisions(t *testing.T) {
 
 	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
 	reporter := &fakeReporter{}
-	epClient := endpointsInformer(endpoints(testNamespace, rev1, breakerParams.InitialCapacity), endpoints(testNamespace, rev2, breakerParams.InitialCapacity))
-	sksClient := sksLister(sks(testNamespace, rev1), sks(testNamespace, rev2))
 	revClient := revisionLister(revision(testNamespace, rev1), revision(testNamespace, rev2))
-	svcClient := serviceLister(service(testNamespace, rev1, "http"), service(testNamespace, rev2, "http"))
 
 	respCh := make(chan *httptest.ResponseRecorder, overallRequests)
 	lockerCh := make(chan struct{})
 
-	throttler := activator.NewThrottler(breakerParams, epClient, sksClient, revClient, TestLogger(t))
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{
+		rev1: podDests(breakerParams.InitialCapacity),
+		rev2: podDests(breakerParams.InitialCapacity),
+	})
 
 	fakeRT := activatortest.FakeRoundTripper{
 		LockerCh: lockerCh,
@@ -458,12 +626,10 @@ func TestActivationHandlerOverflowSeveralRevisions(t *testing.T) {
 		},
 	}
 	rt := network.RoundTripperFunc(fakeRT.RT)
-	handler := (New(TestLogger(t), reporter, throttler,
-		revClient, svcClient, sksClient)).(*activationHandler)
+	handler := (New(TestLogger(t), reporter, throttler, revClient)).(*activationHandler)
 
 	// Setup transports.
 	handler.transport = rt
-	handler.probeTransport = rt
 
 	// set up config store to populate context
 	configStore := setupConfigStore(t)
@@ -484,31 +650,15 @@ func TestActivationHandlerProxyHeader(t *testing.T) {
 		fake := httptest.NewRecorder()
 		return fake.Result(), nil
 	})
-	throttler := activator.NewThrottler(
-		breakerParams,
-		endpointsInformer(endpoints(namespace, revName, breakerParams.InitialCapacity)),
-		sksLister(sks(namespace, revName)),
-		revisionLister(revision(namespace, revName)),
-		TestLogger(t))
-
-	fakeRT := activatortest.FakeRoundTripper{
-		RequestResponse: &activatortest.FakeResponse{
-			Err:  nil,
-			Code: http.StatusOK,
-			Body: wantBody,
-		},
-	}
-	probeRt := network.RoundTripperFunc(fakeRT.RT)
+	dests := podDests(1)
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{revName: dests})
 
 	handler := &activationHandler{
 		transport:      rt,
-		probeTransport: probeRt,
 		logger:         TestLogger(t),
 		reporter:       &fakeReporter{},
 		throttler:      throttler,
 		revisionLister: revisionLister(revision(testNamespace, testRevName)),
-		serviceLister:  serviceLister(service(testNamespace, testRevName, "http")),
-		sksLister:      sksLister(sks(testNamespace, testRevName)),
 	}
 
 	writer := httptest.NewRecorder()
@@ -526,11 +676,72 @@ func TestActivationHandlerProxyHeader(t *testing.T) {
 		if got := httpReq.Header.Get(network.ProxyHeaderName); got != activator.Name {
 			t.Errorf("Header '%s' does not have the expected value. Want = '%s', got = '%s'.", network.ProxyHeaderName, activator.Name, got)
 		}
+		if got, want := httpReq.URL.Host, dests[0]; got != want {
+			t.Errorf("Request host = %q, want: %q", got, want)
+		}
 	case <-time.After(5 * time.Second):
 		t.Fatal("Timed out waiting for a request to be intercepted")
 	}
 }
 
+func TestActivationHandlerIdleTimeout(t *testing.T) {
+	breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
+	rt := network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// The body streams a chunk and stalls until the request is canceled.
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(&stalledReader{chunk: []byte("hi"), ctx: r.Context()}),
+		}, nil
+	})
+	throttler := newTestThrottler(t, breakerParams, map[string][]string{testRevName: podDests(1)})
+
+	rev := revision(testNamespace, testRevName)
+	rev.Spec.IdleTimeoutSeconds = ptr.Int64(1)
+	handler := &activationHandler{
+		transport:      rt,
+		logger:         TestLogger(t),
+		reporter:       &fakeReporter{},
+		throttler:      throttler,
+		revisionLister: revisionLister(rev),
+	}
+
+	configStore := setupConfigStore(t)
+	var resp *httptest.ResponseRecorder
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("Expected the response to be aborted, got: %v", recovered)
+			}
+		}()
+		resp = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		req.Header.Set(activator.RevisionHeaderNamespace, testNamespace)
+		req.Header.Set(activator.RevisionHeaderName, testRevName)
+		handler.ServeHTTP(resp, req.WithContext(configStore.ToContext(req.Context())))
+	}()
+
+	if got, want := resp.Body.String(), "hi"; got != want {
+		t.Errorf("Body = %q, want: %q", got, want)
+	}
+}
+
+// stalledReader returns chunk once and then blocks until ctx is done.
+type stalledReader struct {
+	chunk []byte
+	ctx   context.Context
+}
+
+func (r *stalledReader) Read(p []byte) (int, error) {
+	if len(r.chunk) > 0 {
+		n := copy(p, r.chunk)
+		r.chunk = r.chunk[n:]
+		return n, nil
+	}
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
 func TestActivationHandlerTraceSpans(t *testing.T) {
 	testcases := []struct {
 		name         string
@@ -538,7 +749,7 @@ func TestActivationHandlerTraceSpans(t *testing.T) {
 		traceBackend tracingconfig.BackendType
 	}{{
 		name:         "zipkin trace enabled",
-		wantSpans:    4,
+		wantSpans:    3,
 		traceBackend: tracingconfig.Zipkin,
 	}, {
 		name:         "trace disabled",
@@ -576,27 +787,18 @@ func TestActivationHandlerTraceSpans(t *testing.T) {
 			revName := testRevName
 
 			breakerParams := queue.BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
-			throttler := activator.NewThrottler(
-				breakerParams,
-				endpointsInformer(endpoints(namespace, revName, breakerParams.InitialCapacity)),
-				sksLister(sks(namespace, revName)),
-				revisionLister(revision(namespace, revName)),
-				TestLogger(t))
+			throttler := newTestThrottler(t, breakerParams, map[string][]string{revName: podDests(1)})
 
 			handler := &activationHandler{
 				transport:      rt,
-				probeTransport: rt,
 				logger:         TestLogger(t),
 				reporter:       &fakeReporter{},
 				throttler:      throttler,
 				revisionLister: revisionLister(revision(testNamespace, testRevName)),
-				serviceLister:  serviceLister(service(testNamespace, testRevName, "http")),
-				sksLister:      sksLister(sks(testNamespace, testRevName)),
 			}
 			handler.transport = &ochttp.Transport{
 				Base: rt,
 			}
-			handler.probeTransport = rt
 
 			// set up config store to populate context
 			configStore := setupConfigStore(t)
@@ -608,7 +810,7 @@ func TestActivationHandlerTraceSpans(t *testing.T) {
 				t.Errorf("Got %d spans, expected %d", len(gotSpans), tc.wantSpans)
 			}
 
-			spanNames := []string{"throttler_try", "probe", "/", "proxy"}
+			spanNames := []string{"throttler_try", "/", "proxy"}
 			for i, spanName := range spanNames[0:tc.wantSpans] {
 				if gotSpans[i].Name != spanName {
 					t.Errorf("Got span %d named %q, expected %q", i, gotSpans[i].Name, spanName)
@@ -667,8 +869,8 @@ func assertResponses(wantedSuccess, wantedFailure, overallRequests int, lockerCh
 				}
 			case failureCode:
 				failed++
-				if gotBody != activator.ErrActivatorOverload.Error() {
-					t.Errorf("error message = %q, want: %q", gotBody, activator.ErrActivatorOverload.Error())
+				if gotBody != activatornet.ErrActivatorOverload.Error() {
+					t.Errorf("error message = %q, want: %q", gotBody, activatornet.ErrActivatorOverload.Error())
 				}
 			default:
 				t.Errorf("http response code = %d, want: %d or %d", resp.Code, successCode, failureCode)
@@ -751,6 +953,21 @@ func (f *fakeReporter) ReportRequestCount(ns, service, config, rev string, respo
 	return nil
 }
 
+func (f *fakeReporter) ReportQueuedRequests(ns, service, config, rev string, v int64) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.calls = append(f.calls, reporterCall{
+		Op:        "ReportQueuedRequests",
+		Namespace: ns,
+		Service:   service,
+		Config:    config,
+		Revision:  rev,
+		Value:     v,
+	})
+
+	return nil
+}
+
 func (f *fakeReporter) ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error {
 	f.mux.Lock()
 	defer f.mux.Unlock()
@@ -798,97 +1015,47 @@ func revisionLister(revs ...*v1alpha1.Revision) servinglisters.RevisionLister {
 	return revisions.Lister()
 }
 
-func service(namespace, name string, portName string) *corev1.Service {
-	return &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{{
-				Name: portName,
-				Port: 8080,
-			}},
-		}}
-}
-
-func serviceLister(svcs ...*corev1.Service) corev1listers.ServiceLister {
-	fake := kubefake.NewSimpleClientset()
-	informer := kubeinformers.NewSharedInformerFactory(fake, 0)
-	services := informer.Core().V1().Services()
-
-	for _, svc := range svcs {
-		fake.Core().Services(svc.Namespace).Create(svc)
-		services.Informer().GetIndexer().Add(svc)
-	}
-
-	return services.Lister()
-}
-
 func setupConfigStore(t *testing.T) *activatorconfig.Store {
 	configStore := activatorconfig.NewStore(TestLogger(t))
 	tracingConfig := ConfigMapFromTestFile(t, tracingconfig.ConfigName)
 	configStore.OnConfigChanged(tracingConfig)
+	activatorConfig := ConfigMapFromTestFile(t, activatorconfig.ConfigName)
+	configStore.OnConfigChanged(activatorConfig)
 	return configStore
 }
 
-func sks(namespace, name string) *nv1a1.ServerlessService {
-	return &nv1a1.ServerlessService{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-		Status: nv1a1.ServerlessServiceStatus{
-			// Randomize the test.
-			PrivateServiceName: name,
-			ServiceName:        helpers.AppendRandomString(name),
-		},
-	}
-}
-
-func sksLister(skss ...*nv1a1.ServerlessService) netlisters.ServerlessServiceLister {
+// newTestThrottler returns a Throttler for the revisions of testNamespace in
+// the keys of dests, which routes their requests to the pod dests in the values.
+func newTestThrottler(t *testing.T, params queue.BreakerParams, dests map[string][]string) *activatornet.Throttler {
 	fake := servingfake.NewSimpleClientset()
 	informer := servinginformers.NewSharedInformerFactory(fake, 0)
-	services := informer.Networking().V1alpha1().ServerlessServices()
+	revisions := informer.Serving().V1alpha1().Revisions()
+	endpoints := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0).Core().V1().Endpoints()
 
-	for _, sks := range skss {
-		fake.Networking().ServerlessServices(sks.Namespace).Create(sks)
-		services.Informer().GetIndexer().Add(sks)
+	throttler := activatornet.NewThrottler(params, revisions, endpoints, TestLogger(t))
+	updateCh := make(chan *activatornet.RevisionDestsUpdate, len(dests))
+	for name, revDests := range dests {
+		rev := revision(testNamespace, name)
+		fake.ServingV1alpha1().Revisions(rev.Namespace).Create(rev)
+		revisions.Informer().GetIndexer().Add(rev)
+		updateCh <- &activatornet.RevisionDestsUpdate{
+			Rev:               types.NamespacedName{Namespace: testNamespace, Name: name},
+			Dests:             revDests,
+			ReadyAddressCount: len(revDests),
+		}
 	}
-
-	return services.Lister()
+	close(updateCh)
+	throttler.Run(updateCh)
+	return throttler
 }
 
-func endpoints(namespace, name string, count int) *corev1.Endpoints {
-	ep := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		}}
-
-	epAddresses := []corev1.EndpointAddress{}
+// podDests returns count distinct pod dests.
+func podDests(count int) []string {
+	dests := make([]string, 0, count)
 	for i := 1; i <= count; i++ {
-		ip := fmt.Sprintf("127.0.0.%v", i)
-		epAddresses = append(epAddresses, corev1.EndpointAddress{IP: ip})
+		dests = append(dests, fmt.Sprintf("127.0.0.%d:8012", i))
 	}
-	ep.Subsets = []corev1.EndpointSubset{{
-		Addresses: epAddresses,
-	}}
-
-	return ep
-}
-
-func endpointsInformer(eps ...*corev1.Endpoints) corev1informers.EndpointsInformer {
-	fake := kubefake.NewSimpleClientset()
-	informer := kubeinformers.NewSharedInformerFactory(fake, 0)
-	endpoints := informer.Core().V1().Endpoints()
-
-	for _, ep := range eps {
-		fake.Core().Endpoints(ep.Namespace).Create(ep)
-		endpoints.Informer().GetIndexer().Add(ep)
-	}
-
-	return endpoints
+	return dests
 }
 
 func errMsg(msg string) string {
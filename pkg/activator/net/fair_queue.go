@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrRequestThrottled indicates that the request is over the queue budget of
+// the activator, its namespace or its revision.
+var ErrRequestThrottled = errors.New("request throttled")
+
+type queueBudgetKey struct{}
+
+// QueueBudget is the share of the activator queue a request may use.
+type QueueBudget struct {
+	// Total is the number of requests queued across all the revisions the
+	// namespaces with queued requests share in proportion to their weights.
+	Total int
+	// Namespace and Revision cap the requests queued for the namespace and
+	// revision of the request. Zero means no cap other than the fair share
+	// of Total.
+	Namespace int
+	Revision  int
+	// Weight is the weight of the namespace of the request.
+	Weight int
+}
+
+// WithQueueBudget returns a context instructing the Throttler to reject the
+// request with ErrRequestThrottled when queueing it would exceed the budget.
+// Without it the request is queued regardless of the others.
+func WithQueueBudget(ctx context.Context, budget QueueBudget) context.Context {
+	return context.WithValue(ctx, queueBudgetKey{}, budget)
+}
+
+func queueBudgetFrom(ctx context.Context) (QueueBudget, bool) {
+	budget, ok := ctx.Value(queueBudgetKey{}).(QueueBudget)
+	return budget, ok
+}
+
+type queueObserverKey struct{}
+
+// WithQueueObserver returns a context instructing the Throttler to call
+// observe with the number of requests queued for the revision whenever the
+// request enters and leaves the queue.
+func WithQueueObserver(ctx context.Context, observe func(queued int)) context.Context {
+	return context.WithValue(ctx, queueObserverKey{}, observe)
+}
+
+func queueObserverFrom(ctx context.Context) func(queued int) {
+	observe, _ := ctx.Value(queueObserverKey{}).(func(queued int))
+	return observe
+}
+
+// namespaceQueue is the state of the queue of a namespace.
+type namespaceQueue struct {
+	queued int
+	// weight is the weight of the namespace the last request was queued
+	// with.
+	weight int
+}
+
+// fairQueue counts the requests waiting for capacity in the activator, and
+// admits new ones with weighted fair queuing: a namespace may only queue its
+// weighted share of the total budget among the namespaces with queued
+// requests. A lone namespace may thus use the whole budget, but has to make
+// room as soon as others queue requests too: from then on its new requests
+// are rejected until it is back under its share, while the other namespaces
+// are admitted up to theirs even though the total budget is used up. The
+// queue thus exceeds the total budget by at most what the namespaces held
+// beyond their shares when the others arrived, and only until those drain.
+type fairQueue struct {
+	mux        sync.Mutex
+	queued     int
+	namespaces map[string]*namespaceQueue
+	revisions  map[types.NamespacedName]int
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{
+		namespaces: make(map[string]*namespaceQueue),
+		revisions:  make(map[types.NamespacedName]int),
+	}
+}
+
+// enqueue queues a request for the revision if the budget in ctx allows it, or
+// returns ErrRequestThrottled. The returned function dequeues the request and
+// may be called several times.
+func (q *fairQueue) enqueue(ctx context.Context, revID types.NamespacedName) (func(), error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	ns := q.namespaces[revID.Namespace]
+	if budget, ok := queueBudgetFrom(ctx); ok {
+		queued := 0
+		if ns != nil {
+			queued = ns.queued
+		}
+		if queued >= q.fairShare(revID.Namespace, budget) ||
+			(budget.Namespace > 0 && queued >= budget.Namespace) ||
+			(budget.Revision > 0 && q.revisions[revID] >= budget.Revision) {
+			return nil, ErrRequestThrottled
+		}
+		if ns == nil {
+			ns = &namespaceQueue{}
+			q.namespaces[revID.Namespace] = ns
+		}
+		ns.weight = budget.Weight
+	} else if ns == nil {
+		ns = &namespaceQueue{weight: 1}
+		q.namespaces[revID.Namespace] = ns
+	}
+
+	q.queued++
+	ns.queued++
+	q.revisions[revID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { q.dequeue(revID) })
+	}, nil
+}
+
+func (q *fairQueue) dequeue(revID types.NamespacedName) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.queued--
+	if ns := q.namespaces[revID.Namespace]; ns.queued <= 1 {
+		delete(q.namespaces, revID.Namespace)
+	} else {
+		ns.queued--
+	}
+	if q.revisions[revID] <= 1 {
+		delete(q.revisions, revID)
+	} else {
+		q.revisions[revID]--
+	}
+}
+
+// fairShare returns how many requests the namespace may queue given the
+// namespaces with queued requests. q.mux must be held.
+func (q *fairQueue) fairShare(namespace string, budget QueueBudget) int {
+	weight := minOneOrValue(budget.Weight)
+	totalWeight := weight
+	for name, ns := range q.namespaces {
+		if name != namespace {
+			totalWeight += minOneOrValue(ns.weight)
+		}
+	}
+	return minOneOrValue(budget.Total * weight / totalWeight)
+}
+
+// revisionQueued returns the number of requests queued for the revision.
+func (q *fairQueue) revisionQueued(revID types.NamespacedName) int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.revisions[revID]
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"knative.dev/pkg/controller"
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/apis/networking"
+	servingfake "knative.dev/serving/pkg/client/clientset/versioned/fake"
+	servinginformers "knative.dev/serving/pkg/client/informers/externalversions"
+	"knative.dev/serving/pkg/queue"
+)
+
+func TestFairQueue(t *testing.T) {
+	revA1 := types.NamespacedName{Namespace: "a", Name: "rev1"}
+	revA2 := types.NamespacedName{Namespace: "a", Name: "rev2"}
+	revB := types.NamespacedName{Namespace: "b", Name: "rev"}
+	revC := types.NamespacedName{Namespace: "c", Name: "rev"}
+
+	type request struct {
+		rev    types.NamespacedName
+		weight int
+	}
+	for _, tc := range []struct {
+		name     string
+		budget   QueueBudget
+		queued   []request
+		request  request
+		wantErr  error
+		noBudget bool
+	}{{
+		name:    "empty queue",
+		budget:  QueueBudget{Total: 1},
+		request: request{rev: revA1},
+	}, {
+		name:    "lone namespace uses the whole budget",
+		budget:  QueueBudget{Total: 3},
+		queued:  []request{{rev: revA1}, {rev: revA2}},
+		request: request{rev: revA1},
+	}, {
+		name:    "lone namespace exhausts the budget",
+		budget:  QueueBudget{Total: 2},
+		queued:  []request{{rev: revA1}, {rev: revA2}},
+		request: request{rev: revA1},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "under the fair share of an exhausted budget",
+		budget:  QueueBudget{Total: 2},
+		queued:  []request{{rev: revA1}, {rev: revA2}},
+		request: request{rev: revB},
+	}, {
+		name:    "fair share of an exhausted budget used up",
+		budget:  QueueBudget{Total: 2},
+		queued:  []request{{rev: revA1}, {rev: revA2}, {rev: revB}},
+		request: request{rev: revB},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "over the fair share",
+		budget:  QueueBudget{Total: 4},
+		queued:  []request{{rev: revA1}, {rev: revA2}, {rev: revB}},
+		request: request{rev: revA1},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "under the fair share",
+		budget:  QueueBudget{Total: 4},
+		queued:  []request{{rev: revA1}, {rev: revA2}, {rev: revB}},
+		request: request{rev: revB},
+	}, {
+		name:    "weighted share",
+		budget:  QueueBudget{Total: 6},
+		queued:  []request{{rev: revA1, weight: 2}, {rev: revA1, weight: 2}, {rev: revA2, weight: 2}, {rev: revB}},
+		request: request{rev: revA1, weight: 2},
+	}, {
+		name:    "weighted share exhausted",
+		budget:  QueueBudget{Total: 6},
+		queued:  []request{{rev: revA1}, {rev: revA1}, {rev: revB, weight: 2}},
+		request: request{rev: revA1},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "share of a new namespace",
+		budget:  QueueBudget{Total: 3},
+		queued:  []request{{rev: revA1}, {rev: revB}},
+		request: request{rev: revC},
+	}, {
+		name:    "namespace budget",
+		budget:  QueueBudget{Total: 100, Namespace: 2},
+		queued:  []request{{rev: revA1}, {rev: revA2}},
+		request: request{rev: revA1},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "revision budget",
+		budget:  QueueBudget{Total: 100, Revision: 2},
+		queued:  []request{{rev: revA1}, {rev: revA1}},
+		request: request{rev: revA1},
+		wantErr: ErrRequestThrottled,
+	}, {
+		name:    "revision budget of another revision",
+		budget:  QueueBudget{Total: 100, Revision: 2},
+		queued:  []request{{rev: revA1}, {rev: revA1}},
+		request: request{rev: revA2},
+	}, {
+		name:     "no budget",
+		budget:   QueueBudget{Total: 1},
+		queued:   []request{{rev: revA1}},
+		request:  request{rev: revA1},
+		noBudget: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := newFairQueue()
+			withBudget := func(weight int) context.Context {
+				budget := tc.budget
+				budget.Weight = weight
+				return WithQueueBudget(context.Background(), budget)
+			}
+			for _, r := range tc.queued {
+				// Queue the requests already there with a generous budget.
+				if _, err := q.enqueue(WithQueueBudget(context.Background(), QueueBudget{Total: 100, Weight: r.weight}), r.rev); err != nil {
+					t.Fatalf("enqueue(%v) = %v", r.rev, err)
+				}
+			}
+			ctx := withBudget(tc.request.weight)
+			if tc.noBudget {
+				ctx = context.Background()
+			}
+			if _, err := q.enqueue(ctx, tc.request.rev); err != tc.wantErr {
+				t.Errorf("enqueue(%v) = %v, want: %v", tc.request.rev, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFairQueueDequeue(t *testing.T) {
+	rev := types.NamespacedName{Namespace: "a", Name: "rev"}
+	q := newFairQueue()
+	ctx := WithQueueBudget(context.Background(), QueueBudget{Total: 1})
+
+	dequeue, err := q.enqueue(ctx, rev)
+	if err != nil {
+		t.Fatalf("enqueue() = %v", err)
+	}
+	if got, want := q.revisionQueued(rev), 1; got != want {
+		t.Errorf("revisionQueued() = %d, want: %d", got, want)
+	}
+	if _, err := q.enqueue(ctx, rev); err != ErrRequestThrottled {
+		t.Errorf("enqueue() = %v, want: %v", err, ErrRequestThrottled)
+	}
+
+	// Dequeuing is idempotent.
+	dequeue()
+	dequeue()
+	if got, want := q.revisionQueued(rev), 0; got != want {
+		t.Errorf("revisionQueued() = %d, want: %d", got, want)
+	}
+	if len(q.namespaces) != 0 || len(q.revisions) != 0 || q.queued != 0 {
+		t.Errorf("Queue not empty after dequeuing: %+v", q)
+	}
+	if _, err := q.enqueue(ctx, rev); err != nil {
+		t.Errorf("enqueue() = %v, want no error once dequeued", err)
+	}
+}
+
+func TestThrottlerQueueBudget(t *testing.T) {
+	fake := kubefake.NewSimpleClientset()
+	informer := kubeinformers.NewSharedInformerFactory(fake, 0)
+	endpoints := informer.Core().V1().Endpoints()
+
+	servfake := servingfake.NewSimpleClientset()
+	servinginformer := servinginformers.NewSharedInformerFactory(servfake, 0)
+	revisions := servinginformer.Serving().V1alpha1().Revisions()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	controller.StartInformers(stopCh, endpoints.Informer(), revisions.Informer())
+
+	revID := types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"}
+	rev := revision(revID, networking.ProtocolHTTP1)
+	servfake.ServingV1alpha1().Revisions(rev.Namespace).Create(rev)
+	revisions.Informer().GetIndexer().Add(rev)
+
+	params := queue.BreakerParams{
+		QueueDepth:      10,
+		MaxConcurrency:  defaultMaxConcurrency,
+		InitialCapacity: 0,
+	}
+	throttler := NewThrottler(params, revisions, endpoints, TestLogger(t))
+
+	// Without backends the requests queue until they time out.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ctx = WithQueueBudget(ctx, QueueBudget{Total: 10, Revision: 1})
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- throttler.Try(ctx, revID, func(string) error { return nil })
+	}()
+	for throttler.QueuedRequests(revID) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := throttler.Try(ctx, revID, func(string) error { return nil }); err != ErrRequestThrottled {
+		t.Errorf("Try() = %v, want: %v", err, ErrRequestThrottled)
+	}
+	if err := <-errCh; err != ErrActivatorOverload {
+		t.Errorf("Try() = %v, want: %v", err, ErrActivatorOverload)
+	}
+	if got, want := throttler.QueuedRequests(revID), 0; got != want {
+		t.Errorf("QueuedRequests() = %d, want: %d", got, want)
+	}
+}
+
+func TestThrottlerQueueObserver(t *testing.T) {
+	fake := kubefake.NewSimpleClientset()
+	informer := kubeinformers.NewSharedInformerFactory(fake, 0)
+	endpoints := informer.Core().V1().Endpoints()
+
+	servfake := servingfake.NewSimpleClientset()
+	servinginformer := servinginformers.NewSharedInformerFactory(servfake, 0)
+	revisions := servinginformer.Serving().V1alpha1().Revisions()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	controller.StartInformers(stopCh, endpoints.Informer(), revisions.Informer())
+
+	revID := types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"}
+	rev := revision(revID, networking.ProtocolHTTP1)
+	servfake.ServingV1alpha1().Revisions(rev.Namespace).Create(rev)
+	revisions.Informer().GetIndexer().Add(rev)
+
+	params := queue.BreakerParams{
+		QueueDepth:      10,
+		MaxConcurrency:  defaultMaxConcurrency,
+		InitialCapacity: 0,
+	}
+	throttler := NewThrottler(params, revisions, endpoints, TestLogger(t))
+
+	// Without backends the requests queue until they time out, and the queue
+	// depth is observed as each of them enters and leaves the queue.
+	var (
+		mux      sync.Mutex
+		observed []int
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ctx = WithQueueObserver(ctx, func(queued int) {
+		mux.Lock()
+		defer mux.Unlock()
+		observed = append(observed, queued)
+	})
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- throttler.Try(ctx, revID, func(string) error { return nil })
+	}()
+	for throttler.QueuedRequests(revID) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		errCh <- throttler.Try(ctx, revID, func(string) error { return nil })
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != ErrActivatorOverload {
+			t.Errorf("Try() = %v, want: %v", err, ErrActivatorOverload)
+		}
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if want := []int{1, 2}; !cmp.Equal(observed[:2], want) {
+		t.Errorf("Observed on enqueue = %v, want: %v", observed[:2], want)
+	}
+	if got, want := len(observed), 4; got != want {
+		t.Fatalf("Observed %d times, want: %d", got, want)
+	}
+	if got := observed[3]; got != 0 {
+		t.Errorf("Observed after the last dequeue = %d, want: 0", got)
+	}
+}
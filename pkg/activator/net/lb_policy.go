@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
+
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+type lbKey struct{}
+
+// lbRequest is how to pick the pod for a request.
+type lbRequest struct {
+	policy  serving.LoadBalancingPolicy
+	hashKey string
+}
+
+// WithLoadBalancing returns a context instructing the Throttler to pick the pod
+// for a request with the given policy. hashKey is the value the consistent-hash
+// policy routes on. Without it the Throttler uses the least-connections policy.
+func WithLoadBalancing(ctx context.Context, policy serving.LoadBalancingPolicy, hashKey string) context.Context {
+	return context.WithValue(ctx, lbKey{}, lbRequest{policy: policy, hashKey: hashKey})
+}
+
+func loadBalancingFrom(ctx context.Context) lbRequest {
+	if lb, ok := ctx.Value(lbKey{}).(lbRequest); ok {
+		return lb
+	}
+	return lbRequest{policy: serving.LeastConnectionsPolicy}
+}
+
+// lessLoaded reports whether a is a better pick than b: either only a has spare
+// capacity, or both or neither have and a has fewer requests in flight.
+func lessLoaded(a, b *podIPTracker, capacity int64) bool {
+	aHasCapacity, bHasCapacity := a.hasCapacity(capacity), b.hasCapacity(capacity)
+	if aHasCapacity != bHasCapacity {
+		return aHasCapacity
+	}
+	return atomic.LoadInt32(&a.requests) < atomic.LoadInt32(&b.requests)
+}
+
+// leastConnections returns the tracker with the fewest requests in flight,
+// preferring the ones with spare capacity.
+func leastConnections(trackers []*podIPTracker, capacity int64) *podIPTracker {
+	var leastConn *podIPTracker
+	for _, tracker := range trackers {
+		if leastConn == nil || lessLoaded(tracker, leastConn, capacity) {
+			leastConn = tracker
+		}
+	}
+	return leastConn
+}
+
+// roundRobin returns the next tracker in turn that has spare capacity, or just
+// the next one if none has. next is the index to start from, and is advanced
+// past the returned tracker.
+func roundRobin(trackers []*podIPTracker, capacity int64, next *int) *podIPTracker {
+	if len(trackers) == 0 {
+		return nil
+	}
+	start := *next % len(trackers)
+	for i := 0; i < len(trackers); i++ {
+		idx := (start + i) % len(trackers)
+		if trackers[idx].hasCapacity(capacity) {
+			*next = idx + 1
+			return trackers[idx]
+		}
+	}
+	*next = start + 1
+	return trackers[start]
+}
+
+// powerOfTwoChoices returns the less loaded of two random trackers.
+func powerOfTwoChoices(trackers []*podIPTracker, capacity int64) *podIPTracker {
+	if len(trackers) < 2 {
+		return leastConnections(trackers, capacity)
+	}
+	i := rand.Intn(len(trackers))
+	j := rand.Intn(len(trackers) - 1)
+	if j >= i {
+		j++
+	}
+	if lessLoaded(trackers[j], trackers[i], capacity) {
+		return trackers[j]
+	}
+	return trackers[i]
+}
+
+// consistentHash returns the tracker key hashes to, using rendezvous hashing so
+// that a key only moves when its pod goes away. When that pod has no spare
+// capacity the next one in the ranking of the key with capacity is returned
+// instead, to bound the load sticky routing puts on a pod.
+func consistentHash(trackers []*podIPTracker, capacity int64, key string) *podIPTracker {
+	if key == "" {
+		return leastConnections(trackers, capacity)
+	}
+	var (
+		best            *podIPTracker
+		bestScore       uint64
+		bestHasCapacity bool
+	)
+	for _, tracker := range trackers {
+		score := rendezvousScore(key, tracker.dest)
+		hasCapacity := tracker.hasCapacity(capacity)
+		if best == nil || (hasCapacity && !bestHasCapacity) ||
+			(hasCapacity == bestHasCapacity && score > bestScore) {
+			best, bestScore, bestHasCapacity = tracker, score, hasCapacity
+		}
+	}
+	return best
+}
+
+func rendezvousScore(key, dest string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(dest))
+	return h.Sum64()
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/queue"
+)
+
+func trackers(requests ...int32) []*podIPTracker {
+	ts := make([]*podIPTracker, len(requests))
+	for i, r := range requests {
+		ts[i] = &podIPTracker{
+			dest:     fmt.Sprintf("128.0.0.%d:1234", i+1),
+			requests: r,
+		}
+	}
+	return ts
+}
+
+func TestLoadBalancingFrom(t *testing.T) {
+	if got, want := loadBalancingFrom(context.Background()), (lbRequest{policy: serving.LeastConnectionsPolicy}); got != want {
+		t.Errorf("loadBalancingFrom() = %v, want: %v", got, want)
+	}
+	ctx := WithLoadBalancing(context.Background(), serving.ConsistentHashPolicy, "user")
+	if got, want := loadBalancingFrom(ctx), (lbRequest{policy: serving.ConsistentHashPolicy, hashKey: "user"}); got != want {
+		t.Errorf("loadBalancingFrom() = %v, want: %v", got, want)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		capacity int64
+		requests []int32
+		want     []string
+	}{{
+		name:     "in turn",
+		requests: []int32{5, 0, 3},
+		want:     []string{"128.0.0.1:1234", "128.0.0.2:1234", "128.0.0.3:1234", "128.0.0.1:1234"},
+	}, {
+		name:     "skips pods at capacity",
+		capacity: 2,
+		requests: []int32{0, 2, 0},
+		want:     []string{"128.0.0.1:1234", "128.0.0.3:1234", "128.0.0.1:1234"},
+	}, {
+		name:     "all pods at capacity",
+		capacity: 1,
+		requests: []int32{1, 1},
+		want:     []string{"128.0.0.1:1234", "128.0.0.2:1234", "128.0.0.1:1234"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := trackers(tc.requests...)
+			next := 0
+			var got []string
+			for range tc.want {
+				got = append(got, roundRobin(ts, tc.capacity, &next).dest)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("roundRobin() = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+
+	next := 0
+	if got := roundRobin(nil, 0, &next); got != nil {
+		t.Errorf("roundRobin() = %v, want: nil", got)
+	}
+}
+
+func TestPowerOfTwoChoices(t *testing.T) {
+	// With two pods both are always the choices.
+	for i := 0; i < 10; i++ {
+		if got, want := powerOfTwoChoices(trackers(3, 1), 10).dest, "128.0.0.2:1234"; got != want {
+			t.Fatalf("powerOfTwoChoices() = %s, want: %s", got, want)
+		}
+		if got, want := powerOfTwoChoices(trackers(1, 3), 2).dest, "128.0.0.1:1234"; got != want {
+			t.Fatalf("powerOfTwoChoices() = %s, want: %s", got, want)
+		}
+	}
+
+	// The most loaded pod is never picked.
+	ts := trackers(0, 0, 10)
+	for i := 0; i < 100; i++ {
+		if got := powerOfTwoChoices(ts, 0); got == ts[2] {
+			t.Fatalf("powerOfTwoChoices() = %s, want one of the least loaded pods", got.dest)
+		}
+	}
+
+	if got, want := powerOfTwoChoices(trackers(7), 0).dest, "128.0.0.1:1234"; got != want {
+		t.Errorf("powerOfTwoChoices() = %s, want: %s", got, want)
+	}
+	if got := powerOfTwoChoices(nil, 0); got != nil {
+		t.Errorf("powerOfTwoChoices() = %v, want: nil", got)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	ts := trackers(0, 0, 0, 0, 0)
+
+	// The same key always goes to the same pod, and keys spread across pods.
+	picks := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		picks[key] = consistentHash(ts, 0, key).dest
+		if got := consistentHash(ts, 0, key).dest; got != picks[key] {
+			t.Fatalf("consistentHash(%s) = %s, then %s", key, picks[key], got)
+		}
+	}
+	pods := make(map[string]bool)
+	for _, dest := range picks {
+		pods[dest] = true
+	}
+	if len(pods) != len(ts) {
+		t.Errorf("Keys went to %d pods, want: %d", len(pods), len(ts))
+	}
+
+	// Removing a pod only moves the keys that went to it.
+	removed := ts[1]
+	rest := append([]*podIPTracker{ts[0]}, ts[2:]...)
+	for key, dest := range picks {
+		got := consistentHash(rest, 0, key).dest
+		if dest != removed.dest && got != dest {
+			t.Errorf("consistentHash(%s) = %s, want: %s", key, got, dest)
+		}
+	}
+
+	// A pod without spare capacity is skipped.
+	key := "user-0"
+	for _, tracker := range ts {
+		if tracker.dest == picks[key] {
+			tracker.requests = 1
+		}
+	}
+	if got := consistentHash(ts, 1, key).dest; got == picks[key] {
+		t.Errorf("consistentHash(%s) = %s, want another pod with capacity", key, got)
+	}
+
+	// Without a key it falls back to the least connections.
+	if got, want := consistentHash(trackers(2, 1), 0, "").dest, "128.0.0.2:1234"; got != want {
+		t.Errorf("consistentHash() = %s, want: %s", got, want)
+	}
+}
+
+func TestAcquireDestPolicies(t *testing.T) {
+	rt := newRevisionThrottler(types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"},
+		0, queue.BreakerParams{QueueDepth: 1, MaxConcurrency: 1}, TestLogger(t))
+	rt.podIPTrackers = trackers(1, 0)
+
+	for _, tc := range []struct {
+		policy serving.LoadBalancingPolicy
+		want   string
+	}{{
+		policy: serving.LeastConnectionsPolicy,
+		want:   "128.0.0.2:1234",
+	}, {
+		policy: serving.RoundRobinPolicy,
+		want:   "128.0.0.1:1234",
+	}} {
+		dest, done := rt.acquireDest(WithLoadBalancing(context.Background(), tc.policy, ""), nil)
+		done()
+		if dest != tc.want {
+			t.Errorf("acquireDest(%s) = %s, want: %s", tc.policy, dest, tc.want)
+		}
+	}
+}
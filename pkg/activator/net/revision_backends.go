@@ -22,7 +22,6 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"reflect"
 	"strconv"
 	"sync"
 	"time"
@@ -49,9 +48,9 @@ import (
 )
 
 // RevisionDestsUpdate contains the state of healthy l4 dests for talking to a revision and is the
-// primary output from the RevisionBackendsManager system. If a healthy ClusterIP is found then
-// ClusterIPDest will be set to non empty string and Dests will be nil. Otherwise Dests will be set
-// to a slice of healthy l4 dests for reaching the revision.
+// primary output from the RevisionBackendsManager system. Dests will be set to a slice of healthy
+// l4 pod dests for reaching the revision. Only if none of the pods can be reached directly but the
+// ClusterIP is healthy, ClusterIPDest will be set to non empty string and Dests will be nil.
 type RevisionDestsUpdate struct {
 	Rev           types.NamespacedName
 	ClusterIPDest string
@@ -75,8 +74,11 @@ type revisionWatcher struct {
 
 	// Stores the list of pods that have been successfully probed.
 	healthyPods sets.String
-	// Stores whether the service ClusterIP has been seen as healthy
+	// Stores whether the service ClusterIP has been seen as healthy. The ClusterIP
+	// is only used when none of the pods can be reached directly.
 	clusterIPHealthy bool
+	// Stores the dests the health states were last computed for.
+	dests sets.String
 
 	transport     http.RoundTripper
 	destsChan     <-chan []string
@@ -193,49 +195,50 @@ func (rw *revisionWatcher) probePodIPs(dests []string) (sets.String, error) {
 // assumed this method is not called concurrently.
 func (rw *revisionWatcher) checkDests(dests []string) {
 	if len(dests) == 0 {
-		if rw.clusterIPHealthy {
-			// we have a healthy clusterIP but revision is not ready. We must have scaled down.
+		if rw.clusterIPHealthy || len(rw.healthyPods) > 0 {
+			// we had healthy backends but revision is not ready. We must have scaled down.
 			rw.clusterIPHealthy = false
+			rw.healthyPods = sets.NewString()
 
 			// Send update that were now inactive
 			rw.updateCh <- &RevisionDestsUpdate{Rev: rw.rev}
 		}
+		rw.dests = nil
 
 		// We know this revision cannot be healthy so short circuit
 		return
 	}
 
-	if rw.clusterIPHealthy {
-		// cluster IP is healthy and we havent scaled down, short circuit
-		return
-	}
-
-	// First check the clusterIP
-	svc, err := rw.getK8sPrivateService()
-	if err != nil {
-		rw.logger.Errorw("Failed to lookup private service for revision", zap.Error(err))
-		return
-	}
-
-	// If clusterIP is healthy send this update and we are done
-	if ok, dest, err := rw.probeClusterIP(svc); err != nil {
-		rw.logger.Errorw(fmt.Sprintf("Failed to probe clusterIP %s/%s", svc.Namespace, svc.Name), zap.Error(err))
-	} else if ok {
-		rw.logger.Debug("ClusterIP is successfully probed:", dest)
-		rw.clusterIPHealthy = true
-		rw.healthyPods = nil
-		rw.updateCh <- &RevisionDestsUpdate{Rev: rw.rev, ClusterIPDest: dest, ReadyAddressCount: len(dests)}
+	destsSet := sets.NewString(dests...)
+	if rw.clusterIPHealthy && destsSet.Equal(rw.dests) {
+		// Pods are only reachable via the clusterIP and nothing changed, short circuit
 		return
 	}
+	rw.dests = destsSet
 
+	// Prefer sending requests directly to the pods, so that we can track their capacity
+	// individually and bypass the load balancing of kube-proxy.
 	hs, err := rw.probePodIPs(dests)
 	if err != nil {
 		rw.logger.Errorw("Failed probing", zap.Error(err))
 		// We dont want to return here as an error still affects health states
 	}
-
 	rw.logger.Debug("Done probing, got healthy pods: ", hs)
-	if !reflect.DeepEqual(rw.healthyPods, hs) {
+
+	if hs.Len() == 0 {
+		// None of the pods are reachable directly, which is the case when e.g. a mesh
+		// intercepts the traffic to the pods. Fall back to the clusterIP if it is healthy.
+		if dest, ok := rw.checkClusterIP(); ok {
+			rw.logger.Debug("ClusterIP is successfully probed:", dest)
+			rw.clusterIPHealthy = true
+			rw.healthyPods = nil
+			rw.updateCh <- &RevisionDestsUpdate{Rev: rw.rev, ClusterIPDest: dest, ReadyAddressCount: len(dests)}
+			return
+		}
+	}
+
+	if rw.clusterIPHealthy || !hs.Equal(rw.healthyPods) {
+		rw.clusterIPHealthy = false
 		rw.healthyPods = hs
 		rw.updateCh <- &RevisionDestsUpdate{
 			Rev:               rw.rev,
@@ -245,6 +248,23 @@ func (rw *revisionWatcher) checkDests(dests []string) {
 	}
 }
 
+// checkClusterIP probes the clusterIP of the private service of the revision and
+// returns its l4 dest if it is healthy.
+func (rw *revisionWatcher) checkClusterIP() (string, bool) {
+	svc, err := rw.getK8sPrivateService()
+	if err != nil {
+		rw.logger.Errorw("Failed to lookup private service for revision", zap.Error(err))
+		return "", false
+	}
+
+	ok, dest, err := rw.probeClusterIP(svc)
+	if err != nil {
+		rw.logger.Errorw(fmt.Sprintf("Failed to probe clusterIP %s/%s", svc.Namespace, svc.Name), zap.Error(err))
+		return "", false
+	}
+	return dest, ok
+}
+
 func (rw *revisionWatcher) runWithTickCh(tickCh <-chan time.Time) {
 	var dests []string
 	for {
@@ -106,8 +106,6 @@ func TestRevisionWatcher(t *testing.T) {
 		clusterIP:     "129.0.0.1",
 		expectUpdates: []RevisionDestsUpdate{{Dests: []string{"128.0.0.1:1234"}, ReadyAddressCount: 1}},
 		probeResponses: []activatortest.FakeResponse{{
-			Err: errors.New("clusterIP transport error"),
-		}, {
 			Err:  nil,
 			Code: http.StatusOK,
 			Body: queue.Name,
@@ -197,8 +195,6 @@ func TestRevisionWatcher(t *testing.T) {
 		clusterIP:     "129.0.0.1",
 		expectUpdates: []RevisionDestsUpdate{{Dests: []string{"128.0.0.1:1234"}, ReadyAddressCount: 1}},
 		probeResponses: []activatortest.FakeResponse{{
-			Err: errors.New("clusterIP transport error"),
-		}, {
 			Err:  nil,
 			Code: http.StatusServiceUnavailable,
 			Body: queue.Name,
@@ -220,8 +216,6 @@ func TestRevisionWatcher(t *testing.T) {
 		clusterIP:     "129.0.0.1",
 		expectUpdates: []RevisionDestsUpdate{{Dests: []string{"128.0.0.1:1234", "128.0.0.2:1234"}, ReadyAddressCount: 2}},
 		probeResponses: []activatortest.FakeResponse{{
-			Err: errors.New("clusterIP transport error"),
-		}, {
 			Err:  nil,
 			Code: http.StatusOK,
 			Body: queue.Name,
@@ -249,36 +243,48 @@ func TestRevisionWatcher(t *testing.T) {
 			}},
 		},
 	}, {
-		name:  "podIP slow ready then clusterIP",
+		name:  "healthy podIP preferred over clusterIP",
 		dests: []string{"128.0.0.1:1234"},
 		clusterPort: corev1.ServicePort{
 			Name: "http",
 			Port: 1234,
 		},
-		clusterIP: "129.0.0.1",
-		expectUpdates: []RevisionDestsUpdate{
-			{Dests: []string{"128.0.0.1:1234"}, ReadyAddressCount: 1},
-			{ClusterIPDest: "129.0.0.1:1234", ReadyAddressCount: 1},
-		},
+		clusterIP:     "129.0.0.1",
+		expectUpdates: []RevisionDestsUpdate{{Dests: []string{"128.0.0.1:1234"}, ReadyAddressCount: 1}},
 		probeHostResponses: map[string][]activatortest.FakeResponse{
 			"129.0.0.1:1234": {{
-				Err: errors.New("clusterIP transport error"),
-			}, {
-				Err: errors.New("clusterIP transport error"),
-			}, {
 				Err:  nil,
 				Code: http.StatusOK,
 				Body: queue.Name,
 			}},
 			"128.0.0.1:1234": {{
 				Err:  nil,
-				Code: http.StatusServiceUnavailable,
+				Code: http.StatusOK,
 				Body: queue.Name,
+			}},
+		},
+	}, {
+		name:  "unreachable podIP then clusterIP",
+		dests: []string{"128.0.0.1:1234"},
+		clusterPort: corev1.ServicePort{
+			Name: "http",
+			Port: 1234,
+		},
+		clusterIP:     "129.0.0.1",
+		expectUpdates: []RevisionDestsUpdate{{ClusterIPDest: "129.0.0.1:1234", ReadyAddressCount: 1}},
+		probeHostResponses: map[string][]activatortest.FakeResponse{
+			"129.0.0.1:1234": {{
+				Err: errors.New("clusterIP transport error"),
+			}, {
+				Err: errors.New("clusterIP transport error"),
 			}, {
 				Err:  nil,
 				Code: http.StatusOK,
 				Body: queue.Name,
 			}},
+			"128.0.0.1:1234": {{
+				Err: errors.New("podIP transport error"),
+			}},
 		},
 		ticks: []time.Time{time.Now().Add(-time.Second), time.Now()},
 	}} {
@@ -497,7 +503,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		},
 		updateCnt: 2,
 	}, {
-		name:         "slow podIP then clusterIP",
+		name:         "slow podIP preferred over clusterIP",
 		endpointsArr: []*corev1.Endpoints{ep("test-revision", 1234, "http", "128.0.0.1")},
 		revisions: []*v1alpha1.Revision{
 			revision(types.NamespacedName{"test-namespace", "test-revision"}, networking.ProtocolHTTP1),
@@ -528,11 +534,11 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		},
 		expectDests: map[types.NamespacedName]RevisionDestsUpdate{
 			{Namespace: "test-namespace", Name: "test-revision"}: {
-				ClusterIPDest:     "129.0.0.1:1234",
+				Dests:             []string{"128.0.0.1:1234"},
 				ReadyAddressCount: 1,
 			},
 		},
-		updateCnt: 2,
+		updateCnt: 1,
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			defer ClearAll()
@@ -26,11 +26,13 @@ import (
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/system"
 	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
 	servinginformers "knative.dev/serving/pkg/client/informers/externalversions/serving/v1alpha1"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1alpha1"
@@ -39,11 +41,26 @@ import (
 	"knative.dev/serving/pkg/resources"
 )
 
+// ErrActivatorOverload indicates that throttler has no free slots to buffer the request.
+var ErrActivatorOverload = errors.New("activator overload")
+
+// ErrRetry can be returned by the function passed to Try to have it executed again,
+// with another dest when possible. Every retry waits for capacity like the first
+// attempt.
+var ErrRetry = errors.New("retry the request")
+
+// podIPTracker tracks the requests in flight to a single pod.
 type podIPTracker struct {
 	dest     string
 	requests int32
 }
 
+// hasCapacity reports whether the pod can take another request when it can handle
+// at most capacity concurrent requests. A zero capacity means infinite.
+func (p *podIPTracker) hasCapacity(capacity int64) bool {
+	return capacity == 0 || int64(atomic.LoadInt32(&p.requests)) < capacity
+}
+
 type breaker interface {
 	Capacity() int
 	Maybe(ctx context.Context, thunk func()) bool
@@ -66,12 +83,15 @@ type revisionThrottler struct {
 	// If we have a healthy clusterIPDest this is set to nil.
 	podIPTrackers []*podIPTracker
 
+	// The index of the next podIPTracker for the round-robin policy.
+	roundRobinIndex int
+
 	// If we dont have a healthy clusterIPDest this is set to the default (""), otherwise
 	// it is the l4dest for this revision's private clusterIP
 	clusterIPDest string
 
 	// mux guards "throttle state" which is the state we use during the request path. This
-	// is trackers, clusterIPDest
+	// is trackers, clusterIPDest, roundRobinIndex
 	mux sync.RWMutex
 
 	// used to atomically calculate and set capacity
@@ -86,7 +106,7 @@ func newRevisionThrottler(revID types.NamespacedName,
 	logger *zap.SugaredLogger) *revisionThrottler {
 	var revBreaker breaker
 	if containerConcurrency == 0 {
-		revBreaker = NewInfiniteBreaker()
+		revBreaker = newInfiniteBreaker()
 	} else {
 		revBreaker = queue.NewBreaker(breakerParams)
 	}
@@ -113,70 +133,111 @@ func (rt *revisionThrottler) checkClusterIPDest() (string, error) {
 }
 
 // Returns a dest after incrementing its request count and a completion callback
-// to be called after request completion. If no dest is found it returns "", nil.
-func (rt *revisionThrottler) acquireDest() (string, func()) {
-	var leastConn *podIPTracker
-	clusterIPDest := func() string {
-		rt.mux.Lock()
-		defer rt.mux.Unlock()
-
-		// This is intended to be called only after performing a read lock check on clusterIPDest
-		if rt.clusterIPDest != "" {
-			return rt.clusterIPDest
-		}
+// to be called after request completion. The dest is picked with the load balancing
+// policy in ctx, preferring the pods with capacity that are not in tried. If no dest
+// is found it returns "", nil.
+func (rt *revisionThrottler) acquireDest(ctx context.Context, tried []string) (string, func()) {
+	rt.mux.Lock()
+	defer rt.mux.Unlock()
+
+	// This is intended to be called only after performing a read lock check on clusterIPDest
+	if rt.clusterIPDest != "" {
+		return rt.clusterIPDest, func() {}
+	}
 
-		// Find the dest with fewest active connections.
-		for _, tracker := range rt.podIPTrackers {
-			if leastConn == nil || atomic.LoadInt32(&leastConn.requests) > tracker.requests {
-				leastConn = tracker
-			}
-		}
+	trackers := untried(rt.podIPTrackers, tried, rt.containerConcurrency)
+	var tracker *podIPTracker
+	switch lb := loadBalancingFrom(ctx); lb.policy {
+	case serving.RoundRobinPolicy:
+		tracker = roundRobin(trackers, rt.containerConcurrency, &rt.roundRobinIndex)
+	case serving.PowerOfTwoChoicesPolicy:
+		tracker = powerOfTwoChoices(trackers, rt.containerConcurrency)
+	case serving.ConsistentHashPolicy:
+		tracker = consistentHash(trackers, rt.containerConcurrency, lb.hashKey)
+	default:
+		tracker = leastConnections(trackers, rt.containerConcurrency)
+	}
+	if tracker == nil {
+		return "", nil
+	}
 
-		return ""
-	}()
-	if clusterIPDest != "" {
-		return clusterIPDest, func() {}
+	// Increment under the lock, so that concurrent requests see each other's picks.
+	atomic.AddInt32(&tracker.requests, 1)
+	return tracker.dest, func() {
+		atomic.AddInt32(&tracker.requests, -1)
 	}
+}
 
-	if leastConn != nil {
-		atomic.AddInt32(&leastConn.requests, 1)
-		return leastConn.dest, func() {
-			atomic.AddInt32(&leastConn.requests, -1)
+// untried returns the trackers with capacity whose dest is not in tried. If all
+// the trackers with capacity were tried it returns those, and if none has
+// capacity all of them, leaving the choice to the load balancing policy.
+func untried(trackers []*podIPTracker, tried []string, capacity int64) []*podIPTracker {
+	if len(tried) == 0 {
+		return trackers
+	}
+	triedSet := sets.NewString(tried...)
+	var withCapacity, ret []*podIPTracker
+	for _, tracker := range trackers {
+		if !tracker.hasCapacity(capacity) {
+			continue
 		}
+		withCapacity = append(withCapacity, tracker)
+		if !triedSet.Has(tracker.dest) {
+			ret = append(ret, tracker)
+		}
+	}
+	switch {
+	case len(ret) > 0:
+		return ret
+	case len(withCapacity) > 0:
+		return withCapacity
 	}
-	return "", nil
+	return trackers
 }
 
 func (rt *revisionThrottler) try(ctx context.Context, function func(string) error) error {
-	var ret error
-
 	rt.logger.Debug("Trying")
-	if !rt.breaker.Maybe(ctx, func() {
-		// See if we can get by with only a readlock
-		dest, err := rt.checkClusterIPDest()
-		if err != nil {
-			ret = err
-			return
+	// Every attempt waits for capacity, so that retries don't overload the
+	// pods, and goes to another pod if there is one with capacity.
+	var tried []string
+	for {
+		var (
+			dest string
+			ret  error
+		)
+		if !rt.breaker.Maybe(ctx, func() {
+			dest, ret = rt.tryDest(ctx, tried, function)
+		}) {
+			return ErrActivatorOverload
 		}
-
-		if dest != "" {
-			ret = function(dest)
-			return
+		if ret != ErrRetry {
+			return ret
 		}
+		tried = append(tried, dest)
+	}
+}
 
-		// Try again with a write lock falling back to a podIP dest
-		dest, completionCb := rt.acquireDest()
-		if dest == "" {
-			ret = errors.New("no podIP destination found, this should never happen")
-			return
-		}
+// tryDest executes function with a dest, preferring the pods not in tried, and
+// returns the dest.
+func (rt *revisionThrottler) tryDest(ctx context.Context, tried []string, function func(string) error) (string, error) {
+	// See if we can get by with only a readlock
+	dest, err := rt.checkClusterIPDest()
+	if err != nil {
+		return "", err
+	}
+
+	if dest != "" {
+		return dest, function(dest)
+	}
 
-		defer completionCb()
-		ret = function(dest)
-	}) {
-		return fmt.Errorf("revision %q exceeded capacity, failing request", rt.revID.String())
+	// Try again with a write lock falling back to a podIP dest
+	dest, completionCb := rt.acquireDest(ctx, tried)
+	if dest == "" {
+		return "", errors.New("no podIP destination found, this should never happen")
 	}
-	return ret
+
+	defer completionCb()
+	return dest, function(dest)
 }
 
 func (rt *revisionThrottler) calculateCapacity(size, activatorCount, maxConcurrency int) int {
@@ -241,7 +302,9 @@ func (rt *revisionThrottler) handleUpdate(throttler *Throttler, update *Revision
 			trackers = append(trackers, tracker)
 		}
 
-		rt.updateThrottleState(throttler, update.ReadyAddressCount, trackers, "")
+		// We only send requests to the pods we know to be healthy, so the capacity is
+		// based on them rather than all the ready addresses.
+		rt.updateThrottleState(throttler, len(update.Dests), trackers, "")
 		return
 	}
 
@@ -256,6 +319,7 @@ type Throttler struct {
 	breakerParams           queue.BreakerParams
 	revisionLister          servinglisters.RevisionLister
 	numActivators           int32
+	queue                   *fairQueue
 	logger                  *zap.SugaredLogger
 }
 
@@ -268,6 +332,7 @@ func NewThrottler(breakerParams queue.BreakerParams,
 		revisionThrottlers: make(map[types.NamespacedName]*revisionThrottler),
 		breakerParams:      breakerParams,
 		revisionLister:     revisionInformer.Lister(),
+		queue:              newFairQueue(),
 		logger:             logger,
 	}
 
@@ -301,13 +366,38 @@ func (t *Throttler) Run(updateCh <-chan *RevisionDestsUpdate) {
 	}
 }
 
-// Try waits for capacity and then executes function, passing in a l4 dest to send a request
+// Try waits for capacity and then executes function, passing in a l4 dest to send a request.
+// It returns ErrRequestThrottled right away when the request is over the queue budget in ctx.
 func (t *Throttler) Try(ctx context.Context, revID types.NamespacedName, function func(string) error) error {
 	rt, err := t.getOrCreateRevisionThrottler(revID)
 	if err != nil {
 		return err
 	}
-	return rt.try(ctx, function)
+	dequeue, err := t.queue.enqueue(ctx, revID)
+	if err != nil {
+		return err
+	}
+	if observe := queueObserverFrom(ctx); observe != nil {
+		observe(t.QueuedRequests(revID))
+		leave := dequeue
+		var once sync.Once
+		dequeue = func() {
+			once.Do(func() {
+				leave()
+				observe(t.QueuedRequests(revID))
+			})
+		}
+	}
+	defer dequeue()
+	return rt.try(ctx, func(dest string) error {
+		dequeue()
+		return function(dest)
+	})
+}
+
+// QueuedRequests returns the number of requests waiting for capacity for the revision.
+func (t *Throttler) QueuedRequests(revID types.NamespacedName) int {
+	return t.queue.revisionQueued(revID)
 }
 
 func (t *Throttler) getOrCreateRevisionThrottler(revID types.NamespacedName) (*revisionThrottler, error) {
@@ -400,15 +490,14 @@ func minOneOrValue(num int) int {
 	return 1
 }
 
-// InfiniteBreaker is basically a short circuit.
-// InfiniteBreaker provides us capability to send unlimited number
+// infiniteBreaker is basically a short circuit.
+// infiniteBreaker provides us capability to send unlimited number
 // of requests to the downstream system.
 // This is to be used only when the container concurrency is unset
 // (i.e. infinity).
-// The InfiniteBreaker will, though, block the requests when
+// The infiniteBreaker will, though, block the requests when
 // downstream capacity is 0.
-// TODO(greghaynes) When the old throttler is removed this struct can be private.
-type InfiniteBreaker struct {
+type infiniteBreaker struct {
 	// mu guards `broadcast` channel.
 	mu sync.RWMutex
 
@@ -427,15 +516,15 @@ type InfiniteBreaker struct {
 	concurrency int32
 }
 
-// NewInfiniteBreaker creates an InfiniteBreaker
-func NewInfiniteBreaker() *InfiniteBreaker {
-	return &InfiniteBreaker{
+// newInfiniteBreaker creates an infiniteBreaker
+func newInfiniteBreaker() *infiniteBreaker {
+	return &infiniteBreaker{
 		broadcast: make(chan struct{}),
 	}
 }
 
 // Capacity returns the current capacity of the breaker
-func (ib *InfiniteBreaker) Capacity() int {
+func (ib *infiniteBreaker) Capacity() int {
 	return int(atomic.LoadInt32(&ib.concurrency))
 }
 
@@ -447,7 +536,7 @@ func zeroOrOne(x int) int32 {
 }
 
 // UpdateConcurrency sets the concurrency of the breaker
-func (ib *InfiniteBreaker) UpdateConcurrency(cc int) error {
+func (ib *infiniteBreaker) UpdateConcurrency(cc int) error {
 	rcc := zeroOrOne(cc)
 	// We lock here to make sure two scale up events don't
 	// stomp on each other's feet.
@@ -468,7 +557,7 @@ func (ib *InfiniteBreaker) UpdateConcurrency(cc int) error {
 }
 
 // Maybe executes thunk when capacity is available
-func (ib *InfiniteBreaker) Maybe(ctx context.Context, thunk func()) bool {
+func (ib *infiniteBreaker) Maybe(ctx context.Context, thunk func()) bool {
 	has := ib.Capacity()
 	// We're scaled to serve.
 	if has > 0 {
@@ -18,6 +18,7 @@ package activator
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -143,7 +144,7 @@ func TestThrottler(t *testing.T) {
 		},
 		expectTryResults: []tryResult{
 			{Dest: "129.0.0.1:1234"},
-			{ErrString: "revision \"test-namespace/test-revision\" exceeded capacity, failing request"},
+			{ErrString: ErrActivatorOverload.Error()},
 		},
 	}, {
 		name: "remove before try",
@@ -291,7 +292,7 @@ func TestMultipleActivator(t *testing.T) {
 		results := tryThrottler(throttler, []types.NamespacedName{revID, revID}, tryContext)
 		if diff := cmp.Diff([]tryResult{
 			{Dest: "128.0.0.1:1234"},
-			{ErrString: `revision "test-namespace/test-revision" exceeded capacity, failing request`},
+			{ErrString: ErrActivatorOverload.Error()},
 		}, results); diff != "" {
 			t.Errorf("Got unexpected try results (-want, +got): %v", diff)
 		}
@@ -328,8 +329,156 @@ func tryThrottler(throttler *Throttler, trys []types.NamespacedName, ctx context
 	return res
 }
 
+func TestAcquireDest(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		containerConcurrency int64
+		requests             []int32
+		tried                []string
+		want                 string
+	}{{
+		name:                 "least loaded",
+		containerConcurrency: 10,
+		requests:             []int32{3, 1, 2},
+		want:                 "128.0.0.2:1234",
+	}, {
+		name:                 "skips pods at capacity",
+		containerConcurrency: 2,
+		requests:             []int32{2, 1},
+		want:                 "128.0.0.2:1234",
+	}, {
+		name:                 "all pods at capacity",
+		containerConcurrency: 1,
+		requests:             []int32{2, 1},
+		want:                 "128.0.0.2:1234",
+	}, {
+		name:     "infinite capacity",
+		requests: []int32{5, 4},
+		want:     "128.0.0.2:1234",
+	}, {
+		name:                 "skips tried pods",
+		containerConcurrency: 10,
+		requests:             []int32{3, 1, 2},
+		tried:                []string{"128.0.0.2:1234"},
+		want:                 "128.0.0.3:1234",
+	}, {
+		name:                 "skips tried pods with capacity only",
+		containerConcurrency: 2,
+		requests:             []int32{0, 2, 1},
+		tried:                []string{"128.0.0.1:1234"},
+		want:                 "128.0.0.3:1234",
+	}, {
+		name:                 "tried pod is the only one with capacity",
+		containerConcurrency: 2,
+		requests:             []int32{1, 2, 2},
+		tried:                []string{"128.0.0.1:1234"},
+		want:                 "128.0.0.1:1234",
+	}, {
+		name:                 "all pods tried",
+		containerConcurrency: 10,
+		requests:             []int32{3, 1},
+		tried:                []string{"128.0.0.1:1234", "128.0.0.2:1234"},
+		want:                 "128.0.0.2:1234",
+	}, {
+		name: "no pods",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := newRevisionThrottler(types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"},
+				tc.containerConcurrency, queue.BreakerParams{QueueDepth: 1, MaxConcurrency: 1}, TestLogger(t))
+			for i, requests := range tc.requests {
+				rt.podIPTrackers = append(rt.podIPTrackers, &podIPTracker{
+					dest:     fmt.Sprintf("128.0.0.%d:1234", i+1),
+					requests: requests,
+				})
+			}
+
+			dest, done := rt.acquireDest(context.Background(), tc.tried)
+			if dest != tc.want {
+				t.Fatalf("acquireDest() = %q, want: %q", dest, tc.want)
+			}
+			if dest == "" {
+				return
+			}
+			var i int
+			for i = range rt.podIPTrackers {
+				if rt.podIPTrackers[i].dest == dest {
+					break
+				}
+			}
+			tracker := rt.podIPTrackers[i]
+			if got, want := tracker.requests, tc.requests[i]+1; got != want {
+				t.Errorf("requests = %d, want: %d", got, want)
+			}
+			done()
+			if got, want := tracker.requests, tc.requests[i]; got != want {
+				t.Errorf("requests after completion = %d, want: %d", got, want)
+			}
+		})
+	}
+}
+
+func TestTryRetry(t *testing.T) {
+	rt := newRevisionThrottler(types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"},
+		10, queue.BreakerParams{QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1}, TestLogger(t))
+	rt.podIPTrackers = []*podIPTracker{{dest: "128.0.0.1:1234"}, {dest: "128.0.0.2:1234"}}
+
+	var dests []string
+	err := rt.try(context.Background(), func(dest string) error {
+		dests = append(dests, dest)
+		if len(dests) < 3 {
+			return ErrRetry
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("try() = %v", err)
+	}
+	// The retry goes to the other pod, and once both are tried to either.
+	if len(dests) != 3 || dests[0] == dests[1] {
+		t.Errorf("dests = %v, want a retry on another pod", dests)
+	}
+	for _, tracker := range rt.podIPTrackers {
+		if tracker.requests != 0 {
+			t.Errorf("requests of %s = %d, want: 0", tracker.dest, tracker.requests)
+		}
+	}
+}
+
+// countingBreaker counts the times requests wait for capacity.
+type countingBreaker struct {
+	breaker
+	maybes int
+}
+
+func (b *countingBreaker) Maybe(ctx context.Context, thunk func()) bool {
+	b.maybes++
+	return b.breaker.Maybe(ctx, thunk)
+}
+
+func TestTryRetryWaitsForCapacity(t *testing.T) {
+	rt := newRevisionThrottler(types.NamespacedName{Namespace: "test-namespace", Name: "test-revision"},
+		1, queue.BreakerParams{QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1}, TestLogger(t))
+	b := &countingBreaker{breaker: rt.breaker}
+	rt.breaker = b
+	rt.podIPTrackers = []*podIPTracker{{dest: "128.0.0.1:1234"}}
+
+	attempts := 0
+	if err := rt.try(context.Background(), func(string) error {
+		attempts++
+		if attempts < 3 {
+			return ErrRetry
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("try() = %v", err)
+	}
+	if b.maybes != attempts {
+		t.Errorf("Waited for capacity %d times, want: %d", b.maybes, attempts)
+	}
+}
+
 func TestInfiniteBreaker(t *testing.T) {
-	b := &InfiniteBreaker{
+	b := &infiniteBreaker{
 		broadcast: make(chan struct{}),
 	}
 
@@ -38,6 +38,10 @@ var (
 		"request_count",
 		"The number of requests that are routed to Activator",
 		stats.UnitDimensionless)
+	queuedRequestsM = stats.Int64(
+		"queued_requests",
+		"Requests waiting in Activator for the revision to have capacity",
+		stats.UnitDimensionless)
 	responseTimeInMsecM = stats.Float64(
 		"request_latencies",
 		"The response time in millisecond",
@@ -52,6 +56,7 @@ var (
 type StatsReporter interface {
 	ReportRequestConcurrency(ns, service, config, rev string, v int64) error
 	ReportRequestCount(ns, service, config, rev string, responseCode, numTries int) error
+	ReportQueuedRequests(ns, service, config, rev string, v int64) error
 	ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error
 }
 
@@ -121,6 +126,12 @@ func NewStatsReporter() (*Reporter, error) {
 			Aggregation: view.Count(),
 			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey, r.responseCodeKey, r.responseCodeClassKey, r.numTriesKey},
 		},
+		&view.View{
+			Description: "Requests waiting in Activator for the revision to have capacity",
+			Measure:     queuedRequestsM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
 		&view.View{
 			Description: "The response time in millisecond",
 			Measure:     responseTimeInMsecM,
@@ -188,6 +199,27 @@ func (r *Reporter) ReportRequestCount(ns, service, config, rev string, responseC
 	return nil
 }
 
+// ReportQueuedRequests captures the number of requests queued for the revision with value v.
+func (r *Reporter) ReportQueuedRequests(ns, service, config, rev string, v int64) error {
+	if !r.initialized {
+		return errors.New("StatsReporter is not initialized yet")
+	}
+
+	// Note that service names can be an empty string, so it needs a special treatment.
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(r.namespaceTagKey, ns),
+		tag.Insert(r.serviceTagKey, valueOrUnknown(service)),
+		tag.Insert(r.configTagKey, config),
+		tag.Insert(r.revisionTagKey, rev))
+	if err != nil {
+		return err
+	}
+
+	metrics.Record(ctx, queuedRequestsM.M(v))
+	return nil
+}
+
 // ReportResponseTime captures response time requests
 func (r *Reporter) ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error {
 	if !r.initialized {
@@ -30,7 +30,7 @@ import (
 // Since golang executes test iterations within the same process, the stats reporter
 // returns an error if the metric is already registered and the test panics.
 func unregister() {
-	metricstest.Unregister("request_count", "request_latencies", "request_concurrency")
+	metricstest.Unregister("request_count", "request_latencies", "request_concurrency", "queued_requests")
 }
 
 func TestActivatorReporter(t *testing.T) {
@@ -64,6 +64,16 @@ func TestActivatorReporter(t *testing.T) {
 	})
 	metricstest.CheckLastValueData(t, "request_concurrency", wantTags1, 200)
 
+	// test ReportQueuedRequests
+	expectSuccess(t, func() error {
+		return r.ReportQueuedRequests("testns", "testsvc", "testconfig", "testrev", 10)
+	})
+	metricstest.CheckLastValueData(t, "queued_requests", wantTags1, 10)
+	expectSuccess(t, func() error {
+		return r.ReportQueuedRequests("testns", "testsvc", "testconfig", "testrev", 0)
+	})
+	metricstest.CheckLastValueData(t, "queued_requests", wantTags1, 0)
+
 	// test ReportRequestCount
 	wantTags2 := map[string]string{
 		metricskey.LabelNamespaceName:     "testns",
@@ -19,7 +19,9 @@ package autoscaling
 import (
 	"fmt"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"knative.dev/pkg/apis"
@@ -41,7 +43,9 @@ func ValidateAnnotations(anns map[string]string) *apis.FieldError {
 	if len(anns) == 0 {
 		return nil
 	}
-	return validateMinMaxScale(anns).Also(validateFloats(anns)).Also(validateWindows(anns))
+	return validateMinMaxScale(anns).Also(validateFloats(anns)).Also(validateWindows(anns)).
+		Also(validateAlgorithm(anns)).Also(validateSchedule(anns)).Also(validateCustomMetric(anns)).
+		Also(validateCPUTarget(anns))
 }
 
 func validateFloats(annotations map[string]string) *apis.FieldError {
@@ -77,6 +81,12 @@ func validateFloats(annotations map[string]string) *apis.FieldError {
 		}
 	}
 
+	if v, ok := annotations[MaxScaleDownRateAnnotationKey]; ok {
+		if fv, err := strconv.ParseFloat(v, 64); err != nil || fv <= MaxScaleDownRateMin {
+			errs = errs.Also(apis.ErrInvalidValue(v, MaxScaleDownRateAnnotationKey))
+		}
+	}
+
 	if v, ok := annotations[TargetBurstCapacityKey]; ok {
 		if fv, err := strconv.ParseFloat(v, 64); err != nil || fv < 0 && fv != -1 {
 			errs = errs.Also(apis.ErrInvalidValue(v, TargetBurstCapacityKey))
@@ -95,9 +105,118 @@ func validateWindows(annotations map[string]string) *apis.FieldError {
 			errs = apis.ErrOutOfBoundsValue(v, WindowMin, WindowMax, WindowAnnotationKey)
 		}
 	}
+	if v, ok := annotations[ScaleDownStabilizationWindowAnnotationKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(v, ScaleDownStabilizationWindowAnnotationKey))
+		} else if d < 0 || d > WindowMax {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(v, 0*time.Second, WindowMax,
+				ScaleDownStabilizationWindowAnnotationKey))
+		}
+	}
 	return errs
 }
 
+func validateSchedule(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[MinScaleScheduleAnnotationKey]
+	if !ok {
+		return nil
+	}
+	schedule, err := ParseScaleSchedule(v)
+	if err != nil {
+		return &apis.FieldError{
+			Message: fmt.Sprintf("invalid value: %s", v),
+			Paths:   []string{MinScaleScheduleAnnotationKey},
+			Details: err.Error(),
+		}
+	}
+	// Malformed maxScale is reported by validateMinMaxScale.
+	if max, err := getIntGE0(annotations, MaxScaleAnnotationKey); err == nil && max != 0 {
+		for _, w := range schedule {
+			if int64(w.MinScale) > max {
+				return &apis.FieldError{
+					Message: fmt.Sprintf("maxScale=%d is less than scheduled minScale=%d", max, w.MinScale),
+					Paths:   []string{MaxScaleAnnotationKey, MinScaleScheduleAnnotationKey},
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateCPUTarget checks that the CPU target is given in the unit the
+// class of the autoscaler expects: a percentage of the requested cpu for
+// the HPA and millicores for the KPA. Either annotation alone is a valid
+// number, so a revision moving between the classes with the wrong one
+// would silently target something else.
+func validateCPUTarget(annotations map[string]string) *apis.FieldError {
+	class := annotations[ClassAnnotationKey]
+	if class == "" {
+		class = KPA
+	}
+	kpaCPU := class == KPA && annotations[MetricAnnotationKey] == CPU
+
+	var errs *apis.FieldError
+	if v, ok := annotations[TargetMillicoresAnnotationKey]; ok {
+		if fv, err := strconv.ParseFloat(v, 64); err != nil || fv < TargetMin {
+			errs = errs.Also(apis.ErrInvalidValue(v, TargetMillicoresAnnotationKey))
+		} else if !kpaCPU {
+			errs = errs.Also(&apis.FieldError{
+				Message: fmt.Sprintf("%s is only supported by the %s class autoscaler with the %s metric",
+					TargetMillicoresAnnotationKey, KPA, CPU),
+				Paths: []string{TargetMillicoresAnnotationKey},
+			})
+		}
+	}
+	if _, ok := annotations[TargetAnnotationKey]; ok && kpaCPU {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("the %s class autoscaler takes the %s target in millicores from %s",
+				KPA, CPU, TargetMillicoresAnnotationKey),
+			Paths: []string{TargetAnnotationKey},
+		})
+	}
+	return errs
+}
+
+// metricNameRegexp matches valid Prometheus metric names.
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+func validateCustomMetric(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	if annotations[MetricAnnotationKey] == Custom {
+		for _, k := range []string{CustomMetricNameAnnotationKey, CustomMetricPortAnnotationKey, TargetAnnotationKey} {
+			if _, ok := annotations[k]; !ok {
+				errs = errs.Also(apis.ErrMissingField(k))
+			}
+		}
+	}
+	if v, ok := annotations[CustomMetricNameAnnotationKey]; ok && !metricNameRegexp.MatchString(v) {
+		errs = errs.Also(apis.ErrInvalidValue(v, CustomMetricNameAnnotationKey))
+	}
+	if v, ok := annotations[CustomMetricPortAnnotationKey]; ok {
+		if p, err := strconv.Atoi(v); err != nil || p < 1 || p > 65535 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(v, 1, 65535, CustomMetricPortAnnotationKey))
+		}
+	}
+	if v, ok := annotations[CustomMetricPathAnnotationKey]; ok && !strings.HasPrefix(v, "/") {
+		errs = errs.Also(apis.ErrInvalidValue(v, CustomMetricPathAnnotationKey))
+	}
+	return errs
+}
+
+// algorithmNameRegexp matches valid scaling algorithm names. Algorithms can
+// be registered with the autoscaler only, so the webhook cannot know all of
+// them and just checks the format. The autoscaler rejects unknown names when
+// it reconciles the decider.
+var algorithmNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func validateAlgorithm(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[AlgorithmAnnotationKey]; ok && !algorithmNameRegexp.MatchString(v) {
+		return apis.ErrInvalidValue(v, AlgorithmAnnotationKey)
+	}
+	return nil
+}
+
 func validateMinMaxScale(annotations map[string]string) *apis.FieldError {
 	var errs *apis.FieldError
 
@@ -158,6 +158,117 @@ func TestValidateScaleBoundAnnotations(t *testing.T) {
 		name:        "window too long",
 		annotations: map[string]string{WindowAnnotationKey: "365h"},
 		expectErr:   "expected 6s <= 365h <= 1h0m0s: autoscaling.knative.dev/window",
+	}, {
+		name:        "max scale down rate",
+		annotations: map[string]string{MaxScaleDownRateAnnotationKey: "1.5"},
+	}, {
+		name:        "max scale down rate too small",
+		annotations: map[string]string{MaxScaleDownRateAnnotationKey: "1"},
+		expectErr:   "invalid value: 1: autoscaling.knative.dev/maxScaleDownRate",
+	}, {
+		name:        "max scale down rate is not a float",
+		annotations: map[string]string{MaxScaleDownRateAnnotationKey: "fast"},
+		expectErr:   "invalid value: fast: autoscaling.knative.dev/maxScaleDownRate",
+	}, {
+		name:        "scale down stabilization window",
+		annotations: map[string]string{ScaleDownStabilizationWindowAnnotationKey: "5m"},
+	}, {
+		name:        "scale down stabilization window is not a duration",
+		annotations: map[string]string{ScaleDownStabilizationWindowAnnotationKey: "long"},
+		expectErr:   "invalid value: long: autoscaling.knative.dev/scaleDownStabilizationWindow",
+	}, {
+		name:        "scale down stabilization window too big",
+		annotations: map[string]string{ScaleDownStabilizationWindowAnnotationKey: "2h"},
+		expectErr:   "expected 0s <= 2h <= 1h0m0s: autoscaling.knative.dev/scaleDownStabilizationWindow",
+	}, {
+		name:        "min scale schedule",
+		annotations: map[string]string{MinScaleScheduleAnnotationKey: "weekdays 08:00-18:00 minScale=20"},
+	}, {
+		name:        "malformed min scale schedule",
+		annotations: map[string]string{MinScaleScheduleAnnotationKey: "weekdays 08:00 minScale=20"},
+		expectErr: "invalid value: weekdays 08:00 minScale=20: autoscaling.knative.dev/minScaleSchedule\n" +
+			`invalid window "weekdays 08:00 minScale=20": time range "08:00" is not of the form HH:MM-HH:MM`,
+	}, {
+		name: "min scale schedule above max scale",
+		annotations: map[string]string{
+			MinScaleScheduleAnnotationKey: "daily 08:00-18:00 minScale=2; mon 09:00-10:00 minScale=20",
+			MaxScaleAnnotationKey:         "10",
+		},
+		expectErr: "maxScale=10 is less than scheduled minScale=20: autoscaling.knative.dev/maxScale, autoscaling.knative.dev/minScaleSchedule",
+	}, {
+		name:        "known algorithm",
+		annotations: map[string]string{AlgorithmAnnotationKey: PIDAlgorithm},
+	}, {
+		name: "cpu target in millicores on the kpa",
+		annotations: map[string]string{
+			MetricAnnotationKey:           CPU,
+			TargetMillicoresAnnotationKey: "500",
+		},
+	}, {
+		name: "cpu target percentage on the hpa",
+		annotations: map[string]string{
+			ClassAnnotationKey:  HPA,
+			MetricAnnotationKey: CPU,
+			TargetAnnotationKey: "80",
+		},
+	}, {
+		name: "cpu target percentage on the kpa",
+		annotations: map[string]string{
+			ClassAnnotationKey:  KPA,
+			MetricAnnotationKey: CPU,
+			TargetAnnotationKey: "80",
+		},
+		expectErr: "the kpa.autoscaling.knative.dev class autoscaler takes the cpu target in millicores from autoscaling.knative.dev/targetMillicores: autoscaling.knative.dev/target",
+	}, {
+		name: "cpu target in millicores on the hpa",
+		annotations: map[string]string{
+			ClassAnnotationKey:            HPA,
+			MetricAnnotationKey:           CPU,
+			TargetMillicoresAnnotationKey: "500",
+		},
+		expectErr: "autoscaling.knative.dev/targetMillicores is only supported by the kpa.autoscaling.knative.dev class autoscaler with the cpu metric: autoscaling.knative.dev/targetMillicores",
+	}, {
+		name: "target in millicores for another metric",
+		annotations: map[string]string{
+			MetricAnnotationKey:           Memory,
+			TargetMillicoresAnnotationKey: "500",
+		},
+		expectErr: "autoscaling.knative.dev/targetMillicores is only supported by the kpa.autoscaling.knative.dev class autoscaler with the cpu metric: autoscaling.knative.dev/targetMillicores",
+	}, {
+		name:        "malformed target in millicores",
+		annotations: map[string]string{MetricAnnotationKey: CPU, TargetMillicoresAnnotationKey: "0.5"},
+		expectErr:   "invalid value: 0.5: autoscaling.knative.dev/targetMillicores",
+	}, {
+		name:        "algorithm registered with the autoscaler only",
+		annotations: map[string]string{AlgorithmAnnotationKey: "magic"},
+	}, {
+		name:        "malformed algorithm",
+		annotations: map[string]string{AlgorithmAnnotationKey: "Magic_1"},
+		expectErr:   "invalid value: Magic_1: autoscaling.knative.dev/algorithm",
+	}, {
+		name: "custom metric",
+		annotations: map[string]string{
+			MetricAnnotationKey:           Custom,
+			CustomMetricNameAnnotationKey: "backlog_depth",
+			CustomMetricPortAnnotationKey: "9095",
+			CustomMetricPathAnnotationKey: "/stats",
+			TargetAnnotationKey:           "10",
+		},
+	}, {
+		name: "custom metric missing port and target",
+		annotations: map[string]string{
+			MetricAnnotationKey:           Custom,
+			CustomMetricNameAnnotationKey: "backlog_depth",
+		},
+		expectErr: "missing field(s): autoscaling.knative.dev/customMetricPort, autoscaling.knative.dev/target",
+	}, {
+		name: "custom metric bad values",
+		annotations: map[string]string{
+			CustomMetricNameAnnotationKey: "backlog-depth",
+			CustomMetricPortAnnotationKey: "0",
+			CustomMetricPathAnnotationKey: "stats",
+		},
+		expectErr: "expected 1 <= 0 <= 65535: autoscaling.knative.dev/customMetricPort\ninvalid value: backlog-depth: autoscaling.knative.dev/customMetricName\ninvalid value: stats: autoscaling.knative.dev/customMetricPath",
 	}, {
 		name: "all together now fail",
 		annotations: map[string]string{
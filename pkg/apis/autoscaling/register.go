@@ -43,6 +43,13 @@ const (
 	// the PodAutoscaler should provision. For example,
 	//   autoscaling.knative.dev/maxScale: "10"
 	MaxScaleAnnotationKey = GroupName + "/maxScale"
+	// MinScaleScheduleAnnotationKey is the annotation to specify time windows
+	// during which the PodAutoscaler should provision more Pods than minScale.
+	// The windows are separated by semicolons, times are in UTC. For example,
+	//   autoscaling.knative.dev/minScaleSchedule: "weekdays 08:00-18:00 minScale=20; sat 10:00-14:00 minScale=5"
+	// Only the kpa.autoscaling.knative.dev class autoscaler supports
+	// the minScaleSchedule annotation.
+	MinScaleScheduleAnnotationKey = GroupName + "/minScaleSchedule"
 
 	// MetricAnnotationKey is the annotation to specify what metric the PodAutoscaler
 	// should be scaled on. For example,
@@ -51,19 +58,83 @@ const (
 	// Concurrency is the number of requests in-flight at any given time.
 	Concurrency = "concurrency"
 	// CPU is the amount of the requested cpu actually being consumed by the Pod.
+	// The HPA targets a percentage of the requested cpu given by the target
+	// annotation, while the KPA targets the average usage per Pod in
+	// millicores given by the targetMillicores annotation.
 	CPU = "cpu"
+	// Memory is the working set memory of the Pod. The KPA targets the average
+	// usage per Pod in mebibytes.
+	Memory = "memory"
 	// RPS is the requests per second reaching the Pod.
 	RPS = "rps"
+	// Connections is the number of connections upgraded by the Pod, e.g. to
+	// WebSocket, that are open at any given time.
+	Connections = "connections"
+	// Custom is a metric the user container exposes on its own Prometheus
+	// endpoint. The KPA scales on its sum over the Pods of the revision.
+	Custom = "custom"
+
+	// CustomMetricNameAnnotationKey is the annotation to specify the name of
+	// the Prometheus metric to scale on when the metric is custom. Both the
+	// name and the port of the endpoint, as well as the target, are required.
+	// Gauges are scaled on as they are, while counters are scaled on by their
+	// rate per second. For example,
+	//   autoscaling.knative.dev/metric: custom
+	//   autoscaling.knative.dev/customMetricName: backlog_depth
+	//   autoscaling.knative.dev/customMetricPort: "9095"
+	//   autoscaling.knative.dev/target: "10"
+	CustomMetricNameAnnotationKey = GroupName + "/customMetricName"
+	// CustomMetricPortAnnotationKey is the annotation to specify the port of the
+	// user container that serves the custom metric.
+	CustomMetricPortAnnotationKey = GroupName + "/customMetricPort"
+	// CustomMetricPathAnnotationKey is the annotation to specify the path
+	// the custom metric is served at. Defaults to DefaultCustomMetricPath.
+	CustomMetricPathAnnotationKey = GroupName + "/customMetricPath"
+	// DefaultCustomMetricPath is the conventional path of Prometheus endpoints.
+	DefaultCustomMetricPath = "/metrics"
+
+	// AlgorithmAnnotationKey is the annotation to specify which scaling
+	// algorithm the KPA should use to turn the observed metric values into
+	// a desired scale. For example,
+	//   autoscaling.knative.dev/algorithm: pid
+	// Only the kpa.autoscaling.knative.dev class autoscaler supports
+	// the algorithm annotation.
+	AlgorithmAnnotationKey = GroupName + "/algorithm"
+	// KPADefaultAlgorithm divides the observed stable and panic averages
+	// by the target value. This is the default algorithm.
+	KPADefaultAlgorithm = "kpa-default"
+	// PIDAlgorithm drives the per pod metric value towards the target
+	// using a proportional-integral-derivative controller.
+	PIDAlgorithm = "pid"
+	// StepAlgorithm moves the stable scale towards the desired scale
+	// by at most one pod per tick.
+	StepAlgorithm = "step"
+	// PredictiveAlgorithm extrapolates the trend of the stable metric
+	// to compensate for the lag of the stable window.
+	PredictiveAlgorithm = "predictive"
 
 	// TargetAnnotationKey is the annotation to specify what metric value the
 	// PodAutoscaler should attempt to maintain. For example,
+	//   autoscaling.knative.dev/class: hpa.autoscaling.knative.dev
 	//   autoscaling.knative.dev/metric: cpu
 	//   autoscaling.knative.dev/target: "75"   # target 75% cpu utilization
+	// The kpa.autoscaling.knative.dev class autoscaler takes the cpu target
+	// from TargetMillicoresAnnotationKey instead.
 	TargetAnnotationKey = GroupName + "/target"
 	// TargetMin is the minimum allowable target. Values less than
 	// zero don't make sense.
 	TargetMin = 1
 
+	// TargetMillicoresAnnotationKey is the annotation to specify the average
+	// CPU usage per Pod in millicores the PodAutoscaler should attempt to
+	// maintain. It takes the place of the target annotation, which the HPA
+	// interprets as a percentage of the requested cpu. For example,
+	//   autoscaling.knative.dev/metric: cpu
+	//   autoscaling.knative.dev/targetMillicores: "500"
+	// Only the kpa.autoscaling.knative.dev class autoscaler supports
+	// the targetMillicores annotation, and only for the cpu metric.
+	TargetMillicoresAnnotationKey = GroupName + "/targetMillicores"
+
 	// WindowAnnotationKey is the annotation to specify the time
 	// interval over which to calculate the average metric.  Larger
 	// values result in more smoothing. For example,
@@ -84,6 +155,24 @@ const (
 	// This keeps the event horizon to a resonable enough limit.
 	WindowMax = 1 * time.Hour
 
+	// MaxScaleDownRateAnnotationKey is the annotation to specify the maximum
+	// ratio of observed pods versus desired pods when scaling down. For example,
+	//   autoscaling.knative.dev/maxScaleDownRate: "1.5"
+	// Only the kpa.autoscaling.knative.dev class autoscaler supports
+	// the maxScaleDownRate annotation.
+	MaxScaleDownRateAnnotationKey = GroupName + "/maxScaleDownRate"
+	// MaxScaleDownRateMin is the minimum allowable scale down rate.
+	// The rate of 1 would never permit scaling down.
+	MaxScaleDownRateMin = 1.0
+
+	// ScaleDownStabilizationWindowAnnotationKey is the annotation to
+	// specify the period over which the highest recommendation of the
+	// autoscaler is used when scaling down. For example,
+	//   autoscaling.knative.dev/scaleDownStabilizationWindow: "5m"
+	// Only the kpa.autoscaling.knative.dev class autoscaler supports
+	// the scaleDownStabilizationWindow annotation.
+	ScaleDownStabilizationWindowAnnotationKey = GroupName + "/scaleDownStabilizationWindow"
+
 	// TargetUtilizationPercentageKey is the annotation which specifies the
 	// desired target resource utilization for the revision.
 	// TargetUtilization is a percentage in the 1 <= TU <= 100 range.
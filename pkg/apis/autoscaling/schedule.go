@@ -0,0 +1,217 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaling
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ScaleWindow is a recurring time window during which the revision
+// should run at least MinScale pods.
+type ScaleWindow struct {
+	// Days are the days of the week, indexed by time.Weekday, on which the window opens.
+	Days [7]bool
+	// Start and End are offsets from the midnight (UTC). If End is not after
+	// Start the window closes on the following day.
+	Start time.Duration
+	End   time.Duration
+	// MinScale is the minimum number of pods while the window is open.
+	MinScale int32
+}
+
+// ScaleSchedule is a set of ScaleWindows. When windows overlap the
+// highest MinScale wins.
+type ScaleSchedule []ScaleWindow
+
+// ParseScaleSchedule parses the value of the MinScaleScheduleAnnotationKey
+// annotation. The schedule is a semicolon separated list of windows of the form
+// "<days> <HH:MM>-<HH:MM> minScale=<N>", where days is one of "daily",
+// "weekdays", "weekends" or a comma separated list of day names and day
+// ranges, e.g. "mon-wed,fri". Times are in UTC.
+func ParseScaleSchedule(s string) (ScaleSchedule, error) {
+	var ret ScaleSchedule
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		w, err := parseScaleWindow(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", entry, err)
+		}
+		ret = append(ret, w)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("schedule %q has no windows", s)
+	}
+	return ret, nil
+}
+
+func parseScaleWindow(entry string) (ScaleWindow, error) {
+	var w ScaleWindow
+	fields := strings.Fields(entry)
+	if len(fields) != 3 {
+		return w, fmt.Errorf("want 3 fields, got %d", len(fields))
+	}
+
+	days, err := parseDays(fields[0])
+	if err != nil {
+		return w, err
+	}
+	w.Days = days
+
+	times := strings.Split(fields[1], "-")
+	if len(times) != 2 {
+		return w, fmt.Errorf("time range %q is not of the form HH:MM-HH:MM", fields[1])
+	}
+	if w.Start, err = parseTimeOfDay(times[0]); err != nil {
+		return w, err
+	}
+	if w.End, err = parseTimeOfDay(times[1]); err != nil {
+		return w, err
+	}
+	if w.Start == w.End || w.Start == day {
+		return w, fmt.Errorf("time range %q is empty", fields[1])
+	}
+
+	const prefix = "minScale="
+	if !strings.HasPrefix(fields[2], prefix) {
+		return w, fmt.Errorf("%q is not of the form %s<N>", fields[2], prefix)
+	}
+	min, err := strconv.ParseInt(strings.TrimPrefix(fields[2], prefix), 10, 32)
+	if err != nil || min < 0 {
+		return w, fmt.Errorf("invalid minScale %q", fields[2])
+	}
+	w.MinScale = int32(min)
+	return w, nil
+}
+
+func parseDays(s string) ([7]bool, error) {
+	var ret [7]bool
+	switch strings.ToLower(s) {
+	case "daily", "*":
+		return [7]bool{true, true, true, true, true, true, true}, nil
+	case "weekdays":
+		return [7]bool{false, true, true, true, true, true, false}, nil
+	case "weekends":
+		return [7]bool{true, false, false, false, false, false, true}, nil
+	}
+	for _, r := range strings.Split(strings.ToLower(s), ",") {
+		bounds := strings.Split(r, "-")
+		if len(bounds) > 2 {
+			return ret, fmt.Errorf("invalid day range %q", r)
+		}
+		from, ok := weekdays[bounds[0]]
+		if !ok {
+			return ret, fmt.Errorf("invalid day %q", bounds[0])
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, ok = weekdays[bounds[1]]; !ok {
+				return ret, fmt.Errorf("invalid day %q", bounds[1])
+			}
+		}
+		// Ranges may wrap around the end of the week, e.g. fri-mon.
+		for d := from; ; d = (d + 1) % 7 {
+			ret[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("time %q is not of the form HH:MM", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 || h == 24 && m != 0 {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// midnight returns the start of the UTC day of t.
+func midnight(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Contains returns true if the window is open at time t.
+func (w ScaleWindow) Contains(t time.Time) bool {
+	start := midnight(t)
+	offset := t.Sub(start)
+	wd := t.UTC().Weekday()
+	if w.Start < w.End {
+		return w.Days[wd] && offset >= w.Start && offset < w.End
+	}
+	// The window spans midnight, so it might have opened yesterday.
+	return w.Days[wd] && offset >= w.Start || w.Days[(wd+6)%7] && offset < w.End
+}
+
+// MinScale returns the highest MinScale of the windows open at time t,
+// or 0 if none is open.
+func (s ScaleSchedule) MinScale(t time.Time) int32 {
+	var ret int32
+	for _, w := range s {
+		if w.MinScale > ret && w.Contains(t) {
+			ret = w.MinScale
+		}
+	}
+	return ret
+}
+
+// NextBoundary returns the earliest time after t at which any of the
+// windows might open or close.
+func (s ScaleSchedule) NextBoundary(t time.Time) time.Time {
+	var ret time.Time
+	today := midnight(t)
+	for _, w := range s {
+		// The boundaries repeat daily, so looking at today and tomorrow
+		// is sufficient to find the next one.
+		for _, d := range []time.Time{today, today.Add(day)} {
+			for _, b := range []time.Time{d.Add(w.Start), d.Add(w.End)} {
+				if b.After(t) && (ret.IsZero() || b.Before(ret)) {
+					ret = b
+				}
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScaleSchedule(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    ScaleSchedule
+		wantErr bool
+	}{{
+		name:  "weekdays",
+		value: "weekdays 08:00-18:00 minScale=20",
+		want: ScaleSchedule{{
+			Days:     [7]bool{false, true, true, true, true, true, false},
+			Start:    8 * time.Hour,
+			End:      18 * time.Hour,
+			MinScale: 20,
+		}},
+	}, {
+		name:  "multiple windows and day lists",
+		value: "fri-mon 22:30-06:00 minScale=2; Wed,sat 00:00-24:00 minScale=1;",
+		want: ScaleSchedule{{
+			Days:     [7]bool{true, true, false, false, false, true, true},
+			Start:    22*time.Hour + 30*time.Minute,
+			End:      6 * time.Hour,
+			MinScale: 2,
+		}, {
+			Days:     [7]bool{false, false, false, true, false, false, true},
+			Start:    0,
+			End:      24 * time.Hour,
+			MinScale: 1,
+		}},
+	}, {
+		name:    "empty",
+		value:   " ; ",
+		wantErr: true,
+	}, {
+		name:    "bad day",
+		value:   "someday 08:00-18:00 minScale=20",
+		wantErr: true,
+	}, {
+		name:    "bad time",
+		value:   "daily 08:00-25:00 minScale=20",
+		wantErr: true,
+	}, {
+		name:    "empty range",
+		value:   "daily 08:00-08:00 minScale=20",
+		wantErr: true,
+	}, {
+		name:    "missing minScale",
+		value:   "daily 08:00-18:00 20",
+		wantErr: true,
+	}, {
+		name:    "negative minScale",
+		value:   "daily 08:00-18:00 minScale=-1",
+		wantErr: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseScaleSchedule(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseScaleSchedule() = %v, wantErr: %v", err, tc.wantErr)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("ParseScaleSchedule() (-want, +got) = %s", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestScaleScheduleMinScale(t *testing.T) {
+	schedule, err := ParseScaleSchedule("weekdays 08:00-18:00 minScale=20; fri 22:00-02:00 minScale=5; mon 09:00-10:00 minScale=30")
+	if err != nil {
+		t.Fatalf("ParseScaleSchedule() = %v", err)
+	}
+	// 2019-10-14 is a Monday.
+	monday := time.Date(2019, 10, 14, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		t    time.Time
+		want int32
+	}{{
+		name: "monday night",
+		t:    monday.Add(3 * time.Hour),
+		want: 0,
+	}, {
+		name: "monday morning",
+		t:    monday.Add(8 * time.Hour),
+		want: 20,
+	}, {
+		name: "overlapping windows",
+		t:    monday.Add(9*time.Hour + 30*time.Minute),
+		want: 30,
+	}, {
+		name: "monday evening",
+		t:    monday.Add(18 * time.Hour),
+		want: 0,
+	}, {
+		name: "friday late",
+		t:    monday.Add(4*day + 23*time.Hour),
+		want: 5,
+	}, {
+		name: "past midnight",
+		t:    monday.Add(5*day + time.Hour),
+		want: 5,
+	}, {
+		name: "saturday morning",
+		t:    monday.Add(5*day + 9*time.Hour),
+		want: 0,
+	}, {
+		name: "other time zone",
+		t:    monday.Add(8 * time.Hour).In(time.FixedZone("PDT", -7*3600)),
+		want: 20,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := schedule.MinScale(tc.t); got != tc.want {
+				t.Errorf("MinScale(%v) = %d, want: %d", tc.t, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScaleScheduleNextBoundary(t *testing.T) {
+	schedule, err := ParseScaleSchedule("weekdays 08:00-18:00 minScale=20; fri 22:00-02:00 minScale=5")
+	if err != nil {
+		t.Fatalf("ParseScaleSchedule() = %v", err)
+	}
+	monday := time.Date(2019, 10, 14, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{{
+		name: "before the first window",
+		t:    monday.Add(time.Hour),
+		want: monday.Add(2 * time.Hour),
+	}, {
+		name: "at a boundary",
+		t:    monday.Add(8 * time.Hour),
+		want: monday.Add(18 * time.Hour),
+	}, {
+		name: "late evening",
+		t:    monday.Add(23 * time.Hour),
+		want: monday.Add(day + 2*time.Hour),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := schedule.NextBoundary(tc.t); !got.Equal(tc.want) {
+				t.Errorf("NextBoundary(%v) = %v, want: %v", tc.t, got, tc.want)
+			}
+		})
+	}
+}
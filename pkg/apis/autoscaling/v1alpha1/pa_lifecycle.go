@@ -54,6 +54,16 @@ func (pa *PodAutoscaler) Metric() string {
 	return defaultMetric(pa.Class())
 }
 
+// Algorithm returns the contents of the algorithm annotation or the default
+// KPA scaling algorithm.
+func (pa *PodAutoscaler) Algorithm() string {
+	// The value is validated in the webhook.
+	if a, ok := pa.Annotations[autoscaling.AlgorithmAnnotationKey]; ok {
+		return a
+	}
+	return autoscaling.KPADefaultAlgorithm
+}
+
 func (pa *PodAutoscaler) annotationInt32(key string) int32 {
 	if s, ok := pa.Annotations[key]; ok {
 		// no error check: relying on validation
@@ -87,11 +97,47 @@ func (pa *PodAutoscaler) ScaleBounds() (min, max int32) {
 	return
 }
 
+// MinScaleSchedule returns the parsed min scale schedule annotation value or
+// false if not present, or invalid.
+// Note: like min, the schedule should be ignored if the PA is not reachable.
+func (pa *PodAutoscaler) MinScaleSchedule() (autoscaling.ScaleSchedule, bool) {
+	if s, ok := pa.Annotations[autoscaling.MinScaleScheduleAnnotationKey]; ok {
+		schedule, err := autoscaling.ParseScaleSchedule(s)
+		return schedule, err == nil
+	}
+	return nil, false
+}
+
+// ScaleBoundsAt returns the scale bounds in effect at the given time. They
+// are the bounds of ScaleBounds, with min raised to the min scale of the
+// schedule windows open at that time, but not above max.
+func (pa *PodAutoscaler) ScaleBoundsAt(now time.Time) (min, max int32) {
+	min, max = pa.ScaleBounds()
+	if pa.Spec.Reachability == ReachabilityUnreachable {
+		return
+	}
+	if schedule, ok := pa.MinScaleSchedule(); ok {
+		if scheduled := schedule.MinScale(now); scheduled > min {
+			min = scheduled
+			if max != 0 && min > max {
+				min = max
+			}
+		}
+	}
+	return
+}
+
 // Target returns the target annotation value or false if not present, or invalid.
 func (pa *PodAutoscaler) Target() (float64, bool) {
 	return pa.annotationFloat64(autoscaling.TargetAnnotationKey)
 }
 
+// TargetMillicores returns the targetMillicores annotation value or false if
+// not present, or invalid.
+func (pa *PodAutoscaler) TargetMillicores() (float64, bool) {
+	return pa.annotationFloat64(autoscaling.TargetMillicoresAnnotationKey)
+}
+
 // TargetUtilization returns the target capacity utilization as a fraction,
 // if the corresponding annotation is set.
 func (pa *PodAutoscaler) TargetUtilization() (float64, bool) {
@@ -118,6 +164,24 @@ func (pa *PodAutoscaler) Window() (window time.Duration, ok bool) {
 	return 0, false
 }
 
+// MaxScaleDownRate returns the max scale down rate annotation value or false
+// if not present.
+func (pa *PodAutoscaler) MaxScaleDownRate() (float64, bool) {
+	// The value is validated in the webhook.
+	return pa.annotationFloat64(autoscaling.MaxScaleDownRateAnnotationKey)
+}
+
+// ScaleDownStabilizationWindow returns the scale down stabilization window
+// annotation value or false if not present.
+func (pa *PodAutoscaler) ScaleDownStabilizationWindow() (time.Duration, bool) {
+	// The value is validated in the webhook.
+	if s, ok := pa.Annotations[autoscaling.ScaleDownStabilizationWindowAnnotationKey]; ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	return 0, false
+}
+
 // PanicWindowPercentage returns panic window annotation value or false if not present.
 func (pa *PodAutoscaler) PanicWindowPercentage() (percentage float64, ok bool) {
 	// The value is validated in the webhook.
@@ -590,6 +590,84 @@ func TestScaleBounds(t *testing.T) {
 	}
 }
 
+func TestScaleBoundsAt(t *testing.T) {
+	// A Monday.
+	now := time.Date(2019, 10, 7, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		min          string
+		max          string
+		schedule     string
+		reachability ReachabilityType
+		wantMin      int32
+		wantMax      int32
+	}{{
+		name:    "no schedule",
+		min:     "1",
+		max:     "100",
+		wantMin: 1,
+		wantMax: 100,
+	}, {
+		name:     "window open",
+		min:      "1",
+		max:      "100",
+		schedule: "weekdays 08:00-18:00 minScale=20",
+		wantMin:  20,
+		wantMax:  100,
+	}, {
+		name:     "window closed",
+		min:      "1",
+		max:      "100",
+		schedule: "weekdays 10:00-18:00 minScale=20",
+		wantMin:  1,
+		wantMax:  100,
+	}, {
+		name:     "min above the scheduled min scale",
+		min:      "30",
+		schedule: "weekdays 08:00-18:00 minScale=20",
+		wantMin:  30,
+	}, {
+		name:     "bound by max",
+		max:      "10",
+		schedule: "weekdays 08:00-18:00 minScale=20",
+		wantMin:  10,
+		wantMax:  10,
+	}, {
+		name:         "unreachable",
+		min:          "1",
+		max:          "100",
+		schedule:     "weekdays 08:00-18:00 minScale=20",
+		reachability: ReachabilityUnreachable,
+		wantMin:      0,
+		wantMax:      100,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pa := pa(map[string]string{})
+			if tc.min != "" {
+				pa.Annotations[autoscaling.MinScaleAnnotationKey] = tc.min
+			}
+			if tc.max != "" {
+				pa.Annotations[autoscaling.MaxScaleAnnotationKey] = tc.max
+			}
+			if tc.schedule != "" {
+				pa.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = tc.schedule
+			}
+			pa.Spec.Reachability = tc.reachability
+
+			min, max := pa.ScaleBoundsAt(now)
+
+			if min != tc.wantMin {
+				t.Errorf("got min: %v wanted: %v", min, tc.wantMin)
+			}
+			if max != tc.wantMax {
+				t.Errorf("got max: %v wanted: %v", max, tc.wantMax)
+			}
+		})
+	}
+}
+
 func TestMarkResourceNotOwned(t *testing.T) {
 	pa := pa(map[string]string{})
 	pa.Status.MarkResourceNotOwned("doesn't", "matter")
@@ -696,6 +774,32 @@ func TestMetric(t *testing.T) {
 	}
 }
 
+func TestAlgorithm(t *testing.T) {
+	cases := []struct {
+		name string
+		pa   *PodAutoscaler
+		want string
+	}{{
+		name: "not present",
+		pa:   pa(map[string]string{}),
+		want: autoscaling.KPADefaultAlgorithm,
+	}, {
+		name: "present",
+		pa: pa(map[string]string{
+			autoscaling.AlgorithmAnnotationKey: autoscaling.StepAlgorithm,
+		}),
+		want: autoscaling.StepAlgorithm,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.pa.Algorithm(); got != tc.want {
+				t.Errorf("Algorithm() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestWindowAnnotation(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -736,6 +840,114 @@ func TestWindowAnnotation(t *testing.T) {
 	}
 }
 
+func TestMinScaleScheduleAnnotation(t *testing.T) {
+	cases := []struct {
+		name    string
+		pa      *PodAutoscaler
+		wantLen int
+		wantOk  bool
+	}{{
+		name: "not present",
+		pa:   pa(map[string]string{}),
+	}, {
+		name: "present",
+		pa: pa(map[string]string{
+			autoscaling.MinScaleScheduleAnnotationKey: "weekdays 08:00-18:00 minScale=20; sat 10:00-12:00 minScale=2",
+		}),
+		wantLen: 2,
+		wantOk:  true,
+	}, {
+		name: "invalid",
+		pa: pa(map[string]string{
+			autoscaling.MinScaleScheduleAnnotationKey: "always",
+		}),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, gotOk := tc.pa.MinScaleSchedule()
+			if len(got) != tc.wantLen {
+				t.Errorf("len(MinScaleSchedule()) = %d, want: %d", len(got), tc.wantLen)
+			}
+			if gotOk != tc.wantOk {
+				t.Errorf("MinScaleSchedule() ok = %v, want: %v", gotOk, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestMaxScaleDownRateAnnotation(t *testing.T) {
+	cases := []struct {
+		name     string
+		pa       *PodAutoscaler
+		wantRate float64
+		wantOk   bool
+	}{{
+		name: "not present",
+		pa:   pa(map[string]string{}),
+	}, {
+		name: "present",
+		pa: pa(map[string]string{
+			autoscaling.MaxScaleDownRateAnnotationKey: "1.5",
+		}),
+		wantRate: 1.5,
+		wantOk:   true,
+	}, {
+		name: "invalid",
+		pa: pa(map[string]string{
+			autoscaling.MaxScaleDownRateAnnotationKey: "fast",
+		}),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotRate, gotOk := tc.pa.MaxScaleDownRate()
+			if gotRate != tc.wantRate {
+				t.Errorf("MaxScaleDownRate() = %v, want: %v", gotRate, tc.wantRate)
+			}
+			if gotOk != tc.wantOk {
+				t.Errorf("MaxScaleDownRate() ok = %v, want: %v", gotOk, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestScaleDownStabilizationWindowAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		pa         *PodAutoscaler
+		wantWindow time.Duration
+		wantOk     bool
+	}{{
+		name: "not present",
+		pa:   pa(map[string]string{}),
+	}, {
+		name: "present",
+		pa: pa(map[string]string{
+			autoscaling.ScaleDownStabilizationWindowAnnotationKey: "5m",
+		}),
+		wantWindow: 5 * time.Minute,
+		wantOk:     true,
+	}, {
+		name: "invalid",
+		pa: pa(map[string]string{
+			autoscaling.ScaleDownStabilizationWindowAnnotationKey: "long",
+		}),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotWindow, gotOk := tc.pa.ScaleDownStabilizationWindow()
+			if gotWindow != tc.wantWindow {
+				t.Errorf("ScaleDownStabilizationWindow() = %v, want: %v", gotWindow, tc.wantWindow)
+			}
+			if gotOk != tc.wantOk {
+				t.Errorf("ScaleDownStabilizationWindow() ok = %v, want: %v", gotOk, tc.wantOk)
+			}
+		})
+	}
+}
+
 func TestPanicWindowPercentageAnnotation(t *testing.T) {
 	cases := []struct {
 		name           string
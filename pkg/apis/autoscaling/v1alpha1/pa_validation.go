@@ -48,7 +48,7 @@ func (pa *PodAutoscaler) validateMetric() *apis.FieldError {
 		switch pa.Class() {
 		case autoscaling.KPA:
 			switch metric {
-			case autoscaling.Concurrency, autoscaling.RPS:
+			case autoscaling.Concurrency, autoscaling.RPS, autoscaling.CPU, autoscaling.Memory, autoscaling.Custom, autoscaling.Connections:
 				return nil
 			}
 		case autoscaling.HPA:
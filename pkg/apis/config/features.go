@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// FeaturesConfigName is the name of config map for the features.
+	FeaturesConfigName = "config-features"
+)
+
+// Flag is the state of a feature.
+type Flag string
+
+const (
+	// Enabled turns the feature on.
+	Enabled Flag = "Enabled"
+	// Disabled turns the feature off.
+	Disabled Flag = "Disabled"
+)
+
+// NewFeaturesConfigFromMap creates a Features from the supplied Map
+func NewFeaturesConfigFromMap(data map[string]string) (*Features, error) {
+	nc := &Features{}
+
+	for _, flag := range []struct {
+		key   string
+		field *Flag
+	}{{
+		key:   "kubernetes.podspec-affinity",
+		field: &nc.PodSpecAffinity,
+	}, {
+		key:   "kubernetes.podspec-tolerations",
+		field: &nc.PodSpecTolerations,
+	}, {
+		key:   "kubernetes.podspec-nodeselector",
+		field: &nc.PodSpecNodeSelector,
+	}, {
+		key:   "kubernetes.podspec-securitycontext",
+		field: &nc.PodSpecSecurityContext,
+	}, {
+		key:   "kubernetes.podspec-runtimeclassname",
+		field: &nc.PodSpecRuntimeClassName,
+	}, {
+		key:   "kubernetes.podspec-imagepullsecrets",
+		field: &nc.PodSpecImagePullSecrets,
+	}, {
+		key:   "kubernetes.podspec-priorityclassname",
+		field: &nc.PodSpecPriorityClassName,
+	}} {
+		raw, ok := data[flag.key]
+		if !ok {
+			*flag.field = Disabled
+			continue
+		}
+		switch {
+		case strings.EqualFold(raw, string(Enabled)):
+			*flag.field = Enabled
+		case strings.EqualFold(raw, string(Disabled)):
+			*flag.field = Disabled
+		default:
+			return nil, fmt.Errorf("%s must be %q or %q, was: %q", flag.key, Enabled, Disabled, raw)
+		}
+	}
+
+	return nc, nil
+}
+
+// NewFeaturesConfigFromConfigMap creates a Features from the supplied configMap
+func NewFeaturesConfigFromConfigMap(config *corev1.ConfigMap) (*Features, error) {
+	return NewFeaturesConfigFromMap(config.Data)
+}
+
+// Features holds the flags enabling the optional fields of the Revisions'
+// PodSpec, which are disallowed by default.
+type Features struct {
+	PodSpecAffinity          Flag
+	PodSpecTolerations       Flag
+	PodSpecNodeSelector      Flag
+	PodSpecSecurityContext   Flag
+	PodSpecRuntimeClassName  Flag
+	PodSpecImagePullSecrets  Flag
+	PodSpecPriorityClassName Flag
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/configmap/testing"
+	_ "knative.dev/pkg/system/testing"
+)
+
+func TestFeaturesConfigurationFromFile(t *testing.T) {
+	cm, example := ConfigMapsFromTestFile(t, FeaturesConfigName)
+
+	if _, err := NewFeaturesConfigFromConfigMap(cm); err != nil {
+		t.Errorf("NewFeaturesConfigFromConfigMap(actual) = %v", err)
+	}
+
+	if _, err := NewFeaturesConfigFromConfigMap(example); err != nil {
+		t.Errorf("NewFeaturesConfigFromConfigMap(example) = %v", err)
+	}
+}
+
+func TestFeaturesConfiguration(t *testing.T) {
+	configTests := []struct {
+		name         string
+		wantErr      bool
+		wantFeatures *Features
+		data         map[string]string
+	}{{
+		name: "defaults configuration",
+		wantFeatures: &Features{
+			PodSpecAffinity:          Disabled,
+			PodSpecTolerations:       Disabled,
+			PodSpecNodeSelector:      Disabled,
+			PodSpecSecurityContext:   Disabled,
+			PodSpecRuntimeClassName:  Disabled,
+			PodSpecImagePullSecrets:  Disabled,
+			PodSpecPriorityClassName: Disabled,
+		},
+		data: map[string]string{},
+	}, {
+		name: "specified values",
+		wantFeatures: &Features{
+			PodSpecAffinity:          Enabled,
+			PodSpecTolerations:       Enabled,
+			PodSpecNodeSelector:      Disabled,
+			PodSpecSecurityContext:   Enabled,
+			PodSpecRuntimeClassName:  Disabled,
+			PodSpecImagePullSecrets:  Enabled,
+			PodSpecPriorityClassName: Disabled,
+		},
+		data: map[string]string{
+			"kubernetes.podspec-affinity":         "Enabled",
+			"kubernetes.podspec-tolerations":      "enabled",
+			"kubernetes.podspec-nodeselector":     "Disabled",
+			"kubernetes.podspec-securitycontext":  "ENABLED",
+			"kubernetes.podspec-imagepullsecrets": "Enabled",
+		},
+	}, {
+		name:    "bad flag",
+		wantErr: true,
+		data: map[string]string{
+			"kubernetes.podspec-affinity": "true",
+		},
+	}}
+
+	for _, tt := range configTests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualFeatures, err := NewFeaturesConfigFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      FeaturesConfigName,
+				},
+				Data: tt.data,
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFeaturesConfigFromConfigMap() error = %v, WantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.wantFeatures, actualFeatures); diff != "" {
+				t.Errorf("NewFeaturesConfigFromConfigMap() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ type cfgKey struct{}
 // +k8s:deepcopy-gen=false
 type Config struct {
 	Defaults *Defaults
+	Features *Features
 }
 
 // FromContext extracts a Config from the provided context.
@@ -46,8 +47,10 @@ func FromContextOrDefaults(ctx context.Context) *Config {
 		return cfg
 	}
 	defaults, _ := NewDefaultsConfigFromMap(map[string]string{})
+	features, _ := NewFeaturesConfigFromMap(map[string]string{})
 	return &Config{
 		Defaults: defaults,
+		Features: features,
 	}
 }
 
@@ -71,6 +74,7 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 			logger,
 			configmap.Constructors{
 				DefaultsConfigName: NewDefaultsConfigFromConfigMap,
+				FeaturesConfigName: NewFeaturesConfigFromConfigMap,
 			},
 			onAfterStore...,
 		),
@@ -88,5 +92,6 @@ func (s *Store) ToContext(ctx context.Context) context.Context {
 func (s *Store) Load() *Config {
 	return &Config{
 		Defaults: s.UntypedLoad(DefaultsConfigName).(*Defaults).DeepCopy(),
+		Features: s.UntypedLoad(FeaturesConfigName).(*Features).DeepCopy(),
 	}
 }
@@ -37,8 +37,10 @@ func TestStoreLoadWithContext(t *testing.T) {
 	store := NewStore(logtesting.TestLogger(t))
 
 	defaultsConfig := ConfigMapFromTestFile(t, DefaultsConfigName)
+	featuresConfig := ConfigMapFromTestFile(t, FeaturesConfigName)
 
 	store.OnConfigChanged(defaultsConfig)
+	store.OnConfigChanged(featuresConfig)
 
 	config := FromContextOrDefaults(store.ToContext(context.Background()))
 
@@ -48,12 +50,20 @@ func TestStoreLoadWithContext(t *testing.T) {
 			t.Errorf("Unexpected defaults config (-want, +got): %v", diff)
 		}
 	})
+
+	t.Run("features", func(t *testing.T) {
+		expected, _ := NewFeaturesConfigFromConfigMap(featuresConfig)
+		if diff := cmp.Diff(expected, config.Features); diff != "" {
+			t.Errorf("Unexpected features config (-want, +got): %v", diff)
+		}
+	})
 }
 
 func TestStoreLoadWithContextOrDefaults(t *testing.T) {
 	defer logtesting.ClearAll()
 
 	defaultsConfig := ConfigMapFromTestFile(t, DefaultsConfigName)
+	featuresConfig := ConfigMapFromTestFile(t, FeaturesConfigName)
 	config := FromContextOrDefaults(context.Background())
 
 	t.Run("defaults", func(t *testing.T) {
@@ -62,6 +72,13 @@ func TestStoreLoadWithContextOrDefaults(t *testing.T) {
 			t.Errorf("Unexpected defaults config (-want, +got): %v", diff)
 		}
 	})
+
+	t.Run("features", func(t *testing.T) {
+		expected, _ := NewFeaturesConfigFromConfigMap(featuresConfig)
+		if diff := cmp.Diff(expected, config.Features); diff != "" {
+			t.Errorf("Unexpected features config (-want, +got): %v", diff)
+		}
+	})
 }
 
 func TestStoreImmutableConfig(t *testing.T) {
@@ -69,14 +86,19 @@ func TestStoreImmutableConfig(t *testing.T) {
 	store := NewStore(logtesting.TestLogger(t))
 
 	store.OnConfigChanged(ConfigMapFromTestFile(t, DefaultsConfigName))
+	store.OnConfigChanged(ConfigMapFromTestFile(t, FeaturesConfigName))
 
 	config := store.Load()
 
 	config.Defaults.RevisionTimeoutSeconds = 1234
+	config.Features.PodSpecAffinity = Enabled
 
 	newConfig := store.Load()
 
 	if newConfig.Defaults.RevisionTimeoutSeconds == 1234 {
 		t.Error("Defaults config is not immutable")
 	}
+	if newConfig.Features.PodSpecAffinity == Enabled {
+		t.Error("Features config is not immutable")
+	}
 }
@@ -337,6 +337,11 @@ func TestClusterIngressDefaulting(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{Name: config.DefaultsConfigName},
 				Data:       map[string]string{"max-revision-timeout-seconds": "2000"},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 			return s.ToContext(ctx)
 		},
 	}}
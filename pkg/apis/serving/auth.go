@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// reservedHeaderPrefixes are the prefixes of the headers Knative uses
+// internally, which validated claims must not be forwarded in.
+var reservedHeaderPrefixes = []string{"K-", "Knative-"}
+
+// ParseAuthRequiredClaims parses the value of QueueSideCarAuthRequiredClaimsAnnotation
+// into a map from claim to required value.
+func ParseAuthRequiredClaims(s string) (map[string]string, error) {
+	return parsePairs(s)
+}
+
+// ParseAuthClaimHeaders parses the value of QueueSideCarAuthClaimHeadersAnnotation
+// into a map from claim to the canonical name of the header it is forwarded in.
+func ParseAuthClaimHeaders(s string) (map[string]string, error) {
+	pairs, err := parsePairs(s)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(pairs))
+	for claim, header := range pairs {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return nil, fmt.Errorf("invalid header name %q", header)
+		}
+		header = http.CanonicalHeaderKey(header)
+		if header == "Authorization" {
+			return nil, fmt.Errorf("claim %q can't be forwarded in the Authorization header", claim)
+		}
+		for _, prefix := range reservedHeaderPrefixes {
+			if strings.HasPrefix(header, prefix) {
+				return nil, fmt.Errorf("claim %q can't be forwarded in the internal header %q", claim, header)
+			}
+		}
+		headers[claim] = header
+	}
+	return headers, nil
+}
+
+// parsePairs parses a comma separated list of key=value pairs.
+func parsePairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return pairs, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if key == "" || value == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		if _, ok := pairs[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		pairs[key] = value
+	}
+	return pairs, nil
+}
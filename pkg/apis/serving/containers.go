@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ServingContainerIndex returns the index of the container requests are
+// served by: the only container, or else the one declaring a port. The
+// others are sidecars. It returns -1 if there is no such container, which
+// validation guarantees never happens.
+func ServingContainerIndex(containers []corev1.Container) int {
+	if len(containers) == 1 {
+		return 0
+	}
+	for i := range containers {
+		if len(containers[i].Ports) > 0 {
+			return i
+		}
+	}
+	return -1
+}
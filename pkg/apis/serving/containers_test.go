@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestServingContainerIndex(t *testing.T) {
+	port := []corev1.ContainerPort{{ContainerPort: 8888}}
+
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		want       int
+	}{{
+		name: "no containers",
+		want: -1,
+	}, {
+		name:       "single container",
+		containers: []corev1.Container{{Name: "foo"}},
+		want:       0,
+	}, {
+		name:       "single container with port",
+		containers: []corev1.Container{{Name: "foo", Ports: port}},
+		want:       0,
+	}, {
+		name:       "sidecars",
+		containers: []corev1.Container{{Name: "foo"}, {Name: "bar", Ports: port}, {Name: "baz"}},
+		want:       1,
+	}, {
+		name:       "no container with port",
+		containers: []corev1.Container{{Name: "foo"}, {Name: "bar"}},
+		want:       -1,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ServingContainerIndex(test.containers); got != test.want {
+				t.Errorf("ServingContainerIndex() = %d, want: %d", got, test.want)
+			}
+		})
+	}
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package serving
 
 import (
+	"context"
+
 	corev1 "k8s.io/api/core/v1"
+	"knative.dev/serving/pkg/apis/config"
 )
 
 // VolumeMask performs a _shallow_ copy of the Kubernetes Volume object to a new
@@ -134,20 +137,45 @@ func KeyToPathMask(in *corev1.KeyToPath) *corev1.KeyToPath {
 }
 
 // PodSpecMask performs a _shallow_ copy of the Kubernetes PodSpec object to a new
-// Kubernetes PodSpec object bringing over only the fields allowed in the Knative API. This
+// Kubernetes PodSpec object bringing over only the fields allowed in the Knative API,
+// including the ones enabled in the features config of the context. This
 // does not validate the contents or the bounds of the provided fields.
-func PodSpecMask(in *corev1.PodSpec) *corev1.PodSpec {
+func PodSpecMask(ctx context.Context, in *corev1.PodSpec) *corev1.PodSpec {
 	if in == nil {
 		return nil
 	}
 
 	out := new(corev1.PodSpec)
+	features := config.FromContextOrDefaults(ctx).Features
 
 	// Allowed fields
 	out.ServiceAccountName = in.ServiceAccountName
 	out.Containers = in.Containers
 	out.Volumes = in.Volumes
 
+	// Fields allowed by a feature flag
+	if features.PodSpecAffinity == config.Enabled {
+		out.Affinity = in.Affinity
+	}
+	if features.PodSpecTolerations == config.Enabled {
+		out.Tolerations = in.Tolerations
+	}
+	if features.PodSpecNodeSelector == config.Enabled {
+		out.NodeSelector = in.NodeSelector
+	}
+	if features.PodSpecSecurityContext == config.Enabled {
+		out.SecurityContext = in.SecurityContext
+	}
+	if features.PodSpecRuntimeClassName == config.Enabled {
+		out.RuntimeClassName = in.RuntimeClassName
+	}
+	if features.PodSpecImagePullSecrets == config.Enabled {
+		out.ImagePullSecrets = in.ImagePullSecrets
+	}
+	if features.PodSpecPriorityClassName == config.Enabled {
+		out.PriorityClassName = in.PriorityClassName
+	}
+
 	// Disallowed fields
 	// This list is unnecessary, but added here for clarity
 	out.InitContainers = nil
@@ -155,31 +183,49 @@ func PodSpecMask(in *corev1.PodSpec) *corev1.PodSpec {
 	out.TerminationGracePeriodSeconds = nil
 	out.ActiveDeadlineSeconds = nil
 	out.DNSPolicy = ""
-	out.NodeSelector = nil
 	out.AutomountServiceAccountToken = nil
 	out.NodeName = ""
 	out.HostNetwork = false
 	out.HostPID = false
 	out.HostIPC = false
 	out.ShareProcessNamespace = nil
-	out.SecurityContext = nil
-	out.ImagePullSecrets = nil
 	out.Hostname = ""
 	out.Subdomain = ""
-	out.Affinity = nil
 	out.SchedulerName = ""
-	out.Tolerations = nil
 	out.HostAliases = nil
-	out.PriorityClassName = ""
 	out.Priority = nil
 	out.DNSConfig = nil
 	out.ReadinessGates = nil
-	out.RuntimeClassName = nil
 	// TODO(mattmoor): Coming in 1.13: out.EnableServiceLinks = nil
 
 	return out
 }
 
+// PodSecurityContextMask performs a _shallow_ copy of the Kubernetes PodSecurityContext object to a new
+// Kubernetes PodSecurityContext object bringing over only the fields allowed in the Knative API. This
+// does not validate the contents or the bounds of the provided fields.
+func PodSecurityContextMask(in *corev1.PodSecurityContext) *corev1.PodSecurityContext {
+	if in == nil {
+		return nil
+	}
+
+	out := new(corev1.PodSecurityContext)
+
+	// Allowed fields
+	out.RunAsUser = in.RunAsUser
+	out.RunAsGroup = in.RunAsGroup
+	out.RunAsNonRoot = in.RunAsNonRoot
+	out.FSGroup = in.FSGroup
+	out.SupplementalGroups = in.SupplementalGroups
+
+	// Disallowed fields
+	// This list is unnecessary, but added here for clarity
+	out.SELinuxOptions = nil
+	out.Sysctls = nil
+
+	return out
+}
+
 // ContainerMask performs a _shallow_ copy of the Kubernetes Container object to a new
 // Kubernetes Container object bringing over only the fields allowed in the Knative API. This
 // does not validate the contents or the bounds of the provided fields.
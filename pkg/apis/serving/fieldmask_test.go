@@ -17,12 +17,14 @@ limitations under the License.
 package serving
 
 import (
+	"context"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/kmp"
 	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/config"
 )
 
 func TestVolumeMask(t *testing.T) {
@@ -111,7 +113,7 @@ func TestPodSpecMask(t *testing.T) {
 		}},
 	}
 
-	got := PodSpecMask(in)
+	got := PodSpecMask(context.Background(), in)
 
 	if &want == &got {
 		t.Errorf("Input and output share addresses. Want different addresses")
@@ -123,11 +125,118 @@ func TestPodSpecMask(t *testing.T) {
 		t.Errorf("PodSpecMask (-want, +got): %s", diff)
 	}
 
-	if got = PodSpecMask(nil); got != nil {
+	if got = PodSpecMask(context.Background(), nil); got != nil {
 		t.Errorf("PodSpecMask(nil) = %v, want: nil", got)
 	}
 }
 
+func TestPodSpecMaskWithFeatures(t *testing.T) {
+	in := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Image: "helloworld",
+		}},
+		Affinity:           &corev1.Affinity{},
+		Tolerations:        []corev1.Toleration{{Key: "gpu"}},
+		NodeSelector:       map[string]string{"pool": "gpu"},
+		SecurityContext:    &corev1.PodSecurityContext{},
+		RuntimeClassName:   ptr.String("gvisor"),
+		ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+		PriorityClassName:  "high",
+		HostNetwork:        true,
+		DNSPolicy:          corev1.DNSDefault,
+		ServiceAccountName: "default",
+	}
+	want := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Image: "helloworld",
+		}},
+		Affinity:           &corev1.Affinity{},
+		Tolerations:        []corev1.Toleration{{Key: "gpu"}},
+		NodeSelector:       map[string]string{"pool": "gpu"},
+		SecurityContext:    &corev1.PodSecurityContext{},
+		RuntimeClassName:   ptr.String("gvisor"),
+		ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+		PriorityClassName:  "high",
+		ServiceAccountName: "default",
+	}
+
+	ctx := withFeatures(context.Background(), config.Features{
+		PodSpecAffinity:          config.Enabled,
+		PodSpecTolerations:       config.Enabled,
+		PodSpecNodeSelector:      config.Enabled,
+		PodSpecSecurityContext:   config.Enabled,
+		PodSpecRuntimeClassName:  config.Enabled,
+		PodSpecImagePullSecrets:  config.Enabled,
+		PodSpecPriorityClassName: config.Enabled,
+	})
+	got := PodSpecMask(ctx, in)
+
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Errorf("Got error comparing output, err = %v", err)
+	} else if diff != "" {
+		t.Errorf("PodSpecMask (-want, +got): %s", diff)
+	}
+
+	// The fields are disallowed by default.
+	got = PodSpecMask(context.Background(), in)
+	want = &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Image: "helloworld",
+		}},
+		ServiceAccountName: "default",
+	}
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Errorf("Got error comparing output, err = %v", err)
+	} else if diff != "" {
+		t.Errorf("PodSpecMask (-want, +got): %s", diff)
+	}
+}
+
+func TestPodSecurityContextMask(t *testing.T) {
+	want := &corev1.PodSecurityContext{
+		RunAsUser:          ptr.Int64(1),
+		RunAsGroup:         ptr.Int64(2),
+		RunAsNonRoot:       ptr.Bool(true),
+		FSGroup:            ptr.Int64(3),
+		SupplementalGroups: []int64{4},
+	}
+	in := &corev1.PodSecurityContext{
+		RunAsUser:          ptr.Int64(1),
+		RunAsGroup:         ptr.Int64(2),
+		RunAsNonRoot:       ptr.Bool(true),
+		FSGroup:            ptr.Int64(3),
+		SupplementalGroups: []int64{4},
+		// Stripped out.
+		SELinuxOptions: &corev1.SELinuxOptions{},
+		Sysctls:        []corev1.Sysctl{{Name: "net.core.somaxconn"}},
+	}
+
+	got := PodSecurityContextMask(in)
+
+	if &want == &got {
+		t.Errorf("Input and output share addresses. Want different addresses")
+	}
+
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Errorf("Got error comparing output, err = %v", err)
+	} else if diff != "" {
+		t.Errorf("PodSecurityContextMask (-want, +got): %s", diff)
+	}
+
+	if got = PodSecurityContextMask(nil); got != nil {
+		t.Errorf("PodSecurityContextMask(nil) = %v, want: nil", got)
+	}
+}
+
+// withFeatures returns a context with the given features config.
+func withFeatures(ctx context.Context, features config.Features) context.Context {
+	cfg := config.FromContextOrDefaults(ctx)
+	return config.ToContext(ctx, &config.Config{
+		Defaults: cfg.Defaults,
+		Features: &features,
+	})
+}
+
 func TestContainerMask(t *testing.T) {
 	want := &corev1.Container{
 		Name:                     "foo",
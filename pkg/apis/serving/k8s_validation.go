@@ -17,6 +17,7 @@ limitations under the License.
 package serving
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"path/filepath"
@@ -25,6 +26,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
@@ -57,6 +59,15 @@ var (
 		"K_REVISION",
 	)
 
+	// The ports of the queue-proxy, which no other container can listen on.
+	reservedPorts = sets.NewInt(
+		networking.BackendHTTPPort,
+		networking.BackendHTTP2Port,
+		networking.QueueAdminPort,
+		networking.AutoscalingQueueMetricsPort,
+		networking.UserQueueMetricsPort,
+	)
+
 	// The port is named "user-port" on the deployment, but a user cannot set an arbitrary name on the port
 	// in Configuration. The name field is reserved for content-negotiation. Currently 'h2c' and 'http1' are
 	// allowed.
@@ -235,14 +246,19 @@ func validateEnvFrom(envFromList []corev1.EnvFromSource) *apis.FieldError {
 	return errs
 }
 
-func ValidatePodSpec(ps corev1.PodSpec) *apis.FieldError {
+// ValidatePodSpec validates the PodSpec of a Revision. The optional fields
+// are allowed by the features config of the context.
+func ValidatePodSpec(ctx context.Context, ps corev1.PodSpec) *apis.FieldError {
 	// This is inlined, and so it makes for a less meaningful
 	// error message.
 	// if equality.Semantic.DeepEqual(ps, corev1.PodSpec{}) {
 	// 	return apis.ErrMissingField(apis.CurrentField)
 	// }
 
-	errs := apis.CheckDisallowedFields(ps, *PodSpecMask(&ps))
+	mask := PodSpecMask(ctx, &ps)
+	errs := apis.CheckDisallowedFields(ps, *mask)
+	// The contents of the optional fields are only validated when they are allowed.
+	errs = errs.Also(validatePodSecurityContext(mask.SecurityContext).ViaField("securityContext"))
 
 	volumes, err := ValidateVolumes(ps.Volumes)
 	if err != nil {
@@ -256,7 +272,7 @@ func ValidatePodSpec(ps corev1.PodSpec) *apis.FieldError {
 		errs = errs.Also(ValidateContainer(ps.Containers[0], volumes).
 			ViaFieldIndex("containers", 0))
 	default:
-		errs = errs.Also(apis.ErrMultipleOneOf("containers"))
+		errs = errs.Also(validateContainers(ps.Containers, volumes))
 	}
 	if ps.ServiceAccountName != "" {
 		for range validation.IsDNS1123Subdomain(ps.ServiceAccountName) {
@@ -266,10 +282,113 @@ func ValidatePodSpec(ps corev1.PodSpec) *apis.FieldError {
 	return errs
 }
 
+// validateContainers validates the containers of a PodSpec with sidecars.
+// Exactly one of them has to declare a port, which makes it the serving
+// container, their names have to be unique, and every volume has to be
+// mounted by at least one of them.
+func validateContainers(containers []corev1.Container, volumes sets.String) *apis.FieldError {
+	var (
+		errs      *apis.FieldError
+		portPaths []string
+		allPaths  []string
+	)
+	names := sets.NewString()
+	mounted := sets.NewString()
+	for i := range containers {
+		container := containers[i].DeepCopy()
+		path := fmt.Sprintf("containers[%d].ports", i)
+		allPaths = append(allPaths, path)
+		if len(container.Ports) > 0 {
+			portPaths = append(portPaths, path)
+		} else {
+			// Sidecars aren't probed through the queue-proxy, so their probes
+			// have to name the port they are served on.
+			errs = errs.Also(validateSidecarProbe(container.LivenessProbe).
+				ViaField("livenessProbe").ViaFieldIndex("containers", i))
+			errs = errs.Also(validateSidecarProbe(container.ReadinessProbe).
+				ViaField("readinessProbe").ViaFieldIndex("containers", i))
+		}
+
+		if name := container.Name; name != "" {
+			if names.Has(name) {
+				errs = errs.Also((&apis.FieldError{
+					Message: fmt.Sprintf("duplicate container name %q", name),
+					Paths:   []string{"name"},
+				}).ViaFieldIndex("containers", i))
+			}
+			names.Insert(name)
+		}
+		for _, vm := range container.VolumeMounts {
+			mounted.Insert(vm.Name)
+		}
+
+		errs = errs.Also(validateContainer(*container, volumes).
+			ViaFieldIndex("containers", i))
+	}
+
+	switch len(portPaths) {
+	case 0:
+		errs = errs.Also(apis.ErrMissingOneOf(allPaths...))
+	case 1:
+	default:
+		errs = errs.Also(apis.ErrMultipleOneOf(portPaths...))
+	}
+	return errs.Also(validateVolumesMounted(volumes, mounted).ViaField("containers"))
+}
+
+// validateSidecarProbe validates the port of the HTTP or TCP probe of a
+// sidecar and clears it, to have the rest of the probe validated like the
+// ones of the serving container.
+func validateSidecarProbe(p *corev1.Probe) *apis.FieldError {
+	if p == nil {
+		return nil
+	}
+	var (
+		port  *intstr.IntOrString
+		field string
+	)
+	switch {
+	case p.HTTPGet != nil:
+		port, field = &p.HTTPGet.Port, "httpGet.port"
+	case p.TCPSocket != nil:
+		port, field = &p.TCPSocket.Port, "tcpSocket.port"
+	default:
+		return nil
+	}
+
+	var errs *apis.FieldError
+	switch {
+	case port.Type == intstr.Int && port.IntVal == 0:
+		errs = apis.ErrMissingField(field)
+	case port.Type != intstr.Int:
+		errs = apis.ErrInvalidValue(port.String(), field)
+	case port.IntVal < 1 || port.IntVal > 65535:
+		errs = apis.ErrOutOfBoundsValue(port.IntVal, 1, 65535, field)
+	case reservedPorts.Has(int(port.IntVal)):
+		errs = apis.ErrInvalidValue(port.IntVal, field)
+	}
+	*port = intstr.IntOrString{}
+	return errs
+}
+
+// ValidateContainer validates the only container of a PodSpec, which has to
+// mount all the volumes.
 func ValidateContainer(container corev1.Container, volumes sets.String) *apis.FieldError {
 	if equality.Semantic.DeepEqual(container, corev1.Container{}) {
 		return apis.ErrMissingField(apis.CurrentField)
 	}
+	mounted := sets.NewString()
+	for _, vm := range container.VolumeMounts {
+		mounted.Insert(vm.Name)
+	}
+	return validateContainer(container, volumes).Also(
+		validateVolumesMounted(volumes, mounted).ViaField("volumeMounts"))
+}
+
+func validateContainer(container corev1.Container, volumes sets.String) *apis.FieldError {
+	if equality.Semantic.DeepEqual(container, corev1.Container{}) {
+		return apis.ErrMissingField(apis.CurrentField)
+	}
 
 	errs := apis.CheckDisallowedFields(container, *ContainerMask(&container))
 
@@ -339,11 +458,24 @@ func validateSecurityContext(sc *corev1.SecurityContext) *apis.FieldError {
 	return errs
 }
 
+func validatePodSecurityContext(sc *corev1.PodSecurityContext) *apis.FieldError {
+	if sc == nil {
+		return nil
+	}
+	errs := apis.CheckDisallowedFields(*sc, *PodSecurityContextMask(sc))
+
+	if sc.RunAsUser != nil {
+		uid := *sc.RunAsUser
+		if uid < minUserID || uid > maxUserID {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(uid, minUserID, maxUserID, "runAsUser"))
+		}
+	}
+	return errs
+}
+
 func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *apis.FieldError {
 	var errs *apis.FieldError
-	// Check that volume mounts match names in "volumes", that "volumes" has 100%
-	// coverage, and the field restrictions.
-	seenName := sets.NewString()
+	// Check that volume mounts match names in "volumes", and the field restrictions.
 	seenMountPath := sets.NewString()
 	for i, vm := range mounts {
 		errs = errs.Also(apis.CheckDisallowedFields(vm, *VolumeMountMask(&vm)).ViaIndex(i))
@@ -354,7 +486,6 @@ func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *api
 				Paths:   []string{"name"},
 			}).ViaIndex(i))
 		}
-		seenName.Insert(vm.Name)
 
 		if vm.MountPath == "" {
 			errs = errs.Also(apis.ErrMissingField("mountPath").ViaIndex(i))
@@ -376,14 +507,18 @@ func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *api
 		}
 
 	}
+	return errs
+}
 
-	if missing := volumes.Difference(seenName); missing.Len() > 0 {
-		errs = errs.Also(&apis.FieldError{
+// validateVolumesMounted checks that all the volumes are mounted.
+func validateVolumesMounted(volumes, mounted sets.String) *apis.FieldError {
+	if missing := volumes.Difference(mounted); missing.Len() > 0 {
+		return &apis.FieldError{
 			Message: fmt.Sprintf("volumes not mounted: %v", missing.List()),
 			Paths:   []string{apis.CurrentField},
-		})
+		}
 	}
-	return errs
+	return nil
 }
 
 func validateContainerPorts(ports []corev1.ContainerPort) *apis.FieldError {
@@ -414,11 +549,7 @@ func validateContainerPorts(ports []corev1.ContainerPort) *apis.FieldError {
 	}
 
 	// Don't allow userPort to conflict with QueueProxy sidecar
-	if userPort.ContainerPort == networking.BackendHTTPPort ||
-		userPort.ContainerPort == networking.BackendHTTP2Port ||
-		userPort.ContainerPort == networking.QueueAdminPort ||
-		userPort.ContainerPort == networking.AutoscalingQueueMetricsPort ||
-		userPort.ContainerPort == networking.UserQueueMetricsPort {
+	if reservedPorts.Has(int(userPort.ContainerPort)) {
 		errs = errs.Also(apis.ErrInvalidValue(userPort.ContainerPort, "containerPort"))
 	}
 
@@ -17,6 +17,7 @@ limitations under the License.
 package serving
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"testing"
@@ -28,12 +29,14 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/config"
 )
 
 func TestPodSpecValidation(t *testing.T) {
 	tests := []struct {
 		name string
 		ps   corev1.PodSpec
+		wc   func(context.Context) context.Context
 		want *apis.FieldError
 	}{{
 		name: "valid",
@@ -150,15 +153,218 @@ func TestPodSpecValidation(t *testing.T) {
 		},
 		want: apis.ErrMissingField("containers"),
 	}, {
-		name: "too many containers",
+		name: "sidecars",
 		ps: corev1.PodSpec{
 			Containers: []corev1.Container{{
+				Name:  "proxy",
 				Image: "busybox",
+				ReadinessProbe: &corev1.Probe{
+					SuccessThreshold: 1,
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/ready",
+							Port: intstr.FromInt(8081),
+						},
+					},
+				},
+				VolumeMounts: []corev1.VolumeMount{{
+					MountPath: "/mount/path",
+					Name:      "the-name",
+					ReadOnly:  true,
+				}},
+			}, {
+				Name:  "user-container",
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}, {
+				Name:  "logger",
+				Image: "busybox",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(8082),
+						},
+					},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "the-name",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: "foo",
+					},
+				},
+			}},
+		},
+		want: nil,
+	}, {
+		name: "no serving container",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}, {
+				Image: "helloworld",
+			}},
+		},
+		want: apis.ErrMissingOneOf("containers[0].ports", "containers[1].ports"),
+	}, {
+		name: "too many serving containers",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "busybox",
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}, {
+				Name:  "helloworld",
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 9999,
+				}},
+			}},
+		},
+		want: apis.ErrMultipleOneOf("containers[0].ports", "containers[1].ports"),
+	}, {
+		name: "duplicate container names",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "foo",
+				Image: "busybox",
+			}, {
+				Name:  "foo",
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}},
+		},
+		want: (&apis.FieldError{
+			Message: `duplicate container name "foo"`,
+			Paths:   []string{"name"},
+		}).ViaFieldIndex("containers", 1),
+	}, {
+		name: "volume mounted by no container",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}, {
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "the-name",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: "foo",
+					},
+				},
+			}},
+		},
+		want: &apis.FieldError{
+			Message: "volumes not mounted: [the-name]",
+			Paths:   []string{"containers"},
+		},
+	}, {
+		name: "sidecar probe without port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				ReadinessProbe: &corev1.Probe{
+					SuccessThreshold: 1,
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{},
+					},
+				},
+			}, {
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}},
+		},
+		want: apis.ErrMissingField("containers[0].readinessProbe.tcpSocket.port"),
+	}, {
+		name: "sidecar probe on a named port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Port: intstr.FromString("http"),
+						},
+					},
+				},
+			}, {
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}},
+		},
+		want: apis.ErrInvalidValue("http", "containers[0].livenessProbe.httpGet.port"),
+	}, {
+		name: "sidecar probe on a queue-proxy port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(8012),
+						},
+					},
+				},
 			}, {
 				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
 			}},
 		},
-		want: apis.ErrMultipleOneOf("containers"),
+		want: apis.ErrInvalidValue(8012, "containers[0].livenessProbe.tcpSocket.port"),
+	}, {
+		name: "serving container probe with port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}, {
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(8888),
+						},
+					},
+				},
+			}},
+		},
+		want: apis.ErrDisallowedFields("containers[1].livenessProbe.tcpSocket.port"),
+	}, {
+		name: "invalid sidecar",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "queue-proxy",
+				Image: "busybox",
+			}, {
+				Image: "helloworld",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}},
+		},
+		want: (&apis.FieldError{
+			Message: `"queue-proxy" is a reserved container name`,
+			Paths:   []string{"name"},
+		}).ViaFieldIndex("containers", 0),
 	}, {
 		name: "extra field",
 		ps: corev1.PodSpec{
@@ -179,11 +385,95 @@ func TestPodSpecValidation(t *testing.T) {
 			ServiceAccountName: "foo@bar.baz",
 		},
 		want: apis.ErrInvalidValue("serviceAccountName", "foo@bar.baz"),
+	}, {
+		name: "scheduling fields disabled",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			NodeSelector: map[string]string{"pool": "gpu"},
+			Tolerations: []corev1.Toleration{{
+				Key:    "gpu",
+				Effect: corev1.TaintEffectNoSchedule,
+			}},
+		},
+		want: apis.ErrDisallowedFields("nodeSelector", "tolerations"),
+	}, {
+		name: "scheduling fields enabled",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			NodeSelector: map[string]string{"pool": "gpu"},
+			Tolerations: []corev1.Toleration{{
+				Key:    "gpu",
+				Effect: corev1.TaintEffectNoSchedule,
+			}},
+		},
+		wc: func(ctx context.Context) context.Context {
+			return withFeatures(ctx, config.Features{
+				PodSpecNodeSelector: config.Enabled,
+				PodSpecTolerations:  config.Enabled,
+			})
+		},
+		want: nil,
+	}, {
+		name: "one of the scheduling fields enabled",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			NodeSelector: map[string]string{"pool": "gpu"},
+			Tolerations: []corev1.Toleration{{
+				Key:    "gpu",
+				Effect: corev1.TaintEffectNoSchedule,
+			}},
+		},
+		wc: func(ctx context.Context) context.Context {
+			return withFeatures(ctx, config.Features{
+				PodSpecNodeSelector: config.Enabled,
+			})
+		},
+		want: apis.ErrDisallowedFields("tolerations"),
+	}, {
+		name: "security context disabled",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			SecurityContext: &corev1.PodSecurityContext{
+				SELinuxOptions: &corev1.SELinuxOptions{},
+			},
+		},
+		want: apis.ErrDisallowedFields("securityContext"),
+	}, {
+		name: "security context enabled",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:      ptr.Int64(-1),
+				FSGroup:        ptr.Int64(1000),
+				SELinuxOptions: &corev1.SELinuxOptions{},
+			},
+		},
+		wc: func(ctx context.Context) context.Context {
+			return withFeatures(ctx, config.Features{
+				PodSpecSecurityContext: config.Enabled,
+			})
+		},
+		want: apis.ErrDisallowedFields("securityContext.seLinuxOptions").Also(
+			apis.ErrOutOfBoundsValue(-1, minUserID, maxUserID, "securityContext.runAsUser")),
 	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := ValidatePodSpec(test.ps)
+			ctx := context.Background()
+			if test.wc != nil {
+				ctx = test.wc(ctx)
+			}
+			got := ValidatePodSpec(ctx, test.ps)
 			if !cmp.Equal(test.want.Error(), got.Error()) {
 				t.Errorf("ValidatePodSpec (-want, +got) = %v",
 					cmp.Diff(test.want.Error(), got.Error()))
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import "fmt"
+
+// LoadBalancingPolicy is the policy the activator uses to pick the pod of a
+// revision to send a request to. All the policies prefer the pods that have
+// spare capacity for the request.
+type LoadBalancingPolicy string
+
+const (
+	// RoundRobinPolicy sends the requests to the pods in turn.
+	RoundRobinPolicy LoadBalancingPolicy = "round-robin"
+
+	// PowerOfTwoChoicesPolicy sends a request to the less loaded of two
+	// random pods.
+	PowerOfTwoChoicesPolicy LoadBalancingPolicy = "power-of-two-choices"
+
+	// LeastConnectionsPolicy sends a request to the pod with the fewest
+	// requests in flight.
+	LeastConnectionsPolicy LoadBalancingPolicy = "least-connections"
+
+	// ConsistentHashPolicy sends the requests with the same value of a header
+	// or cookie to the same pod, for session affinity. Requests without a
+	// value are handled like with LeastConnectionsPolicy.
+	ConsistentHashPolicy LoadBalancingPolicy = "consistent-hash"
+)
+
+// ParseLoadBalancingPolicy returns the LoadBalancingPolicy named s.
+func ParseLoadBalancingPolicy(s string) (LoadBalancingPolicy, error) {
+	switch p := LoadBalancingPolicy(s); p {
+	case RoundRobinPolicy, PowerOfTwoChoicesPolicy, LeastConnectionsPolicy, ConsistentHashPolicy:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown load balancing policy %q", s)
+	}
+}
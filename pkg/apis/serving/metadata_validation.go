@@ -18,6 +18,7 @@ package serving
 
 import (
 	"context"
+	"math"
 	"strconv"
 	"strings"
 
@@ -55,11 +56,31 @@ func validateKnativeAnnotations(annotations map[string]string) (errs *apis.Field
 	return
 }
 
-// ValidateQueueSidecarAnnotation validates QueueSideCarResourcePercentageAnnotation
+// ValidateQueueSidecarAnnotation validates QueueSideCarResourcePercentageAnnotation,
+// QueueSideCarAdaptiveConcurrencyAnnotation, QueueSideCarUserSocketAnnotation,
+// the rate limit annotations, the auth annotations and QueueSideCarRouteTemplatesAnnotation
 func ValidateQueueSidecarAnnotation(annotations map[string]string) *apis.FieldError {
 	if len(annotations) == 0 {
 		return nil
 	}
+	for _, key := range []string{QueueSideCarAdaptiveConcurrencyAnnotation, QueueSideCarUserSocketAnnotation} {
+		if v, ok := annotations[key]; ok {
+			if _, err := strconv.ParseBool(v); err != nil {
+				return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(key)
+			}
+		}
+	}
+	if err := validateRateLimitAnnotations(annotations); err != nil {
+		return err
+	}
+	if err := validateAuthAnnotations(annotations); err != nil {
+		return err
+	}
+	if v, ok := annotations[QueueSideCarRouteTemplatesAnnotation]; ok {
+		if _, err := ParseRouteTemplates(v); err != nil {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarRouteTemplatesAnnotation)
+		}
+	}
 	v, ok := annotations[QueueSideCarResourcePercentageAnnotation]
 	if !ok {
 		return nil
@@ -74,6 +95,77 @@ func ValidateQueueSidecarAnnotation(annotations map[string]string) *apis.FieldEr
 	return nil
 }
 
+func validateRateLimitAnnotations(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[QueueSideCarRateLimitAnnotation]; ok {
+		if value, err := strconv.ParseFloat(v, 64); err != nil || value <= 0 {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarRateLimitAnnotation)
+		}
+	}
+	if v, ok := annotations[QueueSideCarRateLimitBurstAnnotation]; ok {
+		if value, err := strconv.Atoi(v); err != nil || value < 1 {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarRateLimitBurstAnnotation)
+		}
+	}
+	if _, ok := annotations[QueueSideCarRateLimitAnnotation]; ok {
+		// The queue-proxies split the limit across maxScale pods as long as
+		// they don't know how many pods are ready. A malformed maxScale is
+		// reported by the autoscaling annotation validation.
+		if max, err := strconv.Atoi(annotations[autoscaling.MaxScaleAnnotationKey]); err != nil || max < 1 {
+			return &apis.FieldError{
+				Message: "rate limiting requires a maxScale of at least 1",
+				Paths:   []string{QueueSideCarRateLimitAnnotation, autoscaling.MaxScaleAnnotationKey},
+			}
+		}
+	}
+	return nil
+}
+
+func validateAuthAnnotations(annotations map[string]string) *apis.FieldError {
+	_, secret := annotations[QueueSideCarAuthJWKSSecretAnnotation]
+	_, configMap := annotations[QueueSideCarAuthJWKSConfigMapAnnotation]
+	if secret && configMap {
+		return apis.ErrMultipleOneOf(QueueSideCarAuthJWKSSecretAnnotation, QueueSideCarAuthJWKSConfigMapAnnotation)
+	}
+	for _, key := range []string{QueueSideCarAuthJWKSSecretAnnotation, QueueSideCarAuthJWKSConfigMapAnnotation} {
+		if v, ok := annotations[key]; ok && v == "" {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(key)
+		}
+	}
+	if !secret && !configMap {
+		// The other auth annotations are meaningless without keys to validate tokens against.
+		for _, key := range []string{QueueSideCarAuthIssuerAnnotation, QueueSideCarAuthAudienceAnnotation,
+			QueueSideCarAuthRequiredClaimsAnnotation, QueueSideCarAuthClaimHeadersAnnotation} {
+			if _, ok := annotations[key]; ok {
+				return apis.ErrMissingOneOf(QueueSideCarAuthJWKSSecretAnnotation, QueueSideCarAuthJWKSConfigMapAnnotation)
+			}
+		}
+		return nil
+	}
+	if v, ok := annotations[QueueSideCarAuthRequiredClaimsAnnotation]; ok {
+		if _, err := ParseAuthRequiredClaims(v); err != nil {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarAuthRequiredClaimsAnnotation)
+		}
+	}
+	if v, ok := annotations[QueueSideCarAuthClaimHeadersAnnotation]; ok {
+		if _, err := ParseAuthClaimHeaders(v); err != nil {
+			return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarAuthClaimHeadersAnnotation)
+		}
+	}
+	return nil
+}
+
+// ValidateLoadBalancingAnnotation validates LoadBalancingPolicyAnnotationKey
+func ValidateLoadBalancingAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[LoadBalancingPolicyAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseLoadBalancingPolicy(v); err != nil {
+		return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(LoadBalancingPolicyAnnotationKey)
+	}
+	return nil
+}
+
 // ValidateTimeoutSeconds validates timeout by comparing MaxRevisionTimeoutSeconds
 func ValidateTimeoutSeconds(ctx context.Context, timeoutSeconds int64) *apis.FieldError {
 	if timeoutSeconds != 0 {
@@ -87,6 +179,15 @@ func ValidateTimeoutSeconds(ctx context.Context, timeoutSeconds int64) *apis.Fie
 	return nil
 }
 
+// ValidateDurationSeconds validates that the optional duration in seconds
+// of the given field is not negative.
+func ValidateDurationSeconds(seconds *int64, field string) *apis.FieldError {
+	if seconds != nil && *seconds < 0 {
+		return apis.ErrOutOfBoundsValue(*seconds, 0, math.MaxInt32, field)
+	}
+	return nil
+}
+
 // ValidateContainerConcurrency function validates the ContainerConcurrency field
 // TODO(#5007): Move this to autoscaling.
 func ValidateContainerConcurrency(containerConcurrency *int64) *apis.FieldError {
@@ -26,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/config"
 	routeconfig "knative.dev/serving/pkg/reconciler/route/config"
 )
@@ -194,6 +195,146 @@ func TestValidateQueueSidecarAnnotation(t *testing.T) {
 			Message: "invalid value: ",
 			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarResourcePercentageAnnotation)},
 		},
+	}, {
+		name: "Invalid queue sidecar adaptive concurrency annotation",
+		annotation: map[string]string{
+			QueueSideCarAdaptiveConcurrencyAnnotation: "sometimes",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: sometimes",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarAdaptiveConcurrencyAnnotation)},
+		},
+	}, {
+		name: "Invalid queue sidecar user socket annotation",
+		annotation: map[string]string{
+			QueueSideCarUserSocketAnnotation: "maybe",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: maybe",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarUserSocketAnnotation)},
+		},
+	}, {
+		name: "Invalid queue sidecar rate limit annotation",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation: "fast",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: fast",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarRateLimitAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar rate limit annotation of 0",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation: "0",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: 0",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarRateLimitAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar rate limit burst annotation of 0",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation:      "10",
+			QueueSideCarRateLimitBurstAnnotation: "0",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: 0",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarRateLimitBurstAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar rate limit annotation without maxScale",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation: "10",
+		},
+		expectErr: &apis.FieldError{
+			Message: "rate limiting requires a maxScale of at least 1",
+			Paths:   []string{QueueSideCarRateLimitAnnotation, autoscaling.MaxScaleAnnotationKey},
+		},
+	}, {
+		name: "Queue sidecar rate limit annotation with unlimited maxScale",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation:   "10",
+			autoscaling.MaxScaleAnnotationKey: "0",
+		},
+		expectErr: &apis.FieldError{
+			Message: "rate limiting requires a maxScale of at least 1",
+			Paths:   []string{QueueSideCarRateLimitAnnotation, autoscaling.MaxScaleAnnotationKey},
+		},
+	}, {
+		name: "Queue sidecar rate limit annotations",
+		annotation: map[string]string{
+			QueueSideCarRateLimitAnnotation:      "10",
+			QueueSideCarRateLimitBurstAnnotation: "20",
+			autoscaling.MaxScaleAnnotationKey:    "5",
+		},
+		expectErr: (*apis.FieldError)(nil),
+	}, {
+		name: "Queue sidecar auth annotations",
+		annotation: map[string]string{
+			QueueSideCarAuthJWKSSecretAnnotation:     "jwks",
+			QueueSideCarAuthIssuerAnnotation:         "https://issuer.example.com",
+			QueueSideCarAuthAudienceAnnotation:       "my-service",
+			QueueSideCarAuthRequiredClaimsAnnotation: "groups=admins, email_verified=true",
+			QueueSideCarAuthClaimHeadersAnnotation:   "sub=X-User, email=X-Email",
+		},
+		expectErr: (*apis.FieldError)(nil),
+	}, {
+		name: "Queue sidecar auth JWKS from both a secret and a config map",
+		annotation: map[string]string{
+			QueueSideCarAuthJWKSSecretAnnotation:    "jwks",
+			QueueSideCarAuthJWKSConfigMapAnnotation: "jwks",
+		},
+		expectErr: apis.ErrMultipleOneOf(QueueSideCarAuthJWKSSecretAnnotation, QueueSideCarAuthJWKSConfigMapAnnotation),
+	}, {
+		name: "Queue sidecar auth JWKS secret empty",
+		annotation: map[string]string{
+			QueueSideCarAuthJWKSSecretAnnotation: "",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: ",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarAuthJWKSSecretAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar auth issuer without JWKS",
+		annotation: map[string]string{
+			QueueSideCarAuthIssuerAnnotation: "https://issuer.example.com",
+		},
+		expectErr: apis.ErrMissingOneOf(QueueSideCarAuthJWKSSecretAnnotation, QueueSideCarAuthJWKSConfigMapAnnotation),
+	}, {
+		name: "Invalid queue sidecar auth required claims annotation",
+		annotation: map[string]string{
+			QueueSideCarAuthJWKSConfigMapAnnotation:  "jwks",
+			QueueSideCarAuthRequiredClaimsAnnotation: "admin",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: admin",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarAuthRequiredClaimsAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar auth claim forwarded in an internal header",
+		annotation: map[string]string{
+			QueueSideCarAuthJWKSConfigMapAnnotation: "jwks",
+			QueueSideCarAuthClaimHeadersAnnotation:  "sub=k-proxy-request",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: sub=k-proxy-request",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarAuthClaimHeadersAnnotation)},
+		},
+	}, {
+		name: "Queue sidecar route templates annotation",
+		annotation: map[string]string{
+			QueueSideCarRouteTemplatesAnnotation: "/users/{id}, /users/{id}/orders, /healthz",
+		},
+		expectErr: (*apis.FieldError)(nil),
+	}, {
+		name: "Invalid queue sidecar route templates annotation",
+		annotation: map[string]string{
+			QueueSideCarRouteTemplatesAnnotation: "users/{id}",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: users/{id}",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSideCarRouteTemplatesAnnotation)},
+		},
 	}}
 
 	for _, c := range cases {
@@ -206,6 +347,39 @@ func TestValidateQueueSidecarAnnotation(t *testing.T) {
 	}
 }
 
+func TestValidateLoadBalancingAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name: "no annotation",
+	}, {
+		name: "valid policy",
+		annotation: map[string]string{
+			LoadBalancingPolicyAnnotationKey: string(ConsistentHashPolicy),
+		},
+	}, {
+		name: "invalid policy",
+		annotation: map[string]string{
+			LoadBalancingPolicyAnnotationKey: "random",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: random",
+			Paths:   []string{fmt.Sprintf("[%s]", LoadBalancingPolicyAnnotationKey)},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateLoadBalancingAnnotation(c.annotation)
+			if !reflect.DeepEqual(c.expectErr, err) {
+				t.Errorf("Expected: '%#v', Got: '%#v'", c.expectErr, err)
+			}
+		})
+	}
+}
+
 func TestValidateTimeoutSecond(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -292,3 +466,117 @@ func TestValidateClusterVisibilityLabel(t *testing.T) {
 	}
 
 }
+
+func TestParseAuthClaimHeaders(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{{
+		name:  "empty",
+		value: " ",
+		want:  map[string]string{},
+	}, {
+		name:  "canonicalized",
+		value: "sub=x-user,https://example.com/groups=x-groups",
+		want: map[string]string{
+			"sub":                        "X-User",
+			"https://example.com/groups": "X-Groups",
+		},
+	}, {
+		name:    "invalid header",
+		value:   "sub=x user",
+		wantErr: true,
+	}, {
+		name:    "authorization header",
+		value:   "sub=authorization",
+		wantErr: true,
+	}, {
+		name:    "knative header",
+		value:   "sub=Knative-Serving-Revision",
+		wantErr: true,
+	}, {
+		name:    "duplicate claim",
+		value:   "sub=x-user,sub=x-subject",
+		wantErr: true,
+	}, {
+		name:    "missing header",
+		value:   "sub=",
+		wantErr: true,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseAuthClaimHeaders(c.value)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParseAuthClaimHeaders(%q) = %v, want error: %v", c.value, err, c.wantErr)
+			}
+			if !reflect.DeepEqual(got, c.want) && !c.wantErr {
+				t.Errorf("ParseAuthClaimHeaders(%q) = %v, want: %v", c.value, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseRouteTemplates(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{{
+		name:  "empty",
+		value: " ",
+	}, {
+		name:  "in order",
+		value: "/users/{id}/orders, /users/{id},/",
+		want:  []string{"/users/{id}/orders", "/users/{id}", "/"},
+	}, {
+		name:    "relative",
+		value:   "users",
+		wantErr: true,
+	}, {
+		name:    "empty template",
+		value:   "/users,",
+		wantErr: true,
+	}, {
+		name:    "duplicate",
+		value:   "/users/{id},/users/{id}",
+		wantErr: true,
+	}, {
+		name:    "empty parameter",
+		value:   "/users/{}",
+		wantErr: true,
+	}, {
+		name:    "partial parameter",
+		value:   "/users/id-{id}",
+		wantErr: true,
+	}, {
+		name:    "nested parameter",
+		value:   "/users/{{id}}",
+		wantErr: true,
+	}, {
+		name: "too many",
+		value: func() string {
+			templates := make([]string, MaxRouteTemplates+1)
+			for i := range templates {
+				templates[i] = fmt.Sprintf("/%d/{id}", i)
+			}
+			return strings.Join(templates, ",")
+		}(),
+		wantErr: true,
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseRouteTemplates(c.value)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParseRouteTemplates(%q) = %v, want error: %v", c.value, err, c.wantErr)
+			}
+			if !reflect.DeepEqual(got, c.want) && !c.wantErr {
+				t.Errorf("ParseRouteTemplates(%q) = %v, want: %v", c.value, got, c.want)
+			}
+		})
+	}
+}
@@ -69,4 +69,57 @@ const (
 	// QueueSideCarResourcePercentageAnnotation is the percentage of user container resources to be used for queue-proxy
 	// It has to be in [0.1,100]
 	QueueSideCarResourcePercentageAnnotation = "queue.sidecar." + GroupName + "/resourcePercentage"
+	// QueueSideCarAdaptiveConcurrencyAnnotation is the annotation key to make queue-proxy discover
+	// the concurrency limit of the user container at runtime, up to its containerConcurrency if set.
+	// It has to be a boolean.
+	QueueSideCarAdaptiveConcurrencyAnnotation = "queue.sidecar." + GroupName + "/adaptiveConcurrency"
+	// QueueSideCarRateLimitAnnotation is the number of requests per second the queue-proxies of
+	// a revision let through to the user containers, rejecting the excess with 429 Too Many
+	// Requests. The limit is split evenly across the ready pods, whose number the autoscaler
+	// passes to the queue-proxies when scraping them, and across maxScale pods until then.
+	// It has to be greater than 0 and requires a maxScale of at least 1.
+	QueueSideCarRateLimitAnnotation = "queue.sidecar." + GroupName + "/rateLimit"
+	// QueueSideCarRateLimitBurstAnnotation is the number of requests the queue-proxies of a
+	// revision let through at once when rate limiting, split across the ready pods like the
+	// rate limit. It has to be at least 1 and defaults to the rate limit.
+	QueueSideCarRateLimitBurstAnnotation = "queue.sidecar." + GroupName + "/rateLimitBurst"
+	// QueueSideCarRateLimitKeyHeaderAnnotation is the request header whose values queue-proxy
+	// rate limits separately, e.g. to limit each client on its own.
+	QueueSideCarRateLimitKeyHeaderAnnotation = "queue.sidecar." + GroupName + "/rateLimitKeyHeader"
+	// QueueSideCarUserSocketAnnotation is the annotation key to make queue-proxy proxy to the user
+	// container over a Unix domain socket in a shared volume, whose path is passed to the user
+	// container in the K_SOCKET_PATH environment variable, instead of over its TCP port.
+	// It has to be a boolean.
+	QueueSideCarUserSocketAnnotation = "queue.sidecar." + GroupName + "/userSocket"
+	// QueueSideCarAuthJWKSSecretAnnotation is the name of the Secret whose jwks.json key holds the
+	// JSON Web Key Set queue-proxy validates the bearer tokens of requests against. Setting it, or
+	// QueueSideCarAuthJWKSConfigMapAnnotation, makes queue-proxy reject unauthenticated requests.
+	QueueSideCarAuthJWKSSecretAnnotation = "queue.sidecar." + GroupName + "/authJWKSSecret"
+	// QueueSideCarAuthJWKSConfigMapAnnotation is the name of the ConfigMap whose jwks.json key
+	// holds the JSON Web Key Set, like QueueSideCarAuthJWKSSecretAnnotation.
+	QueueSideCarAuthJWKSConfigMapAnnotation = "queue.sidecar." + GroupName + "/authJWKSConfigMap"
+	// QueueSideCarAuthIssuerAnnotation is the issuer the bearer tokens have to be issued by.
+	QueueSideCarAuthIssuerAnnotation = "queue.sidecar." + GroupName + "/authIssuer"
+	// QueueSideCarAuthAudienceAnnotation is the audience the bearer tokens have to be issued for.
+	QueueSideCarAuthAudienceAnnotation = "queue.sidecar." + GroupName + "/authAudience"
+	// QueueSideCarAuthRequiredClaimsAnnotation is a comma separated list of claim=value pairs
+	// the bearer tokens have to carry. A claim holding a list has to contain the value.
+	QueueSideCarAuthRequiredClaimsAnnotation = "queue.sidecar." + GroupName + "/authRequiredClaims"
+	// QueueSideCarAuthClaimHeadersAnnotation is a comma separated list of claim=header pairs
+	// naming the request headers queue-proxy forwards the validated claims to the user container in.
+	QueueSideCarAuthClaimHeadersAnnotation = "queue.sidecar." + GroupName + "/authClaimHeaders"
+	// QueueSideCarRouteTemplatesAnnotation is a comma separated list of route templates, such as
+	// /users/{id}, queue-proxy reports per-route request metrics for. A {name} parameter matches
+	// any single path segment, and a path is reported under the first template it matches.
+	QueueSideCarRouteTemplatesAnnotation = "queue.sidecar." + GroupName + "/routeTemplates"
+
+	// LoadBalancingPolicyAnnotationKey is the annotation key to select the policy the activator
+	// uses to pick the pod of a revision to send a request to. See LoadBalancingPolicy.
+	LoadBalancingPolicyAnnotationKey = "activator." + GroupName + "/loadBalancingPolicy"
+	// LoadBalancingHashHeaderAnnotationKey is the annotation key of the request header whose value
+	// the consistent-hash load balancing policy routes on.
+	LoadBalancingHashHeaderAnnotationKey = "activator." + GroupName + "/loadBalancingHashHeader"
+	// LoadBalancingHashCookieAnnotationKey is the annotation key of the request cookie whose value
+	// the consistent-hash load balancing policy routes on, when the request does not have the header.
+	LoadBalancingHashCookieAnnotationKey = "activator." + GroupName + "/loadBalancingHashCookie"
 )
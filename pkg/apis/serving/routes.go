@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxRouteTemplates is the maximum number of route templates of a revision.
+// Every template is a metric label value, so this bounds the cardinality of
+// the per-route metrics.
+const MaxRouteTemplates = 50
+
+// ParseRouteTemplates parses the value of QueueSideCarRouteTemplatesAnnotation
+// into the list of route templates, in their order of precedence.
+func ParseRouteTemplates(s string) ([]string, error) {
+	var templates []string
+	if strings.TrimSpace(s) == "" {
+		return templates, nil
+	}
+	seen := make(map[string]bool)
+	for _, template := range strings.Split(s, ",") {
+		template = strings.TrimSpace(template)
+		if err := validateRouteTemplate(template); err != nil {
+			return nil, err
+		}
+		if seen[template] {
+			return nil, fmt.Errorf("duplicate route template %q", template)
+		}
+		seen[template] = true
+		templates = append(templates, template)
+	}
+	if len(templates) > MaxRouteTemplates {
+		return nil, fmt.Errorf("%d route templates exceed the maximum of %d", len(templates), MaxRouteTemplates)
+	}
+	return templates, nil
+}
+
+// validateRouteTemplate checks that the template is an absolute path whose
+// segments are either literals or {name} parameters.
+func validateRouteTemplate(template string) error {
+	if !strings.HasPrefix(template, "/") {
+		return fmt.Errorf("route template %q does not start with /", template)
+	}
+	for _, segment := range strings.Split(template[1:], "/") {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+		if len(segment) < 3 || segment[0] != '{' || segment[len(segment)-1] != '}' ||
+			strings.ContainsAny(segment[1:len(segment)-1], "{}") {
+			return fmt.Errorf("route template %q has an invalid parameter %q", template, segment)
+		}
+	}
+	return nil
+}
@@ -18,11 +18,14 @@ package v1
 
 import (
 	"context"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/apis"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/ptr"
 	"knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 // SetDefaults implements apis.Defaultable
@@ -49,9 +52,15 @@ func (rs *RevisionSpec) SetDefaults(ctx context.Context) {
 		rs.ContainerConcurrency = ptr.Int64(cfg.Defaults.ContainerConcurrency)
 	}
 
+	servingIdx := serving.ServingContainerIndex(rs.PodSpec.Containers)
 	for idx := range rs.PodSpec.Containers {
 		if rs.PodSpec.Containers[idx].Name == "" {
-			rs.PodSpec.Containers[idx].Name = cfg.Defaults.UserContainerName(ctx)
+			// Sidecars are told apart by their index.
+			name := cfg.Defaults.UserContainerName(ctx)
+			if idx != servingIdx {
+				name = kmeta.ChildName(name, "-"+strconv.Itoa(idx))
+			}
+			rs.PodSpec.Containers[idx].Name = name
 		}
 
 		if rs.PodSpec.Containers[idx].Resources.Requests == nil {
@@ -81,16 +90,21 @@ func (rs *RevisionSpec) SetDefaults(ctx context.Context) {
 				rs.PodSpec.Containers[idx].Resources.Limits[corev1.ResourceMemory] = *rsrc
 			}
 		}
-		if rs.PodSpec.Containers[idx].ReadinessProbe == nil {
-			rs.PodSpec.Containers[idx].ReadinessProbe = &corev1.Probe{}
-		}
-		if rs.PodSpec.Containers[idx].ReadinessProbe.TCPSocket == nil &&
-			rs.PodSpec.Containers[idx].ReadinessProbe.HTTPGet == nil &&
-			rs.PodSpec.Containers[idx].ReadinessProbe.Exec == nil {
-			rs.PodSpec.Containers[idx].ReadinessProbe.TCPSocket = &corev1.TCPSocketAction{}
+		// Only the serving container is probed by default, through the
+		// queue-proxy. The probes of sidecars are left to the kubelet.
+		if idx == servingIdx {
+			if rs.PodSpec.Containers[idx].ReadinessProbe == nil {
+				rs.PodSpec.Containers[idx].ReadinessProbe = &corev1.Probe{}
+			}
+			if rs.PodSpec.Containers[idx].ReadinessProbe.TCPSocket == nil &&
+				rs.PodSpec.Containers[idx].ReadinessProbe.HTTPGet == nil &&
+				rs.PodSpec.Containers[idx].ReadinessProbe.Exec == nil {
+				rs.PodSpec.Containers[idx].ReadinessProbe.TCPSocket = &corev1.TCPSocketAction{}
+			}
 		}
 
-		if rs.PodSpec.Containers[idx].ReadinessProbe.SuccessThreshold == 0 {
+		if rs.PodSpec.Containers[idx].ReadinessProbe != nil &&
+			rs.PodSpec.Containers[idx].ReadinessProbe.SuccessThreshold == 0 {
 			rs.PodSpec.Containers[idx].ReadinessProbe.SuccessThreshold = 1
 		}
 
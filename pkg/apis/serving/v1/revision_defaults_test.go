@@ -72,6 +72,11 @@ func TestRevisionDefaulting(t *testing.T) {
 					"revision-timeout-seconds": "123",
 				},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 
 			return s.ToContext(ctx)
 		},
@@ -135,6 +140,11 @@ func TestRevisionDefaulting(t *testing.T) {
 					"revision-timeout-seconds": "456",
 				},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 
 			return s.ToContext(ctx)
 		},
@@ -268,6 +278,11 @@ func TestRevisionDefaulting(t *testing.T) {
 					"revision-memory-limit":   "400M",
 				},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 
 			return s.ToContext(ctx)
 		},
@@ -301,7 +316,17 @@ func TestRevisionDefaulting(t *testing.T) {
 					Containers: []corev1.Container{{
 						Name: "busybox",
 					}, {
-						Name: "helloworld",
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: 8888,
+						}},
+					}, {
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								Exec: &corev1.ExecAction{
+									Command: []string{"echo", "hi"},
+								},
+							},
+						},
 					}},
 				},
 			},
@@ -312,13 +337,26 @@ func TestRevisionDefaulting(t *testing.T) {
 				ContainerConcurrency: ptr.Int64(config.DefaultContainerConcurrency),
 				PodSpec: corev1.PodSpec{
 					Containers: []corev1.Container{{
-						Name:           "busybox",
-						Resources:      defaultResources,
-						ReadinessProbe: defaultProbe,
+						Name:      "busybox",
+						Resources: defaultResources,
 					}, {
-						Name:           "helloworld",
+						Name: config.DefaultUserContainerName,
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: 8888,
+						}},
 						Resources:      defaultResources,
 						ReadinessProbe: defaultProbe,
+					}, {
+						Name:      config.DefaultUserContainerName + "-2",
+						Resources: defaultResources,
+						ReadinessProbe: &corev1.Probe{
+							SuccessThreshold: 1,
+							Handler: corev1.Handler{
+								Exec: &corev1.ExecAction{
+									Command: []string{"echo", "hi"},
+								},
+							},
+						},
 					}},
 				},
 			},
@@ -138,6 +138,22 @@ func (in *ConfigurationStatusFields) DeepCopy() *ConfigurationStatusFields {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerStatuses) DeepCopyInto(out *ContainerStatuses) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerStatuses.
+func (in *ContainerStatuses) DeepCopy() *ContainerStatuses {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerStatuses)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Revision) DeepCopyInto(out *Revision) {
 	*out = *in
@@ -213,6 +229,16 @@ func (in *RevisionSpec) DeepCopyInto(out *RevisionSpec) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.IdleTimeoutSeconds != nil {
+		in, out := &in.IdleTimeoutSeconds, &out.IdleTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxDurationSeconds != nil {
+		in, out := &in.MaxDurationSeconds, &out.MaxDurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	return
 }
 
@@ -230,6 +256,11 @@ func (in *RevisionSpec) DeepCopy() *RevisionSpec {
 func (in *RevisionStatus) DeepCopyInto(out *RevisionStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	if in.ContainerStatuses != nil {
+		in, out := &in.ContainerStatuses, &out.ContainerStatuses
+		*out = make([]ContainerStatuses, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -52,6 +52,12 @@ func (source *RevisionSpec) ConvertUp(ctx context.Context, sink *v1beta1.Revisio
 	if source.ContainerConcurrency != nil {
 		sink.ContainerConcurrency = ptr.Int64(*source.ContainerConcurrency)
 	}
+	if source.IdleTimeoutSeconds != nil {
+		sink.IdleTimeoutSeconds = ptr.Int64(*source.IdleTimeoutSeconds)
+	}
+	if source.MaxDurationSeconds != nil {
+		sink.MaxDurationSeconds = ptr.Int64(*source.MaxDurationSeconds)
+	}
 	switch {
 	case source.DeprecatedContainer != nil && len(source.Containers) > 0:
 		return apis.ErrMultipleOneOf("container", "containers")
@@ -61,10 +67,8 @@ func (source *RevisionSpec) ConvertUp(ctx context.Context, sink *v1beta1.Revisio
 			Containers:         []corev1.Container{*source.DeprecatedContainer},
 			Volumes:            source.Volumes,
 		}
-	case len(source.Containers) == 1:
+	case len(source.Containers) > 0:
 		sink.PodSpec = source.PodSpec
-	case len(source.Containers) > 1:
-		return apis.ErrMultipleOneOf("containers")
 	default:
 		return apis.ErrMissingOneOf("container", "containers")
 	}
@@ -77,6 +77,45 @@ func TestRevisionConversion(t *testing.T) {
 						}},
 					},
 					TimeoutSeconds:       ptr.Int64(18),
+					IdleTimeoutSeconds:   ptr.Int64(30),
+					MaxDurationSeconds:   ptr.Int64(3600),
+					ContainerConcurrency: ptr.Int64(53),
+				},
+			},
+			Status: RevisionStatus{
+				Status: duckv1beta1.Status{
+					ObservedGeneration: 1,
+					Conditions: duckv1beta1.Conditions{{
+						Type:   "Ready",
+						Status: "True",
+					}},
+				},
+				ServiceName: "foo-bar",
+				LogURL:      "http://logger.io",
+			},
+		},
+	}, {
+		name: "good roundtrip w/ sidecars",
+		in: &Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "asdf",
+				Namespace:  "blah",
+				Generation: 1,
+			},
+			Spec: RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					PodSpec: corev1.PodSpec{
+						ServiceAccountName: "robocop",
+						Containers: []corev1.Container{{
+							Image: "busybox",
+						}, {
+							Image: "helloworld",
+							Ports: []corev1.ContainerPort{{
+								ContainerPort: 8888,
+							}},
+						}},
+					},
+					TimeoutSeconds:       ptr.Int64(18),
 					ContainerConcurrency: ptr.Int64(53),
 				},
 			},
@@ -175,6 +214,11 @@ func TestRevisionConversion(t *testing.T) {
 			}
 		})
 
+		// `container:` can't hold sidecars.
+		if len(test.in.Spec.Containers) > 1 {
+			continue
+		}
+
 		// A variant of the test that uses `container:`,
 		// but end up with what we have above anyways.
 		t.Run(test.name+" (deprecated)", func(t *testing.T) {
@@ -210,41 +254,6 @@ func TestRevisionConversionError(t *testing.T) {
 		in   *Revision
 		want *apis.FieldError
 	}{{
-		name: "multiple containers in podspec",
-		in: &Revision{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:       "asdf",
-				Namespace:  "blah",
-				Generation: 1,
-			},
-			Spec: RevisionSpec{
-				RevisionSpec: v1beta1.RevisionSpec{
-					PodSpec: corev1.PodSpec{
-						ServiceAccountName: "robocop",
-						Containers: []corev1.Container{{
-							Image: "busybox",
-						}, {
-							Image: "helloworld",
-						}},
-					},
-					TimeoutSeconds:       ptr.Int64(18),
-					ContainerConcurrency: ptr.Int64(53),
-				},
-			},
-			Status: RevisionStatus{
-				Status: duckv1beta1.Status{
-					ObservedGeneration: 1,
-					Conditions: duckv1beta1.Conditions{{
-						Type:   "Ready",
-						Status: "True",
-					}},
-				},
-				ServiceName: "foo-bar",
-				LogURL:      "http://logger.io",
-			},
-		},
-		want: apis.ErrMultipleOneOf("containers"),
-	}, {
 		name: "no containers in podspec",
 		in: &Revision{
 			ObjectMeta: metav1.ObjectMeta{
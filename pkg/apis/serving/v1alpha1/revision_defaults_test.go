@@ -96,6 +96,11 @@ func TestRevisionDefaulting(t *testing.T) {
 					"revision-timeout-seconds": "123",
 				},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 
 			return s.ToContext(ctx)
 		},
@@ -68,13 +68,16 @@ func (r *Revision) GetGroupVersionKind() schema.GroupVersionKind {
 	return SchemeGroupVersion.WithKind("Revision")
 }
 
-// GetContainer returns a pointer to the relevant corev1.Container field.
-// It is never nil and should be exactly the specified container as guaranteed
-// by validation.
+// GetContainer returns a pointer to the relevant corev1.Container field,
+// the serving container when there are sidecars. It is never nil and should
+// be exactly the specified container as guaranteed by validation.
 func (rs *RevisionSpec) GetContainer() *corev1.Container {
 	if rs.DeprecatedContainer != nil {
 		return rs.DeprecatedContainer
 	}
+	if idx := serving.ServingContainerIndex(rs.Containers); idx >= 0 {
+		return &rs.Containers[idx]
+	}
 	if len(rs.Containers) > 0 {
 		return &rs.Containers[0]
 	}
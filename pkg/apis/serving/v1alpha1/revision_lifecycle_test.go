@@ -832,6 +832,31 @@ func TestGetContainer(t *testing.T) {
 			Name:  "firstContainer",
 			Image: "firstImage",
 		},
+	}, {
+		name: "get the serving container among sidecars",
+		status: RevisionSpec{
+			RevisionSpec: v1beta1.RevisionSpec{
+				PodSpec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "sidecar",
+						Image: "sidecarImage",
+					}, {
+						Name:  "servingContainer",
+						Image: "servingImage",
+						Ports: []corev1.ContainerPort{{
+							ContainerPort: 8888,
+						}},
+					}},
+				},
+			},
+		},
+		want: &corev1.Container{
+			Name:  "servingContainer",
+			Image: "servingImage",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8888,
+			}},
+		},
 	}}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
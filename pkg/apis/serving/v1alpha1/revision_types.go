@@ -192,6 +192,23 @@ type RevisionStatus struct {
 	// may be empty if the image comes from a registry listed to skip resolution.
 	// +optional
 	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// ContainerStatuses holds the resolved digests of the images of all the
+	// containers of the Revision, the serving container and the sidecars, in
+	// the order of the containers. The containers whose images come from a
+	// registry listed to skip resolution are left out.
+	// +optional
+	ContainerStatuses []ContainerStatuses `json:"containerStatuses,omitempty"`
+}
+
+// ContainerStatuses holds the resolved digest of the image of a container.
+type ContainerStatuses struct {
+	// Name is the name of the container.
+	Name string `json:"name,omitempty"`
+
+	// ImageDigest is the resolved digest of the image of the container.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -84,6 +84,7 @@ func (rt *RevisionTemplateSpec) Validate(ctx context.Context) *apis.FieldError {
 	}
 
 	errs = errs.Also(serving.ValidateQueueSidecarAnnotation(rt.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateLoadBalancingAnnotation(rt.Annotations).ViaField("metadata.annotations"))
 	return errs
 }
 
@@ -154,6 +155,8 @@ func (rs *RevisionSpec) Validate(ctx context.Context) *apis.FieldError {
 	if rs.TimeoutSeconds != nil {
 		errs = errs.Also(serving.ValidateTimeoutSeconds(ctx, *rs.TimeoutSeconds))
 	}
+	errs = errs.Also(serving.ValidateDurationSeconds(rs.IdleTimeoutSeconds, "idleTimeoutSeconds"))
+	errs = errs.Also(serving.ValidateDurationSeconds(rs.MaxDurationSeconds, "maxDurationSeconds"))
 	return errs
 }
 
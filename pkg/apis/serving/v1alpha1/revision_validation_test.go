@@ -266,6 +266,11 @@ func TestRevisionSpecValidation(t *testing.T) {
 					"revision-timeout-seconds":     "25",
 					"max-revision-timeout-seconds": "50"},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 			return s.ToContext(ctx)
 		},
 		want: apis.ErrOutOfBoundsValue(100, 0, 50, "timeoutSeconds"),
@@ -585,6 +590,11 @@ func TestImmutableFields(t *testing.T) {
 					"revision-timeout-seconds":     "25",
 					"max-revision-timeout-seconds": "50"},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 			return s.ToContext(ctx)
 		},
 		want: nil,
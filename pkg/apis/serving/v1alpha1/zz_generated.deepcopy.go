@@ -169,6 +169,22 @@ func (in *ConfigurationStatusFields) DeepCopy() *ConfigurationStatusFields {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerStatuses) DeepCopyInto(out *ContainerStatuses) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerStatuses.
+func (in *ContainerStatuses) DeepCopy() *ContainerStatuses {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerStatuses)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManualType) DeepCopyInto(out *ManualType) {
 	*out = *in
@@ -316,6 +332,11 @@ func (in *RevisionSpec) DeepCopy() *RevisionSpec {
 func (in *RevisionStatus) DeepCopyInto(out *RevisionStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
+	if in.ContainerStatuses != nil {
+		in, out := &in.ContainerStatuses, &out.ContainerStatuses
+		*out = make([]ContainerStatuses, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
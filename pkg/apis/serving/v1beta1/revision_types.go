@@ -83,6 +83,18 @@ type RevisionSpec struct {
 	// be provided.
 	// +optional
 	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// IdleTimeoutSeconds holds the max duration a response that started
+	// is allowed to go without sending any bytes, e.g. a stalled stream.
+	// If unspecified, responses may be idle for any time.
+	// +optional
+	IdleTimeoutSeconds *int64 `json:"idleTimeoutSeconds,omitempty"`
+
+	// MaxDurationSeconds holds the max duration the instance is allowed for
+	// a request, including streaming its response. If unspecified, requests
+	// may take any time once the response started.
+	// +optional
+	MaxDurationSeconds *int64 `json:"maxDurationSeconds,omitempty"`
 }
 
 const (
@@ -112,6 +124,23 @@ type RevisionStatus struct {
 	// may be empty if the image comes from a registry listed to skip resolution.
 	// +optional
 	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// ContainerStatuses holds the resolved digests of the images of all the
+	// containers of the Revision, the serving container and the sidecars, in
+	// the order of the containers. The containers whose images come from a
+	// registry listed to skip resolution are left out.
+	// +optional
+	ContainerStatuses []ContainerStatuses `json:"containerStatuses,omitempty"`
+}
+
+// ContainerStatuses holds the resolved digest of the image of a container.
+type ContainerStatuses struct {
+	// Name is the name of the container.
+	Name string `json:"name,omitempty"`
+
+	// ImageDigest is the resolved digest of the image of the container.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
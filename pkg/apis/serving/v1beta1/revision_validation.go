@@ -78,6 +78,7 @@ func (rts *RevisionTemplateSpec) Validate(ctx context.Context) *apis.FieldError
 	}
 
 	errs = errs.Also(serving.ValidateQueueSidecarAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateLoadBalancingAnnotation(rts.Annotations).ViaField("metadata.annotations"))
 	return errs
 }
 
@@ -111,11 +112,13 @@ func (current *RevisionTemplateSpec) VerifyNameChange(ctx context.Context, og Re
 
 // Validate implements apis.Validatable
 func (rs *RevisionSpec) Validate(ctx context.Context) *apis.FieldError {
-	errs := serving.ValidatePodSpec(rs.PodSpec)
+	errs := serving.ValidatePodSpec(ctx, rs.PodSpec)
 
 	if rs.TimeoutSeconds != nil {
 		errs = errs.Also(serving.ValidateTimeoutSeconds(ctx, *rs.TimeoutSeconds))
 	}
+	errs = errs.Also(serving.ValidateDurationSeconds(rs.IdleTimeoutSeconds, "idleTimeoutSeconds"))
+	errs = errs.Also(serving.ValidateDurationSeconds(rs.MaxDurationSeconds, "maxDurationSeconds"))
 
 	if rs.ContainerConcurrency != nil {
 		errs = errs.Also(serving.ValidateContainerConcurrency(rs.ContainerConcurrency).ViaField("containerConcurrency"))
@@ -18,6 +18,7 @@ package v1beta1
 
 import (
 	"context"
+	"math"
 	"fmt"
 	"testing"
 
@@ -372,7 +373,7 @@ func TestRevisionSpecValidation(t *testing.T) {
 		},
 		want: apis.ErrMissingField("containers"),
 	}, {
-		name: "too many containers",
+		name: "no serving container",
 		rs: &RevisionSpec{
 			PodSpec: corev1.PodSpec{
 				Containers: []corev1.Container{{
@@ -382,7 +383,24 @@ func TestRevisionSpecValidation(t *testing.T) {
 				}},
 			},
 		},
-		want: apis.ErrMultipleOneOf("containers"),
+		want: apis.ErrMissingOneOf("containers[0].ports", "containers[1].ports"),
+	}, {
+		name: "sidecar",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "sidecar",
+					Image: "busybox",
+				}, {
+					Name:  "user-container",
+					Image: "helloworld",
+					Ports: []corev1.ContainerPort{{
+						ContainerPort: 8888,
+					}},
+				}},
+			},
+		},
+		want: nil,
 	}, {
 		name: "exceed max timeout",
 		rs: &RevisionSpec{
@@ -416,6 +434,11 @@ func TestRevisionSpecValidation(t *testing.T) {
 					"revision-timeout-seconds":     "25",
 					"max-revision-timeout-seconds": "50"},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 			return s.ToContext(ctx)
 		},
 		want: apis.ErrOutOfBoundsValue(100, 0, 50, "timeoutSeconds"),
@@ -432,6 +455,28 @@ func TestRevisionSpecValidation(t *testing.T) {
 		want: apis.ErrOutOfBoundsValue(
 			-30, 0, config.DefaultMaxRevisionTimeoutSeconds,
 			"timeoutSeconds"),
+	}, {
+		name: "negative idle timeout",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			IdleTimeoutSeconds: ptr.Int64(-1),
+		},
+		want: apis.ErrOutOfBoundsValue(-1, 0, math.MaxInt32, "idleTimeoutSeconds"),
+	}, {
+		name: "negative max duration",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			MaxDurationSeconds: ptr.Int64(-1),
+		},
+		want: apis.ErrOutOfBoundsValue(-1, 0, math.MaxInt32, "maxDurationSeconds"),
 	}}
 
 	for _, test := range tests {
@@ -523,6 +568,11 @@ func TestImmutableFields(t *testing.T) {
 					"revision-timeout-seconds":     "25",
 					"max-revision-timeout-seconds": "50"},
 			})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.FeaturesConfigName,
+				},
+			})
 			return s.ToContext(ctx)
 		},
 		want: nil,
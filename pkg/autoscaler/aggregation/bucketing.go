@@ -84,6 +84,61 @@ func (t *TimedFloat64Buckets) RemoveOlderThan(time time.Time) {
 	}
 }
 
+// BucketSnapshot is a serializable copy of a single bucket.
+type BucketSnapshot struct {
+	Time   time.Time                `json:"time"`
+	Values map[string]ValueSnapshot `json:"values"`
+}
+
+// ValueSnapshot is a serializable copy of the values recorded under a
+// name in a bucket.
+type ValueSnapshot struct {
+	Sum   float64 `json:"sum"`
+	Count float64 `json:"count"`
+}
+
+// Snapshot returns a copy of the buckets.
+func (t *TimedFloat64Buckets) Snapshot() []BucketSnapshot {
+	t.bucketsMutex.RLock()
+	defer t.bucketsMutex.RUnlock()
+
+	ret := make([]BucketSnapshot, 0, len(t.buckets))
+	for bucketTime, bucket := range t.buckets {
+		values := make(map[string]ValueSnapshot, len(bucket))
+		for name, value := range bucket {
+			values[name] = ValueSnapshot{Sum: value.sum, Count: value.count}
+		}
+		ret = append(ret, BucketSnapshot{Time: bucketTime, Values: values})
+	}
+	return ret
+}
+
+// Restore adds the buckets of the given snapshot to the state. Values
+// recorded under the same name in the same bucket are combined.
+func (t *TimedFloat64Buckets) Restore(snapshot []BucketSnapshot) {
+	t.bucketsMutex.Lock()
+	defer t.bucketsMutex.Unlock()
+
+	for _, b := range snapshot {
+		bucketKey := b.Time.Truncate(t.granularity)
+		bucket, ok := t.buckets[bucketKey]
+		if !ok {
+			bucket = float64Bucket{}
+			t.buckets[bucketKey] = bucket
+		}
+		for name, value := range b.Values {
+			if value.Count <= 0 {
+				continue
+			}
+			current := bucket[name]
+			bucket[name] = float64Value{
+				sum:   current.sum + value.Sum,
+				count: current.count + value.Count,
+			}
+		}
+	}
+}
+
 // float64Bucket keeps all the stats that fall into a defined bucket.
 type float64Bucket map[string]float64Value
 
@@ -123,6 +123,34 @@ func TestTimedFloat64Buckets_ForEachBucket(t *testing.T) {
 	}
 }
 
+func TestTimedFloat64Buckets_SnapshotRestore(t *testing.T) {
+	granularity := 1 * time.Second
+	trunc1 := time.Now().Truncate(granularity)
+	buckets := NewTimedFloat64Buckets(granularity)
+
+	buckets.Record(trunc1, "pod1", 10.0)
+	buckets.Record(trunc1, "pod1", 20.0)
+	buckets.Record(trunc1, "pod2", 5.0)
+	buckets.Record(trunc1.Add(1*time.Second), "pod1", 1.0)
+
+	restored := NewTimedFloat64Buckets(granularity)
+	restored.Restore(buckets.Snapshot())
+	// Values recorded after the restore are averaged with the restored ones.
+	restored.Record(trunc1, "pod1", 45.0)
+
+	got := make(map[time.Time]float64)
+	restored.ForEachBucket(func(time time.Time, bucket float64Bucket) {
+		got[time] = bucket.Sum()
+	})
+	want := map[time.Time]float64{
+		trunc1:                      30.0,
+		trunc1.Add(1 * time.Second): 1.0,
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("Unexpected values (-want +got): %v", cmp.Diff(want, got))
+	}
+}
+
 func TestTimedFloat64Buckets_RemoveOlderThan(t *testing.T) {
 	pod := "pod"
 	zero := time.Now()
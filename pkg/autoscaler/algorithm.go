@@ -0,0 +1,205 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+)
+
+// ScalingInput is the data the Autoscaler hands to a ScalingAlgorithm on every tick.
+type ScalingInput struct {
+	// Now is the time the proposal is requested at.
+	Now time.Time
+	// ReadyPodCount is the number of ready pods, but at least 1.
+	ReadyPodCount float64
+	// ObservedStableValue is the average of the scaling metric over the stable window.
+	ObservedStableValue float64
+	// ObservedPanicValue is the average of the scaling metric over the panic window.
+	ObservedPanicValue float64
+	// Spec is the DeciderSpec in effect at the time of the tick.
+	Spec *DeciderSpec
+}
+
+// ScalingAlgorithm turns the observed metric values into the desired number of pods
+// for the stable and the panic windows. The Autoscaler bounds the results by the
+// maximum scale up rate and applies the panic mode latch on top of them.
+type ScalingAlgorithm interface {
+	// DesiredPodCounts returns the (fractional) stable and panic pod counts.
+	DesiredPodCounts(ScalingInput) (float64, float64)
+}
+
+// ScalingAlgorithmFactory creates a new instance of a ScalingAlgorithm.
+// Algorithms may keep state across ticks, so every Autoscaler gets its own instance.
+type ScalingAlgorithmFactory func() ScalingAlgorithm
+
+var (
+	algorithmsMux sync.RWMutex
+	algorithms    = map[string]ScalingAlgorithmFactory{
+		autoscaling.KPADefaultAlgorithm: func() ScalingAlgorithm { return kpaDefaultAlgorithm{} },
+		autoscaling.PIDAlgorithm:        func() ScalingAlgorithm { return &pidAlgorithm{} },
+		autoscaling.StepAlgorithm:       func() ScalingAlgorithm { return stepAlgorithm{} },
+		autoscaling.PredictiveAlgorithm: func() ScalingAlgorithm { return &predictiveAlgorithm{} },
+	}
+)
+
+// RegisterScalingAlgorithm makes the algorithm created by the factory available
+// under the given name, replacing any algorithm registered with the same name.
+// The name must be a lowercase DNS label to pass the annotation validation.
+func RegisterScalingAlgorithm(name string, factory ScalingAlgorithmFactory) {
+	algorithmsMux.Lock()
+	defer algorithmsMux.Unlock()
+	algorithms[name] = factory
+}
+
+// ScalingAlgorithms returns the sorted names of all the registered algorithms.
+func ScalingAlgorithms() []string {
+	algorithmsMux.RLock()
+	defer algorithmsMux.RUnlock()
+	ret := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// NewScalingAlgorithm creates an instance of the algorithm registered under
+// the given name. The empty name resolves to the default KPA algorithm.
+func NewScalingAlgorithm(name string) (ScalingAlgorithm, error) {
+	if name == "" {
+		name = autoscaling.KPADefaultAlgorithm
+	}
+	algorithmsMux.RLock()
+	defer algorithmsMux.RUnlock()
+	factory, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scaling algorithm %q", name)
+	}
+	return factory(), nil
+}
+
+// kpaDefaultAlgorithm divides the observed averages by the target value.
+type kpaDefaultAlgorithm struct{}
+
+func (kpaDefaultAlgorithm) DesiredPodCounts(in ScalingInput) (float64, float64) {
+	return in.ObservedStableValue / in.Spec.TargetValue, in.ObservedPanicValue / in.Spec.TargetValue
+}
+
+const (
+	// The gains of the PID controller. With only the proportional term
+	// the controller is equivalent to the default algorithm.
+	pidKp = 1.0
+	pidKi = 0.1
+	pidKd = 0.05
+	// pidIntegralLimit bounds the accumulated error to prevent windup,
+	// e.g. while the revision is pinned at its max scale.
+	pidIntegralLimit = 10.0
+)
+
+// pidAlgorithm runs a PID controller on the relative error between the
+// observed per pod stable value and the target. The panic recommendation
+// is not smoothed, since panic mode has to react to bursts immediately.
+type pidAlgorithm struct {
+	lastTime  time.Time
+	lastError float64
+	integral  float64
+}
+
+func (p *pidAlgorithm) DesiredPodCounts(in ScalingInput) (float64, float64) {
+	pnc := in.ObservedPanicValue / in.Spec.TargetValue
+	// The relative error: 0 when every pod is exactly at target.
+	e := (in.ObservedStableValue/in.ReadyPodCount - in.Spec.TargetValue) / in.Spec.TargetValue
+
+	var derivative float64
+	if !p.lastTime.IsZero() && in.Now.After(p.lastTime) {
+		dt := in.Now.Sub(p.lastTime).Seconds()
+		p.integral = math.Max(-pidIntegralLimit, math.Min(pidIntegralLimit, p.integral+e*dt))
+		derivative = (e - p.lastError) / dt
+	}
+	p.lastTime, p.lastError = in.Now, e
+
+	stable := in.ReadyPodCount * (1 + pidKp*e + pidKi*p.integral + pidKd*derivative)
+	return math.Max(0, stable), pnc
+}
+
+// stepAlgorithm moves the stable scale towards the default recommendation
+// by at most one pod per tick, which suits workloads that prefer smooth
+// changes over responsiveness. Scaling to zero is not slowed down.
+type stepAlgorithm struct{}
+
+func (stepAlgorithm) DesiredPodCounts(in ScalingInput) (float64, float64) {
+	stable, pnc := kpaDefaultAlgorithm{}.DesiredPodCounts(in)
+	want := math.Ceil(stable)
+	switch {
+	case want == 0:
+		return 0, pnc
+	case want > in.ReadyPodCount:
+		return in.ReadyPodCount + 1, pnc
+	case want < in.ReadyPodCount:
+		return in.ReadyPodCount - 1, pnc
+	}
+	return want, pnc
+}
+
+// predictiveAlgorithm fits a line through the stable observations made during
+// the last stable window and extrapolates it by half a window, which is the
+// average age of the data the stable value is computed from.
+type predictiveAlgorithm struct {
+	times  []time.Time
+	values []float64
+}
+
+func (p *predictiveAlgorithm) DesiredPodCounts(in ScalingInput) (float64, float64) {
+	stable, pnc := kpaDefaultAlgorithm{}.DesiredPodCounts(in)
+
+	// Forget the observations that fell out of the stable window.
+	cutoff := in.Now.Add(-in.Spec.StableWindow)
+	i := 0
+	for i < len(p.times) && !p.times[i].After(cutoff) {
+		i++
+	}
+	p.times = append(p.times[i:], in.Now)
+	p.values = append(p.values[i:], in.ObservedStableValue)
+	if len(p.times) < 2 {
+		return stable, pnc
+	}
+
+	// Least squares fit with the time measured in seconds relative to now.
+	var sx, sy, sxx, sxy float64
+	n := float64(len(p.times))
+	for i, t := range p.times {
+		x := t.Sub(in.Now).Seconds()
+		sx += x
+		sy += p.values[i]
+		sxx += x * x
+		sxy += x * p.values[i]
+	}
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return stable, pnc
+	}
+	slope := (n*sxy - sx*sy) / denom
+	intercept := (sy - slope*sx) / n
+	predicted := intercept + slope*(in.Spec.StableWindow/2).Seconds()
+	return math.Max(0, predicted) / in.Spec.TargetValue, pnc
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	autoscalerfake "knative.dev/serving/pkg/autoscaler/fake"
+)
+
+func TestNewScalingAlgorithm(t *testing.T) {
+	for _, name := range []string{"", autoscaling.KPADefaultAlgorithm, autoscaling.PIDAlgorithm,
+		autoscaling.StepAlgorithm, autoscaling.PredictiveAlgorithm} {
+		if _, err := NewScalingAlgorithm(name); err != nil {
+			t.Errorf("NewScalingAlgorithm(%q) = %v", name, err)
+		}
+	}
+	if _, err := NewScalingAlgorithm("magic"); err == nil {
+		t.Error("NewScalingAlgorithm(magic) succeeded, want error")
+	}
+}
+
+type fixedAlgorithm float64
+
+func (f fixedAlgorithm) DesiredPodCounts(ScalingInput) (float64, float64) {
+	return float64(f), float64(f)
+}
+
+func TestRegisterScalingAlgorithm(t *testing.T) {
+	const name = "fixed"
+	RegisterScalingAlgorithm(name, func() ScalingAlgorithm { return fixedAlgorithm(7) })
+	defer func() {
+		algorithmsMux.Lock()
+		defer algorithmsMux.Unlock()
+		delete(algorithms, name)
+	}()
+
+	if err := autoscaling.ValidateAnnotations(map[string]string{autoscaling.AlgorithmAnnotationKey: name}); err != nil {
+		t.Errorf("ValidateAnnotations() = %v, want the registered algorithm to be accepted", err)
+	}
+
+	want := []string{name, autoscaling.KPADefaultAlgorithm, autoscaling.PIDAlgorithm,
+		autoscaling.PredictiveAlgorithm, autoscaling.StepAlgorithm}
+	if got := ScalingAlgorithms(); !cmp.Equal(got, want) {
+		t.Errorf("ScalingAlgorithms = %v, want: %v", got, want)
+	}
+
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 50.0}
+	a := newTestAutoscaler(t, 10, 0, metrics)
+	a.expectScale(t, time.Now(), 5, 0, true)
+
+	spec := *a.deciderSpec
+	spec.Algorithm = name
+	if err := a.Update(&spec); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+	a.expectScale(t, time.Now(), 7, 0, true)
+
+	badSpec := spec
+	badSpec.Algorithm = "magic"
+	if err := a.Update(&badSpec); err == nil {
+		t.Error("Update with unknown algorithm succeeded, want error")
+	}
+}
+
+func TestKPADefaultAlgorithm(t *testing.T) {
+	spec := &DeciderSpec{TargetValue: 10}
+	stable, pnc := kpaDefaultAlgorithm{}.DesiredPodCounts(ScalingInput{
+		ReadyPodCount:       1,
+		ObservedStableValue: 25,
+		ObservedPanicValue:  50,
+		Spec:                spec,
+	})
+	if stable != 2.5 || pnc != 5 {
+		t.Errorf("DesiredPodCounts = (%v, %v), want: (2.5, 5)", stable, pnc)
+	}
+}
+
+func TestPIDAlgorithm(t *testing.T) {
+	spec := &DeciderSpec{TargetValue: 10}
+	p := &pidAlgorithm{}
+	now := time.Now()
+
+	// The first tick has no history, so only the proportional term applies.
+	stable, _ := p.DesiredPodCounts(ScalingInput{
+		Now:                 now,
+		ReadyPodCount:       2,
+		ObservedStableValue: 40,
+		Spec:                spec,
+	})
+	if stable != 4 {
+		t.Errorf("DesiredPodCounts = %v, want: 4", stable)
+	}
+
+	// Persistent error accumulates in the integral term.
+	stable, _ = p.DesiredPodCounts(ScalingInput{
+		Now:                 now.Add(2 * time.Second),
+		ReadyPodCount:       2,
+		ObservedStableValue: 40,
+		Spec:                spec,
+	})
+	if stable <= 4 {
+		t.Errorf("DesiredPodCounts = %v, want > 4", stable)
+	}
+
+	// At target the recommendation stays around the current scale.
+	p = &pidAlgorithm{}
+	stable, _ = p.DesiredPodCounts(ScalingInput{
+		Now:                 now,
+		ReadyPodCount:       3,
+		ObservedStableValue: 30,
+		Spec:                spec,
+	})
+	if stable != 3 {
+		t.Errorf("DesiredPodCounts = %v, want: 3", stable)
+	}
+}
+
+func TestStepAlgorithm(t *testing.T) {
+	spec := &DeciderSpec{TargetValue: 10}
+	cases := []struct {
+		name   string
+		ready  float64
+		stable float64
+		want   float64
+	}{{
+		name:   "scale up",
+		ready:  2,
+		stable: 100,
+		want:   3,
+	}, {
+		name:   "scale down",
+		ready:  5,
+		stable: 10,
+		want:   4,
+	}, {
+		name:   "no change",
+		ready:  3,
+		stable: 25,
+		want:   3,
+	}, {
+		name:   "scale to zero",
+		ready:  5,
+		stable: 0,
+		want:   0,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := stepAlgorithm{}.DesiredPodCounts(ScalingInput{
+				ReadyPodCount:       tc.ready,
+				ObservedStableValue: tc.stable,
+				Spec:                spec,
+			})
+			if got != tc.want {
+				t.Errorf("DesiredPodCounts = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPredictiveAlgorithm(t *testing.T) {
+	spec := &DeciderSpec{TargetValue: 10, StableWindow: 60 * time.Second}
+	p := &predictiveAlgorithm{}
+	now := time.Now()
+
+	// A single observation has no trend.
+	stable, _ := p.DesiredPodCounts(ScalingInput{
+		Now:                 now,
+		ReadyPodCount:       1,
+		ObservedStableValue: 10,
+		Spec:                spec,
+	})
+	if stable != 1 {
+		t.Errorf("DesiredPodCounts = %v, want: 1", stable)
+	}
+
+	// The value grows by 1 per second, so in 30s we expect 30 more.
+	stable, _ = p.DesiredPodCounts(ScalingInput{
+		Now:                 now.Add(10 * time.Second),
+		ReadyPodCount:       1,
+		ObservedStableValue: 20,
+		Spec:                spec,
+	})
+	if got, want := stable, 5.0; got != want {
+		t.Errorf("DesiredPodCounts = %v, want: %v", got, want)
+	}
+
+	// Old observations are forgotten.
+	stable, _ = p.DesiredPodCounts(ScalingInput{
+		Now:                 now.Add(10 * time.Minute),
+		ReadyPodCount:       1,
+		ObservedStableValue: 20,
+		Spec:                spec,
+	})
+	if got, want := stable, 2.0; got != want {
+		t.Errorf("DesiredPodCounts = %v, want: %v", got, want)
+	}
+	if got := len(p.times); got != 1 {
+		t.Errorf("len(history) = %d, want: 1", got)
+	}
+}
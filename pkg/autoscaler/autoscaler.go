@@ -49,24 +49,38 @@ type Autoscaler struct {
 	stateMux     sync.Mutex
 	panicTime    *time.Time
 	maxPanicPods int32
-
-	// specMux guards the current DeciderSpec and the PodCounter.
+	// The recommendations made during the scale down stabilization
+	// window, oldest first. Guarded by the stateMux.
+	recommendations []timedRecommendation
+	// The inputs and outcome of the last scaling decision. Guarded by
+	// the stateMux.
+	lastObservation *Observation
+
+	// specMux guards the current DeciderSpec, the PodCounter and the
+	// ScalingAlgorithm.
 	specMux     sync.RWMutex
 	deciderSpec *DeciderSpec
 	podCounter  resources.ReadyPodCounter
+	algorithm   ScalingAlgorithm
 }
 
-// New creates a new instance of autoscaler
+// New creates a new instance of autoscaler, which scales with the given
+// algorithm. The algorithm has to be the one named by the DeciderSpec,
+// see NewScalingAlgorithm.
 func New(
 	namespace string,
 	revision string,
 	metricClient MetricClient,
 	lister corev1listers.EndpointsLister,
 	deciderSpec *DeciderSpec,
+	algorithm ScalingAlgorithm,
 	reporter StatsReporter) (*Autoscaler, error) {
 	if lister == nil {
 		return nil, errors.New("'lister' must not be nil")
 	}
+	if algorithm == nil {
+		return nil, errors.New("scaling algorithm must not be nil")
+	}
 	if reporter == nil {
 		return nil, errors.New("stats reporter must not be nil")
 	}
@@ -77,6 +91,7 @@ func New(
 	// momentarily scale down, and that is not a desired behaviour.
 	// Thus, we're keeping at least the current scale until we
 	// accumulate enough data to make conscious decisions.
+	// If the history is checkpointed, Restore replaces this state.
 	podCounter := resources.NewScopedEndpointsCounter(lister,
 		namespace, deciderSpec.ServiceName)
 	curC, err := podCounter.ReadyCount()
@@ -103,22 +118,100 @@ func New(
 
 		deciderSpec: deciderSpec,
 		podCounter:  podCounter,
+		algorithm:   algorithm,
 
 		panicTime:    pt,
 		maxPanicPods: int32(curC),
 	}, nil
 }
 
+// DeciderSnapshot is a serializable copy of the panic state of an Autoscaler.
+type DeciderSnapshot struct {
+	PanicTime    *time.Time `json:"panicTime,omitempty"`
+	MaxPanicPods int32      `json:"maxPanicPods,omitempty"`
+}
+
+// Snapshot returns a copy of the panic state.
+func (a *Autoscaler) Snapshot() DeciderSnapshot {
+	a.stateMux.Lock()
+	defer a.stateMux.Unlock()
+	return DeciderSnapshot{
+		PanicTime:    a.panicTime,
+		MaxPanicPods: a.maxPanicPods,
+	}
+}
+
+// Observation is what an Autoscaler based a scaling decision on, and the
+// scale it decided on.
+type Observation struct {
+	Time   time.Time `json:"time"`
+	Metric string    `json:"metric"`
+	// ReadyPodCount is the number of ready pods at the time.
+	ReadyPodCount       int     `json:"readyPodCount"`
+	ObservedStableValue float64 `json:"observedStableValue"`
+	ObservedPanicValue  float64 `json:"observedPanicValue"`
+	// DesiredStablePodCount and DesiredPanicPodCount are the scales
+	// proposed by the stable and the panic window respectively.
+	DesiredStablePodCount int32 `json:"desiredStablePodCount"`
+	DesiredPanicPodCount  int32 `json:"desiredPanicPodCount"`
+	// DesiredPodCount is the scale decided on, after panicking and
+	// stabilization.
+	DesiredPodCount     int32 `json:"desiredPodCount"`
+	ExcessBurstCapacity int32 `json:"excessBurstCapacity"`
+}
+
+// Inspect returns a copy of the panic state and of the last observation,
+// which is nil if no scaling decision was made yet.
+func (a *Autoscaler) Inspect() (DeciderSnapshot, *Observation) {
+	a.stateMux.Lock()
+	defer a.stateMux.Unlock()
+	var observation *Observation
+	if a.lastObservation != nil {
+		o := *a.lastObservation
+		observation = &o
+	}
+	return DeciderSnapshot{
+		PanicTime:    a.panicTime,
+		MaxPanicPods: a.maxPanicPods,
+	}, observation
+}
+
+// Restore replaces the panic state with the given one. Since the metric
+// history is restored along with it, there is no need to start in panic
+// mode as New does.
+func (a *Autoscaler) Restore(snapshot DeciderSnapshot) {
+	a.stateMux.Lock()
+	defer a.stateMux.Unlock()
+	a.panicTime = snapshot.PanicTime
+	a.maxPanicPods = snapshot.MaxPanicPods
+	if a.panicTime != nil {
+		a.reporter.ReportPanic(1)
+	} else {
+		a.reporter.ReportPanic(0)
+	}
+}
+
 // Update reconfigures the UniScaler according to the DeciderSpec.
 func (a *Autoscaler) Update(deciderSpec *DeciderSpec) error {
 	a.specMux.Lock()
 	defer a.specMux.Unlock()
 
+	// Algorithms may be stateful, so only recreate it if it changes.
+	// Resolve it before changing any state, so that a failed update
+	// leaves the Autoscaler untouched.
+	algorithm := a.algorithm
+	if deciderSpec.Algorithm != a.deciderSpec.Algorithm {
+		var err error
+		if algorithm, err = NewScalingAlgorithm(deciderSpec.Algorithm); err != nil {
+			return err
+		}
+	}
 	// Update the podCounter if service name changes.
 	if deciderSpec.ServiceName != a.deciderSpec.ServiceName {
 		a.podCounter = resources.NewScopedEndpointsCounter(a.lister, a.namespace,
 			deciderSpec.ServiceName)
 	}
+	a.algorithm = algorithm
 	a.deciderSpec = deciderSpec
 	return nil
 }
@@ -129,7 +222,7 @@ func (a *Autoscaler) Update(deciderSpec *DeciderSpec) error {
 func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount int32, excessBC int32, validScale bool) {
 	logger := logging.FromContext(ctx)
 
-	spec, podCounter := a.currentSpecAndPC()
+	spec, podCounter, algorithm := a.currentSpecPCAndAlgorithm()
 	originalReadyPodsCount, err := podCounter.ReadyCount()
 	// If the error is NotFound, then presume 0.
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -149,9 +242,22 @@ func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount
 		a.reporter.ReportStableRPS(observedStableValue)
 		a.reporter.ReportPanicRPS(observedPanicValue)
 		a.reporter.ReportTargetRPS(spec.TargetValue)
+	case autoscaling.CPU:
+		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicCPU(metricKey, now)
+	case autoscaling.Memory:
+		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicMemory(metricKey, now)
+	case autoscaling.Custom:
+		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicCustom(metricKey, now)
+	case autoscaling.Connections:
+		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicConnections(metricKey, now)
 	default:
 		metricName = autoscaling.Concurrency // concurrency is used by default
 		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicConcurrency(metricKey, now)
+		// Queue-proxies that limit concurrency adaptively report the limit they
+		// discovered, which takes the place of the configured one.
+		if limit, lerr := a.metricClient.StableConcurrencyLimit(metricKey, now); lerr == nil && limit > 0 {
+			spec = withTotalValue(spec, limit/readyPodsCount)
+		}
 		a.reporter.ReportStableRequestConcurrency(observedStableValue)
 		a.reporter.ReportPanicRequestConcurrency(observedPanicValue)
 		a.reporter.ReportTargetRequestConcurrency(spec.TargetValue)
@@ -175,8 +281,33 @@ func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount
 	// E.g. MSUR=1.1, OCC=3, RPC=2, TV=1 => OCC/RPC=3, MSU=2.2 => DSPC=2, while we definitely, need
 	// 3 pods. See the unit test for this scenario in action.
 	maxScaleUp := math.Ceil(spec.MaxScaleUpRate * readyPodsCount)
-	desiredStablePodCount := int32(math.Min(math.Ceil(observedStableValue/spec.TargetValue), maxScaleUp))
-	desiredPanicPodCount := int32(math.Min(math.Ceil(observedPanicValue/spec.TargetValue), maxScaleUp))
+	// Similarly, don't drop more pods than MaxScaleDownRate permits in a single step,
+	// e.g. right after we exit the panic mode.
+	maxScaleDown := 0.
+	if spec.MaxScaleDownRate > 0 {
+		maxScaleDown = math.Floor(readyPodsCount / spec.MaxScaleDownRate)
+	}
+	stablePodCount, panicPodCount := algorithm.DesiredPodCounts(ScalingInput{
+		Now:                 now,
+		ReadyPodCount:       readyPodsCount,
+		ObservedStableValue: observedStableValue,
+		ObservedPanicValue:  observedPanicValue,
+		Spec:                spec,
+	})
+	if metricName == autoscaling.CPU || metricName == autoscaling.Memory || metricName == autoscaling.Connections {
+		// Pods consume resources even when idle, so the resource usage alone
+		// would never let the revision scale to zero, nor scale it up from zero.
+		// Connections are only upgraded once a pod serves them, so they can't
+		// scale up from zero either.
+		// Use the request concurrency to tell whether there is any traffic.
+		stableConcurrency, panicConcurrency, err := a.metricClient.StableAndPanicConcurrency(metricKey, now)
+		if err == nil {
+			stablePodCount = gateOnTraffic(stablePodCount, stableConcurrency)
+			panicPodCount = gateOnTraffic(panicPodCount, panicConcurrency)
+		}
+	}
+	desiredStablePodCount := int32(math.Min(math.Max(math.Ceil(stablePodCount), maxScaleDown), maxScaleUp))
+	desiredPanicPodCount := int32(math.Min(math.Max(math.Ceil(panicPodCount), maxScaleDown), maxScaleUp))
 
 	logger.Debugw(fmt.Sprintf("Observed average scaling metric value: %0.3f, targeting %0.3f.",
 		observedStableValue, spec.TargetValue),
@@ -189,6 +320,21 @@ func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount
 
 	a.stateMux.Lock()
 	defer a.stateMux.Unlock()
+	observation := &Observation{
+		Time:                  now,
+		Metric:                metricName,
+		ReadyPodCount:         originalReadyPodsCount,
+		ObservedStableValue:   observedStableValue,
+		ObservedPanicValue:    observedPanicValue,
+		DesiredStablePodCount: desiredStablePodCount,
+		DesiredPanicPodCount:  desiredPanicPodCount,
+	}
+	defer func() {
+		observation.DesiredPodCount = desiredPodCount
+		observation.ExcessBurstCapacity = excessBC
+		a.lastObservation = observation
+	}()
+
 	if a.panicTime == nil && isOverPanicThreshold {
 		// Begin panicking when we cross the threshold in the panic window.
 		logger.Info("PANICKING")
@@ -216,21 +362,31 @@ func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount
 		desiredPodCount = desiredStablePodCount
 	}
 
+	if stabilized := a.stabilize(now, desiredPodCount, spec.ScaleDownStabilizationWindow); stabilized != desiredPodCount {
+		logger.Debugf("Holding scale down from %d to %d within the stabilization window.", stabilized, desiredPodCount)
+		desiredPodCount = stabilized
+	}
+
 	// Compute the excess burst capacity based on stable value for now, since we don't want to
 	// be making knee-jerk decisions about Activator in the request path. Negative EBC means
 	// that the deployment does not have enough capacity to serve the desired burst off hand.
 	// EBC = TotCapacity - Cur#ReqInFlight - TargetBurstCapacity
 	excessBC = int32(-1)
 	switch {
-	case a.deciderSpec.TargetBurstCapacity == 0:
+	case spec.TargetBurstCapacity == 0:
 		excessBC = 0
-	case a.deciderSpec.TargetBurstCapacity >= 0:
-		excessBC = int32(math.Floor(float64(originalReadyPodsCount)*a.deciderSpec.TotalValue - observedStableValue -
-			a.deciderSpec.TargetBurstCapacity))
+	case spec.TargetBurstCapacity > 0 && metricName != autoscaling.Concurrency && metricName != autoscaling.RPS:
+		// The burst capacity is a number of requests, which can't be compared with
+		// the resources or connections a pod can take. Keep the Activator out of
+		// the request path, unless it's asked to be in it for good.
+		excessBC = 0
+	case spec.TargetBurstCapacity >= 0:
+		excessBC = int32(math.Floor(float64(originalReadyPodsCount)*spec.TotalValue - observedStableValue -
+			spec.TargetBurstCapacity))
 		logger.Infof("PodCount=%v TotalValue=%v ObservedStableValue=%v TargetBC=%v ExcessBC=%v",
 			originalReadyPodsCount,
-			a.deciderSpec.TotalValue,
-			observedStableValue, a.deciderSpec.TargetBurstCapacity, excessBC)
+			spec.TotalValue,
+			observedStableValue, spec.TargetBurstCapacity, excessBC)
 	}
 
 	a.reporter.ReportExcessBurstCapacity(float64(excessBC))
@@ -239,8 +395,57 @@ func (a *Autoscaler) Scale(ctx context.Context, now time.Time) (desiredPodCount
 	return desiredPodCount, excessBC, true
 }
 
-func (a *Autoscaler) currentSpecAndPC() (*DeciderSpec, resources.ReadyPodCounter) {
+// withTotalValue returns a copy of spec with the given per pod total value.
+// The target value is scaled along, to keep the same utilization.
+func withTotalValue(spec *DeciderSpec, total float64) *DeciderSpec {
+	ret := *spec
+	if spec.TotalValue > 0 {
+		ret.TargetValue = math.Max(1, total*spec.TargetValue/spec.TotalValue)
+	}
+	ret.TotalValue = total
+	return &ret
+}
+
+// gateOnTraffic returns 0 if there was no traffic and otherwise
+// makes sure there is at least one pod to serve it.
+func gateOnTraffic(podCount, concurrency float64) float64 {
+	if concurrency == 0 {
+		return 0
+	}
+	return math.Max(1, podCount)
+}
+
+// timedRecommendation is a desired scale proposed at a certain time.
+type timedRecommendation struct {
+	time  time.Time
+	scale int32
+}
+
+// stabilize records the recommendation and returns the highest one made during
+// the scale down stabilization window, so that we only scale down once the
+// lower recommendation has persisted for the whole window.
+// stateMux must be held by the caller.
+func (a *Autoscaler) stabilize(now time.Time, desired int32, window time.Duration) int32 {
+	if window <= 0 {
+		a.recommendations = nil
+		return desired
+	}
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(a.recommendations) && a.recommendations[i].time.Before(cutoff) {
+		i++
+	}
+	a.recommendations = append(a.recommendations[i:], timedRecommendation{time: now, scale: desired})
+	for _, r := range a.recommendations {
+		if r.scale > desired {
+			desired = r.scale
+		}
+	}
+	return desired
+}
+
+func (a *Autoscaler) currentSpecPCAndAlgorithm() (*DeciderSpec, resources.ReadyPodCounter, ScalingAlgorithm) {
 	a.specMux.RLock()
 	defer a.specMux.RUnlock()
-	return a.deciderSpec, a.podCounter
+	return a.deciderSpec, a.podCounter, a.algorithm
 }
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -43,18 +44,27 @@ var (
 )
 
 func TestNewErrorWhenGivenNilReadyPodCounter(t *testing.T) {
-	_, err := New(testNamespace, testRevision, &autoscalerfake.MetricClient{}, nil, &DeciderSpec{TargetValue: 10, ServiceName: testService}, &mockReporter{})
+	_, err := New(testNamespace, testRevision, &autoscalerfake.MetricClient{}, nil, &DeciderSpec{TargetValue: 10, ServiceName: testService}, kpaDefaultAlgorithm{}, &mockReporter{})
 	if err == nil {
 		t.Error("Expected error when ReadyPodCounter interface is nil, but got none.")
 	}
 }
 
+func TestNewErrorWhenGivenNilScalingAlgorithm(t *testing.T) {
+	l := kubeInformer.Core().V1().Endpoints().Lister()
+	_, err := New(testNamespace, testRevision, &autoscalerfake.MetricClient{}, l,
+		&DeciderSpec{TargetValue: 10, ServiceName: testService}, nil, &mockReporter{})
+	if err == nil {
+		t.Error("Expected error when ScalingAlgorithm is nil, but got none.")
+	}
+}
+
 func TestNewErrorWhenGivenNilStatsReporter(t *testing.T) {
 	var reporter StatsReporter
 
 	l := kubeInformer.Core().V1().Endpoints().Lister()
 	_, err := New(testNamespace, testRevision, &autoscalerfake.MetricClient{}, l,
-		&DeciderSpec{TargetValue: 10, ServiceName: testService}, reporter)
+		&DeciderSpec{TargetValue: 10, ServiceName: testService}, kpaDefaultAlgorithm{}, reporter)
 	if err == nil {
 		t.Error("Expected error when EndpointsInformer interface is nil, but got none.")
 	}
@@ -121,6 +131,24 @@ func TestAutoscalerChangeOfPodCountService(t *testing.T) {
 	a.expectScale(t, time.Now(), 5, expectedEBC(10, 100, 50, 2), true)
 }
 
+func TestAutoscalerFailedUpdateKeepsState(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 50.0}
+	a := newTestAutoscaler(t, 10, 100, metrics)
+	a.expectScale(t, time.Now(), 5, expectedEBC(10, 100, 50, 1), true)
+
+	const newTS = testService + "3"
+	newDS := *a.deciderSpec
+	newDS.ServiceName = newTS
+	newDS.Algorithm = "unknown"
+	if err := a.Update(&newDS); err == nil {
+		t.Fatal("Update() = nil, want an error for an unknown algorithm")
+	}
+
+	// Pods in the new service must not be counted after the failed update.
+	endpoints(2, newTS)
+	a.expectScale(t, time.Now(), 5, expectedEBC(10, 100, 50, 1), true)
+}
+
 func TestAutoscalerStableModeNoChangeAlreadyScaled(t *testing.T) {
 	metrics := &autoscalerfake.MetricClient{StableConcurrency: 50.0}
 	a := newTestAutoscaler(t, 10, 100, metrics)
@@ -154,6 +182,80 @@ func TestAutoscalerStableModeIncreaseWithRPS(t *testing.T) {
 	a.expectScale(t, time.Now(), 10, expectedEBC(10, 101, 100, 1), true)
 }
 
+func TestAutoscalerStableModeWithCPU(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableCPU: 500, StableConcurrency: 1}
+	a := newTestAutoscalerWithScalingMetric(t, 100, 100, metrics, "cpu")
+	a.expectScale(t, time.Now(), 5, 0, true)
+
+	// Idle pods still use some CPU, but with no traffic we scale to zero.
+	metrics.StableConcurrency = 0
+	a.expectScale(t, time.Now(), 0, 0, true)
+
+	// The burst capacity, in requests, doesn't apply to CPU, but an unlimited
+	// one still keeps the Activator in the path.
+	a = newTestAutoscalerWithScalingMetric(t, 100, -1, metrics, "cpu")
+	a.expectScale(t, time.Now(), 0, -1, true)
+}
+
+func TestAutoscalerStableModeWithMemory(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableMemory: 300 << 20, StableConcurrency: 1}
+	a := newTestAutoscalerWithScalingMetric(t, 100<<20, 100, metrics, "memory")
+	a.expectScale(t, time.Now(), 3, 0, true)
+
+	// At zero there is no memory usage, but the traffic needs a pod.
+	metrics.StableMemory = 0
+	a.expectScale(t, time.Now(), 1, 0, true)
+}
+
+func TestAutoscalerStableModeWithCustomMetric(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableCustom: 45}
+	a := newTestAutoscalerWithScalingMetric(t, 10, 100, metrics, "custom")
+	a.expectScale(t, time.Now(), 5, 0, true)
+
+	metrics.StableCustom = 0
+	a.expectScale(t, time.Now(), 0, 0, true)
+
+	// The burst capacity is in requests, so it can't be compared with a
+	// custom metric, however large its total.
+	spec := a.deciderSpec
+	spec.TotalValue = math.MaxFloat64
+	a.Update(spec)
+	metrics.StableCustom = 45
+	a.expectScale(t, time.Now(), 5, 0, true)
+}
+
+func TestAutoscalerStableModeWithConcurrencyLimit(t *testing.T) {
+	// Two pods that discovered a limit of 10 each.
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 50, StableLimit: 20}
+	a := newTestAutoscaler(t, 10, 100, metrics)
+	endpoints(2, testService)
+	a.expectScale(t, time.Now(), 7, expectedEBC(7.5, 100, 50, 2), true)
+
+	// Without a discovered limit the configured one is used.
+	metrics.StableLimit = 0
+	a.expectScale(t, time.Now(), 5, expectedEBC(10, 100, 50, 2), true)
+
+	// The limit is ignored for other metrics.
+	metrics = &autoscalerfake.MetricClient{StableRPS: 50, StableLimit: 20}
+	a = newTestAutoscalerWithScalingMetric(t, 10, 100, metrics, "rps")
+	a.expectScale(t, time.Now(), 5, expectedEBC(10, 100, 50, 1), true)
+}
+
+func TestAutoscalerStableModeWithConnections(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableConnections: 250, StableConcurrency: 250}
+	a := newTestAutoscalerWithScalingMetric(t, 100, 100, metrics, "connections")
+	a.expectScale(t, time.Now(), 3, 0, true)
+
+	// Before any connection is upgraded, the traffic needs a pod.
+	metrics.StableConnections = 0
+	metrics.StableConcurrency = 1
+	a.expectScale(t, time.Now(), 1, 0, true)
+
+	// Without any traffic we scale to zero.
+	metrics.StableConcurrency = 0
+	a.expectScale(t, time.Now(), 0, 0, true)
+}
+
 func TestAutoscalerStableModeDecrease(t *testing.T) {
 	metrics := &autoscalerfake.MetricClient{StableConcurrency: 100.0}
 	a := newTestAutoscaler(t, 10, 98, metrics)
@@ -223,6 +325,36 @@ func TestAutoscalerPanicThenUnPanicScaleDown(t *testing.T) {
 	a.expectScale(t, panicTime.Add(61*time.Second), 1, expectedEBC(10, 93, 1, 10), true)
 }
 
+func TestAutoscalerInspect(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 50, PanicConcurrency: 100}
+	a := newTestAutoscaler(t, 10, 84, metrics)
+	if _, observation := a.Inspect(); observation != nil {
+		t.Errorf("Inspect() = %+v, want no observation before scaling", observation)
+	}
+
+	endpoints(1, testService)
+	now := time.Now()
+	a.expectScale(t, now, 10, expectedEBC(10, 84, 50, 1), true)
+	snapshot, observation := a.Inspect()
+	if snapshot.PanicTime == nil || !snapshot.PanicTime.Equal(now) || snapshot.MaxPanicPods != 10 {
+		t.Errorf("Inspect() = %+v, want to panic since %v with 10 pods", snapshot, now)
+	}
+	want := &Observation{
+		Time:                  now,
+		Metric:                "concurrency",
+		ReadyPodCount:         1,
+		ObservedStableValue:   50,
+		ObservedPanicValue:    100,
+		DesiredStablePodCount: 5,
+		DesiredPanicPodCount:  10,
+		DesiredPodCount:       10,
+		ExcessBurstCapacity:   expectedEBC(10, 84, 50, 1),
+	}
+	if !cmp.Equal(observation, want) {
+		t.Errorf("Inspect() observation diff(-want,+got): %s", cmp.Diff(want, observation))
+	}
+}
+
 func TestAutoscalerRateLimitScaleUp(t *testing.T) {
 	metrics := &autoscalerfake.MetricClient{StableConcurrency: 1000}
 	a := newTestAutoscaler(t, 10, 61, metrics)
@@ -235,6 +367,47 @@ func TestAutoscalerRateLimitScaleUp(t *testing.T) {
 	a.expectScale(t, time.Now(), 100, expectedEBC(10, 61, 1000, 10), true)
 }
 
+func TestAutoscalerRateLimitScaleDown(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 1}
+	a := newTestAutoscaler(t, 10, 61, metrics)
+	a.deciderSpec.MaxScaleDownRate = 2
+
+	// Need 1 pod but only scale /2.
+	endpoints(100, testService)
+	a.expectScale(t, time.Now(), 50, expectedEBC(10, 61, 1, 100), true)
+
+	endpoints(50, testService)
+	a.expectScale(t, time.Now(), 25, expectedEBC(10, 61, 1, 50), true)
+
+	// Scale to zero is still possible.
+	metrics.StableConcurrency = 0
+	endpoints(1, testService)
+	a.expectScale(t, time.Now(), 0, expectedEBC(10, 61, 0, 1), true)
+}
+
+func TestAutoscalerScaleDownStabilizationWindow(t *testing.T) {
+	metrics := &autoscalerfake.MetricClient{StableConcurrency: 100}
+	a := newTestAutoscaler(t, 10, 61, metrics)
+	a.deciderSpec.ScaleDownStabilizationWindow = 30 * time.Second
+	endpoints(10, testService)
+
+	now := time.Now()
+	a.expectScale(t, now, 10, expectedEBC(10, 61, 100, 10), true)
+
+	// A brief dip does not scale down.
+	metrics.StableConcurrency = 20
+	a.expectScale(t, now.Add(10*time.Second), 10, expectedEBC(10, 61, 20, 10), true)
+
+	// Scale up is applied immediately.
+	metrics.StableConcurrency = 120
+	a.expectScale(t, now.Add(20*time.Second), 12, expectedEBC(10, 61, 120, 10), true)
+
+	// Scale down once the higher recommendations are out of the window.
+	metrics.StableConcurrency = 20
+	a.expectScale(t, now.Add(40*time.Second), 12, expectedEBC(10, 61, 20, 10), true)
+	a.expectScale(t, now.Add(51*time.Second), 2, expectedEBC(10, 61, 20, 10), true)
+}
+
 func eraseEndpoints() {
 	ep, _ := kubeClient.CoreV1().Endpoints(testNamespace).Get(testService, metav1.GetOptions{})
 	kubeClient.CoreV1().Endpoints(testNamespace).Delete(testService, nil)
@@ -351,7 +524,7 @@ func newTestAutoscalerWithScalingMetric(t *testing.T, targetValue, targetBurstCa
 	l := kubeInformer.Core().V1().Endpoints().Lister()
 	// This ensures that we have endpoints object to start the autoscaler.
 	endpoints(0, testService)
-	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, &mockReporter{})
+	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, kpaDefaultAlgorithm{}, &mockReporter{})
 	if err != nil {
 		t.Fatalf("Error creating test autoscaler: %v", err)
 	}
@@ -408,7 +581,7 @@ func TestStartInPanicMode(t *testing.T) {
 	l := kubeInformer.Core().V1().Endpoints().Lister()
 	for i := 0; i < 2; i++ {
 		endpoints(i, testService)
-		a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, &mockReporter{})
+		a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, kpaDefaultAlgorithm{}, &mockReporter{})
 		if err != nil {
 			t.Fatalf("Error creating test autoscaler: %v", err)
 		}
@@ -422,7 +595,7 @@ func TestStartInPanicMode(t *testing.T) {
 
 	// Now start with 2 and make sure we're in panic mode.
 	endpoints(2, testService)
-	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, &mockReporter{})
+	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, kpaDefaultAlgorithm{}, &mockReporter{})
 	if err != nil {
 		t.Fatalf("Error creating test autoscaler: %v", err)
 	}
@@ -448,7 +621,7 @@ func TestNewFail(t *testing.T) {
 	}
 
 	l := kubeInformer.Core().V1().Endpoints().Lister()
-	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, &mockReporter{})
+	a, err := New(testNamespace, testRevision, metrics, l, deciderSpec, kpaDefaultAlgorithm{}, &mockReporter{})
 	if err != nil {
 		t.Errorf("No endpoints should succeed, err = %v", err)
 	}
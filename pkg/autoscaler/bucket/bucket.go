@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bucket shards the work of the autoscaler among its replicas. The
+// revisions are hashed into a fixed number of buckets, each of which is owned
+// by the replica holding the bucket's Lease.
+package bucket
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Name returns the name of the ordinal-th of total buckets, which is also
+// the name of its Lease.
+func Name(ordinal, total int) string {
+	return fmt.Sprintf("autoscaler-bucket-%02d-of-%02d", ordinal, total)
+}
+
+// Set is the set of buckets the keys are distributed among.
+type Set struct {
+	names []string
+}
+
+// NewSet creates a Set of total buckets.
+func NewSet(total int) *Set {
+	names := make([]string, total)
+	for i := range names {
+		names[i] = Name(i, total)
+	}
+	return &Set{names: names}
+}
+
+// Names returns the names of the buckets.
+func (s *Set) Names() []string {
+	return s.names
+}
+
+// Bucket returns the name of the bucket the given key belongs to.
+func (s *Set) Bucket(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.names[h.Sum32()%uint32(len(s.names))]
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSet(t *testing.T) {
+	set := NewSet(3)
+	want := []string{
+		"autoscaler-bucket-00-of-03",
+		"autoscaler-bucket-01-of-03",
+		"autoscaler-bucket-02-of-03",
+	}
+	if got := set.Names(); !cmp.Equal(got, want) {
+		t.Errorf("Names() = %v, want: %v", got, want)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("ns/rev-%d", i)
+		b := set.Bucket(key)
+		if again := set.Bucket(key); again != b {
+			t.Fatalf("Bucket(%s) = %s, then %s", key, b, again)
+		}
+		counts[b]++
+	}
+	// Every bucket gets a fair share of the keys.
+	for _, name := range want {
+		if counts[name] < 50 {
+			t.Errorf("Bucket %s got %d of 300 keys, want at least 50", name, counts[name])
+		}
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	"knative.dev/pkg/ptr"
+)
+
+const (
+	// LeaseDuration is how long a Lease is valid after it was last renewed.
+	LeaseDuration = 15 * time.Second
+	// RetryPeriod is the time between attempts to acquire or renew a Lease.
+	RetryPeriod = 2 * time.Second
+	// renewDeadline is how long the leader keeps leading a bucket without
+	// managing to renew its Lease. It's shorter than the LeaseDuration, so
+	// that the leader steps down before anyone else can take over.
+	renewDeadline = 10 * time.Second
+)
+
+// Elector runs a leader election per bucket of a Set, using a Lease per
+// bucket. The identity of the holder of a Lease is the address other
+// replicas reach the holder at.
+type Elector struct {
+	client    coordinationclient.LeasesGetter
+	namespace string
+	identity  string
+	set       *Set
+	logger    *zap.SugaredLogger
+
+	// onStarted and onStopped are called when this replica starts and
+	// stops leading a bucket.
+	onStarted func(bucket string)
+	onStopped func(bucket string)
+
+	mu sync.RWMutex
+	// holders are the last observed holders of the buckets.
+	holders map[string]string
+	// renewed are the times this replica last renewed the buckets it leads.
+	renewed map[string]time.Time
+}
+
+// NewElector creates an Elector for the buckets of set, whose Leases live in
+// namespace, competing under the given identity.
+func NewElector(client coordinationclient.LeasesGetter, namespace, identity string, set *Set,
+	onStarted, onStopped func(bucket string), logger *zap.SugaredLogger) *Elector {
+	return &Elector{
+		client:    client,
+		namespace: namespace,
+		identity:  identity,
+		set:       set,
+		logger:    logger,
+		onStarted: onStarted,
+		onStopped: onStopped,
+		holders:   make(map[string]string, len(set.Names())),
+		renewed:   make(map[string]time.Time, len(set.Names())),
+	}
+}
+
+// Run competes for the buckets until stopCh is closed, then releases the
+// buckets this replica leads, so that the others can take over right away.
+func (e *Elector) Run(stopCh <-chan struct{}) {
+	var wg sync.WaitGroup
+	for _, b := range e.set.Names() {
+		wg.Add(1)
+		go func(b string) {
+			defer wg.Done()
+			ticker := time.NewTicker(RetryPeriod)
+			defer ticker.Stop()
+			for {
+				if err := e.tryAcquireOrRenew(b, time.Now()); err != nil {
+					e.logger.Warnw("Failed to acquire or renew the Lease of "+b, zap.Error(err))
+				}
+				select {
+				case <-stopCh:
+					e.release(b)
+					return
+				case <-ticker.C:
+				}
+			}
+		}(b)
+	}
+	wg.Wait()
+}
+
+// Owns returns true if this replica leads the bucket the key belongs to.
+func (e *Elector) Owns(key string) bool {
+	return e.Holder(e.set.Bucket(key)) == e.identity
+}
+
+// Owner returns the identity of the replica leading the bucket the key
+// belongs to, or an empty string if it is unknown.
+func (e *Elector) Owner(key string) string {
+	return e.Holder(e.set.Bucket(key))
+}
+
+// Partition returns the bucket the key belongs to. Along with Owned, it
+// partitions the metric snapshots by bucket.
+func (e *Elector) Partition(key string) string {
+	return e.set.Bucket(key)
+}
+
+// Owned returns the buckets this replica leads.
+func (e *Elector) Owned() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var ret []string
+	for _, b := range e.set.Names() {
+		if e.holders[b] == e.identity {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
+
+// Holder returns the identity of the replica leading the given bucket, or
+// an empty string if it is unknown.
+func (e *Elector) Holder(bucket string) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.holders[bucket]
+}
+
+// tryAcquireOrRenew renews the Lease of the bucket if this replica holds
+// it, or acquires it if nobody holds it or its holder failed to renew it.
+func (e *Elector) tryAcquireOrRenew(bucket string, now time.Time) error {
+	leases := e.client.Leases(e.namespace)
+	lease, err := leases.Get(bucket, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = leases.Create(&coordinationv1beta1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: e.namespace,
+				Name:      bucket,
+			},
+			Spec: e.leaseSpec(now, now, 0),
+		})
+		if err != nil {
+			e.expire(bucket, now)
+			return err
+		}
+		e.observe(bucket, e.identity, now)
+		return nil
+	} else if err != nil {
+		e.expire(bucket, now)
+		return err
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if holder != "" && holder != e.identity && !expired(lease, now) {
+		e.observe(bucket, holder, now)
+		return nil
+	}
+
+	var transitions int32
+	if lease.Spec.LeaseTransitions != nil {
+		transitions = *lease.Spec.LeaseTransitions
+	}
+	acquired := now
+	if holder == e.identity && lease.Spec.AcquireTime != nil {
+		acquired = lease.Spec.AcquireTime.Time
+	} else {
+		transitions++
+	}
+	lease = lease.DeepCopy()
+	lease.Spec = e.leaseSpec(acquired, now, transitions)
+	// The update fails on a conflict if another replica updated the Lease
+	// since we read it, so at most one of them acquires it.
+	if _, err := leases.Update(lease); err != nil {
+		e.expire(bucket, now)
+		return err
+	}
+	e.observe(bucket, e.identity, now)
+	return nil
+}
+
+// release gives up the Lease of the bucket if this replica holds it.
+func (e *Elector) release(bucket string) {
+	if e.Holder(bucket) != e.identity {
+		return
+	}
+	e.setHolder(bucket, "")
+	leases := e.client.Leases(e.namespace)
+	lease, err := leases.Get(bucket, metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != e.identity {
+		return
+	}
+	lease = lease.DeepCopy()
+	lease.Spec.HolderIdentity = nil
+	if _, err := leases.Update(lease); err != nil {
+		e.logger.Warnw("Failed to release the Lease of "+bucket, zap.Error(err))
+	}
+}
+
+func (e *Elector) leaseSpec(acquired, renewed time.Time, transitions int32) coordinationv1beta1.LeaseSpec {
+	return coordinationv1beta1.LeaseSpec{
+		HolderIdentity:       ptr.String(e.identity),
+		LeaseDurationSeconds: ptr.Int32(int32(LeaseDuration / time.Second)),
+		AcquireTime:          &metav1.MicroTime{Time: acquired},
+		RenewTime:            &metav1.MicroTime{Time: renewed},
+		LeaseTransitions:     ptr.Int32(transitions),
+	}
+}
+
+func expired(lease *coordinationv1beta1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	duration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	return lease.Spec.RenewTime.Add(duration).Before(now)
+}
+
+// observe records the holder of the bucket, and when this replica renewed it.
+func (e *Elector) observe(bucket, holder string, now time.Time) {
+	if holder == e.identity {
+		e.mu.Lock()
+		e.renewed[bucket] = now
+		e.mu.Unlock()
+	}
+	e.setHolder(bucket, holder)
+}
+
+// expire steps down from leading the bucket if this replica failed to renew
+// it for longer than the renewDeadline.
+func (e *Elector) expire(bucket string, now time.Time) {
+	e.mu.RLock()
+	renewed, ok := e.renewed[bucket]
+	e.mu.RUnlock()
+	if ok && now.Sub(renewed) > renewDeadline {
+		e.setHolder(bucket, "")
+	}
+}
+
+func (e *Elector) setHolder(bucket, holder string) {
+	e.mu.Lock()
+	previous := e.holders[bucket]
+	e.holders[bucket] = holder
+	if holder != e.identity {
+		delete(e.renewed, bucket)
+	}
+	e.mu.Unlock()
+
+	switch {
+	case previous != e.identity && holder == e.identity:
+		e.logger.Info("Started leading ", bucket)
+		if e.onStarted != nil {
+			e.onStarted(bucket)
+		}
+	case previous == e.identity && holder != e.identity:
+		e.logger.Info("Stopped leading ", bucket)
+		if e.onStopped != nil {
+			e.onStopped(bucket)
+		}
+	}
+}
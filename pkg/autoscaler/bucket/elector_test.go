@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+
+	. "knative.dev/pkg/logging/testing"
+)
+
+const testNamespace = "knative-serving"
+
+type transitions struct {
+	started, stopped []string
+}
+
+func newTestElector(t *testing.T, client *fakek8s.Clientset, identity string, set *Set) (*Elector, *transitions) {
+	tr := &transitions{}
+	e := NewElector(client.CoordinationV1beta1(), testNamespace, identity, set,
+		func(b string) { tr.started = append(tr.started, b) },
+		func(b string) { tr.stopped = append(tr.stopped, b) },
+		TestLogger(t))
+	return e, tr
+}
+
+func TestElector(t *testing.T) {
+	client := fakek8s.NewSimpleClientset()
+	set := NewSet(1)
+	b := set.Names()[0]
+	key := "ns/rev"
+	a, trA := newTestElector(t, client, "10.0.0.1", set)
+	other, trOther := newTestElector(t, client, "10.0.0.2", set)
+	now := time.Now()
+
+	// The first one to try acquires the bucket.
+	if err := a.tryAcquireOrRenew(b, now); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	if !a.Owns(key) || len(trA.started) != 1 {
+		t.Errorf("Owns() = %v, started = %v, want the bucket to be acquired", a.Owns(key), trA.started)
+	}
+	if got := a.Owned(); len(got) != 1 || got[0] != a.Partition(key) {
+		t.Errorf("Owned() = %v, want: [%s]", got, b)
+	}
+
+	// The other one sees it's held.
+	if err := other.tryAcquireOrRenew(b, now.Add(time.Second)); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	if other.Owns(key) || other.Owner(key) != "10.0.0.1" {
+		t.Errorf("Owns() = %v, Owner() = %q, want the bucket to be held by 10.0.0.1", other.Owns(key), other.Owner(key))
+	}
+	if got := other.Owned(); len(got) != 0 {
+		t.Errorf("Owned() = %v, want none", got)
+	}
+
+	// Renewing keeps the acquire time.
+	if err := a.tryAcquireOrRenew(b, now.Add(2*time.Second)); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	lease, err := client.CoordinationV1beta1().Leases(testNamespace).Get(b, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if !lease.Spec.AcquireTime.Time.Equal(now) || *lease.Spec.LeaseTransitions != 0 {
+		t.Errorf("Lease = %+v, want to be acquired at %v without transitions", lease.Spec, now)
+	}
+
+	// Once the lease expires the other one takes over.
+	later := now.Add(2*time.Second + LeaseDuration + time.Second)
+	if err := other.tryAcquireOrRenew(b, later); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	if !other.Owns(key) || len(trOther.started) != 1 {
+		t.Errorf("Owns() = %v, started = %v, want the bucket to be taken over", other.Owns(key), trOther.started)
+	}
+	if err := a.tryAcquireOrRenew(b, later); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	if a.Owns(key) || len(trA.stopped) != 1 {
+		t.Errorf("Owns() = %v, stopped = %v, want the bucket to be lost", a.Owns(key), trA.stopped)
+	}
+
+	// Releasing hands the bucket over right away.
+	other.release(b)
+	if other.Owns(key) || len(trOther.stopped) != 1 {
+		t.Errorf("Owns() = %v, stopped = %v, want the bucket to be released", other.Owns(key), trOther.stopped)
+	}
+	if err := a.tryAcquireOrRenew(b, later.Add(time.Second)); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+	if !a.Owns(key) || len(trA.started) != 2 {
+		t.Errorf("Owns() = %v, started = %v, want the bucket to be acquired again", a.Owns(key), trA.started)
+	}
+}
+
+func TestElectorStepsDown(t *testing.T) {
+	client := fakek8s.NewSimpleClientset()
+	set := NewSet(1)
+	b := set.Names()[0]
+	e, tr := newTestElector(t, client, "10.0.0.1", set)
+	now := time.Now()
+	if err := e.tryAcquireOrRenew(b, now); err != nil {
+		t.Fatalf("tryAcquireOrRenew() = %v", err)
+	}
+
+	// Failing to renew keeps leading until the renew deadline.
+	e.expire(b, now.Add(renewDeadline/2))
+	if !e.Owns("ns/rev") {
+		t.Error("Owns() = false, want to keep leading before the renew deadline")
+	}
+	e.expire(b, now.Add(renewDeadline+time.Second))
+	if e.Owns("ns/rev") || len(tr.stopped) != 1 {
+		t.Errorf("Owns() = %v, stopped = %v, want to step down after the renew deadline", e.Owns("ns/rev"), tr.stopped)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/controller"
+)
+
+// ownedReconciler only reconciles the keys whose bucket this replica leads.
+type ownedReconciler struct {
+	controller.Reconciler
+	owns func(key string) bool
+	drop func(ctx context.Context, namespace, name string) error
+}
+
+// NewReconciler wraps r, so that it only reconciles the keys owns returns
+// true for. The other keys are passed to drop instead, which releases the
+// local state kept for them, e.g. after their bucket moved to another replica.
+func NewReconciler(r controller.Reconciler, owns func(key string) bool,
+	drop func(ctx context.Context, namespace, name string) error) controller.Reconciler {
+	return &ownedReconciler{
+		Reconciler: r,
+		owns:       owns,
+		drop:       drop,
+	}
+}
+
+// Reconcile implements controller.Reconciler.
+func (r *ownedReconciler) Reconcile(ctx context.Context, key string) error {
+	if r.owns(key) {
+		return r.Reconciler.Reconcile(ctx, key)
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	return r.drop(ctx, namespace, name)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bucket
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeReconciler struct {
+	reconciled []string
+}
+
+func (r *fakeReconciler) Reconcile(_ context.Context, key string) error {
+	r.reconciled = append(r.reconciled, key)
+	return nil
+}
+
+func TestReconciler(t *testing.T) {
+	inner := &fakeReconciler{}
+	var dropped []string
+	r := NewReconciler(inner, func(key string) bool {
+		return key == "ns/owned"
+	}, func(_ context.Context, namespace, name string) error {
+		dropped = append(dropped, namespace+"/"+name)
+		return nil
+	})
+
+	for _, key := range []string{"ns/owned", "ns/other"} {
+		if err := r.Reconcile(context.Background(), key); err != nil {
+			t.Errorf("Reconcile(%s) = %v", key, err)
+		}
+	}
+	if len(inner.reconciled) != 1 || inner.reconciled[0] != "ns/owned" {
+		t.Errorf("Reconciled %v, want [ns/owned]", inner.reconciled)
+	}
+	if len(dropped) != 1 || dropped[0] != "ns/other" {
+		t.Errorf("Dropped %v, want [ns/other]", dropped)
+	}
+
+	if err := r.Reconcile(context.Background(), "too/many/slashes"); err == nil {
+		t.Error("Reconcile() = nil, wanted an error for a malformed key")
+	}
+}
@@ -68,6 +68,23 @@ type Stat struct {
 
 	// Part of RequestCount, for requests going through a proxy.
 	ProxiedRequestCount float64
+
+	// Average CPU usage of this pod since last Stat, in millicores.
+	AverageCPUUsage float64
+
+	// Memory working set of this pod, in bytes.
+	MemoryUsage float64
+
+	// Value of the custom metric the user container exposes, if any.
+	CustomMetricValue float64
+
+	// Concurrency limit the queue-proxy of this pod discovered at runtime,
+	// if it limits adaptively.
+	ConcurrencyLimit float64
+
+	// Average number of connections currently upgraded by this pod, e.g. to
+	// WebSocket. They are part of AverageConcurrentRequests.
+	AverageUpgradedConnections float64
 }
 
 // StatMessage wraps a Stat with identifying information so it can be routed
@@ -97,6 +114,26 @@ type MetricClient interface {
 	// StableAndPanicRPS returns both the stable and the panic RPS
 	// for the given replica as of the given time.
 	StableAndPanicRPS(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableAndPanicCPU returns both the stable and the panic CPU usage
+	// in millicores for the given replica as of the given time.
+	StableAndPanicCPU(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableAndPanicMemory returns both the stable and the panic memory usage
+	// in bytes for the given replica as of the given time.
+	StableAndPanicMemory(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableAndPanicCustom returns both the stable and the panic value of
+	// the custom metric for the given replica as of the given time.
+	StableAndPanicCustom(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableAndPanicConnections returns both the stable and the panic number
+	// of upgraded connections for the given replica as of the given time.
+	StableAndPanicConnections(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableConcurrencyLimit returns the stable total of the concurrency limits
+	// the queue-proxies of the given replica discovered, as of the given time.
+	StableConcurrencyLimit(key types.NamespacedName, now time.Time) (float64, error)
 }
 
 // MetricCollector manages collection of metrics for many entities.
@@ -107,6 +144,18 @@ type MetricCollector struct {
 
 	collections      map[types.NamespacedName]*collection
 	collectionsMutex sync.RWMutex
+
+	// restored holds the restored metric history of the collections that
+	// have not been created yet, keyed by the string form of their key.
+	// Guarded by the collectionsMutex.
+	restored map[string]restoredCollection
+}
+
+// restoredCollection is the restored metric history of a collection, which
+// is stale from expires on.
+type restoredCollection struct {
+	snapshot CollectionSnapshot
+	expires  time.Time
 }
 
 var _ Collector = (*MetricCollector)(nil)
@@ -153,10 +202,73 @@ func (c *MetricCollector) CreateOrUpdate(metric *av1alpha1.Metric) error {
 		return nil
 	}
 
-	c.collections[key] = newCollection(metric, scraper, c.logger)
+	collection = newCollection(metric, scraper, c.logger)
+	if restored, ok := c.restored[key.String()]; ok {
+		if time.Now().Before(restored.expires) {
+			c.logger.Info("Restoring metric history for ", key.String())
+			collection.restore(restored.snapshot)
+		}
+		delete(c.restored, key.String())
+	}
+	c.collections[key] = collection
 	return nil
 }
 
+// Snapshot returns a copy of the metric history of all the collections,
+// keyed by the string form of their key.
+func (c *MetricCollector) Snapshot() map[string]CollectionSnapshot {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	ret := make(map[string]CollectionSnapshot, len(c.collections))
+	for key, collection := range c.collections {
+		ret[key.String()] = collection.snapshot()
+	}
+	return ret
+}
+
+// Restore makes the collector seed the collections with the given metric
+// history when they are created before it expires.
+func (c *MetricCollector) Restore(snapshots map[string]CollectionSnapshot, expires time.Time) {
+	c.collectionsMutex.Lock()
+	defer c.collectionsMutex.Unlock()
+
+	if c.restored == nil {
+		c.restored = make(map[string]restoredCollection, len(snapshots))
+	}
+	for key, snapshot := range snapshots {
+		c.restored[key] = restoredCollection{snapshot: snapshot, expires: expires}
+	}
+	// The history of the revisions deleted in the meantime is never claimed.
+	time.AfterFunc(time.Until(expires), c.dropExpiredRestored)
+}
+
+// dropExpiredRestored drops the restored metric history that expired.
+func (c *MetricCollector) dropExpiredRestored() {
+	c.collectionsMutex.Lock()
+	defer c.collectionsMutex.Unlock()
+
+	now := time.Now()
+	for key, restored := range c.restored {
+		if !now.Before(restored.expires) {
+			delete(c.restored, key)
+		}
+	}
+}
+
+// Buckets returns a copy of the metric history collected for the given
+// key, or false if it is not being collected.
+func (c *MetricCollector) Buckets(key types.NamespacedName) (CollectionSnapshot, bool) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return CollectionSnapshot{}, false
+	}
+	return collection.snapshot(), true
+}
+
 // Delete deletes a Metric and halts collection.
 func (c *MetricCollector) Delete(namespace, name string) error {
 	c.collectionsMutex.Lock()
@@ -210,6 +322,88 @@ func (c *MetricCollector) StableAndPanicRPS(key types.NamespacedName, now time.T
 	return collection.StableAndPanicRPS(now)
 }
 
+// StableAndPanicCPU returns both the stable and the panic CPU usage.
+// It may truncate metric buckets as a side-effect.
+func (c *MetricCollector) StableAndPanicCPU(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, 0, ErrNotScraping
+	}
+
+	return collection.stableAndPanicStats(now, collection.cpuBuckets)
+}
+
+// StableAndPanicMemory returns both the stable and the panic memory usage.
+// It may truncate metric buckets as a side-effect.
+func (c *MetricCollector) StableAndPanicMemory(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, 0, ErrNotScraping
+	}
+
+	return collection.stableAndPanicStats(now, collection.memoryBuckets)
+}
+
+// StableAndPanicCustom returns both the stable and the panic value of the custom metric.
+// It may truncate metric buckets as a side-effect.
+func (c *MetricCollector) StableAndPanicCustom(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, 0, ErrNotScraping
+	}
+
+	return collection.stableAndPanicStats(now, collection.customBuckets)
+}
+
+// StableAndPanicConnections returns both the stable and the panic number of upgraded connections.
+// It may truncate metric buckets as a side-effect.
+func (c *MetricCollector) StableAndPanicConnections(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, 0, ErrNotScraping
+	}
+
+	return collection.stableAndPanicStats(now, collection.connectionsBuckets)
+}
+
+// StableConcurrencyLimit returns the stable total of the discovered concurrency limits.
+// It returns ErrNoData if no queue-proxy limits adaptively.
+func (c *MetricCollector) StableConcurrencyLimit(key types.NamespacedName, now time.Time) (float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, ErrNotScraping
+	}
+
+	stable, _, err := collection.stableAndPanicStats(now, collection.limitBuckets)
+	return stable, err
+}
+
+// CollectionSnapshot is a serializable copy of the metric history of a collection.
+type CollectionSnapshot struct {
+	Concurrency []aggregation.BucketSnapshot `json:"concurrency,omitempty"`
+	RPS         []aggregation.BucketSnapshot `json:"rps,omitempty"`
+	CPU         []aggregation.BucketSnapshot `json:"cpu,omitempty"`
+	Memory      []aggregation.BucketSnapshot `json:"memory,omitempty"`
+	Custom      []aggregation.BucketSnapshot `json:"custom,omitempty"`
+	Limit       []aggregation.BucketSnapshot `json:"limit,omitempty"`
+	Connections []aggregation.BucketSnapshot `json:"connections,omitempty"`
+}
+
 // collection represents the collection of metrics for one specific entity.
 type collection struct {
 	metricMutex sync.RWMutex
@@ -219,6 +413,11 @@ type collection struct {
 	scraper            StatsScraper
 	concurrencyBuckets *aggregation.TimedFloat64Buckets
 	rpsBuckets         *aggregation.TimedFloat64Buckets
+	cpuBuckets         *aggregation.TimedFloat64Buckets
+	memoryBuckets      *aggregation.TimedFloat64Buckets
+	customBuckets      *aggregation.TimedFloat64Buckets
+	limitBuckets       *aggregation.TimedFloat64Buckets
+	connectionsBuckets *aggregation.TimedFloat64Buckets
 
 	grp    sync.WaitGroup
 	stopCh chan struct{}
@@ -243,6 +442,11 @@ func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, logger *zap.S
 		metric:             metric,
 		concurrencyBuckets: aggregation.NewTimedFloat64Buckets(BucketSize),
 		rpsBuckets:         aggregation.NewTimedFloat64Buckets(BucketSize),
+		cpuBuckets:         aggregation.NewTimedFloat64Buckets(BucketSize),
+		memoryBuckets:      aggregation.NewTimedFloat64Buckets(BucketSize),
+		customBuckets:      aggregation.NewTimedFloat64Buckets(BucketSize),
+		limitBuckets:       aggregation.NewTimedFloat64Buckets(BucketSize),
+		connectionsBuckets: aggregation.NewTimedFloat64Buckets(BucketSize),
 		scraper:            scraper,
 
 		stopCh: make(chan struct{}),
@@ -259,11 +463,11 @@ func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, logger *zap.S
 				scrapeTicker.Stop()
 				return
 			case <-scrapeTicker.C:
-				message, err := c.getScraper().Scrape()
+				messages, err := c.getScraper().Scrape()
 				if err != nil {
 					logger.Errorw("Failed to scrape metrics", zap.Error(err))
 				}
-				if message != nil {
+				for _, message := range messages {
 					c.record(message.Stat)
 				}
 			}
@@ -297,11 +501,52 @@ func (c *collection) record(stat Stat) {
 	// them to avoid double counting.
 	c.concurrencyBuckets.Record(*stat.Time, stat.PodName, stat.AverageConcurrentRequests-stat.AverageProxiedConcurrentRequests)
 	c.rpsBuckets.Record(*stat.Time, stat.PodName, stat.RequestCount-stat.ProxiedRequestCount)
+	// The activator doesn't consume any resources of the revision, so its
+	// stats add nothing, but keep data available when scaled to zero.
+	c.cpuBuckets.Record(*stat.Time, stat.PodName, stat.AverageCPUUsage)
+	c.memoryBuckets.Record(*stat.Time, stat.PodName, stat.MemoryUsage)
+	c.customBuckets.Record(*stat.Time, stat.PodName, stat.CustomMetricValue)
+	// Only adaptively limiting queue-proxies report a limit.
+	if stat.ConcurrencyLimit > 0 {
+		c.limitBuckets.Record(*stat.Time, stat.PodName, stat.ConcurrencyLimit)
+	}
+	// The activator does not track upgraded connections, so unlike the
+	// requests they are not counted twice.
+	c.connectionsBuckets.Record(*stat.Time, stat.PodName, stat.AverageUpgradedConnections)
 
 	// Delete outdated stats taking stat.Time as current time.
 	now := stat.Time
 	c.concurrencyBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
 	c.rpsBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+	c.cpuBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+	c.memoryBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+	c.customBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+	c.limitBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+	c.connectionsBuckets.RemoveOlderThan(now.Add(-spec.StableWindow))
+}
+
+// snapshot returns a copy of the metric history of the collection.
+func (c *collection) snapshot() CollectionSnapshot {
+	return CollectionSnapshot{
+		Concurrency: c.concurrencyBuckets.Snapshot(),
+		RPS:         c.rpsBuckets.Snapshot(),
+		CPU:         c.cpuBuckets.Snapshot(),
+		Memory:      c.memoryBuckets.Snapshot(),
+		Custom:      c.customBuckets.Snapshot(),
+		Limit:       c.limitBuckets.Snapshot(),
+		Connections: c.connectionsBuckets.Snapshot(),
+	}
+}
+
+// restore adds the given metric history to the collection.
+func (c *collection) restore(snapshot CollectionSnapshot) {
+	c.concurrencyBuckets.Restore(snapshot.Concurrency)
+	c.rpsBuckets.Restore(snapshot.RPS)
+	c.cpuBuckets.Restore(snapshot.CPU)
+	c.memoryBuckets.Restore(snapshot.Memory)
+	c.customBuckets.Restore(snapshot.Custom)
+	c.limitBuckets.Restore(snapshot.Limit)
+	c.connectionsBuckets.Restore(snapshot.Connections)
 }
 
 // stableAndPanicConcurrency calculates both stable and panic concurrency based on the
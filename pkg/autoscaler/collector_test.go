@@ -50,13 +50,13 @@ func TestMetricCollectorCRUD(t *testing.T) {
 	logger := TestLogger(t)
 
 	scraper := &testScraper{
-		s: func() (*StatMessage, error) {
+		s: func() ([]*StatMessage, error) {
 			return nil, nil
 		},
 		url: "just-right",
 	}
 	scraper2 := &testScraper{
-		s: func() (*StatMessage, error) {
+		s: func() ([]*StatMessage, error) {
 			return nil, nil
 		},
 		url: "slightly-off",
@@ -127,8 +127,8 @@ func TestMetricCollectorScraper(t *testing.T) {
 		},
 	}
 	scraper := &testScraper{
-		s: func() (*StatMessage, error) {
-			return stat, nil
+		s: func() ([]*StatMessage, error) {
+			return []*StatMessage{stat}, nil
 		},
 	}
 	factory := scraperFactory(scraper, nil)
@@ -196,9 +196,19 @@ func TestMetricCollectorRecord(t *testing.T) {
 		AverageProxiedConcurrentRequests: 10, // this should be subtracted from the above.
 		RequestCount:                     want + 20,
 		ProxiedRequestCount:              20, // this should be subtracted from the above.
+		AverageCPUUsage:                  want,
+		MemoryUsage:                      want,
+		CustomMetricValue:                want,
+		ConcurrencyLimit:                 want,
+		AverageUpgradedConnections:       want,
+	}
+	// Stats without a concurrency limit, like the activator's, don't count towards it.
+	unlimitedStat := Stat{
+		Time:    &now,
+		PodName: "activator",
 	}
 	scraper := &testScraper{
-		s: func() (*StatMessage, error) {
+		s: func() ([]*StatMessage, error) {
 			return nil, nil
 		},
 	}
@@ -214,17 +224,36 @@ func TestMetricCollectorRecord(t *testing.T) {
 	if _, _, err := coll.StableAndPanicRPS(metricKey, now); err == nil {
 		t.Error("StableAndPanicRPS() = nil, wanted an error")
 	}
+	if _, err := coll.StableConcurrencyLimit(metricKey, now); err != ErrNoData {
+		t.Errorf("StableConcurrencyLimit() = %v, wanted %v", err, ErrNoData)
+	}
 
 	// Add two stats. The second record operation will remove the first outdated one.
 	// After this the concurrencies are calculated correctly.
 	coll.Record(metricKey, outdatedStat)
 	coll.Record(metricKey, stat)
+	coll.Record(metricKey, unlimitedStat)
 	if stable, panic, err := coll.StableAndPanicConcurrency(metricKey, now); stable != panic || stable != want || err != nil {
 		t.Errorf("StableAndPanicConcurrency() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
 	}
 	if stable, panic, err := coll.StableAndPanicRPS(metricKey, now); stable != panic || stable != want || err != nil {
 		t.Errorf("StableAndPanicRPS() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
 	}
+	if stable, panic, err := coll.StableAndPanicCPU(metricKey, now); stable != panic || stable != want || err != nil {
+		t.Errorf("StableAndPanicCPU() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
+	}
+	if stable, panic, err := coll.StableAndPanicMemory(metricKey, now); stable != panic || stable != want || err != nil {
+		t.Errorf("StableAndPanicMemory() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
+	}
+	if stable, panic, err := coll.StableAndPanicCustom(metricKey, now); stable != panic || stable != want || err != nil {
+		t.Errorf("StableAndPanicCustom() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
+	}
+	if stable, panic, err := coll.StableAndPanicConnections(metricKey, now); stable != panic || stable != want || err != nil {
+		t.Errorf("StableAndPanicConnections() = %v, %v, %v; want %v, %v, nil", stable, panic, err, want, want)
+	}
+	if stable, err := coll.StableConcurrencyLimit(metricKey, now); stable != want || err != nil {
+		t.Errorf("StableConcurrencyLimit() = %v, %v; want %v, nil", stable, err, want)
+	}
 }
 
 func scraperFactory(scraper StatsScraper, err error) StatsScraperFactory {
@@ -234,10 +263,10 @@ func scraperFactory(scraper StatsScraper, err error) StatsScraperFactory {
 }
 
 type testScraper struct {
-	s   func() (*StatMessage, error)
+	s   func() ([]*StatMessage, error)
 	url string
 }
 
-func (s *testScraper) Scrape() (*StatMessage, error) {
+func (s *testScraper) Scrape() ([]*StatMessage, error) {
 	return s.s()
 }
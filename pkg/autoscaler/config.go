@@ -39,6 +39,12 @@ const (
 type Config struct {
 	// Feature flags.
 	EnableScaleToZero bool
+	// EnablePodScraping makes the autoscaler scrape the pods directly
+	// rather than sampling them through the K8S service.
+	EnablePodScraping bool
+	// ExactScrapingMaxPods is the number of pods up to which every pod is
+	// scraped when EnablePodScraping is set. Above it a subset is scraped.
+	ExactScrapingMaxPods int
 
 	// Target concurrency knobs for different container concurrency configurations.
 	ContainerConcurrencyTargetFraction float64
@@ -51,19 +57,40 @@ type Config struct {
 	TargetUtilization float64
 	// RPSTargetDefault is the default target value for requests per second.
 	RPSTargetDefault float64
+	// CPUTargetDefault is the default target value for the CPU usage
+	// per pod in millicores, used by the KPA.
+	CPUTargetDefault float64
+	// MemoryTargetDefault is the default target value for the memory
+	// usage per pod in mebibytes, used by the KPA.
+	MemoryTargetDefault float64
+	// ConnectionsTargetDefault is the default target value for the number
+	// of upgraded connections per pod, used by the KPA.
+	ConnectionsTargetDefault float64
 	// NB: most of our computations are in floats, so this is float to avoid casting.
 	TargetBurstCapacity float64
 
 	// General autoscaler algorithm configuration.
 	MaxScaleUpRate           float64
+	MaxScaleDownRate         float64
 	StableWindow             time.Duration
 	PanicWindowPercentage    float64
 	PanicThresholdPercentage float64
 	// Deprecated in favor of PanicWindowPercentage.
 	PanicWindow  time.Duration
 	TickInterval time.Duration
+	// ScaleDownStabilizationWindow is the period over which the highest
+	// recommendation is used when scaling down.
+	ScaleDownStabilizationWindow time.Duration
 
 	ScaleToZeroGracePeriod time.Duration
+
+	// MetricSnapshotStore is where the metric history and the panic state
+	// are checkpointed to survive restarts: none, configmap or file.
+	MetricSnapshotStore string
+	// MetricSnapshotPath is the file the file store writes to.
+	MetricSnapshotPath string
+	// MetricSnapshotInterval is the time between checkpoints.
+	MetricSnapshotInterval time.Duration
 }
 
 // NewConfigFromMap creates a Config from the supplied map
@@ -81,6 +108,10 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		key:          "enable-scale-to-zero",
 		field:        &lc.EnableScaleToZero,
 		defaultValue: true,
+	}, {
+		key:          "enable-pod-scraping",
+		field:        &lc.EnablePodScraping,
+		defaultValue: false,
 	}} {
 		if raw, ok := data[b.key]; !ok {
 			*b.field = b.defaultValue
@@ -89,6 +120,25 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		}
 	}
 
+	// Process int fields
+	for _, i := range []struct {
+		key          string
+		field        *int
+		defaultValue int
+	}{{
+		key:          "exact-scraping-max-pods",
+		field:        &lc.ExactScrapingMaxPods,
+		defaultValue: 10,
+	}} {
+		if raw, ok := data[i.key]; !ok {
+			*i.field = i.defaultValue
+		} else if val, err := strconv.Atoi(raw); err != nil {
+			return nil, err
+		} else {
+			*i.field = val
+		}
+	}
+
 	// Process Float64 fields
 	for _, f64 := range []struct {
 		key   string
@@ -99,6 +149,10 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		key:          "max-scale-up-rate",
 		field:        &lc.MaxScaleUpRate,
 		defaultValue: 1000.0,
+	}, {
+		key:          "max-scale-down-rate",
+		field:        &lc.MaxScaleDownRate,
+		defaultValue: 2.0,
 	}, {
 		key:   "container-concurrency-target-percentage",
 		field: &lc.ContainerConcurrencyTargetFraction,
@@ -112,6 +166,18 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		key:          "requests-per-second-target-default",
 		field:        &lc.RPSTargetDefault,
 		defaultValue: 200.0,
+	}, {
+		key:          "cpu-target-default",
+		field:        &lc.CPUTargetDefault,
+		defaultValue: 1000.0,
+	}, {
+		key:          "memory-target-default",
+		field:        &lc.MemoryTargetDefault,
+		defaultValue: 1024.0,
+	}, {
+		key:          "connections-target-default",
+		field:        &lc.ConnectionsTargetDefault,
+		defaultValue: 1000.0,
 	}, {
 		key:          "target-burst-capacity",
 		field:        &lc.TargetBurstCapacity,
@@ -142,6 +208,27 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		lc.ContainerConcurrencyTargetFraction /= 100.0
 	}
 
+	// Process string fields
+	for _, str := range []struct {
+		key          string
+		field        *string
+		defaultValue string
+	}{{
+		key:          "metric-snapshot-store",
+		field:        &lc.MetricSnapshotStore,
+		defaultValue: SnapshotStoreNone,
+	}, {
+		key:          "metric-snapshot-path",
+		field:        &lc.MetricSnapshotPath,
+		defaultValue: "/var/lib/autoscaler/snapshot.json",
+	}} {
+		if raw, ok := data[str.key]; !ok {
+			*str.field = str.defaultValue
+		} else {
+			*str.field = raw
+		}
+	}
+
 	// Process Duration fields
 	for _, dur := range []struct {
 		key          string
@@ -163,6 +250,15 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		key:          "tick-interval",
 		field:        &lc.TickInterval,
 		defaultValue: 2 * time.Second,
+	}, {
+		key:   "scale-down-stabilization-window",
+		field: &lc.ScaleDownStabilizationWindow,
+		// Disabled by default.
+		defaultValue: 0,
+	}, {
+		key:          "metric-snapshot-interval",
+		field:        &lc.MetricSnapshotInterval,
+		defaultValue: 10 * time.Second,
 	}} {
 		if raw, ok := data[dur.key]; !ok {
 			*dur.field = dur.defaultValue
@@ -196,10 +292,49 @@ func validate(lc *Config) (*Config, error) {
 		return nil, fmt.Errorf("requests-per-second-target-default must be at least %v, got %v", autoscaling.TargetMin, lc.RPSTargetDefault)
 	}
 
+	if lc.CPUTargetDefault < autoscaling.TargetMin {
+		return nil, fmt.Errorf("cpu-target-default must be at least %v, got %v", autoscaling.TargetMin, lc.CPUTargetDefault)
+	}
+
+	if lc.MemoryTargetDefault < autoscaling.TargetMin {
+		return nil, fmt.Errorf("memory-target-default must be at least %v, got %v", autoscaling.TargetMin, lc.MemoryTargetDefault)
+	}
+
+	if lc.ConnectionsTargetDefault < autoscaling.TargetMin {
+		return nil, fmt.Errorf("connections-target-default must be at least %v, got %v", autoscaling.TargetMin, lc.ConnectionsTargetDefault)
+	}
+
+	switch lc.MetricSnapshotStore {
+	case SnapshotStoreNone, SnapshotStoreConfigMap:
+	case SnapshotStoreFile:
+		if lc.MetricSnapshotPath == "" {
+			return nil, fmt.Errorf("metric-snapshot-path must not be empty for the %s store", SnapshotStoreFile)
+		}
+	default:
+		return nil, fmt.Errorf("metric-snapshot-store = %q, must be one of %s, %s or %s",
+			lc.MetricSnapshotStore, SnapshotStoreNone, SnapshotStoreConfigMap, SnapshotStoreFile)
+	}
+	if lc.MetricSnapshotInterval < time.Second {
+		return nil, fmt.Errorf("metric-snapshot-interval must be at least 1s, got %v", lc.MetricSnapshotInterval)
+	}
+
+	if lc.ExactScrapingMaxPods < 0 {
+		return nil, fmt.Errorf("exact-scraping-max-pods must be non-negative, got %d", lc.ExactScrapingMaxPods)
+	}
+
 	if lc.MaxScaleUpRate <= 1.0 {
 		return nil, fmt.Errorf("max-scale-up-rate = %v, must be greater than 1.0", lc.MaxScaleUpRate)
 	}
 
+	if lc.MaxScaleDownRate <= 1.0 {
+		return nil, fmt.Errorf("max-scale-down-rate = %v, must be greater than 1.0", lc.MaxScaleDownRate)
+	}
+
+	if lc.ScaleDownStabilizationWindow < 0 || lc.ScaleDownStabilizationWindow > autoscaling.WindowMax {
+		return nil, fmt.Errorf("scale-down-stabilization-window = %v, must be in [0s, %v] interval",
+			lc.ScaleDownStabilizationWindow, autoscaling.WindowMax)
+	}
+
 	// We can't permit stable window be less than our aggregation window for correctness.
 	if lc.StableWindow < autoscaling.WindowMin {
 		return nil, fmt.Errorf("stable-window = %v, must be at least %v", lc.StableWindow, BucketSize)
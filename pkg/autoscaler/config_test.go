@@ -28,18 +28,26 @@ import (
 
 var defaultConfig = Config{
 	EnableScaleToZero:                  true,
+	ExactScrapingMaxPods:               10,
 	ContainerConcurrencyTargetFraction: 0.7,
 	ContainerConcurrencyTargetDefault:  100.0,
 	RPSTargetDefault:                   200.0,
+	CPUTargetDefault:                   1000.0,
+	MemoryTargetDefault:                1024.0,
+	ConnectionsTargetDefault:           1000.0,
 	TargetUtilization:                  0.7,
 	TargetBurstCapacity:                200,
 	MaxScaleUpRate:                     1000.0,
+	MaxScaleDownRate:                   2.0,
 	StableWindow:                       time.Minute,
 	PanicWindow:                        6 * time.Second,
 	ScaleToZeroGracePeriod:             30 * time.Second,
 	TickInterval:                       2 * time.Second,
 	PanicWindowPercentage:              10.0,
 	PanicThresholdPercentage:           200.0,
+	MetricSnapshotStore:                SnapshotStoreNone,
+	MetricSnapshotPath:                 "/var/lib/autoscaler/snapshot.json",
+	MetricSnapshotInterval:             10 * time.Second,
 }
 
 func TestNewConfig(t *testing.T) {
@@ -96,10 +104,20 @@ func TestNewConfig(t *testing.T) {
 		name: "with toggles on",
 		input: map[string]string{
 			"enable-scale-to-zero":                    "true",
+			"enable-pod-scraping":                     "true",
+			"exact-scraping-max-pods":                 "5",
+			"metric-snapshot-store":                   "file",
+			"metric-snapshot-path":                    "/tmp/snapshot.json",
+			"metric-snapshot-interval":                "1m",
 			"max-scale-up-rate":                       "1.01",
+			"max-scale-down-rate":                     "3.0",
+			"scale-down-stabilization-window":         "2m",
 			"container-concurrency-target-percentage": "0.71",
 			"container-concurrency-target-default":    "10.5",
 			"requests-per-second-target-default":      "10.11",
+			"cpu-target-default":                      "500",
+			"memory-target-default":                   "256",
+			"connections-target-default":              "50",
 			"target-burst-capacity":                   "12345",
 			"stable-window":                           "5m",
 			"panic-window":                            "11s",
@@ -108,11 +126,21 @@ func TestNewConfig(t *testing.T) {
 			"panic-threshold-percentage":              "200",
 		},
 		want: func(c Config) *Config {
+			c.EnablePodScraping = true
+			c.ExactScrapingMaxPods = 5
+			c.MetricSnapshotStore = SnapshotStoreFile
+			c.MetricSnapshotPath = "/tmp/snapshot.json"
+			c.MetricSnapshotInterval = time.Minute
 			c.TargetBurstCapacity = 12345
 			c.ContainerConcurrencyTargetDefault = 10.5
 			c.ContainerConcurrencyTargetFraction = 0.71
 			c.RPSTargetDefault = 10.11
+			c.CPUTargetDefault = 500
+			c.MemoryTargetDefault = 256
+			c.ConnectionsTargetDefault = 50
 			c.MaxScaleUpRate = 1.01
+			c.MaxScaleDownRate = 3
+			c.ScaleDownStabilizationWindow = 2 * time.Minute
 			c.StableWindow = 5 * time.Minute
 			c.PanicWindow = 11 * time.Second
 			return &c
@@ -149,6 +177,37 @@ func TestNewConfig(t *testing.T) {
 			"max-scale-up-rate": "not a float",
 		},
 		wantErr: true,
+	}, {
+		name: "malformed int",
+		input: map[string]string{
+			"exact-scraping-max-pods": "not an int",
+		},
+		wantErr: true,
+	}, {
+		name: "negative exact scraping max pods",
+		input: map[string]string{
+			"exact-scraping-max-pods": "-1",
+		},
+		wantErr: true,
+	}, {
+		name: "unknown metric snapshot store",
+		input: map[string]string{
+			"metric-snapshot-store": "etcd",
+		},
+		wantErr: true,
+	}, {
+		name: "file metric snapshot store without path",
+		input: map[string]string{
+			"metric-snapshot-store": "file",
+			"metric-snapshot-path":  "",
+		},
+		wantErr: true,
+	}, {
+		name: "metric snapshot interval too short",
+		input: map[string]string{
+			"metric-snapshot-interval": "100ms",
+		},
+		wantErr: true,
 	}, {
 		name: "malformed duration",
 		input: map[string]string{
@@ -179,6 +238,24 @@ func TestNewConfig(t *testing.T) {
 			"requests-per-second-target-default": "-5.25",
 		},
 		wantErr: true,
+	}, {
+		name: "invalid CPU target, too small",
+		input: map[string]string{
+			"cpu-target-default": "0.5",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid memory target, too small",
+		input: map[string]string{
+			"memory-target-default": "0",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid connections target, too small",
+		input: map[string]string{
+			"connections-target-default": "0",
+		},
+		wantErr: true,
 	}, {
 		name: "target capacity less than 1",
 		input: map[string]string{
@@ -192,6 +269,18 @@ func TestNewConfig(t *testing.T) {
 			"max-scale-up-rate": "1",
 		},
 		wantErr: true,
+	}, {
+		name: "max scale down rate 1.0",
+		input: map[string]string{
+			"max-scale-down-rate": "1",
+		},
+		wantErr: true,
+	}, {
+		name: "negative scale down stabilization window",
+		input: map[string]string{
+			"scale-down-stabilization-window": "-1s",
+		},
+		wantErr: true,
 	}, {
 		name: "stable window too small",
 		input: map[string]string{
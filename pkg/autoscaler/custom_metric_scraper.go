@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"knative.dev/pkg/system"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/resources"
+)
+
+// customMetricScraperPodName is the name used in the stats of the custom metric.
+const customMetricScraperPodName = "custom-metric-scraper"
+
+// CustomMetricScraper scrapes a metric the user container exposes on its own
+// Prometheus endpoint directly from the pods of the Revision. The queue-proxy
+// stats are still scraped by the wrapped StatsScraper, so that the concurrency
+// and RPS stay available, e.g. to compute the excess burst capacity.
+// Counters only ever grow, so they are turned into their rate per second
+// since the previous scrape of the same pod.
+type CustomMetricScraper struct {
+	base       StatsScraper
+	lister     corev1listers.EndpointsLister
+	httpClient *http.Client
+	clock      system.Clock
+	namespace  string
+	service    string
+	name       string
+	port       int
+	path       string
+
+	mu sync.Mutex
+	// counters holds the last counter value scraped from each URL.
+	counters map[string]counterSample
+}
+
+// counterSample is a counter value scraped at a given time.
+type counterSample struct {
+	value float64
+	time  time.Time
+}
+
+// NewCustomMetricScraper creates a new StatsScraper for the custom metric the given
+// Metric is configured with, reporting it alongside the stats scraped by base.
+func NewCustomMetricScraper(metric *av1alpha1.Metric, lister corev1listers.EndpointsLister, base StatsScraper) (*CustomMetricScraper, error) {
+	return newCustomMetricScraperWithClient(metric, lister, base, cacheDisabledClient)
+}
+
+func newCustomMetricScraperWithClient(
+	metric *av1alpha1.Metric,
+	lister corev1listers.EndpointsLister,
+	base StatsScraper,
+	httpClient *http.Client) (*CustomMetricScraper, error) {
+	if metric == nil {
+		return nil, errors.New("metric must not be nil")
+	}
+	if lister == nil {
+		return nil, errors.New("lister must not be nil")
+	}
+	if base == nil {
+		return nil, errors.New("base scraper must not be nil")
+	}
+	name := metric.Annotations[autoscaling.CustomMetricNameAnnotationKey]
+	if name == "" {
+		return nil, fmt.Errorf("no custom metric name found for Metric %s", metric.Name)
+	}
+	port, err := strconv.Atoi(metric.Annotations[autoscaling.CustomMetricPortAnnotationKey])
+	if err != nil {
+		return nil, fmt.Errorf("no valid custom metric port found for Metric %s", metric.Name)
+	}
+	path := metric.Annotations[autoscaling.CustomMetricPathAnnotationKey]
+	if path == "" {
+		path = autoscaling.DefaultCustomMetricPath
+	}
+
+	return &CustomMetricScraper{
+		base:       base,
+		lister:     lister,
+		httpClient: httpClient,
+		clock:      system.RealClock{},
+		namespace:  metric.Namespace,
+		service:    metric.Spec.ScrapeTarget,
+		name:       name,
+		port:       port,
+		path:       path,
+		counters:   make(map[string]counterSample),
+	}, nil
+}
+
+// Scrape scrapes the stats using the wrapped scraper and then the custom
+// metric from a sample of the pods. The custom metric is reported in a stat
+// of its own, since the wrapped scraper might report a stat per pod.
+// Sampled pods that fail to serve the custom metric are skipped. The stats
+// of the wrapped scraper are returned even if the custom metric fails, along
+// with an error describing the failure, so that the concurrency is still
+// available to scale to zero.
+func (s *CustomMetricScraper) Scrape() ([]*StatMessage, error) {
+	messages, err := s.base.Scrape()
+	if err != nil || len(messages) == 0 {
+		return messages, err
+	}
+
+	endpoints, err := s.lister.Endpoints(s.namespace).Get(s.service)
+	if err != nil {
+		return messages, errors.Wrap(err, "failed to get endpoints")
+	}
+	ips := resources.ReadyAddresses(endpoints)
+	if len(ips) == 0 {
+		return messages, nil
+	}
+
+	sampleSize := populationMeanSampleSize(len(ips))
+	values := make([]float64, sampleSize)
+	ok := make([]bool, sampleSize)
+	errs := make([]error, sampleSize)
+	var wg sync.WaitGroup
+	wg.Add(sampleSize)
+	for i, j := range rand.Perm(len(ips))[:sampleSize] {
+		go func(i int, url string) {
+			defer wg.Done()
+			values[i], ok[i], errs[i] = s.scrapeURL(url)
+		}(i, s.urlFromIP(ips[j]))
+	}
+	wg.Wait()
+	s.forgetCounters(ips)
+
+	var sum float64
+	var n int
+	for i, v := range values {
+		if ok[i] {
+			sum += v
+			n++
+		}
+	}
+	err = scrapeErrors(errs)
+	if err != nil {
+		err = errors.Wrapf(err, "unsuccessful scrape of %s, sampleSize=%d", s.name, sampleSize)
+	}
+	if n == 0 {
+		// None of the sampled pods was scraped before, so a counter has no
+		// rate yet, or every sampled pod failed.
+		return messages, err
+	}
+	now := time.Now()
+	return append(messages, &StatMessage{
+		Key: messages[0].Key,
+		Stat: Stat{
+			Time:    &now,
+			PodName: customMetricScraperPodName,
+			// Like the ServiceScraper, presume that the sampled pods stand for the others.
+			CustomMetricValue: sum / float64(n) * float64(len(ips)),
+		},
+	}), err
+}
+
+// scrapeErrors returns the first of the given errors along with the number
+// of pods that failed, or nil if none failed.
+func scrapeErrors(errs []error) error {
+	var first error
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return errors.Wrapf(first, "%d of %d pods failed", failed, len(errs))
+}
+
+func (s *CustomMetricScraper) urlFromIP(ip string) string {
+	return fmt.Sprintf("http://%s:%d%s", ip, s.port, s.path)
+}
+
+// scrapeURL returns the sum of all the series of the custom metric served at
+// the given URL. For counters, it returns the rate of the sum instead, which
+// is only available if the URL was scraped before.
+func (s *CustomMetricScraper) scrapeURL(url string) (float64, bool, error) {
+	now := s.clock.Now()
+	resp, err := s.httpClient.Get(url)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return 0, false, fmt.Errorf("GET request for URL %q returned HTTP status %v", url, resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	metricFamilies, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return 0, false, fmt.Errorf("reading text format failed: %v", err)
+	}
+	family, ok := metricFamilies[s.name]
+	if !ok || len(family.Metric) == 0 {
+		return 0, false, fmt.Errorf("could not find value for %s in response", s.name)
+	}
+	var sum float64
+	for _, m := range family.Metric {
+		v, ok := metricValue(m)
+		if !ok {
+			return 0, false, fmt.Errorf("%s is of unsupported type %v", s.name, family.GetType())
+		}
+		sum += v
+	}
+	if family.GetType() == dto.MetricType_COUNTER {
+		rate, ok := s.counterRate(url, sum, now)
+		return rate, ok, nil
+	}
+	return sum, true, nil
+}
+
+// counterRate records the counter value scraped from the given URL and
+// returns its rate per second since the previous scrape, if any.
+func (s *CustomMetricScraper) counterRate(url string, value float64, now time.Time) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.counters[url]
+	s.counters[url] = counterSample{value: value, time: now}
+	if !ok || !now.After(prev.time) {
+		return 0, false
+	}
+	delta := value - prev.value
+	if delta < 0 {
+		// The counter was reset, e.g. because the container restarted.
+		delta = value
+	}
+	return delta / now.Sub(prev.time).Seconds(), true
+}
+
+// forgetCounters drops the counter values of the pods that are no longer ready.
+func (s *CustomMetricScraper) forgetCounters(ips []string) {
+	ready := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		ready[s.urlFromIP(ip)] = struct{}{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for url := range s.counters {
+		if _, ok := ready[url]; !ok {
+			delete(s.counters, url)
+		}
+	}
+}
+
+// metricValue returns the value of a gauge, counter or untyped metric.
+func metricValue(m *dto.Metric) (float64, bool) {
+	switch {
+	case m.Gauge != nil:
+		return m.Gauge.GetValue(), true
+	case m.Counter != nil:
+		return m.Counter.GetValue(), true
+	case m.Untyped != nil:
+		return m.Untyped.GetValue(), true
+	}
+	return 0, false
+}
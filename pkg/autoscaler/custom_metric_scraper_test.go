@@ -0,0 +1,323 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+const testBacklogContext = `# HELP backlog_depth Number of messages waiting to be processed
+# TYPE backlog_depth gauge
+backlog_depth{queue="a"} 3
+backlog_depth{queue="b"} 2
+`
+
+const testRequestsTemplate = `# HELP requests_total Number of requests processed
+# TYPE requests_total counter
+requests_total{code="200"} %d
+requests_total{code="500"} 10
+`
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func customMetric() *av1alpha1.Metric {
+	metric := testMetric()
+	metric.Annotations = map[string]string{
+		autoscaling.MetricAnnotationKey:           autoscaling.Custom,
+		autoscaling.CustomMetricNameAnnotationKey: "backlog_depth",
+		autoscaling.CustomMetricPortAnnotationKey: "9095",
+	}
+	return metric
+}
+
+func TestNewCustomMetricScraperErrorCases(t *testing.T) {
+	lister := kubeInformer.Core().V1().Endpoints().Lister()
+	base := &testScraper{}
+	noName := customMetric()
+	delete(noName.Annotations, autoscaling.CustomMetricNameAnnotationKey)
+	badPort := customMetric()
+	badPort.Annotations[autoscaling.CustomMetricPortAnnotationKey] = "http"
+
+	testCases := []struct {
+		name        string
+		metric      *av1alpha1.Metric
+		base        StatsScraper
+		expectedErr string
+	}{{
+		name:        "Empty metric",
+		base:        base,
+		expectedErr: "metric must not be nil",
+	}, {
+		name:        "Empty base scraper",
+		metric:      customMetric(),
+		expectedErr: "base scraper must not be nil",
+	}, {
+		name:        "Missing metric name",
+		metric:      noName,
+		base:        base,
+		expectedErr: "no custom metric name found for Metric test-revision",
+	}, {
+		name:        "Bad port",
+		metric:      badPort,
+		base:        base,
+		expectedErr: "no valid custom metric port found for Metric test-revision",
+	}}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewCustomMetricScraper(test.metric, lister, test.base); err == nil {
+				t.Error("Expected error from NewCustomMetricScraper, got nil")
+			} else if got, want := err.Error(), test.expectedErr; got != want {
+				t.Errorf("Got error message: %v. Want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCustomMetricScraperScrape(t *testing.T) {
+	metric := customMetric()
+	metric.Annotations[autoscaling.CustomMetricPathAnnotationKey] = "/stats"
+	base := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return []*StatMessage{{Key: testPAKey, Stat: Stat{AverageConcurrentRequests: 1}}}, nil
+		},
+	}
+	var urls []string
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			urls = append(urls, r.URL.String())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(testBacklogContext)),
+			}, nil
+		}),
+	}
+	scraper, err := newCustomMetricScraperWithClient(metric, kubeInformer.Core().V1().Endpoints().Lister(), base, client)
+	if err != nil {
+		t.Fatalf("newCustomMetricScraperWithClient() = %v", err)
+	}
+
+	endpoints(1, metric.Spec.ScrapeTarget)
+	got, err := scraper.Scrape()
+	if err != nil {
+		t.Fatalf("Scrape() = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len(Scrape()) = %d, want 2", len(got))
+	}
+	if got[0].Stat.AverageConcurrentRequests != 1 {
+		t.Errorf("AverageConcurrentRequests = %v, want 1", got[0].Stat.AverageConcurrentRequests)
+	}
+	// The series of the metric are summed up and reported in a stat of their own.
+	if got[1].Key != testPAKey || got[1].Stat.PodName != customMetricScraperPodName {
+		t.Errorf("Custom metric stat = %v, want key %v and pod %s", got[1], testPAKey, customMetricScraperPodName)
+	}
+	if got[1].Stat.CustomMetricValue != 5 {
+		t.Errorf("CustomMetricValue = %v, want 5", got[1].Stat.CustomMetricValue)
+	}
+	if want := "http://127.0.0.1:9095/stats"; len(urls) != 1 || urls[0] != want {
+		t.Errorf("Scraped URLs = %v, want [%s]", urls, want)
+	}
+}
+
+func TestCustomMetricScraperCounterRate(t *testing.T) {
+	metric := customMetric()
+	metric.Annotations[autoscaling.CustomMetricNameAnnotationKey] = "requests_total"
+	base := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return []*StatMessage{{Key: testPAKey}}, nil
+		},
+	}
+	var total int
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf(testRequestsTemplate, total))),
+			}, nil
+		}),
+	}
+	scraper, err := newCustomMetricScraperWithClient(metric, kubeInformer.Core().V1().Endpoints().Lister(), base, client)
+	if err != nil {
+		t.Fatalf("newCustomMetricScraperWithClient() = %v", err)
+	}
+	clock := &manualClock{now: time.Now()}
+	scraper.clock = clock
+	endpoints(1, metric.Spec.ScrapeTarget)
+
+	// A counter has no rate before its second scrape.
+	total = 1000
+	got, err := scraper.Scrape()
+	if err != nil {
+		t.Fatalf("Scrape() = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("len(Scrape()) = %d, want 1", len(got))
+	}
+
+	for _, step := range []struct {
+		name  string
+		total int
+		want  float64
+	}{{
+		name:  "increase",
+		total: 1050,
+		want:  5,
+	}, {
+		name:  "no increase",
+		total: 1050,
+		want:  0,
+	}, {
+		name:  "reset",
+		total: 90,
+		want:  10,
+	}} {
+		clock.now = clock.now.Add(10 * time.Second)
+		total = step.total
+		got, err := scraper.Scrape()
+		if err != nil {
+			t.Fatalf("%s: Scrape() = %v", step.name, err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s: len(Scrape()) = %d, want 2", step.name, len(got))
+		}
+		if got[1].Stat.CustomMetricValue != step.want {
+			t.Errorf("%s: CustomMetricValue = %v, want %v", step.name, got[1].Stat.CustomMetricValue, step.want)
+		}
+	}
+
+	// The counters of the pods that are gone are forgotten.
+	scraper.forgetCounters(nil)
+	if len(scraper.counters) != 0 {
+		t.Errorf("len(counters) = %d, want 0", len(scraper.counters))
+	}
+}
+
+func TestCustomMetricScraperErrors(t *testing.T) {
+	base := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return []*StatMessage{{Key: testPAKey}}, nil
+		},
+	}
+	testCases := []struct {
+		name        string
+		status      int
+		body        string
+		expectedErr string
+	}{{
+		name:        "Non 200 return code",
+		status:      http.StatusNotFound,
+		expectedErr: `returned HTTP status 404`,
+	}, {
+		name:        "Missing metric",
+		status:      http.StatusOK,
+		body:        testAverageConcurrencyContext,
+		expectedErr: "could not find value for backlog_depth in response",
+	}}
+
+	metric := customMetric()
+	endpoints(1, metric.Spec.ScrapeTarget)
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			client := &http.Client{
+				Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: test.status,
+						Body:       ioutil.NopCloser(bytes.NewBufferString(test.body)),
+					}, nil
+				}),
+			}
+			scraper, err := newCustomMetricScraperWithClient(metric, kubeInformer.Core().V1().Endpoints().Lister(), base, client)
+			if err != nil {
+				t.Fatalf("newCustomMetricScraperWithClient() = %v", err)
+			}
+			got, err := scraper.Scrape()
+			if err == nil {
+				t.Error("Expected error from Scrape, got nil")
+			} else if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Got error message: %v. Want it to contain: %v", err, test.expectedErr)
+			}
+			// The stats of the wrapped scraper survive the failure.
+			if len(got) != 1 || got[0].Key != testPAKey {
+				t.Errorf("Scrape() = %v, want only the stat of the wrapped scraper", got)
+			}
+		})
+	}
+}
+
+func TestCustomMetricScraperSkipsFailingPods(t *testing.T) {
+	base := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return []*StatMessage{{Key: testPAKey, Stat: Stat{AverageConcurrentRequests: 1}}}, nil
+		},
+	}
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Hostname() == "127.0.0.1" {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(testBacklogContext)),
+			}, nil
+		}),
+	}
+	metric := customMetric()
+	scraper, err := newCustomMetricScraperWithClient(metric, kubeInformer.Core().V1().Endpoints().Lister(), base, client)
+	if err != nil {
+		t.Fatalf("newCustomMetricScraperWithClient() = %v", err)
+	}
+
+	// Two pods are both sampled, and one of them fails.
+	endpoints(2, metric.Spec.ScrapeTarget)
+	got, err := scraper.Scrape()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 pods failed") {
+		t.Errorf("Scrape() error = %v, want it to report the failing pod", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len(Scrape()) = %d, want 2", len(got))
+	}
+	if got[0].Stat.AverageConcurrentRequests != 1 {
+		t.Errorf("AverageConcurrentRequests = %v, want 1", got[0].Stat.AverageConcurrentRequests)
+	}
+	// The pod that was scraped stands for both.
+	if got[1].Stat.CustomMetricValue != 10 {
+		t.Errorf("CustomMetricValue = %v, want 10", got[1].Stat.CustomMetricValue)
+	}
+}
@@ -28,6 +28,15 @@ type MetricClient struct {
 	PanicConcurrency  float64
 	StableRPS         float64
 	PanicRPS          float64
+	StableCPU         float64
+	PanicCPU          float64
+	StableMemory      float64
+	PanicMemory       float64
+	StableCustom      float64
+	PanicCustom       float64
+	StableLimit       float64
+	StableConnections float64
+	PanicConnections  float64
 	ErrF              func(key types.NamespacedName, now time.Time) error
 }
 
@@ -51,6 +60,56 @@ func (t *MetricClient) StableAndPanicRPS(key types.NamespacedName, now time.Time
 	return t.StableRPS, t.PanicRPS, err
 }
 
+// StableAndPanicCPU returns stable/panic CPU usage stored in the object
+// and the result of Errf as the error.
+func (t *MetricClient) StableAndPanicCPU(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	var err error
+	if t.ErrF != nil {
+		err = t.ErrF(key, now)
+	}
+	return t.StableCPU, t.PanicCPU, err
+}
+
+// StableAndPanicMemory returns stable/panic memory usage stored in the object
+// and the result of Errf as the error.
+func (t *MetricClient) StableAndPanicMemory(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	var err error
+	if t.ErrF != nil {
+		err = t.ErrF(key, now)
+	}
+	return t.StableMemory, t.PanicMemory, err
+}
+
+// StableAndPanicCustom returns stable/panic custom metric values stored in the object
+// and the result of Errf as the error.
+func (t *MetricClient) StableAndPanicCustom(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	var err error
+	if t.ErrF != nil {
+		err = t.ErrF(key, now)
+	}
+	return t.StableCustom, t.PanicCustom, err
+}
+
+// StableAndPanicConnections returns stable/panic upgraded connections stored in the object
+// and the result of Errf as the error.
+func (t *MetricClient) StableAndPanicConnections(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	var err error
+	if t.ErrF != nil {
+		err = t.ErrF(key, now)
+	}
+	return t.StableConnections, t.PanicConnections, err
+}
+
+// StableConcurrencyLimit returns the stable concurrency limit stored in the object
+// and the result of Errf as the error.
+func (t *MetricClient) StableConcurrencyLimit(key types.NamespacedName, now time.Time) (float64, error) {
+	var err error
+	if t.ErrF != nil {
+		err = t.ErrF(key, now)
+	}
+	return t.StableLimit, err
+}
+
 // StaticMetricClient returns stable/panic concurrency and RPS with static value, i.e. 10.
 var StaticMetricClient = MetricClient{
 	StableConcurrency: 10.0,
@@ -84,6 +84,20 @@ func extractData(body io.Reader) (*Stat, error) {
 			}
 		}
 	}
+
+	// The resource usage and the upgraded connections are optional, since
+	// queue-proxies of older releases don't report them, and so is the
+	// concurrency limit, which only adaptively limiting queue-proxies report.
+	for m, pv := range map[string]*float64{
+		"queue_cpu_usage_millicores":         &stat.AverageCPUUsage,
+		"queue_memory_usage_bytes":           &stat.MemoryUsage,
+		"queue_concurrency_limit":            &stat.ConcurrencyLimit,
+		"queue_average_upgraded_connections": &stat.AverageUpgradedConnections,
+	} {
+		if pm := prometheusMetric(metricFamilies, m); pm != nil {
+			*pv = *pm.Gauge.Value
+		}
+	}
 	return &stat, nil
 }
 
@@ -44,6 +44,22 @@ queue_average_proxied_concurrent_requests{destination_namespace="test-namespace"
 	testProxiedQPSContext = `# HELP queue_proxied_operations_per_second Number of proxied requests received since last Stat
 # TYPE queue_proxied_operations_per_second gauge
 queue_proxied_operations_per_second{destination_namespace="test-namespace",destination_revision="test-revision",destination_pod="test-revision-1234"} 4
+`
+	testCPUUsageContext = `# HELP queue_cpu_usage_millicores Average CPU usage of this pod in millicores
+# TYPE queue_cpu_usage_millicores gauge
+queue_cpu_usage_millicores{destination_namespace="test-namespace",destination_revision="test-revision",destination_pod="test-revision-1234"} 250
+`
+	testMemoryUsageContext = `# HELP queue_memory_usage_bytes Memory working set of this pod in bytes
+# TYPE queue_memory_usage_bytes gauge
+queue_memory_usage_bytes{destination_namespace="test-namespace",destination_revision="test-revision",destination_pod="test-revision-1234"} 1048576
+`
+	testConcurrencyLimitContext = `# HELP queue_concurrency_limit Concurrency limit discovered for this pod, or 0 if it is not limited adaptively
+# TYPE queue_concurrency_limit gauge
+queue_concurrency_limit{destination_namespace="test-namespace",destination_revision="test-revision",destination_pod="test-revision-1234"} 12
+`
+	testUpgradedConnectionsContext = `# HELP queue_average_upgraded_connections Number of upgraded connections, e.g. WebSockets, currently open on this pod
+# TYPE queue_average_upgraded_connections gauge
+queue_average_upgraded_connections{destination_namespace="test-namespace",destination_revision="test-revision",destination_pod="test-revision-1234"} 7
 `
 	testFullContext = testAverageConcurrencyContext + testQPSContext + testAverageProxiedConcurrenyContext + testProxiedQPSContext
 )
@@ -99,6 +115,66 @@ func TestHTTPScrapeClient_Scrape_HappyCase(t *testing.T) {
 	if stat.PodName != "test-revision-1234" {
 		t.Errorf("stat.PodName = %s, want test-revision-1234", stat.PodName)
 	}
+	if stat.AverageCPUUsage != 0 || stat.MemoryUsage != 0 {
+		t.Errorf("stat resource usage = (%v, %v), want (0, 0)", stat.AverageCPUUsage, stat.MemoryUsage)
+	}
+	if stat.ConcurrencyLimit != 0 {
+		t.Errorf("stat.ConcurrencyLimit = %v, want 0", stat.ConcurrencyLimit)
+	}
+}
+
+func TestHTTPScrapeClient_Scrape_ConcurrencyLimit(t *testing.T) {
+	hClient := newTestHTTPClient(getHTTPResponse(http.StatusOK,
+		testFullContext+testConcurrencyLimitContext), nil)
+	sClient, err := newHTTPScrapeClient(hClient)
+	if err != nil {
+		t.Fatalf("newHTTPScrapeClient = %v, want no error", err)
+	}
+
+	stat, err := sClient.Scrape(testURL)
+	if err != nil {
+		t.Fatalf("scrapeViaURL = %v, want no error", err)
+	}
+	if stat.ConcurrencyLimit != 12 {
+		t.Errorf("stat.ConcurrencyLimit = %v, want 12", stat.ConcurrencyLimit)
+	}
+}
+
+func TestHTTPScrapeClient_Scrape_UpgradedConnections(t *testing.T) {
+	hClient := newTestHTTPClient(getHTTPResponse(http.StatusOK,
+		testFullContext+testUpgradedConnectionsContext), nil)
+	sClient, err := newHTTPScrapeClient(hClient)
+	if err != nil {
+		t.Fatalf("newHTTPScrapeClient = %v, want no error", err)
+	}
+
+	stat, err := sClient.Scrape(testURL)
+	if err != nil {
+		t.Fatalf("scrapeViaURL = %v, want no error", err)
+	}
+	if stat.AverageUpgradedConnections != 7 {
+		t.Errorf("stat.AverageUpgradedConnections = %v, want 7", stat.AverageUpgradedConnections)
+	}
+}
+
+func TestHTTPScrapeClient_Scrape_ResourceUsage(t *testing.T) {
+	hClient := newTestHTTPClient(getHTTPResponse(http.StatusOK,
+		testFullContext+testCPUUsageContext+testMemoryUsageContext), nil)
+	sClient, err := newHTTPScrapeClient(hClient)
+	if err != nil {
+		t.Fatalf("newHTTPScrapeClient = %v, want no error", err)
+	}
+
+	stat, err := sClient.Scrape(testURL)
+	if err != nil {
+		t.Fatalf("scrapeViaURL = %v, want no error", err)
+	}
+	if stat.AverageCPUUsage != 250 {
+		t.Errorf("stat.AverageCPUUsage = %v, want 250", stat.AverageCPUUsage)
+	}
+	if stat.MemoryUsage != 1<<20 {
+		t.Errorf("stat.MemoryUsage = %v, want %v", stat.MemoryUsage, 1<<20)
+	}
 }
 
 func TestHTTPScrapeClient_Scrape_ErrorCases(t *testing.T) {
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package introspection provides a read-only HTTP handler exposing the
+// state of the autoscaler's deciders, to debug their scaling decisions.
+package introspection
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/serving/pkg/autoscaler"
+)
+
+// Path is the path the handler is served at.
+const Path = "/debug/deciders"
+
+// DeciderInspector returns the state of the deciders in the given namespace
+// with the given name. An empty namespace or name matches all.
+type DeciderInspector interface {
+	Inspect(namespace, name string) []autoscaler.DeciderInspection
+}
+
+// BucketInspector returns the metric history collected for the given key.
+type BucketInspector interface {
+	Buckets(key types.NamespacedName) (autoscaler.CollectionSnapshot, bool)
+}
+
+// Decider is the state of a decider along with the metric history it
+// scales on.
+type Decider struct {
+	autoscaler.DeciderInspection
+	Buckets *autoscaler.CollectionSnapshot `json:"buckets,omitempty"`
+}
+
+// Response is the body of the handler's responses.
+type Response struct {
+	Deciders []Decider `json:"deciders"`
+}
+
+type handler struct {
+	deciders DeciderInspector
+	buckets  BucketInspector
+	logger   *zap.SugaredLogger
+}
+
+// NewHandler creates a handler returning the state of the deciders as JSON.
+// The namespace and revision query parameters filter the deciders returned.
+func NewHandler(deciders DeciderInspector, buckets BucketInspector, logger *zap.SugaredLogger) http.Handler {
+	return &handler{
+		deciders: deciders,
+		buckets:  buckets,
+		logger:   logger,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	inspections := h.deciders.Inspect(query.Get("namespace"), query.Get("revision"))
+	resp := Response{Deciders: make([]Decider, 0, len(inspections))}
+	for _, inspection := range inspections {
+		d := Decider{DeciderInspection: inspection}
+		key := types.NamespacedName{Namespace: inspection.Namespace, Name: inspection.Name}
+		if buckets, ok := h.buckets.Buckets(key); ok {
+			d.Buckets = &buckets
+		}
+		resp.Deciders = append(resp.Deciders, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Errorw("Failed to write the deciders", zap.Error(err))
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package introspection
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/autoscaler"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+)
+
+type fakeInspector struct {
+	deciders []autoscaler.DeciderInspection
+	buckets  map[types.NamespacedName]autoscaler.CollectionSnapshot
+
+	namespace, name string
+}
+
+func (f *fakeInspector) Inspect(namespace, name string) []autoscaler.DeciderInspection {
+	f.namespace, f.name = namespace, name
+	return f.deciders
+}
+
+func (f *fakeInspector) Buckets(key types.NamespacedName) (autoscaler.CollectionSnapshot, bool) {
+	b, ok := f.buckets[key]
+	return b, ok
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now().UTC()
+	collected := autoscaler.DeciderInspection{
+		Namespace: "ns",
+		Name:      "collected",
+		Spec:      autoscaler.DeciderSpec{TargetValue: 10},
+		Status:    autoscaler.DeciderStatus{DesiredScale: 3},
+		PanicTime: &now,
+		LastObservation: &autoscaler.Observation{
+			Time:                now,
+			Metric:              "concurrency",
+			ReadyPodCount:       2,
+			ObservedStableValue: 25,
+			DesiredPodCount:     3,
+		},
+	}
+	buckets := autoscaler.CollectionSnapshot{
+		Concurrency: []aggregation.BucketSnapshot{{
+			Time:   now,
+			Values: map[string]aggregation.ValueSnapshot{"pod": {Sum: 50, Count: 2}},
+		}},
+	}
+	uncollected := autoscaler.DeciderInspection{Namespace: "ns", Name: "uncollected"}
+	inspector := &fakeInspector{
+		deciders: []autoscaler.DeciderInspection{collected, uncollected},
+		buckets: map[types.NamespacedName]autoscaler.CollectionSnapshot{
+			{Namespace: "ns", Name: "collected"}: buckets,
+		},
+	}
+	h := NewHandler(inspector, inspector, TestLogger(t))
+
+	req := httptest.NewRequest(http.MethodGet, Path+"?namespace=ns&revision=collected", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("StatusCode = %d, want: %d", rec.Code, http.StatusOK)
+	}
+	if got, want := rec.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Content-Type = %q, want: %q", got, want)
+	}
+	if inspector.namespace != "ns" || inspector.name != "collected" {
+		t.Errorf("Inspect(%q, %q), want: Inspect(ns, collected)", inspector.namespace, inspector.name)
+	}
+	var got Response
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("Decode() = %v", err)
+	}
+	want := Response{Deciders: []Decider{{
+		DeciderInspection: collected,
+		Buckets:           &buckets,
+	}, {
+		DeciderInspection: uncollected,
+	}}}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Response diff(-want,+got): %s", cmp.Diff(want, got))
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	h := NewHandler(&fakeInspector{}, &fakeInspector{}, TestLogger(t))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("StatusCode = %d, want: %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
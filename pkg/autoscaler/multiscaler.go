@@ -19,6 +19,7 @@ package autoscaler
 import (
 	"context"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -44,8 +45,17 @@ type Decider struct {
 type DeciderSpec struct {
 	TickInterval   time.Duration
 	MaxScaleUpRate float64
+	// MaxScaleDownRate is the maximum ratio of the ready pods versus the
+	// desired pods when scaling down. Zero means scale down is not limited.
+	MaxScaleDownRate float64
+	// ScaleDownStabilizationWindow is the period over which the highest
+	// recommendation is used when scaling down.
+	ScaleDownStabilizationWindow time.Duration
 	// The metric used for scaling, i.e. concurrency, rps.
 	ScalingMetric string
+	// The name of the ScalingAlgorithm used to compute the desired scale.
+	// Empty means the default KPA algorithm.
+	Algorithm string
 	// The value of scaling metric per pod that we target to maintain.
 	// TargetValue <= TotalValue.
 	TargetValue float64
@@ -86,6 +96,31 @@ type UniScaler interface {
 	Update(*DeciderSpec) error
 }
 
+// snapshotter is implemented by the UniScalers whose state can be
+// checkpointed and restored.
+type snapshotter interface {
+	Snapshot() DeciderSnapshot
+	Restore(DeciderSnapshot)
+}
+
+// inspector is implemented by the UniScalers whose state can be inspected.
+type inspector interface {
+	Inspect() (DeciderSnapshot, *Observation)
+}
+
+// DeciderInspection is the state of a Decider and the reasoning behind its
+// current recommendation.
+type DeciderInspection struct {
+	Namespace    string        `json:"namespace"`
+	Name         string        `json:"name"`
+	Spec         DeciderSpec   `json:"spec"`
+	Status       DeciderStatus `json:"status"`
+	PanicTime    *time.Time    `json:"panicTime,omitempty"`
+	MaxPanicPods int32         `json:"maxPanicPods,omitempty"`
+	// LastObservation is nil until the Decider made a recommendation.
+	LastObservation *Observation `json:"lastObservation,omitempty"`
+}
+
 // UniScalerFactory creates a UniScaler for a given PA using the given dynamic configuration.
 type UniScalerFactory func(*Decider) (UniScaler, error)
 
@@ -135,6 +170,11 @@ type MultiScaler struct {
 
 	uniScalerFactory UniScalerFactory
 
+	// restored holds the restored state of the Deciders that have not been
+	// created yet, keyed by the string form of their key. Guarded by the
+	// scalersMutex.
+	restored map[string]restoredDecider
+
 	logger *zap.SugaredLogger
 
 	watcher      func(string)
@@ -202,9 +242,11 @@ func (m *MultiScaler) Update(ctx context.Context, decider *Decider) (*Decider, e
 		scaler.mux.Lock()
 		defer scaler.mux.Unlock()
 		oldDeciderSpec := scaler.decider.Spec
+		if err := scaler.scaler.Update(&decider.Spec); err != nil {
+			return nil, err
+		}
 		// Make sure we store the copy.
 		scaler.decider = decider.DeepCopy()
-		scaler.scaler.Update(&decider.Spec)
 		if oldDeciderSpec.TickInterval != decider.Spec.TickInterval {
 			m.updateRunner(ctx, scaler)
 		}
@@ -226,6 +268,93 @@ func (m *MultiScaler) Delete(ctx context.Context, namespace, name string) error
 	return nil
 }
 
+// Snapshot returns a copy of the state of all the Deciders, keyed by the
+// string form of their key.
+func (m *MultiScaler) Snapshot() map[string]DeciderSnapshot {
+	m.scalersMutex.RLock()
+	defer m.scalersMutex.RUnlock()
+
+	ret := make(map[string]DeciderSnapshot, len(m.scalers))
+	for key, runner := range m.scalers {
+		if s, ok := runner.scaler.(snapshotter); ok {
+			ret[key.String()] = s.Snapshot()
+		}
+	}
+	return ret
+}
+
+// Inspect returns the state of the Deciders in the given namespace with the
+// given name, sorted by their key. An empty namespace or name matches all.
+func (m *MultiScaler) Inspect(namespace, name string) []DeciderInspection {
+	m.scalersMutex.RLock()
+	defer m.scalersMutex.RUnlock()
+
+	ret := []DeciderInspection{}
+	for key, runner := range m.scalers {
+		if (namespace != "" && key.Namespace != namespace) || (name != "" && key.Name != name) {
+			continue
+		}
+		runner.mux.RLock()
+		inspection := DeciderInspection{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Spec:      runner.decider.Spec,
+			Status:    runner.decider.Status,
+		}
+		runner.mux.RUnlock()
+		if i, ok := runner.scaler.(inspector); ok {
+			var snapshot DeciderSnapshot
+			snapshot, inspection.LastObservation = i.Inspect()
+			inspection.PanicTime = snapshot.PanicTime
+			inspection.MaxPanicPods = snapshot.MaxPanicPods
+		}
+		ret = append(ret, inspection)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// restoredDecider is the restored state of a Decider, which is stale from
+// expires on.
+type restoredDecider struct {
+	snapshot DeciderSnapshot
+	expires  time.Time
+}
+
+// Restore makes the MultiScaler restore the given state of the Deciders
+// when they are created before it expires.
+func (m *MultiScaler) Restore(snapshots map[string]DeciderSnapshot, expires time.Time) {
+	m.scalersMutex.Lock()
+	defer m.scalersMutex.Unlock()
+
+	if m.restored == nil {
+		m.restored = make(map[string]restoredDecider, len(snapshots))
+	}
+	for key, snapshot := range snapshots {
+		m.restored[key] = restoredDecider{snapshot: snapshot, expires: expires}
+	}
+	// The state of the revisions deleted in the meantime is never claimed.
+	time.AfterFunc(time.Until(expires), m.dropExpiredRestored)
+}
+
+// dropExpiredRestored drops the restored state that expired.
+func (m *MultiScaler) dropExpiredRestored() {
+	m.scalersMutex.Lock()
+	defer m.scalersMutex.Unlock()
+
+	now := time.Now()
+	for key, restored := range m.restored {
+		if !now.Before(restored.expires) {
+			delete(m.restored, key)
+		}
+	}
+}
+
 // Watch registers a singleton function to call when DeciderStatus is updated.
 func (m *MultiScaler) Watch(fn func(string)) {
 	m.watcherMutex.Lock()
@@ -280,6 +409,13 @@ func (m *MultiScaler) createScaler(ctx context.Context, decider *Decider) (*scal
 	if err != nil {
 		return nil, err
 	}
+	key := types.NamespacedName{Namespace: d.Namespace, Name: d.Name}.String()
+	if restored, ok := m.restored[key]; ok {
+		if s, ok := scaler.(snapshotter); ok && time.Now().Before(restored.expires) {
+			s.Restore(restored.snapshot)
+		}
+		delete(m.restored, key)
+	}
 
 	runner := &scalerRunner{
 		scaler:  scaler,
@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
@@ -367,6 +368,82 @@ func TestMultiScalerUpdate(t *testing.T) {
 	if got, want := m.Spec.TargetValue, 10.0; got != want {
 		t.Errorf("Got target concurrency %v. Wanted %v", got, want)
 	}
+
+	// A spec the scaler rejects is not applied.
+	uniScaler.mutex.Lock()
+	uniScaler.updateErr = errors.New("unknown scaling algorithm")
+	uniScaler.mutex.Unlock()
+	decider.Spec.TargetValue = 20.0
+	if _, err = ms.Update(ctx, decider); err == nil {
+		t.Error("Update() = nil, wanted an error")
+	}
+	m, err = ms.Get(ctx, decider.Namespace, decider.Name)
+	if err != nil {
+		t.Errorf("Get() = %v", err)
+	}
+	if got, want := m.Spec.TargetValue, 10.0; got != want {
+		t.Errorf("Got target concurrency %v. Wanted %v", got, want)
+	}
+}
+
+func TestMultiScalerInspect(t *testing.T) {
+	ctx := context.Background()
+	ms, stopCh, statCh, _ := createMultiScaler(t)
+	defer close(stopCh)
+	defer close(statCh)
+
+	for _, key := range []types.NamespacedName{
+		{Namespace: "b", Name: "rev"},
+		{Namespace: "a", Name: "rev2"},
+		{Namespace: "a", Name: "rev"},
+	} {
+		decider := newDecider()
+		decider.Namespace, decider.Name = key.Namespace, key.Name
+		if _, err := ms.Create(ctx, decider); err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		namespace, rev  string
+		wantDeciderKeys []string
+	}{{
+		name:            "all",
+		wantDeciderKeys: []string{"a/rev", "a/rev2", "b/rev"},
+	}, {
+		name:            "namespace",
+		namespace:       "a",
+		wantDeciderKeys: []string{"a/rev", "a/rev2"},
+	}, {
+		name:            "revision",
+		rev:             "rev",
+		wantDeciderKeys: []string{"a/rev", "b/rev"},
+	}, {
+		name:            "both",
+		namespace:       "b",
+		rev:             "rev",
+		wantDeciderKeys: []string{"b/rev"},
+	}, {
+		name:            "none",
+		namespace:       "c",
+		wantDeciderKeys: []string{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, i := range ms.Inspect(test.namespace, test.rev) {
+				got = append(got, i.Namespace+"/"+i.Name)
+				if i.Spec.TargetValue != 1 || i.Status.DesiredScale != -1 {
+					t.Errorf("Inspect() = %+v, want the spec and status of the decider", i)
+				}
+			}
+			if !cmp.Equal(got, test.wantDeciderKeys) {
+				t.Errorf("Inspect() = %v, want: %v", got, test.wantDeciderKeys)
+			}
+		})
+	}
 }
 
 func createMultiScaler(t *testing.T) (*MultiScaler, chan<- struct{}, chan *StatMessage, *fakeUniScaler) {
@@ -386,6 +463,7 @@ type fakeUniScaler struct {
 	surplus    int32
 	scaled     bool
 	scaleCount int
+	updateErr  error
 }
 
 func (u *fakeUniScaler) fakeUniScalerFactory(*Decider) (UniScaler, error) {
@@ -415,7 +493,9 @@ func (u *fakeUniScaler) setScaleResult(replicas, surplus int32, scaled bool) {
 }
 
 func (u *fakeUniScaler) Update(*DeciderSpec) error {
-	return nil
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	return u.updateErr
 }
 
 func newDecider() *Decider {
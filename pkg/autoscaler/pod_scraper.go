@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/types"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/networking"
+	"knative.dev/serving/pkg/resources"
+)
+
+// PodScraper scrapes Revision metrics from the pods directly, using the pod
+// IPs of the Revision's Endpoints instead of going through the K8S service.
+// If the Revision has at most maxExactPods ready pods, every pod is scraped
+// and a stat per pod is reported. Otherwise a deterministic subset of the pods
+// is scraped, rotating through the pods on every scrape, and the stats are
+// extrapolated like the ServiceScraper does.
+type PodScraper struct {
+	sClient      scrapeClient
+	lister       corev1listers.EndpointsLister
+	namespace    string
+	service      string
+	metricKey    types.NamespacedName
+	maxExactPods int
+
+	mu sync.Mutex
+	// offset is the index of the first pod of the next subset to scrape.
+	offset int
+}
+
+// NewPodScraper creates a new StatsScraper for the Revision which the given
+// Metric is responsible for, scraping every pod if there are at most
+// maxExactPods of them.
+func NewPodScraper(metric *av1alpha1.Metric, lister corev1listers.EndpointsLister, maxExactPods int) (*PodScraper, error) {
+	sClient, err := newHTTPScrapeClient(cacheDisabledClient)
+	if err != nil {
+		return nil, err
+	}
+	return newPodScraperWithClient(metric, lister, maxExactPods, sClient)
+}
+
+func newPodScraperWithClient(
+	metric *av1alpha1.Metric,
+	lister corev1listers.EndpointsLister,
+	maxExactPods int,
+	sClient scrapeClient) (*PodScraper, error) {
+	if metric == nil {
+		return nil, errors.New("metric must not be nil")
+	}
+	if lister == nil {
+		return nil, errors.New("lister must not be nil")
+	}
+	if sClient == nil {
+		return nil, errors.New("scrape client must not be nil")
+	}
+	if maxExactPods < 0 {
+		return nil, fmt.Errorf("maxExactPods must not be negative, was: %d", maxExactPods)
+	}
+
+	return &PodScraper{
+		sClient:      sClient,
+		lister:       lister,
+		namespace:    metric.Namespace,
+		service:      metric.Spec.ScrapeTarget,
+		metricKey:    types.NamespacedName{Namespace: metric.Namespace, Name: metric.Name},
+		maxExactPods: maxExactPods,
+	}, nil
+}
+
+func urlFromIP(ip string) string {
+	return fmt.Sprintf("http://%s:%d/metrics", ip, networking.AutoscalingQueueMetricsPort)
+}
+
+// Scrape scrapes the ready pods of the Revision and reports either a stat per
+// pod or a single extrapolated stat.
+func (s *PodScraper) Scrape() ([]*StatMessage, error) {
+	endpoints, err := s.lister.Endpoints(s.namespace).Get(s.service)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get endpoints")
+	}
+	ips := resources.ReadyAddresses(endpoints)
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	// Sort the IPs, so that the subsets are stable across scrapes.
+	sort.Strings(ips)
+
+	if len(ips) <= s.maxExactPods {
+		stats, err := s.scrapePods(ips, len(ips))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unsuccessful scrape, podCount=%d", len(ips))
+		}
+		now := time.Now()
+		messages := make([]*StatMessage, len(stats))
+		for i, stat := range stats {
+			// The stat might be shared with the client, so copy it before setting the time.
+			stat := *stat
+			stat.Time = &now
+			if stat.PodName == "" {
+				stat.PodName = ips[i]
+			}
+			messages[i] = &StatMessage{Key: s.metricKey, Stat: stat}
+		}
+		return messages, nil
+	}
+
+	sampleSize := populationMeanSampleSize(len(ips))
+	stats, err := s.scrapePods(s.nextSubset(ips, sampleSize), len(ips))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unsuccessful scrape, sampleSize=%d", sampleSize)
+	}
+	return []*StatMessage{{
+		Stat: extrapolate(stats, len(ips)),
+		Key:  s.metricKey,
+	}}, nil
+}
+
+// nextSubset returns the next size IPs, wrapping around the end of the
+// list, and advances the offset past them.
+func (s *PodScraper) nextSubset(ips []string, size int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make([]string, size)
+	for i := range ret {
+		ret[i] = ips[(s.offset+i)%len(ips)]
+	}
+	s.offset = (s.offset + size) % len(ips)
+	return ret
+}
+
+// scrapePods scrapes the given pods out of readyPods concurrently and returns
+// their stats in the same order.
+func (s *PodScraper) scrapePods(ips []string, readyPods int) ([]*Stat, error) {
+	stats := make([]*Stat, len(ips))
+	grp := errgroup.Group{}
+	for i, ip := range ips {
+		i, url := i, withReadyPods(urlFromIP(ip), readyPods)
+		grp.Go(func() (err error) {
+			stats[i], err = s.sClient.Scrape(url)
+			return err
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ScrapeModeScraper scrapes the Revision either like a PodScraper or like a
+// ServiceScraper, as the Config in effect at the time of every scrape says, so
+// that changing the scraping mode applies to the existing Metrics too.
+type ScrapeModeScraper struct {
+	metric  *av1alpha1.Metric
+	lister  corev1listers.EndpointsLister
+	sClient scrapeClient
+	config  func() *Config
+	service *ServiceScraper
+
+	mu sync.Mutex
+	// pod is the PodScraper for the last maxExactPods seen.
+	pod *PodScraper
+}
+
+// NewScrapeModeScraper creates a new StatsScraper for the Revision which the
+// given Metric is responsible for, reading the scraping mode from config on
+// every scrape.
+func NewScrapeModeScraper(metric *av1alpha1.Metric, lister corev1listers.EndpointsLister,
+	counter resources.ReadyPodCounter, config func() *Config) (*ScrapeModeScraper, error) {
+	sClient, err := newHTTPScrapeClient(cacheDisabledClient)
+	if err != nil {
+		return nil, err
+	}
+	return newScrapeModeScraperWithClient(metric, lister, counter, config, sClient)
+}
+
+func newScrapeModeScraperWithClient(
+	metric *av1alpha1.Metric,
+	lister corev1listers.EndpointsLister,
+	counter resources.ReadyPodCounter,
+	config func() *Config,
+	sClient scrapeClient) (*ScrapeModeScraper, error) {
+	if lister == nil {
+		return nil, errors.New("lister must not be nil")
+	}
+	if config == nil {
+		return nil, errors.New("config must not be nil")
+	}
+	service, err := newServiceScraperWithClient(metric, counter, sClient)
+	if err != nil {
+		return nil, err
+	}
+	return &ScrapeModeScraper{
+		metric:  metric,
+		lister:  lister,
+		sClient: sClient,
+		config:  config,
+		service: service,
+	}, nil
+}
+
+// Scrape scrapes the pods directly if pod scraping is enabled, or else
+// through the K8S service.
+func (s *ScrapeModeScraper) Scrape() ([]*StatMessage, error) {
+	cfg := s.config()
+	if !cfg.EnablePodScraping {
+		return s.service.Scrape()
+	}
+	pod, err := s.podScraper(cfg.ExactScrapingMaxPods)
+	if err != nil {
+		return nil, err
+	}
+	return pod.Scrape()
+}
+
+// podScraper returns the PodScraper scraping every pod if there are at most
+// maxExactPods of them, creating it if that changed.
+func (s *ScrapeModeScraper) podScraper(maxExactPods int) (*PodScraper, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pod == nil || s.pod.maxExactPods != maxExactPods {
+		pod, err := newPodScraperWithClient(s.metric, s.lister, maxExactPods, s.sClient)
+		if err != nil {
+			return nil, err
+		}
+		s.pod = pod
+	}
+	return s.pod, nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/resources"
+)
+
+const testPodScraperService = "test-pod-scraper-service"
+
+// urlScrapeClient returns a stat for every URL, recording the scraped URLs.
+type urlScrapeClient struct {
+	mu   sync.Mutex
+	urls []string
+	err  error
+}
+
+func (c *urlScrapeClient) Scrape(url string) (*Stat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.urls = append(c.urls, url)
+	return &Stat{
+		PodName:                   url,
+		AverageConcurrentRequests: 2,
+		RequestCount:              3,
+	}, c.err
+}
+
+func (c *urlScrapeClient) scraped() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := c.urls
+	c.urls = nil
+	sort.Strings(ret)
+	return ret
+}
+
+func podScraperForTest(t *testing.T, podCount, maxExactPods int, client scrapeClient) *PodScraper {
+	t.Helper()
+	metric := testMetric()
+	metric.Spec.ScrapeTarget = testPodScraperService
+	endpoints(podCount, testPodScraperService)
+	scraper, err := newPodScraperWithClient(metric, kubeInformer.Core().V1().Endpoints().Lister(), maxExactPods, client)
+	if err != nil {
+		t.Fatalf("newPodScraperWithClient() = %v", err)
+	}
+	return scraper
+}
+
+func TestNewPodScraperWithClientErrorCases(t *testing.T) {
+	lister := kubeInformer.Core().V1().Endpoints().Lister()
+	client := &urlScrapeClient{}
+	testCases := []struct {
+		name         string
+		metric       *av1alpha1.Metric
+		maxExactPods int
+		client       scrapeClient
+		expectedErr  string
+	}{{
+		name:        "Empty metric",
+		client:      client,
+		expectedErr: "metric must not be nil",
+	}, {
+		name:        "Empty scrape client",
+		metric:      testMetric(),
+		expectedErr: "scrape client must not be nil",
+	}, {
+		name:         "Negative maxExactPods",
+		metric:       testMetric(),
+		client:       client,
+		maxExactPods: -1,
+		expectedErr:  "maxExactPods must not be negative, was: -1",
+	}}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newPodScraperWithClient(test.metric, lister, test.maxExactPods, test.client); err == nil {
+				t.Error("Expected error from newPodScraperWithClient, got nil")
+			} else if got, want := err.Error(), test.expectedErr; got != want {
+				t.Errorf("Got error message: %v. Want: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPodScraperScrapesEveryPod(t *testing.T) {
+	client := &urlScrapeClient{}
+	scraper := podScraperForTest(t, 3, 3, client)
+
+	got, err := scraper.Scrape()
+	if err != nil {
+		t.Fatalf("Scrape() = %v", err)
+	}
+	want := []string{
+		"http://127.0.0.1:9090/metrics?readyPods=3",
+		"http://127.0.0.2:9090/metrics?readyPods=3",
+		"http://127.0.0.3:9090/metrics?readyPods=3",
+	}
+	if diff := cmp.Diff(want, client.scraped()); diff != "" {
+		t.Errorf("Scraped URLs differ (-want, +got): %s", diff)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("len(Scrape()) = %d, want %d", len(got), len(want))
+	}
+	for i, message := range got {
+		if message.Key != testPAKey {
+			t.Errorf("StatMessage.Key = %v, want %v", message.Key, testPAKey)
+		}
+		// Every pod keeps its own stat, rather than an extrapolated one.
+		if message.Stat.PodName != want[i] {
+			t.Errorf("StatMessage.Stat.PodName = %s, want %s", message.Stat.PodName, want[i])
+		}
+		if message.Stat.Time == nil {
+			t.Error("StatMessage.Stat.Time = nil, want a timestamp")
+		}
+		if message.Stat.AverageConcurrentRequests != 2 {
+			t.Errorf("StatMessage.Stat.AverageConcurrentRequests = %v, want 2", message.Stat.AverageConcurrentRequests)
+		}
+	}
+}
+
+func TestPodScraperRotatesSubsets(t *testing.T) {
+	client := &urlScrapeClient{}
+	// 20 pods exceed maxExactPods, so samples of 9 pods are scraped.
+	scraper := podScraperForTest(t, 20, 10, client)
+
+	seen := map[string]int{}
+	for i := 0; i < 20; i++ {
+		got, err := scraper.Scrape()
+		if err != nil {
+			t.Fatalf("Scrape() = %v", err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("len(Scrape()) = %d, want 1", len(got))
+		}
+		if got[0].Stat.PodName != scraperPodName {
+			t.Errorf("StatMessage.Stat.PodName = %s, want %s", got[0].Stat.PodName, scraperPodName)
+		}
+		if got[0].Stat.AverageConcurrentRequests != 40 {
+			t.Errorf("StatMessage.Stat.AverageConcurrentRequests = %v, want 40", got[0].Stat.AverageConcurrentRequests)
+		}
+		urls := client.scraped()
+		if len(urls) != 9 {
+			t.Fatalf("Scraped %d pods, want 9", len(urls))
+		}
+		for i, url := range urls {
+			if i > 0 && urls[i-1] == url {
+				t.Errorf("Scraped %s more than once", url)
+			}
+			seen[url]++
+		}
+	}
+	// 20 scrapes of 9 pods out of 20 hit every pod exactly 9 times.
+	for url, count := range seen {
+		if count != 9 {
+			t.Errorf("Scraped %s %d times, want 9", url, count)
+		}
+	}
+	if len(seen) != 20 {
+		t.Errorf("Scraped %d distinct pods, want 20", len(seen))
+	}
+}
+
+func TestPodScraperNoPods(t *testing.T) {
+	client := &urlScrapeClient{}
+	scraper := podScraperForTest(t, 0, 10, client)
+
+	got, err := scraper.Scrape()
+	if err != nil {
+		t.Fatalf("Scrape() = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Scrape() = %v, want no stats", got)
+	}
+	if urls := client.scraped(); len(urls) != 0 {
+		t.Errorf("Scraped %v, want nothing", urls)
+	}
+}
+
+func TestPodScraperError(t *testing.T) {
+	client := &urlScrapeClient{err: errors.New("connection refused")}
+	scraper := podScraperForTest(t, 2, 10, client)
+
+	if _, err := scraper.Scrape(); err == nil {
+		t.Error("Expected error from Scrape, got nil")
+	} else if !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Got error message: %v. Want it to contain: connection refused", err)
+	}
+}
+
+func TestScrapeModeScraper(t *testing.T) {
+	metric := testMetric()
+	metric.Spec.ScrapeTarget = testPodScraperService
+	endpoints(1, testPodScraperService)
+	lister := kubeInformer.Core().V1().Endpoints().Lister()
+	counter := resources.NewScopedEndpointsCounter(lister, testNamespace, testPodScraperService)
+
+	var mu sync.Mutex
+	cfg := &Config{}
+	client := &urlScrapeClient{}
+	scraper, err := newScrapeModeScraperWithClient(metric, lister, counter, func() *Config {
+		mu.Lock()
+		defer mu.Unlock()
+		return cfg
+	}, client)
+	if err != nil {
+		t.Fatalf("newScrapeModeScraperWithClient() = %v", err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		cfg     *Config
+		wantURL string
+	}{{
+		name:    "service scraping",
+		cfg:     &Config{},
+		wantURL: "http://test-pod-scraper-service.test-namespace:9090/metrics?readyPods=1",
+	}, {
+		name:    "pod scraping",
+		cfg:     &Config{EnablePodScraping: true, ExactScrapingMaxPods: 1},
+		wantURL: "http://127.0.0.1:9090/metrics?readyPods=1",
+	}, {
+		name:    "pod scraping with another maximum",
+		cfg:     &Config{EnablePodScraping: true, ExactScrapingMaxPods: 5},
+		wantURL: "http://127.0.0.1:9090/metrics?readyPods=1",
+	}, {
+		name:    "back to service scraping",
+		cfg:     &Config{},
+		wantURL: "http://test-pod-scraper-service.test-namespace:9090/metrics?readyPods=1",
+	}} {
+		mu.Lock()
+		cfg = test.cfg
+		mu.Unlock()
+		if _, err := scraper.Scrape(); err != nil {
+			t.Fatalf("%s: Scrape() = %v", test.name, err)
+		}
+		if got, want := client.scraped(), []string{test.wantURL}; !cmp.Equal(got, want) {
+			t.Errorf("%s: scraped %v, want: %v", test.name, got, want)
+		}
+		if test.cfg.EnablePodScraping && scraper.pod.maxExactPods != test.cfg.ExactScrapingMaxPods {
+			t.Errorf("%s: maxExactPods = %d, want: %d", test.name, scraper.pod.maxExactPods, test.cfg.ExactScrapingMaxPods)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// SnapshotStoreNone disables checkpointing.
+	SnapshotStoreNone = "none"
+	// SnapshotStoreConfigMap checkpoints into a ConfigMap.
+	SnapshotStoreConfigMap = "configmap"
+	// SnapshotStoreFile checkpoints into a local file.
+	SnapshotStoreFile = "file"
+
+	// snapshotConfigMapKey is the key of the ConfigMap data the snapshot is stored
+	// under. The snapshots of partitions are stored under the key suffixed with
+	// the name of the partition.
+	snapshotConfigMapKey = "snapshot"
+)
+
+// Snapshot is the state of the autoscaler that survives restarts.
+type Snapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Collections is the metric history, keyed by the Metric key.
+	Collections map[string]CollectionSnapshot `json:"collections,omitempty"`
+	// Deciders is the panic state, keyed by the Decider key.
+	Deciders map[string]DeciderSnapshot `json:"deciders,omitempty"`
+}
+
+// SnapshotStore persists Snapshots. When the autoscaler is sharded, each
+// partition of the state is saved separately by the replica owning it. The
+// whole state of an autoscaler that isn't sharded is the partition "".
+type SnapshotStore interface {
+	// Save persists the given snapshot of the partition, replacing the previous one.
+	Save(partition string, snapshot *Snapshot) error
+	// Load returns the last saved snapshot of the partition, or nil if there is none.
+	Load(partition string) (*Snapshot, error)
+}
+
+// Partitioner splits the state of a sharded autoscaler into partitions.
+type Partitioner interface {
+	// Partition returns the partition the given Metric or Decider key belongs to.
+	Partition(key string) string
+	// Owned returns the partitions this replica owns.
+	Owned() []string
+}
+
+// FileSnapshotStore stores the snapshot as JSON in a local file.
+type FileSnapshotStore struct {
+	path string
+}
+
+var _ SnapshotStore = (*FileSnapshotStore)(nil)
+
+// NewFileSnapshotStore creates a SnapshotStore writing to the file at path.
+// The snapshots of partitions are written next to it, to the path suffixed
+// with the name of the partition.
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{path: path}
+}
+
+func (s *FileSnapshotStore) partitionPath(partition string) string {
+	if partition == "" {
+		return s.path
+	}
+	return s.path + "-" + partition
+}
+
+// Save implements SnapshotStore. The file is replaced atomically, so that a
+// crash while saving doesn't corrupt the previous snapshot.
+func (s *FileSnapshotStore) Save(partition string, snapshot *Snapshot) error {
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	path := s.partitionPath(partition)
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load implements SnapshotStore.
+func (s *FileSnapshotStore) Load(partition string) (*Snapshot, error) {
+	path := s.partitionPath(partition)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(b, snapshot); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse snapshot %s", path)
+	}
+	return snapshot, nil
+}
+
+// ConfigMapSnapshotStore stores the snapshots as JSON in a ConfigMap, each
+// partition under a key of its own. Since ConfigMaps are limited to 1MiB, it
+// is best suited to clusters running a moderate number of Revisions.
+type ConfigMapSnapshotStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ SnapshotStore = (*ConfigMapSnapshotStore)(nil)
+
+// NewConfigMapSnapshotStore creates a SnapshotStore writing to the given ConfigMap,
+// which is created if it doesn't exist.
+func NewConfigMapSnapshotStore(client kubernetes.Interface, namespace, name string) *ConfigMapSnapshotStore {
+	return &ConfigMapSnapshotStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func configMapKey(partition string) string {
+	if partition == "" {
+		return snapshotConfigMapKey
+	}
+	return snapshotConfigMapKey + "-" + partition
+}
+
+// Save implements SnapshotStore. Only the key of the partition is replaced,
+// and the update is retried on conflicts, so that the replicas of a sharded
+// autoscaler don't overwrite each other's partitions.
+func (s *ConfigMapSnapshotStore) Save(partition string, snapshot *Snapshot) error {
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	key := configMapKey(partition)
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = configMaps.Create(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.namespace,
+					Name:      s.name,
+				},
+				Data: map[string]string{key: string(b)},
+			})
+			if apierrors.IsAlreadyExists(err) {
+				// Another replica created it in the meantime, so update it instead.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = make(map[string]string, 1)
+		}
+		cm.Data[key] = string(b)
+		_, err = configMaps.Update(cm)
+		return err
+	})
+}
+
+// Load implements SnapshotStore.
+func (s *ConfigMapSnapshotStore) Load(partition string) (*Snapshot, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, ok := cm.Data[configMapKey(partition)]
+	if !ok {
+		return nil, nil
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal([]byte(data), snapshot); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse snapshot in ConfigMap %s/%s", s.namespace, s.name)
+	}
+	return snapshot, nil
+}
+
+// Checkpointer periodically saves the state of a MetricCollector and a
+// MultiScaler to a SnapshotStore and restores it on startup. If the
+// autoscaler is sharded, the state of each partition is saved and restored
+// separately by the replica owning it.
+type Checkpointer struct {
+	store       SnapshotStore
+	collector   *MetricCollector
+	scaler      *MultiScaler
+	partitioner Partitioner
+	logger      *zap.SugaredLogger
+}
+
+// NewCheckpointer creates a new Checkpointer. The partitioner is nil if the
+// autoscaler isn't sharded.
+func NewCheckpointer(store SnapshotStore, collector *MetricCollector, scaler *MultiScaler,
+	partitioner Partitioner, logger *zap.SugaredLogger) *Checkpointer {
+	return &Checkpointer{
+		store:       store,
+		collector:   collector,
+		scaler:      scaler,
+		partitioner: partitioner,
+		logger:      logger,
+	}
+}
+
+// Restore loads the last snapshot of the partition and hands it to the
+// collector and the MultiScaler, which apply it as the Metrics and Deciders
+// are created. It must be called before those are created, i.e. on startup,
+// or when this replica starts owning the partition. Snapshots older than
+// maxAge are ignored, since they no longer reflect the load.
+func (c *Checkpointer) Restore(partition string, now time.Time, maxAge time.Duration) error {
+	snapshot, err := c.store.Load(partition)
+	if err != nil {
+		return errors.Wrap(err, "failed to load snapshot")
+	}
+	logger := c.logger
+	if partition != "" {
+		logger = logger.With(zap.String("partition", partition))
+	}
+	if snapshot == nil {
+		logger.Info("No snapshot found, starting without metric history")
+		return nil
+	}
+	if age := now.Sub(snapshot.Time); age > maxAge {
+		logger.Infof("Ignoring snapshot taken %v ago", age)
+		return nil
+	}
+	logger.Infof("Restoring %d collections and %d deciders from snapshot taken at %v",
+		len(snapshot.Collections), len(snapshot.Deciders), snapshot.Time)
+	// The history of the revisions that are not created again goes stale
+	// like the snapshot.
+	expires := snapshot.Time.Add(maxAge)
+	c.collector.Restore(snapshot.Collections, expires)
+	c.scaler.Restore(snapshot.Deciders, expires)
+	return nil
+}
+
+// Checkpoint saves the current state of the partitions this replica owns.
+func (c *Checkpointer) Checkpoint(now time.Time) error {
+	snapshot := &Snapshot{
+		Time:        now,
+		Collections: c.collector.Snapshot(),
+		Deciders:    c.scaler.Snapshot(),
+	}
+	if c.partitioner == nil {
+		return c.store.Save("", snapshot)
+	}
+	for _, partition := range c.partitioner.Owned() {
+		if err := c.store.Save(partition, c.partitionSnapshot(snapshot, partition)); err != nil {
+			return errors.Wrapf(err, "failed to save partition %s", partition)
+		}
+	}
+	return nil
+}
+
+// partitionSnapshot returns the part of the snapshot in the given partition.
+func (c *Checkpointer) partitionSnapshot(snapshot *Snapshot, partition string) *Snapshot {
+	ret := &Snapshot{
+		Time:        snapshot.Time,
+		Collections: make(map[string]CollectionSnapshot),
+		Deciders:    make(map[string]DeciderSnapshot),
+	}
+	for key, collection := range snapshot.Collections {
+		if c.partitioner.Partition(key) == partition {
+			ret.Collections[key] = collection
+		}
+	}
+	for key, decider := range snapshot.Deciders {
+		if c.partitioner.Partition(key) == partition {
+			ret.Deciders[key] = decider
+		}
+	}
+	return ret
+}
+
+// Run checkpoints every interval until stopCh is closed.
+func (c *Checkpointer) Run(stopCh <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := c.Checkpoint(time.Now()); err != nil {
+				c.logger.Errorw("Failed to checkpoint", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+	autoscalerfake "knative.dev/serving/pkg/autoscaler/fake"
+)
+
+func testSnapshot(now time.Time) *Snapshot {
+	return &Snapshot{
+		Time: now,
+		Collections: map[string]CollectionSnapshot{
+			"ns/rev": {
+				Concurrency: []aggregation.BucketSnapshot{{
+					Time: now,
+					Values: map[string]aggregation.ValueSnapshot{
+						"pod": {Sum: 10, Count: 2},
+					},
+				}},
+			},
+		},
+		Deciders: map[string]DeciderSnapshot{
+			"ns/rev": {PanicTime: ptr.Time(now), MaxPanicPods: 3},
+		},
+	}
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileSnapshotStore(filepath.Join(dir, "snapshot.json"))
+
+	if got, err := store.Load(""); err != nil || got != nil {
+		t.Fatalf("Load() = %v, %v, want nil, nil", got, err)
+	}
+
+	want := testSnapshot(time.Unix(1000, 0))
+	for i := 0; i < 2; i++ {
+		if err := store.Save("", want); err != nil {
+			t.Fatalf("Save() = %v", err)
+		}
+	}
+	got, err := store.Load("")
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("Load() differs (-want, +got): %s", cmp.Diff(want, got))
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "snapshot.json"), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	if _, err := store.Load(""); err == nil {
+		t.Error("Load() = nil, wanted an error for a malformed snapshot")
+	}
+}
+
+func TestConfigMapSnapshotStore(t *testing.T) {
+	store := NewConfigMapSnapshotStore(fakek8s.NewSimpleClientset(), testNamespace, "autoscaler-snapshot")
+
+	if got, err := store.Load(""); err != nil || got != nil {
+		t.Fatalf("Load() = %v, %v, want nil, nil", got, err)
+	}
+
+	// The first Save creates the ConfigMap, the second one updates it.
+	if err := store.Save("", &Snapshot{Time: time.Unix(1, 0)}); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	want := testSnapshot(time.Unix(1000, 0))
+	if err := store.Save("", want); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	got, err := store.Load("")
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("Load() differs (-want, +got): %s", cmp.Diff(want, got))
+	}
+}
+
+func TestCheckpointerRoundTrip(t *testing.T) {
+	defer ClearAll()
+	logger := TestLogger(t)
+	now := time.Now()
+	metricKey := types.NamespacedName{Namespace: defaultNamespace, Name: defaultName}
+	decider := newDecider()
+	// Don't let the deciders tick, the test only cares about their state.
+	decider.Spec.TickInterval = time.Hour
+	deciderKey := types.NamespacedName{Namespace: decider.Namespace, Name: decider.Name}
+	scraper := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return nil, nil
+		},
+	}
+
+	var autoscalers []*Autoscaler
+	newScalers := func() (*MetricCollector, *MultiScaler, chan struct{}) {
+		stopCh := make(chan struct{})
+		collector := NewMetricCollector(scraperFactory(scraper, nil), logger)
+		ms := NewMultiScaler(stopCh, func(*Decider) (UniScaler, error) {
+			a := newTestAutoscaler(t, 10, 100, &autoscalerfake.MetricClient{})
+			autoscalers = append(autoscalers, a)
+			return a, nil
+		}, logger)
+		return collector, ms, stopCh
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileSnapshotStore(filepath.Join(dir, "snapshot.json"))
+
+	// Collect some history and panic, then checkpoint.
+	collector, ms, stopCh := newScalers()
+	defer close(stopCh)
+	collector.CreateOrUpdate(defaultMetric)
+	collector.Record(metricKey, Stat{
+		Time:                      &now,
+		PodName:                   "pod",
+		AverageConcurrentRequests: 42,
+	})
+	if _, err := ms.Create(context.Background(), decider); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	wantDecider := DeciderSnapshot{PanicTime: ptr.Time(now.Add(-time.Second).Round(0)), MaxPanicPods: 5}
+	autoscalers[0].Restore(wantDecider)
+	if err := NewCheckpointer(store, collector, ms, nil, logger).Checkpoint(now); err != nil {
+		t.Fatalf("Checkpoint() = %v", err)
+	}
+
+	// A stale snapshot is ignored.
+	collector, ms, stopCh = newScalers()
+	defer close(stopCh)
+	if err := NewCheckpointer(store, collector, ms, nil, logger).Restore("", now.Add(2*time.Minute), time.Minute); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	collector.CreateOrUpdate(defaultMetric)
+	if _, _, err := collector.StableAndPanicConcurrency(metricKey, now); err == nil {
+		t.Error("StableAndPanicConcurrency() = nil, wanted an error since the snapshot is stale")
+	}
+
+	// A fresh one seeds the collections and deciders created afterwards.
+	collector, ms, stopCh = newScalers()
+	defer close(stopCh)
+	if err := NewCheckpointer(store, collector, ms, nil, logger).Restore("", now.Add(time.Second), time.Minute); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	collector.CreateOrUpdate(defaultMetric)
+	if stable, panic, err := collector.StableAndPanicConcurrency(metricKey, now); stable != 42 || panic != 42 || err != nil {
+		t.Errorf("StableAndPanicConcurrency() = %v, %v, %v; want 42, 42, nil", stable, panic, err)
+	}
+	if _, err := ms.Create(context.Background(), decider); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	if got := ms.Snapshot()[deciderKey.String()]; !cmp.Equal(wantDecider, got) {
+		t.Errorf("Restored decider differs (-want, +got): %s", cmp.Diff(wantDecider, got))
+	}
+}
+
+func TestRestoredStateExpires(t *testing.T) {
+	defer ClearAll()
+	logger := TestLogger(t)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	collector := NewMetricCollector(scraperFactory(&testScraper{
+		s: func() ([]*StatMessage, error) {
+			return nil, nil
+		},
+	}, nil), logger)
+	ms := NewMultiScaler(stopCh, func(*Decider) (UniScaler, error) {
+		return nil, nil
+	}, logger)
+
+	// The state of a revision that was deleted while the autoscaler was down
+	// is never claimed, and is dropped once it is stale.
+	snapshot := testSnapshot(time.Now())
+	expires := time.Now().Add(50 * time.Millisecond)
+	collector.Restore(snapshot.Collections, expires)
+	ms.Restore(snapshot.Deciders, expires)
+	if err := wait.PollImmediate(10*time.Millisecond, 3*time.Second, func() (bool, error) {
+		collector.collectionsMutex.RLock()
+		defer collector.collectionsMutex.RUnlock()
+		ms.scalersMutex.RLock()
+		defer ms.scalersMutex.RUnlock()
+		return len(collector.restored) == 0 && len(ms.restored) == 0, nil
+	}); err != nil {
+		t.Errorf("Restored state was not dropped after it expired: %v, %v", collector.restored, ms.restored)
+	}
+
+	// Nor is stale state restored if it was not dropped yet.
+	collector.Restore(snapshot.Collections, time.Now().Add(-time.Second))
+	metric := defaultMetric.DeepCopy()
+	metric.Namespace, metric.Name = "ns", "rev"
+	collector.CreateOrUpdate(metric)
+	if _, _, err := collector.StableAndPanicConcurrency(types.NamespacedName{Namespace: "ns", Name: "rev"}, time.Now()); err == nil {
+		t.Error("StableAndPanicConcurrency() = nil, wanted an error since the restored history expired")
+	}
+}
+
+type testPartitioner struct {
+	owned []string
+}
+
+// Partition puts every revision in the partition named like it.
+func (p *testPartitioner) Partition(key string) string {
+	return key[strings.Index(key, "/")+1:]
+}
+
+func (p *testPartitioner) Owned() []string {
+	return p.owned
+}
+
+func TestCheckpointerPartitions(t *testing.T) {
+	defer ClearAll()
+	logger := TestLogger(t)
+	now := time.Now()
+	store := NewConfigMapSnapshotStore(fakek8s.NewSimpleClientset(), testNamespace, "autoscaler-snapshot")
+	scraper := &testScraper{
+		s: func() ([]*StatMessage, error) {
+			return nil, nil
+		},
+	}
+	metric := func(name string) *av1alpha1.Metric {
+		m := defaultMetric.DeepCopy()
+		m.Name = name
+		return m
+	}
+	newReplica := func(owned ...string) (*MetricCollector, *Checkpointer, chan struct{}) {
+		stopCh := make(chan struct{})
+		collector := NewMetricCollector(scraperFactory(scraper, nil), logger)
+		ms := NewMultiScaler(stopCh, func(*Decider) (UniScaler, error) {
+			return nil, nil
+		}, logger)
+		return collector, NewCheckpointer(store, collector, ms, &testPartitioner{owned: owned}, logger), stopCh
+	}
+
+	// Two replicas collect the history of the revisions in the partitions
+	// they own, and checkpoint to the same ConfigMap.
+	for _, test := range []struct {
+		owned string
+		value float64
+	}{{owned: "a", value: 1}, {owned: "b", value: 2}} {
+		collector, checkpointer, stopCh := newReplica(test.owned)
+		defer close(stopCh)
+		collector.CreateOrUpdate(metric(test.owned))
+		collector.Record(types.NamespacedName{Namespace: defaultNamespace, Name: test.owned}, Stat{
+			Time:                      &now,
+			PodName:                   "pod",
+			AverageConcurrentRequests: test.value,
+		})
+		if err := checkpointer.Checkpoint(now); err != nil {
+			t.Fatalf("Checkpoint() = %v", err)
+		}
+	}
+
+	// Taking over a partition restores its history only.
+	collector, checkpointer, stopCh := newReplica("b")
+	defer close(stopCh)
+	if err := checkpointer.Restore("b", now.Add(time.Second), time.Minute); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	for _, test := range []struct {
+		name    string
+		want    float64
+		wantErr bool
+	}{{name: "a", wantErr: true}, {name: "b", want: 2}} {
+		collector.CreateOrUpdate(metric(test.name))
+		stable, _, err := collector.StableAndPanicConcurrency(types.NamespacedName{Namespace: defaultNamespace, Name: test.name}, now)
+		if (err != nil) != test.wantErr || stable != test.want {
+			t.Errorf("StableAndPanicConcurrency(%s) = %v, %v; want %v, error: %v", test.name, stable, err, test.want, test.wantErr)
+		}
+	}
+
+	// The partition of the other replica was left alone.
+	snapshot, err := store.Load("a")
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if _, ok := snapshot.Collections[defaultNamespace+"/a"]; !ok || len(snapshot.Collections) != 1 {
+		t.Errorf("Collections = %v, want only the one of a", snapshot.Collections)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package statforwarder forwards the stats received by an autoscaler replica
+// to the replica owning the revision they are about.
+package statforwarder
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/websocket"
+	"knative.dev/serving/pkg/autoscaler"
+)
+
+// idleTimeout is how long a connection to a replica is kept after it was
+// last used. Connections to replicas that no longer own any revision are
+// closed after it, rather than reconnecting forever.
+const idleTimeout = time.Minute
+
+// ErrNoOwner denotes that the owner of a stat is unknown, e.g. while its
+// bucket is being taken over.
+var ErrNoOwner = errors.New("the owner of the stat is unknown")
+
+type connection struct {
+	conn     *websocket.ManagedConnection
+	lastUsed time.Time
+}
+
+// Forwarder sends stats to the stat servers of the replicas owning them.
+type Forwarder struct {
+	owner  func(key string) string
+	port   int
+	logger *zap.SugaredLogger
+
+	mu    sync.Mutex
+	conns map[string]*connection
+
+	stopCh chan struct{}
+}
+
+// New creates a Forwarder. owner returns the address of the replica owning
+// the given key, whose stat server listens on port.
+func New(owner func(key string) string, port int, logger *zap.SugaredLogger) *Forwarder {
+	f := &Forwarder{
+		owner:  owner,
+		port:   port,
+		logger: logger,
+		conns:  make(map[string]*connection),
+		stopCh: make(chan struct{}),
+	}
+	go f.closeIdle()
+	return f
+}
+
+// Forward sends the stat to its owner.
+func (f *Forwarder) Forward(sm *autoscaler.StatMessage) error {
+	addr := f.owner(sm.Key.String())
+	if addr == "" {
+		return ErrNoOwner
+	}
+	return f.connection(addr).Send(sm)
+}
+
+func (f *Forwarder) connection(addr string) *websocket.ManagedConnection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conns[addr]
+	if !ok {
+		target := fmt.Sprintf("ws://%s", net.JoinHostPort(addr, strconv.Itoa(f.port)))
+		f.logger.Info("Connecting to autoscaler replica at ", target)
+		c = &connection{conn: websocket.NewDurableSendingConnection(target, f.logger)}
+		f.conns[addr] = c
+	}
+	c.lastUsed = time.Now()
+	return c.conn
+}
+
+func (f *Forwarder) closeIdle() {
+	ticker := time.NewTicker(idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case now := <-ticker.C:
+			f.mu.Lock()
+			for addr, c := range f.conns {
+				if now.Sub(c.lastUsed) > idleTimeout {
+					c.conn.Shutdown()
+					delete(f.conns, addr)
+				}
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+// Shutdown closes all the connections.
+func (f *Forwarder) Shutdown() {
+	close(f.stopCh)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for addr, c := range f.conns {
+		c.conn.Shutdown()
+		delete(f.conns, addr)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statforwarder
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/autoscaler"
+)
+
+func TestForward(t *testing.T) {
+	received := make(chan autoscaler.StatMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var upgrader websocket.Upgrader
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var sm autoscaler.StatMessage
+			if err := gob.NewDecoder(bytes.NewBuffer(msg)).Decode(&sm); err == nil {
+				received <- sm
+			}
+		}
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("SplitHostPort() = %v", err)
+	}
+	p, _ := strconv.Atoi(port)
+	owned := types.NamespacedName{Namespace: "ns", Name: "owned"}
+	f := New(func(key string) string {
+		if key == owned.String() {
+			return host
+		}
+		return ""
+	}, p, TestLogger(t))
+	defer f.Shutdown()
+
+	if err := f.Forward(&autoscaler.StatMessage{Key: types.NamespacedName{Namespace: "ns", Name: "unowned"}}); err != ErrNoOwner {
+		t.Errorf("Forward() = %v, want: %v", err, ErrNoOwner)
+	}
+
+	want := autoscaler.StatMessage{
+		Key:  owned,
+		Stat: autoscaler.Stat{PodName: "activator", AverageConcurrentRequests: 3},
+	}
+	// The connection is established asynchronously, so retry until it is.
+	deadline := time.Now().Add(10 * time.Second)
+	for err := f.Forward(&want); err != nil; err = f.Forward(&want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Forward() = %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case got := <-received:
+		if got.Key != want.Key || got.Stat.AverageConcurrentRequests != 3 {
+			t.Errorf("Received %+v, want: %+v", got, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("Timed out waiting for the stat")
+	}
+}
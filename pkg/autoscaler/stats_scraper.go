@@ -19,6 +19,7 @@ package autoscaler
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -46,12 +47,19 @@ const (
 	// to retry if a Scrape returns an error or if the Scrape goes to a pod we already
 	// scraped.
 	scraperMaxRetries = 10
+
+	// ReadyPodsQueryParam is the query parameter passing the number of ready
+	// pods of the Revision to the queue-proxies when scraping them, which split
+	// the rate limit of the Revision across the pods.
+	ReadyPodsQueryParam = "readyPods"
 )
 
 // StatsScraper defines the interface for collecting Revision metrics
 type StatsScraper interface {
-	// Scrape scrapes the Revision queue metric endpoint.
-	Scrape() (*StatMessage, error)
+	// Scrape scrapes the Revision queue metric endpoint. Depending on the
+	// implementation the result is either a single stat extrapolated for
+	// all the pods, or a stat per pod.
+	Scrape() ([]*StatMessage, error)
 }
 
 // scrapeClient defines the interface for collecting Revision metrics for a given
@@ -126,9 +134,14 @@ func urlFromTarget(t, ns string) string {
 		t, ns, networking.AutoscalingQueueMetricsPort)
 }
 
+// withReadyPods adds the number of ready pods to the given scrape URL.
+func withReadyPods(url string, readyPods int) string {
+	return url + "?" + ReadyPodsQueryParam + "=" + strconv.Itoa(readyPods)
+}
+
 // Scrape calls the destination service then sends it
 // to the given stats channel.
-func (s *ServiceScraper) Scrape() (*StatMessage, error) {
+func (s *ServiceScraper) Scrape() ([]*StatMessage, error) {
 	readyPodsCount, err := s.counter.ReadyCount()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get endpoints")
@@ -146,7 +159,7 @@ func (s *ServiceScraper) Scrape() (*StatMessage, error) {
 	for i := 0; i < sampleSize; i++ {
 		grp.Go(func() error {
 			for tries := 1; ; tries++ {
-				stat, err := s.tryScrape(scrapedPods)
+				stat, err := s.tryScrape(scrapedPods, readyPodsCount)
 				if err == nil {
 					statCh <- stat
 					return nil
@@ -166,27 +179,43 @@ func (s *ServiceScraper) Scrape() (*StatMessage, error) {
 	}
 	close(statCh)
 
+	stats := make([]*Stat, 0, sampleSize)
+	for stat := range statCh {
+		stats = append(stats, stat)
+	}
+	return []*StatMessage{{
+		Stat: extrapolate(stats, readyPodsCount),
+		Key:  s.metricKey,
+	}}, nil
+}
+
+// extrapolate averages the stats of the sampled pods and extrapolates them to
+// the given number of ready pods.
+func extrapolate(stats []*Stat, readyPodsCount int) Stat {
 	var (
 		avgConcurrency        float64
 		avgProxiedConcurrency float64
 		reqCount              float64
 		proxiedReqCount       float64
-		successCount          float64
+		cpuUsage              float64
+		memoryUsage           float64
+		concurrencyLimit      float64
+		upgradedConnections   float64
 	)
 
-	for stat := range statCh {
-		successCount++
+	for _, stat := range stats {
 		avgConcurrency += stat.AverageConcurrentRequests
 		avgProxiedConcurrency += stat.AverageProxiedConcurrentRequests
 		reqCount += stat.RequestCount
 		proxiedReqCount += stat.ProxiedRequestCount
+		cpuUsage += stat.AverageCPUUsage
+		memoryUsage += stat.MemoryUsage
+		concurrencyLimit += stat.ConcurrencyLimit
+		upgradedConnections += stat.AverageUpgradedConnections
 	}
 
-	frpc := float64(readyPodsCount)
-	avgConcurrency = avgConcurrency / successCount
-	avgProxiedConcurrency = avgProxiedConcurrency / successCount
-	reqCount = reqCount / successCount
-	proxiedReqCount = proxiedReqCount / successCount
+	// Scale the sums of the sample to the whole population.
+	f := float64(readyPodsCount) / float64(len(stats))
 	now := time.Now()
 
 	// Assumption: A particular pod can stand for other pods, i.e. other pods
@@ -196,25 +225,24 @@ func (s *ServiceScraper) Scrape() (*StatMessage, error) {
 	// customer pods per scraping. The pod name is set to a unique value, i.e.
 	// scraperPodName so in autoscaler all stats are either from activator or
 	// scraper.
-	extrapolatedStat := Stat{
+	return Stat{
 		Time:                             &now,
 		PodName:                          scraperPodName,
-		AverageConcurrentRequests:        avgConcurrency * frpc,
-		AverageProxiedConcurrentRequests: avgProxiedConcurrency * frpc,
-		RequestCount:                     reqCount * frpc,
-		ProxiedRequestCount:              proxiedReqCount * frpc,
+		AverageConcurrentRequests:        avgConcurrency * f,
+		AverageProxiedConcurrentRequests: avgProxiedConcurrency * f,
+		RequestCount:                     reqCount * f,
+		ProxiedRequestCount:              proxiedReqCount * f,
+		AverageCPUUsage:                  cpuUsage * f,
+		MemoryUsage:                      memoryUsage * f,
+		ConcurrencyLimit:                 concurrencyLimit * f,
+		AverageUpgradedConnections:       upgradedConnections * f,
 	}
-
-	return &StatMessage{
-		Stat: extrapolatedStat,
-		Key:  s.metricKey,
-	}, nil
 }
 
 // tryScrape runs a single scrape and checks if this pod wasn't already scraped
 // against the given already scraped pods.
-func (s *ServiceScraper) tryScrape(scrapedPods *sync.Map) (*Stat, error) {
-	stat, err := s.sClient.Scrape(s.url)
+func (s *ServiceScraper) tryScrape(scrapedPods *sync.Map, readyPods int) (*Stat, error) {
+	stat, err := s.sClient.Scrape(withReadyPods(s.url, readyPods))
 	if err != nil {
 		return nil, err
 	}
@@ -45,18 +45,30 @@ var (
 			AverageProxiedConcurrentRequests: 2.0,
 			RequestCount:                     5,
 			ProxiedRequestCount:              4,
+			AverageCPUUsage:                  100,
+			MemoryUsage:                      1000,
+			ConcurrencyLimit:                 10,
+			AverageUpgradedConnections:       1,
 		}, {
 			PodName:                          "pod-2",
 			AverageConcurrentRequests:        5.0,
 			AverageProxiedConcurrentRequests: 4.0,
 			RequestCount:                     7,
 			ProxiedRequestCount:              6,
+			AverageCPUUsage:                  200,
+			MemoryUsage:                      2000,
+			ConcurrencyLimit:                 20,
+			AverageUpgradedConnections:       2,
 		}, {
 			PodName:                          "pod-3",
 			AverageConcurrentRequests:        3.0,
 			AverageProxiedConcurrentRequests: 2.0,
 			RequestCount:                     5,
 			ProxiedRequestCount:              4,
+			AverageCPUUsage:                  300,
+			MemoryUsage:                      3000,
+			ConcurrencyLimit:                 30,
+			AverageUpgradedConnections:       3,
 		},
 	}
 )
@@ -140,10 +152,14 @@ func TestScrapeReportStatWhenAllCallsSucceed(t *testing.T) {
 
 	// Scrape will set a timestamp bigger than this.
 	now := time.Now()
-	got, err := scraper.Scrape()
+	messages, err := scraper.Scrape()
 	if err != nil {
 		t.Fatalf("unexpected error from scraper.Scrape(): %v", err)
 	}
+	if len(messages) != 1 {
+		t.Fatalf("len(messages)=%d, want 1", len(messages))
+	}
+	got := messages[0]
 
 	if got.Key != testPAKey {
 		t.Errorf("StatMessage.Key=%v, want %v", got.Key, testPAKey)
@@ -172,6 +188,22 @@ func TestScrapeReportStatWhenAllCallsSucceed(t *testing.T) {
 	if got.Stat.ProxiedRequestCount != 14 {
 		t.Errorf("StatMessage.Stat.ProxiedCount=%v, want %v", got.Stat.ProxiedRequestCount, 12)
 	}
+	// ((100 + 200 + 300) / 3.0) * 3 = 600
+	if got.Stat.AverageCPUUsage != 600 {
+		t.Errorf("StatMessage.Stat.AverageCPUUsage=%v, want %v", got.Stat.AverageCPUUsage, 600)
+	}
+	// ((1000 + 2000 + 3000) / 3.0) * 3 = 6000
+	if got.Stat.MemoryUsage != 6000 {
+		t.Errorf("StatMessage.Stat.MemoryUsage=%v, want %v", got.Stat.MemoryUsage, 6000)
+	}
+	// ((10 + 20 + 30) / 3.0) * 3 = 60
+	if got.Stat.ConcurrencyLimit != 60 {
+		t.Errorf("StatMessage.Stat.ConcurrencyLimit=%v, want %v", got.Stat.ConcurrencyLimit, 60)
+	}
+	// ((1 + 2 + 3) / 3.0) * 3 = 6
+	if got.Stat.AverageUpgradedConnections != 6 {
+		t.Errorf("StatMessage.Stat.AverageUpgradedConnections=%v, want %v", got.Stat.AverageUpgradedConnections, 6)
+	}
 }
 
 func TestScrapeReportErrorCannotFindEnoughPods(t *testing.T) {
@@ -220,11 +252,11 @@ func TestScrapeDoNotScrapeIfNoPodsFound(t *testing.T) {
 	// Make an Endpoints with 0 pods.
 	endpoints(0, testService)
 
-	stat, err := scraper.Scrape()
+	messages, err := scraper.Scrape()
 	if err != nil {
 		t.Fatalf("got error from scraper.Scrape() = %v", err)
 	}
-	if stat != nil {
+	if len(messages) != 0 {
 		t.Error("Received unexpected StatMessage.")
 	}
 }
@@ -279,3 +311,9 @@ func TestURLFromTarget(t *testing.T) {
 		t.Errorf("urlFromTarget = %s, want: %s, diff: %s", got, want, cmp.Diff(got, want))
 	}
 }
+
+func TestWithReadyPods(t *testing.T) {
+	if got, want := withReadyPods("http://dance.now:9090/metrics", 5), "http://dance.now:9090/metrics?readyPods=5"; got != want {
+		t.Errorf("withReadyPods = %s, want: %s", got, want)
+	}
+}
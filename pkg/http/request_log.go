@@ -18,25 +18,95 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
 	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/tracecontext"
 )
 
+const (
+	// RequestLogFormatTemplate renders the request logs with a Go text/template.
+	RequestLogFormatTemplate = "template"
+	// RequestLogFormatJSON renders the request logs as JSON objects.
+	RequestLogFormatJSON = "json"
+)
+
+// RequestLogFields are the fields of JSON request logs, in their default order.
+var RequestLogFields = []string{
+	"method", "path", "host", "protocol", "userAgent", "remoteIp", "referer", "requestSize",
+	"status", "responseSize", "latency", "traceId",
+	"revision", "namespace", "service", "configuration", "podName", "podIp",
+}
+
+// RequestLogConfig defines which requests are logged and how.
+type RequestLogConfig struct {
+	// Format is either RequestLogFormatTemplate, the default, or RequestLogFormatJSON.
+	Format string
+	// Template is the template request logs are rendered with in the template
+	// format. An empty template turns off writing request logs in that format.
+	Template string
+	// Fields are the fields of JSON request logs. Empty means RequestLogFields.
+	Fields []string
+	// SampleRate is the fraction of requests, in [0, 1], that are logged. The
+	// decision is made when the request comes in.
+	SampleRate float64
+	// AlwaysLogErrors makes the requests answered with a 5xx status logged
+	// whether they were sampled or not.
+	AlwaysLogErrors bool
+	// SlowThreshold makes the requests taking at least that long logged whether
+	// they were sampled or not, if greater than 0.
+	SlowThreshold time.Duration
+}
+
+// Validate returns an error if the format or the fields are unknown, or the
+// sample rate is out of bounds.
+func (c RequestLogConfig) Validate() error {
+	switch c.Format {
+	case "", RequestLogFormatTemplate, RequestLogFormatJSON:
+	default:
+		return fmt.Errorf("unknown request log format %q", c.Format)
+	}
+	for _, f := range c.Fields {
+		if !knownRequestLogField(f) {
+			return fmt.Errorf("unknown request log field %q", f)
+		}
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("request log sample rate %v is not in [0, 1]", c.SampleRate)
+	}
+	if c.SlowThreshold < 0 {
+		return fmt.Errorf("request log slow threshold %v is negative", c.SlowThreshold)
+	}
+	return nil
+}
+
+func knownRequestLogField(field string) bool {
+	for _, f := range RequestLogFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 // RequestLogHandler implements an http.Handler that writes request logs
 // and calls the next handler.
 type RequestLogHandler struct {
 	handler     http.Handler
 	inputGetter RequestLogTemplateInputGetter
 	writer      io.Writer
-	templateMux sync.RWMutex
-	templateStr string
+	configMux   sync.RWMutex
+	config      RequestLogConfig
 	template    *template.Template
 }
 
@@ -95,13 +165,25 @@ func NewRequestLogHandler(h http.Handler, w io.Writer, templateStr string,
 	return reqHandler, nil
 }
 
-// SetTemplate sets the template to use for formatting request logs.
+// SetTemplate sets the template to use for formatting request logs, and
+// makes all requests logged in the template format.
 // Setting the template to an empty string turns of writing request logs.
 func (h *RequestLogHandler) SetTemplate(templateStr string) error {
+	return h.SetConfig(RequestLogConfig{
+		Template:   templateStr,
+		SampleRate: 1,
+	})
+}
+
+// SetConfig sets which requests are logged and how.
+func (h *RequestLogHandler) SetConfig(config RequestLogConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
 	var t *template.Template
-	// If templateStr is empty, we will set the template to nil
-	// and effectively disable request logs.
-	if templateStr != "" {
+	// If the template is empty, we will set the template to nil
+	// and effectively disable request logs in the template format.
+	if templateStr := config.Template; templateStr != "" {
 		// Make sure that the template ends with a newline. Otherwise,
 		// logging backends will not be able to parse entries separately.
 		if !strings.HasSuffix(templateStr, "\n") {
@@ -112,30 +194,35 @@ func (h *RequestLogHandler) SetTemplate(templateStr string) error {
 		if err != nil {
 			return err
 		}
+		config.Template = templateStr
+	}
+	if len(config.Fields) == 0 {
+		config.Fields = RequestLogFields
 	}
 
-	h.templateMux.Lock()
-	defer h.templateMux.Unlock()
-	h.templateStr = templateStr
+	h.configMux.Lock()
+	defer h.configMux.Unlock()
+	h.config = config
 	h.template = t
 	return nil
 }
 
-func (h *RequestLogHandler) getTemplate() *template.Template {
-	h.templateMux.RLock()
-	defer h.templateMux.RUnlock()
-	return h.template
+func (h *RequestLogHandler) getConfig() (RequestLogConfig, *template.Template) {
+	h.configMux.RLock()
+	defer h.configMux.RUnlock()
+	return h.config, h.template
 }
 
 func (h *RequestLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	t := h.getTemplate()
-	if t == nil {
+	config, t := h.getConfig()
+	if t == nil && config.Format != RequestLogFormatJSON {
 		h.handler.ServeHTTP(w, r)
 		return
 	}
 
 	rr := NewResponseRecorder(w, http.StatusOK)
 	startTime := time.Now()
+	sampled := config.SampleRate >= 1 || rand.Float64() < config.SampleRate
 
 	defer func() {
 		// Filter probe requests for request logs.
@@ -147,20 +234,26 @@ func (h *RequestLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		// If ServeHTTP panics, recover, record the failure and panic again.
 		err := recover()
-		latency := time.Since(startTime).Seconds()
+		latency := time.Since(startTime)
+		resp := &RequestLogResponse{
+			Code:    rr.ResponseCode,
+			Latency: latency.Seconds(),
+			Size:    (int)(rr.ResponseSize),
+		}
+		if err != nil {
+			resp.Code = http.StatusInternalServerError
+			resp.Size = 0
+		}
+		if sampled || (config.AlwaysLogErrors && resp.Code >= http.StatusInternalServerError) ||
+			(config.SlowThreshold > 0 && latency >= config.SlowThreshold) {
+			if config.Format == RequestLogFormatJSON {
+				h.writeJSON(config.Fields, h.inputGetter(r, resp))
+			} else {
+				h.write(t, h.inputGetter(r, resp))
+			}
+		}
 		if err != nil {
-			h.write(t, h.inputGetter(r, &RequestLogResponse{
-				Code:    http.StatusInternalServerError,
-				Latency: latency,
-				Size:    0,
-			}))
 			panic(err)
-		} else {
-			h.write(t, h.inputGetter(r, &RequestLogResponse{
-				Code:    rr.ResponseCode,
-				Latency: latency,
-				Size:    (int)(rr.ResponseSize),
-			}))
 		}
 	}()
 
@@ -179,3 +272,84 @@ func (h *RequestLogHandler) write(t *template.Template, in *RequestLogTemplateIn
 	}
 	h.writer.Write(w.Bytes())
 }
+
+// writeJSON writes the given fields of the request log as a JSON object
+// on a single line.
+func (h *RequestLogHandler) writeJSON(fields []string, in *RequestLogTemplateInput) {
+	w := &bytes.Buffer{}
+	w.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		value, err := json.Marshal(requestLogField(field, in))
+		if err != nil {
+			value = []byte("null")
+		}
+		fmt.Fprintf(w, "%q:%s", field, value)
+	}
+	w.WriteString("}\n")
+	h.writer.Write(w.Bytes())
+}
+
+// requestLogField returns the value of a field of JSON request logs.
+func requestLogField(field string, in *RequestLogTemplateInput) interface{} {
+	r, resp, rev := in.Request, in.Response, in.Revision
+	if rev == nil {
+		rev = &RequestLogRevision{}
+	}
+	switch field {
+	case "method":
+		return r.Method
+	case "path":
+		return r.URL.Path
+	case "host":
+		return r.Host
+	case "protocol":
+		return r.Proto
+	case "userAgent":
+		return r.UserAgent()
+	case "remoteIp":
+		return r.RemoteAddr
+	case "referer":
+		return r.Referer()
+	case "requestSize":
+		return r.ContentLength
+	case "status":
+		return resp.Code
+	case "responseSize":
+		return resp.Size
+	case "latency":
+		return resp.Latency
+	case "traceId":
+		return traceID(r)
+	case "revision":
+		return rev.Name
+	case "namespace":
+		return rev.Namespace
+	case "service":
+		return rev.Service
+	case "configuration":
+		return rev.Configuration
+	case "podName":
+		return rev.PodName
+	case "podIp":
+		return rev.PodIP
+	}
+	return nil
+}
+
+// traceID returns the ID of the trace the request is part of, from its span
+// if it has one or else from its B3 or W3C Trace Context headers.
+func traceID(r *http.Request) string {
+	if span := trace.FromContext(r.Context()); span != nil {
+		return span.SpanContext().TraceID.String()
+	}
+	if id := r.Header.Get(b3.TraceIDHeader); id != "" {
+		return id
+	}
+	if sc, ok := tracecontext.ParseTraceparent(r.Header.Get(tracecontext.TraceparentHeader)); ok {
+		return sc.TraceID.String()
+	}
+	return ""
+}
@@ -20,8 +20,10 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"knative.dev/serving/pkg/network"
 )
@@ -232,3 +234,154 @@ func TestSetTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestSetConfig(t *testing.T) {
+	url, body := "http://example.com/testpage?q=1", "test"
+	tests := []struct {
+		name    string
+		config  RequestLogConfig
+		status  int
+		delay   time.Duration
+		header  http.Header
+		want    string
+		wantErr bool
+	}{{
+		name: "json",
+		config: RequestLogConfig{
+			Format:     RequestLogFormatJSON,
+			SampleRate: 1,
+		},
+		header: http.Header{"X-B3-Traceid": {"abc"}},
+		want: `{"method":"POST","path":"/testpage","host":"example.com","protocol":"HTTP/1.1","userAgent":"",` +
+			`"remoteIp":"192.0.2.1:1234","referer":"","requestSize":4,"status":200,"responseSize":0,"latency":0,` +
+			`"traceId":"abc","revision":"rev","namespace":"ns","service":"svc","configuration":"cfg","podName":"pn","podIp":"ip"}` + "\n",
+	}, {
+		name: "json with fields",
+		config: RequestLogConfig{
+			Format:     RequestLogFormatJSON,
+			Fields:     []string{"status", "method", "revision"},
+			SampleRate: 1,
+		},
+		status: http.StatusNotFound,
+		want:   `{"status":404,"method":"POST","revision":"rev"}` + "\n",
+	}, {
+		name: "json ignores the template",
+		config: RequestLogConfig{
+			Format:     RequestLogFormatJSON,
+			Template:   "{{.Request.URL}}",
+			Fields:     []string{"path"},
+			SampleRate: 1,
+		},
+		want: `{"path":"/testpage"}` + "\n",
+	}, {
+		name: "json with traceparent",
+		config: RequestLogConfig{
+			Format:     RequestLogFormatJSON,
+			Fields:     []string{"traceId"},
+			SampleRate: 1,
+		},
+		header: http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		want:   `{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}` + "\n",
+	}, {
+		name: "not sampled",
+		config: RequestLogConfig{
+			Template:   "{{.Response.Code}}",
+			SampleRate: 0,
+		},
+		want: "",
+	}, {
+		name: "not sampled error",
+		config: RequestLogConfig{
+			Template:        "{{.Response.Code}}",
+			AlwaysLogErrors: true,
+		},
+		status: http.StatusBadGateway,
+		want:   "502\n",
+	}, {
+		name: "not sampled client error",
+		config: RequestLogConfig{
+			Template:        "{{.Response.Code}}",
+			AlwaysLogErrors: true,
+		},
+		status: http.StatusNotFound,
+		want:   "",
+	}, {
+		name: "not sampled slow request",
+		config: RequestLogConfig{
+			Format:        RequestLogFormatJSON,
+			Fields:        []string{"status"},
+			SlowThreshold: 10 * time.Millisecond,
+		},
+		delay: 20 * time.Millisecond,
+		want:  `{"status":200}` + "\n",
+	}, {
+		name: "not sampled fast request",
+		config: RequestLogConfig{
+			Format:        RequestLogFormatJSON,
+			Fields:        []string{"status"},
+			SlowThreshold: time.Second,
+		},
+		want: "",
+	}, {
+		name:    "unknown format",
+		config:  RequestLogConfig{Format: "xml"},
+		wantErr: true,
+	}, {
+		name: "unknown field",
+		config: RequestLogConfig{
+			Format: RequestLogFormatJSON,
+			Fields: []string{"status", "cookie"},
+		},
+		wantErr: true,
+	}, {
+		name:    "sample rate out of bounds",
+		config:  RequestLogConfig{Format: RequestLogFormatJSON, SampleRate: 1.5},
+		wantErr: true,
+	}, {
+		name:    "negative slow threshold",
+		config:  RequestLogConfig{Format: RequestLogFormatJSON, SlowThreshold: -time.Second},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(test.delay)
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+				}
+			})
+			buf := &bytes.Buffer{}
+			handler, err := NewRequestLogHandler(baseHandler, buf, "",
+				RequestLogTemplateInputGetterFromRevision(defaultRevInfo))
+			if err != nil {
+				t.Fatalf("want: no error, got: %v", err)
+			}
+
+			err = handler.SetConfig(test.config)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("got %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			got := buf.String()
+			if test.delay == 0 {
+				// Zero the latency, which varies from run to run.
+				got = latencyRE.ReplaceAllString(got, `"latency":0`)
+			}
+			if got != test.want {
+				t.Errorf("got '%v', want '%v'", got, test.want)
+			}
+		})
+	}
+}
+
+var latencyRE = regexp.MustCompile(`"latency":[0-9.e-]+`)
@@ -62,14 +62,22 @@ func (rr *ResponseRecorder) Flush() {
 // Hijack calls Hijack() on the wrapped http.ResponseWriter if it implements
 // http.Hijacker interface, which is required for net/http/httputil/reverseproxy
 // to handle connection upgrade/switching protocol.  Otherwise returns an error.
+// The response of a hijacked connection is recorded as 101 Switching Protocols,
+// since the handler writes it to the connection directly.
 func (rr *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	c, rw, err := websocket.HijackIfPossible(rr.writer)
-	if err != nil {
-		atomic.StoreInt32(&rr.hijacked, 1)
+	if err == nil && atomic.CompareAndSwapInt32(&rr.hijacked, 0, 1) {
+		rr.ResponseCode = http.StatusSwitchingProtocols
 	}
 	return c, rw, err
 }
 
+// Hijacked returns whether the connection has been hijacked, e.g. to be
+// upgraded to the WebSocket protocol.
+func (rr *ResponseRecorder) Hijacked() bool {
+	return atomic.LoadInt32(&rr.hijacked) == 1
+}
+
 // Header returns the header map that will be sent by WriteHeader.
 func (rr *ResponseRecorder) Header() http.Header {
 	return rr.writer.Header()
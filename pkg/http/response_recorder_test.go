@@ -17,6 +17,9 @@ limitations under the License.
 package http
 
 import (
+	"bufio"
+	"errors"
+	"net"
 	"net/http"
 	"testing"
 
@@ -30,6 +33,13 @@ func (w *fakeResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
 func (w *fakeResponseWriter) WriteHeader(code int)        {}
 func (w *fakeResponseWriter) Flush()                      {}
 
+type fakeHijacker struct {
+	fakeResponseWriter
+	err error
+}
+
+func (w *fakeHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, w.err }
+
 var defaultHeader = http.Header{"item1": {"value1"}}
 
 func TestResponseRecorder(t *testing.T) {
@@ -38,9 +48,11 @@ func TestResponseRecorder(t *testing.T) {
 		initialStatus int
 		finalStatus   int
 		hijack        bool
+		hijackErr     error
 		writeSize     int
 		wantStatus    int
 		wantSize      int32
+		wantHijacked  bool
 	}{{
 		name:          "no hijack",
 		initialStatus: http.StatusAccepted,
@@ -55,13 +67,23 @@ func TestResponseRecorder(t *testing.T) {
 		finalStatus:   http.StatusBadGateway,
 		hijack:        true,
 		writeSize:     12,
-		wantStatus:    http.StatusAccepted,
+		wantStatus:    http.StatusSwitchingProtocols,
+		wantSize:      12,
+		wantHijacked:  true,
+	}, {
+		name:          "failed hijack",
+		initialStatus: http.StatusAccepted,
+		finalStatus:   http.StatusBadGateway,
+		hijack:        true,
+		hijackErr:     errors.New("not hijackable"),
+		writeSize:     12,
+		wantStatus:    http.StatusBadGateway,
 		wantSize:      12,
 	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			rr := NewResponseRecorder(&fakeResponseWriter{}, test.initialStatus)
+			rr := NewResponseRecorder(&fakeHijacker{err: test.hijackErr}, test.initialStatus)
 			if test.hijack {
 				rr.Hijack()
 			}
@@ -77,6 +99,9 @@ func TestResponseRecorder(t *testing.T) {
 			if got, want := rr.ResponseSize, test.wantSize; got != want {
 				t.Errorf("got %v, want %v", got, want)
 			}
+			if got, want := rr.Hijacked(), test.wantHijacked; got != want {
+				t.Errorf("Hijacked() = %v, want %v", got, want)
+			}
 			if diff := cmp.Diff(rr.Header(), defaultHeader); diff != "" {
 				t.Errorf("Headers are different (-want, +got) = %v", diff)
 			}
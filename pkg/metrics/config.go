@@ -17,10 +17,15 @@ limitations under the License.
 package metrics
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	pkghttp "knative.dev/serving/pkg/http"
+	"knative.dev/serving/pkg/otlp"
 )
 
 const (
@@ -40,14 +45,45 @@ type ObservabilityConfig struct {
 	// RequestLogTemplate is the go template to use to shape the request logs.
 	RequestLogTemplate string
 
+	// RequestLogFormat is the format of the request logs, either the template
+	// or JSON. See pkghttp.RequestLogConfig for the meaning of the RequestLog
+	// settings below.
+	RequestLogFormat string
+
+	// RequestLogFields are the fields of JSON request logs.
+	RequestLogFields []string
+
+	// RequestLogSampleRate is the fraction of requests that are logged.
+	RequestLogSampleRate float64
+
+	// RequestLogAlwaysLogErrors specifies whether the requests failing with
+	// a 5xx status are logged whether they were sampled or not.
+	RequestLogAlwaysLogErrors bool
+
+	// RequestLogSlowThreshold is the latency from which requests are logged
+	// whether they were sampled or not, if greater than 0.
+	RequestLogSlowThreshold time.Duration
+
 	// RequestMetricsBackend specifies the request metrics destination, e.g. Prometheus,
 	// Stackdriver.
 	RequestMetricsBackend string
+
+	// OTLPEndpoint is the URL of the OTLP receiver, e.g. of an OpenTelemetry
+	// collector, the metrics and spans are exported to in addition to the
+	// backends, if not empty.
+	OTLPEndpoint string
+
+	// OTLPProtocol is the OTLP transport used to export to the OTLPEndpoint.
+	OTLPProtocol otlp.Protocol
 }
 
 // NewObservabilityConfigFromConfigMap creates a ObservabilityConfig from the supplied ConfigMap
 func NewObservabilityConfigFromConfigMap(configMap *corev1.ConfigMap) (*ObservabilityConfig, error) {
-	oc := &ObservabilityConfig{}
+	oc := &ObservabilityConfig{
+		RequestLogFormat:          pkghttp.RequestLogFormatTemplate,
+		RequestLogSampleRate:      1,
+		RequestLogAlwaysLogErrors: true,
+	}
 	if evlc, ok := configMap.Data["logging.enable-var-log-collection"]; ok {
 		oc.EnableVarLogCollection = strings.ToLower(evlc) == "true"
 	}
@@ -66,9 +102,72 @@ func NewObservabilityConfigFromConfigMap(configMap *corev1.ConfigMap) (*Observab
 		oc.RequestLogTemplate = rlt
 	}
 
+	if f, ok := configMap.Data["logging.request-log-format"]; ok {
+		oc.RequestLogFormat = f
+	}
+
+	if f, ok := configMap.Data["logging.request-log-fields"]; ok {
+		for _, field := range strings.Split(f, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				oc.RequestLogFields = append(oc.RequestLogFields, field)
+			}
+		}
+	}
+
+	if sr, ok := configMap.Data["logging.request-log-sample-rate"]; ok {
+		rate, err := strconv.ParseFloat(sr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse logging.request-log-sample-rate: %v", err)
+		}
+		oc.RequestLogSampleRate = rate
+	}
+
+	if ale, ok := configMap.Data["logging.request-log-always-log-errors"]; ok {
+		oc.RequestLogAlwaysLogErrors = strings.ToLower(ale) == "true"
+	}
+
+	if st, ok := configMap.Data["logging.request-log-slow-threshold"]; ok {
+		threshold, err := time.ParseDuration(st)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse logging.request-log-slow-threshold: %v", err)
+		}
+		oc.RequestLogSlowThreshold = threshold
+	}
+
+	// Verify that we get a valid request log configuration.
+	if err := oc.RequestLogConfig().Validate(); err != nil {
+		return nil, err
+	}
+
 	if mb, ok := configMap.Data["metrics.request-metrics-backend-destination"]; ok {
 		oc.RequestMetricsBackend = mb
 	}
 
+	if ep, ok := configMap.Data["otlp.endpoint"]; ok && ep != "" {
+		if err := otlp.ValidateEndpoint(ep); err != nil {
+			return nil, err
+		}
+		oc.OTLPEndpoint = ep
+	}
+
+	if p, ok := configMap.Data["otlp.protocol"]; ok && p != "" {
+		if err := otlp.ValidateProtocol(otlp.Protocol(p)); err != nil {
+			return nil, err
+		}
+		oc.OTLPProtocol = otlp.Protocol(p)
+	}
+
 	return oc, nil
 }
+
+// RequestLogConfig returns the configuration of the request logs.
+func (oc *ObservabilityConfig) RequestLogConfig() pkghttp.RequestLogConfig {
+	return pkghttp.RequestLogConfig{
+		Format:          oc.RequestLogFormat,
+		Template:        oc.RequestLogTemplate,
+		Fields:          oc.RequestLogFields,
+		SampleRate:      oc.RequestLogSampleRate,
+		AlwaysLogErrors: oc.RequestLogAlwaysLogErrors,
+		SlowThreshold:   oc.RequestLogSlowThreshold,
+	}
+}
@@ -18,12 +18,14 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/metrics"
 	"knative.dev/pkg/system"
+	"knative.dev/serving/pkg/otlp"
 
 	. "knative.dev/pkg/configmap/testing"
 	_ "knative.dev/pkg/system/testing"
@@ -51,10 +53,17 @@ func TestObservabilityConfiguration(t *testing.T) {
 		name:    "observability configuration with all inputs",
 		wantErr: false,
 		wantController: &ObservabilityConfig{
-			LoggingURLTemplate:     "https://logging.io",
-			EnableVarLogCollection: true,
-			RequestLogTemplate:     `{"requestMethod": "{{.Request.Method}}"}`,
-			RequestMetricsBackend:  "stackdriver",
+			LoggingURLTemplate:        "https://logging.io",
+			EnableVarLogCollection:    true,
+			RequestLogTemplate:        `{"requestMethod": "{{.Request.Method}}"}`,
+			RequestLogFormat:          "json",
+			RequestLogFields:          []string{"method", "status", "latency"},
+			RequestLogSampleRate:      0.01,
+			RequestLogAlwaysLogErrors: false,
+			RequestLogSlowThreshold:   2 * time.Second,
+			RequestMetricsBackend:     "stackdriver",
+			OTLPEndpoint:              "http://otel-collector.observability:4317",
+			OTLPProtocol:              otlp.ProtocolGRPC,
 		},
 		config: &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
@@ -66,17 +75,27 @@ func TestObservabilityConfiguration(t *testing.T) {
 				"logging.revision-url-template":               "https://logging.io",
 				"logging.write-request-logs":                  "true",
 				"logging.request-log-template":                `{"requestMethod": "{{.Request.Method}}"}`,
+				"logging.request-log-format":                  "json",
+				"logging.request-log-fields":                  "method, status,latency",
+				"logging.request-log-sample-rate":             "0.01",
+				"logging.request-log-always-log-errors":       "false",
+				"logging.request-log-slow-threshold":          "2s",
 				"metrics.request-metrics-backend-destination": "stackdriver",
+				"otlp.endpoint":                               "http://otel-collector.observability:4317",
+				"otlp.protocol":                               "grpc",
 			},
 		},
 	}, {
 		name:    "observability config with no map",
 		wantErr: false,
 		wantController: &ObservabilityConfig{
-			EnableVarLogCollection: false,
-			LoggingURLTemplate:     defaultLogURLTemplate,
-			RequestLogTemplate:     "",
-			RequestMetricsBackend:  "",
+			EnableVarLogCollection:    false,
+			LoggingURLTemplate:        defaultLogURLTemplate,
+			RequestLogTemplate:        "",
+			RequestLogFormat:          "template",
+			RequestLogSampleRate:      1,
+			RequestLogAlwaysLogErrors: true,
+			RequestMetricsBackend:     "",
 		},
 		config: &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
@@ -97,6 +116,85 @@ func TestObservabilityConfiguration(t *testing.T) {
 				"logging.request-log-template": `{{ something }}`,
 			},
 		},
+	}, {
+		name:           "invalid request log format",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"logging.request-log-format": "xml",
+			},
+		},
+	}, {
+		name:           "invalid request log field",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"logging.request-log-fields": "method,cookie",
+			},
+		},
+	}, {
+		name:           "request log sample rate out of bounds",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"logging.request-log-sample-rate": "2",
+			},
+		},
+	}, {
+		name:           "invalid request log slow threshold",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"logging.request-log-slow-threshold": "slow",
+			},
+		},
+	}, {
+		name:           "invalid otlp endpoint",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"otlp.endpoint": "otel-collector:4317",
+			},
+		},
+	}, {
+		name:           "invalid otlp protocol",
+		wantErr:        true,
+		wantController: (*ObservabilityConfig)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      metrics.ConfigMapName(),
+			},
+			Data: map[string]string{
+				"otlp.endpoint": "http://otel-collector:4317",
+				"otlp.protocol": "http/protobuf",
+			},
+		},
 	}}
 
 	for _, tt := range observabilityConfigTests {
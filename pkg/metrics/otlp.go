@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/serving/pkg/otlp"
+)
+
+// UpdateOTLPExporterFromConfigMap returns a helper func that can be used to
+// update the OTLP exporter of the component when the observability config
+// map is updated.
+func UpdateOTLPExporterFromConfigMap(component string, logger *zap.SugaredLogger) func(configMap *corev1.ConfigMap) {
+	return func(configMap *corev1.ConfigMap) {
+		oc, err := NewObservabilityConfigFromConfigMap(configMap)
+		if err != nil {
+			logger.Errorw("Failed to parse the observability config", zap.Error(err))
+			return
+		}
+		if err := otlp.SetEndpoint(component, oc.OTLPEndpoint, oc.OTLPProtocol, logger); err != nil {
+			logger.Errorw("Failed to update the OTLP exporter", zap.Error(err), "endpoint", oc.OTLPEndpoint)
+		}
+	}
+}
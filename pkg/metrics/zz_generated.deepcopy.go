@@ -23,6 +23,11 @@ package metrics
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObservabilityConfig) DeepCopyInto(out *ObservabilityConfig) {
 	*out = *in
+	if in.RequestLogFields != nil {
+		in, out := &in.RequestLogFields, &out.RequestLogFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -17,11 +17,13 @@ package network
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net"
 	"net/http"
 	"time"
 
+	"golang.org/x/net/http2"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -117,5 +119,20 @@ func NewAutoTransport() http.RoundTripper {
 		NewH2CTransport())
 }
 
+// NewUnixSocketTransport creates a RoundTripper like NewAutoTransport, that
+// dials the Unix domain socket at path for every request, whatever its host.
+func NewUnixSocketTransport(path string) http.RoundTripper {
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialWithBackOff(ctx, "unix", path)
+	}
+	v1 := newHTTPTransport(DefaultConnTimeout, false /*disable keep-alives*/).(*http.Transport)
+	v1.DialContext = dial
+	v2 := NewH2CTransport().(*http2.Transport)
+	v2.DialTLS = func(_, _ string, _ *tls.Config) (net.Conn, error) {
+		return dial(context.Background(), "", "")
+	}
+	return newAutoTransport(v1, v2)
+}
+
 // AutoTransport uses h2c for HTTP2 requests and falls back to `http.DefaultTransport` for all others
 var AutoTransport = NewAutoTransport()
@@ -17,8 +17,12 @@ package network
 
 import (
 	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -98,3 +102,41 @@ func TestDialWithBackoff(t *testing.T) {
 	}
 	c.Close()
 }
+
+func TestUnixSocketTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	s.Listener = l
+	s.Start()
+	defer s.Close()
+
+	// The host of the request is irrelevant, the socket is dialed regardless.
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewUnixSocketTransport(path).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "127.0.0.1:8080"; got != want {
+		t.Errorf("Host = %q, want: %q", got, want)
+	}
+}
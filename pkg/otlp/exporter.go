@@ -0,0 +1,330 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package otlp exports OpenCensus spans and views to an OpenTelemetry
+// collector with the OTLP/HTTP protocol, in its JSON encoding, or with the
+// OTLP/gRPC protocol.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Protocol is the OTLP transport, named like in OTEL_EXPORTER_OTLP_PROTOCOL.
+type Protocol string
+
+const (
+	// ProtocolHTTPJSON posts JSON to the /v1/traces and /v1/metrics paths
+	// of the endpoint.
+	ProtocolHTTPJSON Protocol = "http/json"
+	// ProtocolGRPC calls the Export methods of the OTLP trace and metrics
+	// services on the host of the endpoint, with TLS if it is an https URL.
+	ProtocolGRPC Protocol = "grpc"
+)
+
+const (
+	tracesPath  = "/v1/traces"
+	metricsPath = "/v1/metrics"
+
+	tracesMethod  = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+	metricsMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+	// defaultFlushInterval is how often the buffered spans and views are sent.
+	defaultFlushInterval = 5 * time.Second
+	// maxBufferedSpans bounds the memory used when the collector is slow or
+	// unavailable. Spans beyond it are dropped.
+	maxBufferedSpans = 2048
+	// requestTimeout bounds the time a flush can take.
+	requestTimeout = 10 * time.Second
+)
+
+// ValidateEndpoint checks that the endpoint is the URL of an OTLP receiver,
+// such as http://otel-collector.observability:4318 for OTLP/HTTP or
+// http://otel-collector.observability:4317 for OTLP/gRPC.
+func ValidateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("OTLP endpoint %q is not an http or https URL", endpoint)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("OTLP endpoint %q has no host", endpoint)
+	}
+	return nil
+}
+
+// ValidateProtocol checks that the protocol is a supported OTLP transport.
+func ValidateProtocol(protocol Protocol) error {
+	if protocol != ProtocolHTTPJSON && protocol != ProtocolGRPC {
+		return fmt.Errorf("OTLP protocol %q is neither %q nor %q", protocol, ProtocolHTTPJSON, ProtocolGRPC)
+	}
+	return nil
+}
+
+// Options configures an Exporter.
+type Options struct {
+	// Endpoint is the URL of the OTLP receiver. With OTLP/HTTP, the spans and
+	// the metrics are sent to its /v1/traces and /v1/metrics paths.
+	Endpoint string
+	// Protocol is the OTLP transport. It defaults to OTLP/HTTP.
+	Protocol Protocol
+	// Component is the service.name resource attribute of the spans and
+	// metrics, and the prefix of the metric names.
+	Component string
+	// FlushInterval is how often the spans and views are sent. It defaults
+	// to 5s.
+	FlushInterval time.Duration
+	// Client is the client sending the OTLP/HTTP requests. It defaults to a
+	// client timing out after 10s.
+	Client *http.Client
+	// Logger logs the failures to send in the background, if set.
+	Logger *zap.SugaredLogger
+}
+
+// Exporter is a trace.Exporter and view.Exporter buffering spans and views
+// and sending them to the OTLP receiver in the background.
+type Exporter struct {
+	opts     Options
+	resource resource
+	// conn is the connection to the OTLP/gRPC receiver, nil with OTLP/HTTP.
+	conn *grpc.ClientConn
+
+	mux   sync.Mutex
+	spans []*trace.SpanData
+	// views holds the latest data of every view. The views are cumulative,
+	// so older data is superseded.
+	views map[string]*view.Data
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+var (
+	_ trace.Exporter = (*Exporter)(nil)
+	_ view.Exporter  = (*Exporter)(nil)
+)
+
+// NewExporter creates an Exporter and starts sending to the receiver.
+// Close stops it.
+func NewExporter(opts Options) (*Exporter, error) {
+	if err := ValidateEndpoint(opts.Endpoint); err != nil {
+		return nil, err
+	}
+	if opts.Component == "" {
+		return nil, errors.New("component must not be empty")
+	}
+	if opts.Protocol == "" {
+		opts.Protocol = ProtocolHTTPJSON
+	}
+	if err := ValidateProtocol(opts.Protocol); err != nil {
+		return nil, err
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: requestTimeout}
+	}
+
+	e := &Exporter{
+		opts:     opts,
+		resource: newResource(opts.Component),
+		views:    make(map[string]*view.Data),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	if opts.Protocol == ProtocolGRPC {
+		conn, err := dial(opts.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		e.conn = conn
+	}
+	go e.run()
+	return e, nil
+}
+
+// dial connects to the OTLP/gRPC receiver at the host of the endpoint in
+// the background.
+func dial(endpoint string) (*grpc.ClientConn, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	security := grpc.WithInsecure()
+	if u.Scheme == "https" {
+		security = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	return grpc.Dial(u.Host, security)
+}
+
+// ExportSpan buffers the span.
+func (e *Exporter) ExportSpan(sd *trace.SpanData) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if len(e.spans) < maxBufferedSpans {
+		e.spans = append(e.spans, sd)
+	}
+}
+
+// ExportView buffers the view data.
+func (e *Exporter) ExportView(vd *view.Data) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.views[vd.View.Name] = vd
+}
+
+func (e *Exporter) run() {
+	defer close(e.doneCh)
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flushAndLog()
+		case <-e.stopCh:
+			e.flushAndLog()
+			return
+		}
+	}
+}
+
+func (e *Exporter) flushAndLog() {
+	if err := e.Flush(); err != nil && e.opts.Logger != nil {
+		e.opts.Logger.Errorw("Failed to export to the OTLP endpoint", zap.Error(err))
+	}
+}
+
+// Close stops the Exporter after sending what is buffered.
+func (e *Exporter) Close() error {
+	close(e.stopCh)
+	<-e.doneCh
+	if e.conn != nil {
+		return e.conn.Close()
+	}
+	return nil
+}
+
+// Flush sends the buffered spans and views. They are dropped if sending
+// fails, as retrying could pile them up indefinitely.
+func (e *Exporter) Flush() error {
+	e.mux.Lock()
+	spans, views := e.spans, e.views
+	e.spans, e.views = nil, make(map[string]*view.Data, len(views))
+	e.mux.Unlock()
+
+	var errs []string
+	if len(spans) > 0 {
+		if err := e.send(tracesPath, tracesMethod, e.tracesRequest(spans)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(views) > 0 {
+		if err := e.send(metricsPath, metricsMethod, e.metricsRequest(views)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// request is an OTLP export request, which is encoded as JSON for OTLP/HTTP
+// and as protobuf for OTLP/gRPC.
+type request interface {
+	marshalProto() []byte
+}
+
+// send sends the request to the path of the OTLP/HTTP receiver or calls the
+// method of the OTLP/gRPC receiver with it.
+func (e *Exporter) send(path, method string, req request) error {
+	if e.conn == nil {
+		return e.post(path, req)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	// The response only reports partially rejected data, which is not retried either.
+	var resp []byte
+	if err := e.conn.Invoke(ctx, method, req.marshalProto(), &resp, grpc.CallCustomCodec(rawCodec{})); err != nil {
+		return fmt.Errorf("failed to call %s: %v", method, err)
+	}
+	return nil
+}
+
+// rawCodec passes the messages, which are encoded already, through as is.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// String is the content-subtype of the messages, which are protobuf.
+func (rawCodec) String() string {
+	return "proto"
+}
+
+func (e *Exporter) post(path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := e.opts.Client.Post(e.opts.Endpoint+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to send to %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sending to %s failed with status %d", path, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+)
+
+// fakeReceiver records the bodies sent to an OTLP/HTTP receiver by path.
+type fakeReceiver struct {
+	*httptest.Server
+
+	mux    sync.Mutex
+	bodies map[string][]map[string]interface{}
+	status int
+}
+
+func newFakeReceiver(t *testing.T) *fakeReceiver {
+	f := &fakeReceiver{bodies: make(map[string][]map[string]interface{}), status: http.StatusOK}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Content-Type"), "application/json"; got != want {
+			t.Errorf("Content-Type = %q, want: %q", got, want)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Errorf("Failed to parse the body %s: %v", b, err)
+		}
+		f.mux.Lock()
+		defer f.mux.Unlock()
+		f.bodies[r.URL.Path] = append(f.bodies[r.URL.Path], body)
+		w.WriteHeader(f.status)
+	}))
+	return f
+}
+
+func (f *fakeReceiver) received(path string) []map[string]interface{} {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.bodies[path]
+}
+
+// jsonOf returns v as it is parsed from JSON.
+func jsonOf(t *testing.T, v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	for endpoint, wantErr := range map[string]bool{
+		"http://otel-collector.observability:4318": false,
+		"https://otel-collector.example.com/otlp/": false,
+		"otel-collector:4318":                      true,
+		"grpc://otel-collector:4317":               true,
+		"http://":                                  true,
+		"%":                                        true,
+	} {
+		if err := ValidateEndpoint(endpoint); (err != nil) != wantErr {
+			t.Errorf("ValidateEndpoint(%q) = %v, want error: %v", endpoint, err, wantErr)
+		}
+	}
+}
+
+func TestNewExporterErrors(t *testing.T) {
+	if _, err := NewExporter(Options{Endpoint: "otel-collector:4318", Component: "activator"}); err == nil {
+		t.Error("NewExporter() succeeded with an invalid endpoint, want error")
+	}
+	if _, err := NewExporter(Options{Endpoint: "http://otel-collector:4318"}); err == nil {
+		t.Error("NewExporter() succeeded without a component, want error")
+	}
+	if _, err := NewExporter(Options{Endpoint: "http://otel-collector:4318", Protocol: "http/protobuf", Component: "activator"}); err == nil {
+		t.Error("NewExporter() succeeded with an unsupported protocol, want error")
+	}
+}
+
+// fakeGRPCReceiver records the messages sent to an OTLP/gRPC receiver by method.
+type fakeGRPCReceiver struct {
+	*grpc.Server
+	addr string
+
+	mux      sync.Mutex
+	messages map[string][][]byte
+}
+
+func newFakeGRPCReceiver(t *testing.T) *fakeGRPCReceiver {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() = %v", err)
+	}
+	f := &fakeGRPCReceiver{addr: lis.Addr().String(), messages: make(map[string][][]byte)}
+	f.Server = grpc.NewServer(grpc.CustomCodec(rawCodec{}), grpc.UnknownServiceHandler(
+		func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			var msg []byte
+			if err := stream.RecvMsg(&msg); err != nil {
+				return err
+			}
+			f.mux.Lock()
+			f.messages[method] = append(f.messages[method], msg)
+			f.mux.Unlock()
+			return stream.SendMsg([]byte{})
+		}))
+	go f.Serve(lis)
+	return f
+}
+
+func (f *fakeGRPCReceiver) received(method string) [][]byte {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.messages[method]
+}
+
+func TestExportGRPC(t *testing.T) {
+	receiver := newFakeGRPCReceiver(t)
+	defer receiver.Stop()
+	e, err := NewExporter(Options{Endpoint: "http://" + receiver.addr, Protocol: ProtocolGRPC,
+		Component: "activator", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() = %v", err)
+	}
+	defer e.Close()
+
+	sd := &trace.SpanData{Name: "/users", StartTime: time.Unix(1, 0), EndTime: time.Unix(2, 0)}
+	e.ExportSpan(sd)
+	vd := &view.Data{
+		View: &view.View{Name: "request_count", Measure: stats.Int64("request_count", "", stats.UnitDimensionless),
+			Aggregation: view.Count()},
+		Rows: []*view.Row{{Data: &view.CountData{Value: 3}}},
+	}
+	e.ExportView(vd)
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	if got, want := receiver.received(tracesMethod), [][]byte{e.tracesRequest([]*trace.SpanData{sd}).marshalProto()}; !cmp.Equal(got, want) {
+		t.Errorf("Sent traces (-want, +got) = %s", cmp.Diff(want, got))
+	}
+	if got, want := receiver.received(metricsMethod), [][]byte{e.metricsRequest(map[string]*view.Data{"request_count": vd}).marshalProto()}; !cmp.Equal(got, want) {
+		t.Errorf("Sent metrics (-want, +got) = %s", cmp.Diff(want, got))
+	}
+
+	// Failing calls are reported.
+	receiver.Stop()
+	e.ExportSpan(sd)
+	if err := e.Flush(); err == nil {
+		t.Error("Flush() succeeded without a receiver, want error")
+	}
+}
+
+func TestExportSpan(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	defer receiver.Close()
+	e, err := NewExporter(Options{Endpoint: receiver.URL + "/", Component: "activator", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() = %v", err)
+	}
+	defer e.Close()
+
+	start := time.Unix(1, 0)
+	e.ExportSpan(&trace.SpanData{
+		SpanContext: trace.SpanContext{
+			TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		ParentSpanID: trace.SpanID{8, 7, 6, 5, 4, 3, 2, 1},
+		SpanKind:     trace.SpanKindServer,
+		Name:         "/users",
+		StartTime:    start,
+		EndTime:      start.Add(time.Second),
+		Attributes: map[string]interface{}{
+			"http.status_code": int64(503),
+			"http.path":        "/users",
+		},
+		Annotations: []trace.Annotation{{Time: start, Message: "retrying"}},
+		Status:      trace.Status{Code: 14, Message: "unavailable"},
+	})
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	got := receiver.received(tracesPath)
+	want := jsonOf(t, map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []interface{}{map[string]interface{}{
+					"key":   "service.name",
+					"value": map[string]interface{}{"stringValue": "activator"},
+				}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "knative.dev/serving"},
+				"spans": []interface{}{map[string]interface{}{
+					"traceId":           "0102030405060708090a0b0c0d0e0f10",
+					"spanId":            "0102030405060708",
+					"parentSpanId":      "0807060504030201",
+					"name":              "/users",
+					"kind":              2,
+					"startTimeUnixNano": "1000000000",
+					"endTimeUnixNano":   "2000000000",
+					"attributes": []interface{}{map[string]interface{}{
+						"key":   "http.path",
+						"value": map[string]interface{}{"stringValue": "/users"},
+					}, map[string]interface{}{
+						"key":   "http.status_code",
+						"value": map[string]interface{}{"intValue": "503"},
+					}},
+					"events": []interface{}{map[string]interface{}{
+						"timeUnixNano": "1000000000",
+						"name":         "retrying",
+					}},
+					"status": map[string]interface{}{"code": 2, "message": "unavailable"},
+				}},
+			}},
+		}},
+	})
+	if len(got) != 1 || !cmp.Equal(jsonOf(t, got[0]), want) {
+		t.Errorf("Sent traces (-want, +got) = %s", cmp.Diff(want, got))
+	}
+
+	// Nothing is sent without new spans.
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+	if got := len(receiver.received(tracesPath)); got != 1 {
+		t.Errorf("Sent traces %d times, want: 1", got)
+	}
+}
+
+func TestExportView(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	defer receiver.Close()
+	e, err := NewExporter(Options{Endpoint: receiver.URL, Component: "queue-proxy", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() = %v", err)
+	}
+	defer e.Close()
+
+	key, _ := tag.NewKey("response_code")
+	measure := stats.Float64("request_latencies", "The latencies", stats.UnitMilliseconds)
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+	latencies := &view.Data{
+		View: &view.View{
+			Name:        "request_latencies",
+			Description: "The latencies",
+			Measure:     measure,
+			Aggregation: view.Distribution(10, 100),
+		},
+		Start: start,
+		End:   end,
+		Rows: []*view.Row{{
+			Tags: []tag.Tag{{Key: key, Value: "200"}},
+			Data: &view.DistributionData{Count: 2, Min: 5, Max: 50, Mean: 27.5, CountPerBucket: []int64{1, 1, 0}},
+		}},
+	}
+	// Only the latest data of a view is sent.
+	e.ExportView(latencies)
+	e.ExportView(latencies)
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        "request_count",
+			Measure:     stats.Int64("request_count", "The requests", stats.UnitDimensionless),
+			Aggregation: view.Count(),
+		},
+		Start: start,
+		End:   end,
+		Rows: []*view.Row{{
+			Tags: []tag.Tag{{Key: key, Value: "200"}},
+			Data: &view.CountData{Value: 3},
+		}},
+	})
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        "queue_depth",
+			Measure:     stats.Int64("queue_depth", "The depth", stats.UnitDimensionless),
+			Aggregation: view.LastValue(),
+		},
+		Start: start,
+		End:   end,
+		Rows:  []*view.Row{{Data: &view.LastValueData{Value: 4}}},
+	})
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	attrs := []interface{}{map[string]interface{}{
+		"key":   "response_code",
+		"value": map[string]interface{}{"stringValue": "200"},
+	}}
+	want := jsonOf(t, []interface{}{map[string]interface{}{
+		"name": "queue_depth",
+		"unit": "1",
+		"gauge": map[string]interface{}{
+			"dataPoints": []interface{}{map[string]interface{}{
+				"startTimeUnixNano": "1000000000",
+				"timeUnixNano":      "2000000000",
+				"asDouble":          4,
+			}},
+		},
+	}, map[string]interface{}{
+		"name": "request_count",
+		"unit": "1",
+		"sum": map[string]interface{}{
+			"dataPoints": []interface{}{map[string]interface{}{
+				"attributes":        attrs,
+				"startTimeUnixNano": "1000000000",
+				"timeUnixNano":      "2000000000",
+				"asInt":             "3",
+			}},
+			"aggregationTemporality": 2,
+			"isMonotonic":            true,
+		},
+	}, map[string]interface{}{
+		"name":        "request_latencies",
+		"description": "The latencies",
+		"unit":        "ms",
+		"histogram": map[string]interface{}{
+			"dataPoints": []interface{}{map[string]interface{}{
+				"attributes":        attrs,
+				"startTimeUnixNano": "1000000000",
+				"timeUnixNano":      "2000000000",
+				"count":             "2",
+				"sum":               55,
+				"bucketCounts":      []interface{}{"1", "1", "0"},
+				"explicitBounds":    []interface{}{10, 100},
+				"min":               5,
+				"max":               50,
+			}},
+			"aggregationTemporality": 2,
+		},
+	}})
+
+	got := receiver.received(metricsPath)
+	if len(got) != 1 {
+		t.Fatalf("Sent metrics %d times, want: 1", len(got))
+	}
+	metrics := got[0]["resourceMetrics"].([]interface{})[0].(map[string]interface{})["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"]
+	if !cmp.Equal(metrics, want) {
+		t.Errorf("Sent metrics (-want, +got) = %s", cmp.Diff(want, metrics))
+	}
+}
+
+func TestFlushError(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	defer receiver.Close()
+	receiver.status = http.StatusServiceUnavailable
+	e, err := NewExporter(Options{Endpoint: receiver.URL, Component: "autoscaler", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() = %v", err)
+	}
+	defer e.Close()
+
+	e.ExportSpan(&trace.SpanData{Name: "test"})
+	if err := e.Flush(); err == nil {
+		t.Error("Flush() succeeded, want error")
+	}
+}
+
+func TestCloseFlushes(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	defer receiver.Close()
+	e, err := NewExporter(Options{Endpoint: receiver.URL, Component: "autoscaler", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewExporter() = %v", err)
+	}
+
+	e.ExportSpan(&trace.SpanData{Name: "test"})
+	e.Close()
+	if got := len(receiver.received(tracesPath)); got != 1 {
+		t.Errorf("Sent traces %d times, want: 1", got)
+	}
+}
+
+func TestSetEndpoint(t *testing.T) {
+	receiver := newFakeReceiver(t)
+	defer receiver.Close()
+
+	if err := SetEndpoint("activator", "otel-collector:4318", "", nil); err == nil {
+		t.Error("SetEndpoint() succeeded with an invalid endpoint, want error")
+	}
+	if Enabled() {
+		t.Error("Enabled() = true before the endpoint is set")
+	}
+	if err := SetEndpoint("activator", receiver.URL, "", nil); err != nil {
+		t.Fatalf("SetEndpoint() = %v", err)
+	}
+	if !Enabled() {
+		t.Error("Enabled() = false after the endpoint is set")
+	}
+	if err := SetEndpoint("activator", "", "", nil); err != nil {
+		t.Fatalf("SetEndpoint() = %v", err)
+	}
+	if Enabled() {
+		t.Error("Enabled() = true after the endpoint is removed")
+	}
+}
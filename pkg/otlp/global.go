@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"sync"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	tracingconfig "knative.dev/pkg/tracing/config"
+)
+
+// OpenCensus keeps its exporters in globals, so there is one OTLP exporter
+// per process.
+var (
+	globalMux      sync.Mutex
+	globalExporter *Exporter
+	globalEndpoint string
+	globalProtocol Protocol
+	globalTracing  *tracingconfig.Config
+)
+
+// SetEndpoint exports the spans and views of the component to the OTLP
+// endpoint with the protocol, in addition to the tracing and metrics
+// backends, replacing the previous endpoint. An empty endpoint stops the
+// export, and an empty protocol defaults to OTLP/HTTP.
+func SetEndpoint(component, endpoint string, protocol Protocol, logger *zap.SugaredLogger) error {
+	globalMux.Lock()
+	defer globalMux.Unlock()
+	if protocol == "" {
+		protocol = ProtocolHTTPJSON
+	}
+	if endpoint == globalEndpoint && protocol == globalProtocol {
+		return nil
+	}
+
+	var exporter *Exporter
+	if endpoint != "" {
+		var err error
+		exporter, err = NewExporter(Options{Endpoint: endpoint, Protocol: protocol, Component: component, Logger: logger})
+		if err != nil {
+			return err
+		}
+		trace.RegisterExporter(exporter)
+		view.RegisterExporter(exporter)
+	}
+	if globalExporter != nil {
+		trace.UnregisterExporter(globalExporter)
+		view.UnregisterExporter(globalExporter)
+		globalExporter.Close()
+	}
+	globalExporter, globalEndpoint, globalProtocol = exporter, endpoint, protocol
+	applySampler()
+	return nil
+}
+
+// Enabled returns whether spans are exported with OTLP, so they have to be
+// created even without a tracing backend.
+func Enabled() bool {
+	globalMux.Lock()
+	defer globalMux.Unlock()
+	return globalExporter != nil
+}
+
+// ApplyTracingConfig samples the spans exported with OTLP as configured in
+// config-tracing, even if its backend is none. It has to be called after the
+// OpenCensusTracer applied the config, which disables sampling without a
+// backend.
+func ApplyTracingConfig(cfg *tracingconfig.Config) {
+	globalMux.Lock()
+	defer globalMux.Unlock()
+	globalTracing = cfg
+	applySampler()
+}
+
+// applySampler has to be called with globalMux held.
+func applySampler() {
+	if globalTracing == nil || globalTracing.Backend != tracingconfig.None {
+		// The OpenCensusTracer samples for its backend already.
+		return
+	}
+	sampler := trace.NeverSample()
+	if globalExporter != nil {
+		if globalTracing.Debug {
+			sampler = trace.AlwaysSample()
+		} else {
+			sampler = trace.ProbabilitySampler(globalTracing.SampleRate)
+		}
+	}
+	trace.ApplyConfig(trace.Config{DefaultSampler: sampler})
+}
+
+// Flush sends the spans and views buffered by the exporter, if any.
+func Flush() error {
+	globalMux.Lock()
+	defer globalMux.Unlock()
+	if globalExporter == nil {
+		return nil
+	}
+	return globalExporter.Flush()
+}
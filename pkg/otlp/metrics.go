@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"sort"
+	"strconv"
+
+	"go.opencensus.io/stats/view"
+)
+
+// aggregationTemporalityCumulative is the temporality of all OpenCensus views.
+const aggregationTemporalityCumulative = 2
+
+type exportMetricsServiceRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Sum         *sum       `json:"sum,omitempty"`
+	Gauge       *gauge     `json:"gauge,omitempty"`
+	Histogram   *histogram `json:"histogram,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+	Min               float64    `json:"min"`
+	Max               float64    `json:"max"`
+}
+
+func (e *Exporter) metricsRequest(views map[string]*view.Data) *exportMetricsServiceRequest {
+	metrics := make([]metric, 0, len(views))
+	for _, vd := range views {
+		if m, ok := convertView(vd); ok {
+			metrics = append(metrics, m)
+		}
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return &exportMetricsServiceRequest{
+		ResourceMetrics: []resourceMetrics{{
+			Resource: e.resource,
+			ScopeMetrics: []scopeMetrics{{
+				Scope:   scope{Name: scopeName},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+// convertView converts the rows of the view to data points of the OTLP
+// metric type matching its aggregation: counts and sums are cumulative sums,
+// last values gauges, and distributions histograms.
+func convertView(vd *view.Data) (metric, bool) {
+	m := metric{
+		Name:        vd.View.Name,
+		Description: vd.View.Description,
+		Unit:        vd.View.Measure.Unit(),
+	}
+	start, end := unixNano(vd.Start), unixNano(vd.End)
+	var (
+		numbers    []numberDataPoint
+		histograms []histogramDataPoint
+	)
+	for _, row := range vd.Rows {
+		attrs := make([]keyValue, 0, len(row.Tags))
+		for _, t := range row.Tags {
+			attrs = append(attrs, stringAttribute(t.Key.Name(), t.Value))
+		}
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			value := strconv.FormatInt(data.Value, 10)
+			numbers = append(numbers, numberDataPoint{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsInt: &value,
+			})
+		case *view.SumData:
+			value := data.Value
+			numbers = append(numbers, numberDataPoint{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsDouble: &value,
+			})
+		case *view.LastValueData:
+			value := data.Value
+			numbers = append(numbers, numberDataPoint{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: end, AsDouble: &value,
+			})
+		case *view.DistributionData:
+			counts := make([]string, 0, len(data.CountPerBucket))
+			for _, c := range data.CountPerBucket {
+				counts = append(counts, strconv.FormatInt(c, 10))
+			}
+			histograms = append(histograms, histogramDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				Count:             strconv.FormatInt(data.Count, 10),
+				Sum:               data.Mean * float64(data.Count),
+				BucketCounts:      counts,
+				ExplicitBounds:    vd.View.Aggregation.Buckets,
+				Min:               data.Min,
+				Max:               data.Max,
+			})
+		}
+	}
+
+	switch vd.View.Aggregation.Type {
+	case view.AggTypeCount:
+		m.Sum = &sum{DataPoints: numbers, AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+	case view.AggTypeSum:
+		// Sums of negative values decrease.
+		m.Sum = &sum{DataPoints: numbers, AggregationTemporality: aggregationTemporalityCumulative}
+	case view.AggTypeLastValue:
+		m.Gauge = &gauge{DataPoints: numbers}
+	case view.AggTypeDistribution:
+		m.Histogram = &histogram{DataPoints: histograms, AggregationTemporality: aggregationTemporalityCumulative}
+	default:
+		return metric{}, false
+	}
+	return m, true
+}
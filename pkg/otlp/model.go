@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The types below are the parts of the OTLP protobuf messages Knative sends,
+// in their JSON encoding: 64 bit integers are strings and IDs are hex.
+// See https://github.com/open-telemetry/opentelemetry-proto.
+
+// scopeName is the instrumentation scope of everything Knative exports.
+const scopeName = "knative.dev/serving"
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+func newResource(component string) resource {
+	return resource{Attributes: []keyValue{{
+		Key:   "service.name",
+		Value: anyValue{StringValue: &component},
+	}}}
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// attributes converts OpenCensus attributes, whose values are strings,
+// bools or int64s, sorted by key.
+func attributes(attrs map[string]interface{}) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for k, v := range attrs {
+		var value anyValue
+		switch v := v.(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			continue
+		}
+		kvs = append(kvs, keyValue{Key: k, Value: value})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+func stringAttribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: &value}}
+}
+
+// unixNano formats a time as OTLP timestamps, zero times as 0.
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
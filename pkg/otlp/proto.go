@@ -0,0 +1,329 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+)
+
+// The OTLP/gRPC messages are encoded in the protobuf wire format from the
+// same types as the JSON ones, since the generated OTLP types are not
+// vendored. The field numbers are those of opentelemetry-proto v1.
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoEncoder appends protobuf fields to buf. Like protobuf itself, it
+// omits the scalar fields with their zero value, except for the members of
+// oneofs and optional fields, which are always written.
+type protoEncoder struct {
+	buf []byte
+}
+
+func (e *protoEncoder) tag(field, wireType int) {
+	e.uvarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (e *protoEncoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *protoEncoder) fixed64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *protoEncoder) varintField(field int, v uint64) {
+	if v != 0 {
+		e.tag(field, wireVarint)
+		e.uvarint(v)
+	}
+}
+
+func (e *protoEncoder) boolField(field int, v bool) {
+	if v {
+		e.varintField(field, 1)
+	}
+}
+
+func (e *protoEncoder) fixed64Field(field int, v uint64) {
+	if v != 0 {
+		e.tag(field, wireFixed64)
+		e.fixed64(v)
+	}
+}
+
+// doubleField always writes the value, since the doubles OTLP sends are
+// oneof members or optional.
+func (e *protoEncoder) doubleField(field int, v float64) {
+	e.tag(field, wireFixed64)
+	e.fixed64(math.Float64bits(v))
+}
+
+// bytesField always writes the value, even if empty.
+func (e *protoEncoder) bytesField(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *protoEncoder) stringField(field int, s string) {
+	if s != "" {
+		e.bytesField(field, []byte(s))
+	}
+}
+
+// hexField writes the bytes of an ID in its JSON encoding, if not empty.
+func (e *protoEncoder) hexField(field int, s string) {
+	if b, err := hex.DecodeString(s); err == nil && len(b) > 0 {
+		e.bytesField(field, b)
+	}
+}
+
+// messageField writes the message encoded by encode.
+func (e *protoEncoder) messageField(field int, encode func(*protoEncoder)) {
+	var m protoEncoder
+	encode(&m)
+	e.bytesField(field, m.buf)
+}
+
+func (e *protoEncoder) packedFixed64Field(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	var m protoEncoder
+	for _, v := range vs {
+		m.fixed64(v)
+	}
+	e.bytesField(field, m.buf)
+}
+
+func (e *protoEncoder) packedDoubleField(field int, vs []float64) {
+	if len(vs) == 0 {
+		return
+	}
+	var m protoEncoder
+	for _, v := range vs {
+		m.fixed64(math.Float64bits(v))
+	}
+	e.bytesField(field, m.buf)
+}
+
+// parseUint64 parses the 64 bit integers of the JSON encoding, which this
+// package formats itself.
+func parseUint64(s string) uint64 {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return uint64(v)
+	}
+	return 0
+}
+
+func (r *exportTraceServiceRequest) marshalProto() []byte {
+	var e protoEncoder
+	for i := range r.ResourceSpans {
+		e.messageField(1, r.ResourceSpans[i].encode)
+	}
+	return e.buf
+}
+
+func (r *resourceSpans) encode(e *protoEncoder) {
+	e.messageField(1, r.Resource.encode)
+	for i := range r.ScopeSpans {
+		e.messageField(2, r.ScopeSpans[i].encode)
+	}
+}
+
+func (s *scopeSpans) encode(e *protoEncoder) {
+	e.messageField(1, s.Scope.encode)
+	for i := range s.Spans {
+		e.messageField(2, s.Spans[i].encode)
+	}
+}
+
+func (s *span) encode(e *protoEncoder) {
+	e.hexField(1, s.TraceID)
+	e.hexField(2, s.SpanID)
+	e.stringField(3, s.TraceState)
+	e.hexField(4, s.ParentSpanID)
+	e.stringField(5, s.Name)
+	e.varintField(6, uint64(s.Kind))
+	e.fixed64Field(7, parseUint64(s.StartTimeUnixNano))
+	e.fixed64Field(8, parseUint64(s.EndTimeUnixNano))
+	encodeAttributes(e, 9, s.Attributes)
+	e.varintField(10, uint64(s.DroppedAttributesCount))
+	for i := range s.Events {
+		e.messageField(11, s.Events[i].encode)
+	}
+	e.varintField(12, uint64(s.DroppedEventsCount))
+	for i := range s.Links {
+		e.messageField(13, s.Links[i].encode)
+	}
+	e.varintField(14, uint64(s.DroppedLinksCount))
+	e.messageField(15, s.Status.encode)
+}
+
+func (ev *event) encode(e *protoEncoder) {
+	e.fixed64Field(1, parseUint64(ev.TimeUnixNano))
+	e.stringField(2, ev.Name)
+	encodeAttributes(e, 3, ev.Attributes)
+}
+
+func (l *link) encode(e *protoEncoder) {
+	e.hexField(1, l.TraceID)
+	e.hexField(2, l.SpanID)
+	encodeAttributes(e, 4, l.Attributes)
+}
+
+func (s *status) encode(e *protoEncoder) {
+	e.stringField(2, s.Message)
+	e.varintField(3, uint64(s.Code))
+}
+
+func (r *exportMetricsServiceRequest) marshalProto() []byte {
+	var e protoEncoder
+	for i := range r.ResourceMetrics {
+		e.messageField(1, r.ResourceMetrics[i].encode)
+	}
+	return e.buf
+}
+
+func (r *resourceMetrics) encode(e *protoEncoder) {
+	e.messageField(1, r.Resource.encode)
+	for i := range r.ScopeMetrics {
+		e.messageField(2, r.ScopeMetrics[i].encode)
+	}
+}
+
+func (s *scopeMetrics) encode(e *protoEncoder) {
+	e.messageField(1, s.Scope.encode)
+	for i := range s.Metrics {
+		e.messageField(2, s.Metrics[i].encode)
+	}
+}
+
+func (m *metric) encode(e *protoEncoder) {
+	e.stringField(1, m.Name)
+	e.stringField(2, m.Description)
+	e.stringField(3, m.Unit)
+	switch {
+	case m.Gauge != nil:
+		e.messageField(5, m.Gauge.encode)
+	case m.Sum != nil:
+		e.messageField(7, m.Sum.encode)
+	case m.Histogram != nil:
+		e.messageField(9, m.Histogram.encode)
+	}
+}
+
+func (g *gauge) encode(e *protoEncoder) {
+	for i := range g.DataPoints {
+		e.messageField(1, g.DataPoints[i].encode)
+	}
+}
+
+func (s *sum) encode(e *protoEncoder) {
+	for i := range s.DataPoints {
+		e.messageField(1, s.DataPoints[i].encode)
+	}
+	e.varintField(2, uint64(s.AggregationTemporality))
+	e.boolField(3, s.IsMonotonic)
+}
+
+func (h *histogram) encode(e *protoEncoder) {
+	for i := range h.DataPoints {
+		e.messageField(1, h.DataPoints[i].encode)
+	}
+	e.varintField(2, uint64(h.AggregationTemporality))
+}
+
+func (p *numberDataPoint) encode(e *protoEncoder) {
+	e.fixed64Field(2, parseUint64(p.StartTimeUnixNano))
+	e.fixed64Field(3, parseUint64(p.TimeUnixNano))
+	switch {
+	case p.AsDouble != nil:
+		e.doubleField(4, *p.AsDouble)
+	case p.AsInt != nil:
+		// as_int is a sfixed64.
+		e.tag(6, wireFixed64)
+		e.fixed64(parseUint64(*p.AsInt))
+	}
+	encodeAttributes(e, 7, p.Attributes)
+}
+
+func (p *histogramDataPoint) encode(e *protoEncoder) {
+	e.fixed64Field(2, parseUint64(p.StartTimeUnixNano))
+	e.fixed64Field(3, parseUint64(p.TimeUnixNano))
+	e.fixed64Field(4, parseUint64(p.Count))
+	e.doubleField(5, p.Sum)
+	counts := make([]uint64, 0, len(p.BucketCounts))
+	for _, c := range p.BucketCounts {
+		counts = append(counts, parseUint64(c))
+	}
+	e.packedFixed64Field(6, counts)
+	e.packedDoubleField(7, p.ExplicitBounds)
+	encodeAttributes(e, 9, p.Attributes)
+	e.doubleField(11, p.Min)
+	e.doubleField(12, p.Max)
+}
+
+func (r *resource) encode(e *protoEncoder) {
+	encodeAttributes(e, 1, r.Attributes)
+}
+
+func (s *scope) encode(e *protoEncoder) {
+	e.stringField(1, s.Name)
+}
+
+func encodeAttributes(e *protoEncoder, field int, kvs []keyValue) {
+	for i := range kvs {
+		e.messageField(field, kvs[i].encode)
+	}
+}
+
+func (kv *keyValue) encode(e *protoEncoder) {
+	e.stringField(1, kv.Key)
+	e.messageField(2, kv.Value.encode)
+}
+
+func (v *anyValue) encode(e *protoEncoder) {
+	switch {
+	case v.StringValue != nil:
+		e.bytesField(1, []byte(*v.StringValue))
+	case v.BoolValue != nil:
+		e.tag(2, wireVarint)
+		if *v.BoolValue {
+			e.uvarint(1)
+		} else {
+			e.uvarint(0)
+		}
+	case v.IntValue != nil:
+		e.tag(3, wireVarint)
+		e.uvarint(parseUint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		e.doubleField(4, *v.DoubleValue)
+	}
+}
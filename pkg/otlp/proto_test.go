@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestProtoEncoding(t *testing.T) {
+	str := func(s string) *string { return &s }
+	no := false
+
+	tests := []struct {
+		name   string
+		encode func(*protoEncoder)
+		// want is the hex encoding, with spaces between the fields.
+		want string
+	}{{
+		name:   "string attribute",
+		encode: (&keyValue{Key: "a", Value: anyValue{StringValue: str("b")}}).encode,
+		want:   "0a0161 1203 0a0162",
+	}, {
+		name:   "false attribute",
+		encode: (&keyValue{Key: "ok", Value: anyValue{BoolValue: &no}}).encode,
+		want:   "0a026f6b 1202 1000",
+	}, {
+		name:   "negative int attribute",
+		encode: (&keyValue{Key: "n", Value: anyValue{IntValue: str("-1")}}).encode,
+		want:   "0a016e 120b 18ffffffffffffffffff01",
+	}, {
+		name: "span",
+		encode: (&span{
+			TraceID:           "0102",
+			SpanID:            "03",
+			Name:              "x",
+			Kind:              2,
+			StartTimeUnixNano: "1",
+			Status:            status{Code: 1},
+		}).encode,
+		want: "0a020102 120103 2a0178 3002 390100000000000000 7a02 1801",
+	}, {
+		name:   "int data point",
+		encode: (&numberDataPoint{TimeUnixNano: "5", AsInt: str("-2")}).encode,
+		want:   "190500000000000000 31feffffffffffffff",
+	}, {
+		name: "histogram data point",
+		encode: (&histogramDataPoint{
+			Count:          "2",
+			Sum:            1.5,
+			BucketCounts:   []string{"1", "1"},
+			ExplicitBounds: []float64{1},
+		}).encode,
+		want: "210200000000000000 29000000000000f83f 3210 01000000000000000100000000000000 " +
+			"3a08 000000000000f03f 590000000000000000 610000000000000000",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var e protoEncoder
+			test.encode(&e)
+			if got, want := hex.EncodeToString(e.buf), strings.Replace(test.want, " ", "", -1); got != want {
+				t.Errorf("encode() = %s, want %s", got, want)
+			}
+		})
+	}
+}
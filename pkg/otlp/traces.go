@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// OTLP span kinds.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// OTLP status codes.
+const (
+	statusCodeUnset = 0
+	statusCodeError = 2
+)
+
+type exportTraceServiceRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type span struct {
+	TraceID                string     `json:"traceId"`
+	SpanID                 string     `json:"spanId"`
+	TraceState             string     `json:"traceState,omitempty"`
+	ParentSpanID           string     `json:"parentSpanId,omitempty"`
+	Name                   string     `json:"name"`
+	Kind                   int        `json:"kind"`
+	StartTimeUnixNano      string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano        string     `json:"endTimeUnixNano"`
+	Attributes             []keyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int        `json:"droppedAttributesCount,omitempty"`
+	Events                 []event    `json:"events,omitempty"`
+	DroppedEventsCount     int        `json:"droppedEventsCount,omitempty"`
+	Links                  []link     `json:"links,omitempty"`
+	DroppedLinksCount      int        `json:"droppedLinksCount,omitempty"`
+	Status                 status     `json:"status"`
+}
+
+type event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type link struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+func (e *Exporter) tracesRequest(spans []*trace.SpanData) *exportTraceServiceRequest {
+	converted := make([]span, 0, len(spans))
+	for _, sd := range spans {
+		converted = append(converted, convertSpan(sd))
+	}
+	return &exportTraceServiceRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: converted,
+			}},
+		}},
+	}
+}
+
+func convertSpan(sd *trace.SpanData) span {
+	s := span{
+		TraceID:                sd.TraceID.String(),
+		SpanID:                 sd.SpanID.String(),
+		Name:                   sd.Name,
+		Kind:                   spanKindInternal,
+		StartTimeUnixNano:      unixNano(sd.StartTime),
+		EndTimeUnixNano:        unixNano(sd.EndTime),
+		Attributes:             attributes(sd.Attributes),
+		DroppedAttributesCount: sd.DroppedAttributeCount,
+		DroppedEventsCount:     sd.DroppedAnnotationCount + sd.DroppedMessageEventCount,
+		DroppedLinksCount:      sd.DroppedLinkCount,
+		Status:                 status{Code: statusCodeUnset},
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		s.ParentSpanID = sd.ParentSpanID.String()
+	}
+	if sd.Tracestate != nil {
+		entries := make([]string, 0, len(sd.Tracestate.Entries()))
+		for _, e := range sd.Tracestate.Entries() {
+			entries = append(entries, e.Key+"="+e.Value)
+		}
+		s.TraceState = strings.Join(entries, ",")
+	}
+	switch sd.SpanKind {
+	case trace.SpanKindServer:
+		s.Kind = spanKindServer
+	case trace.SpanKindClient:
+		s.Kind = spanKindClient
+	}
+	if sd.Code != 0 {
+		// OpenCensus codes are gRPC codes, where everything but OK is a failure.
+		s.Status = status{Code: statusCodeError, Message: sd.Message}
+	}
+
+	for _, a := range sd.Annotations {
+		s.Events = append(s.Events, event{
+			TimeUnixNano: unixNano(a.Time),
+			Name:         a.Message,
+			Attributes:   attributes(a.Attributes),
+		})
+	}
+	for _, m := range sd.MessageEvents {
+		s.Events = append(s.Events, event{
+			TimeUnixNano: unixNano(m.Time),
+			Name:         "message",
+			Attributes: attributes(map[string]interface{}{
+				"message.type":              messageEventType(m.EventType),
+				"message.id":                m.MessageID,
+				"message.uncompressed_size": m.UncompressedByteSize,
+				"message.compressed_size":   m.CompressedByteSize,
+			}),
+		})
+	}
+	for _, l := range sd.Links {
+		s.Links = append(s.Links, link{
+			TraceID:    l.TraceID.String(),
+			SpanID:     l.SpanID.String(),
+			Attributes: attributes(l.Attributes),
+		})
+	}
+	return s
+}
+
+func messageEventType(t trace.MessageEventType) string {
+	switch t {
+	case trace.MessageEventTypeSent:
+		return "SENT"
+	case trace.MessageEventTypeRecv:
+		return "RECEIVED"
+	default:
+		return "UNSPECIFIED"
+	}
+}
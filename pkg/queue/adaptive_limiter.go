@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"knative.dev/pkg/system"
+	pkghttp "knative.dev/serving/pkg/http"
+)
+
+// AdaptiveLimiterParams defines the parameters of the adaptive limiter.
+type AdaptiveLimiterParams struct {
+	// MinLimit and MaxLimit bound the concurrency limit.
+	MinLimit int
+	MaxLimit int
+	// InitialLimit is the limit before any request was observed.
+	InitialLimit int
+	// BackoffRatio is the factor the limit is multiplied with on overload.
+	BackoffRatio float64
+	// Tolerance is how many times the lowest observed latency a request
+	// may take before the container is considered overloaded.
+	Tolerance float64
+	// LatencyWindow is how long the lowest observed latency is kept
+	// before it is measured anew.
+	LatencyWindow time.Duration
+}
+
+// DefaultAdaptiveLimiterParams returns the parameters of an adaptive limiter
+// that probes the limit between 1 and maxLimit.
+func DefaultAdaptiveLimiterParams(maxLimit int) AdaptiveLimiterParams {
+	initial := 10
+	if initial > maxLimit {
+		initial = maxLimit
+	}
+	return AdaptiveLimiterParams{
+		MinLimit:      1,
+		MaxLimit:      maxLimit,
+		InitialLimit:  initial,
+		BackoffRatio:  0.9,
+		Tolerance:     2,
+		LatencyWindow: time.Minute,
+	}
+}
+
+// AdaptiveLimiter discovers the concurrency limit of a container from the
+// latency of the requests it serves. It follows an additive increase,
+// multiplicative decrease scheme: while requests complete in time and the
+// limit is in use it is raised by one, and when a request fails or takes
+// longer than Tolerance times the lowest observed latency it is backed off.
+// Every change of the limit is handed to the update function, e.g. the
+// UpdateConcurrency method of a Breaker.
+type AdaptiveLimiter struct {
+	params AdaptiveLimiterParams
+	clock  system.Clock
+	update func(int) error
+
+	mux          sync.Mutex
+	limit        float64
+	inFlight     int
+	minLatency   time.Duration
+	minLatencyAt time.Time
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter with the given parameters.
+func NewAdaptiveLimiter(params AdaptiveLimiterParams, clock system.Clock, update func(int) error) *AdaptiveLimiter {
+	if params.MinLimit < 1 || params.MinLimit > params.MaxLimit {
+		panic(fmt.Sprintf("Min limit must be between 1 and max limit. Got %v.", params.MinLimit))
+	}
+	if params.InitialLimit < params.MinLimit || params.InitialLimit > params.MaxLimit {
+		panic(fmt.Sprintf("Initial limit must be between min and max limit. Got %v.", params.InitialLimit))
+	}
+	if params.BackoffRatio <= 0 || params.BackoffRatio >= 1 {
+		panic(fmt.Sprintf("Backoff ratio must be between 0 and 1. Got %v.", params.BackoffRatio))
+	}
+	if params.Tolerance < 1 {
+		panic(fmt.Sprintf("Tolerance must be 1 or greater. Got %v.", params.Tolerance))
+	}
+	return &AdaptiveLimiter{
+		params: params,
+		clock:  clock,
+		update: update,
+		limit:  float64(params.InitialLimit),
+	}
+}
+
+// Start records the start of a request. The returned function must be called
+// once the request completed, with whether it failed.
+func (l *AdaptiveLimiter) Start() func(failed bool) {
+	l.mux.Lock()
+	l.inFlight++
+	inFlight := l.inFlight
+	l.mux.Unlock()
+
+	start := l.clock.Now()
+	return func(failed bool) {
+		l.sample(l.clock.Now().Sub(start), inFlight, failed)
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return int(l.limit)
+}
+
+// sample adjusts the limit to a request that took latency and was
+// started with inFlight requests in flight, itself included.
+func (l *AdaptiveLimiter) sample(latency time.Duration, inFlight int, failed bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.inFlight--
+
+	now := l.clock.Now()
+	if !failed && (l.minLatency == 0 || latency < l.minLatency || now.Sub(l.minLatencyAt) > l.params.LatencyWindow) {
+		l.minLatency = latency
+		l.minLatencyAt = now
+	}
+
+	old := int(l.limit)
+	switch {
+	case failed || float64(latency) > l.params.Tolerance*float64(l.minLatency):
+		l.limit = math.Max(float64(l.params.MinLimit), l.limit*l.params.BackoffRatio)
+	case inFlight*2 >= old:
+		// Only raise the limit when at least half of it is used, otherwise
+		// the latency tells nothing about a higher limit.
+		l.limit = math.Min(float64(l.params.MaxLimit), l.limit+1)
+	}
+
+	if limit := int(l.limit); limit != old {
+		l.update(limit)
+	}
+}
+
+// AdaptiveLimitHandler records the requests h serves with l. Requests that
+// panic or are answered with a 5xx code count as failed.
+func AdaptiveLimitHandler(h http.Handler, l *AdaptiveLimiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr := pkghttp.NewResponseRecorder(w, http.StatusOK)
+		done := l.Start()
+		defer func() {
+			if err := recover(); err != nil {
+				done(true)
+				panic(err)
+			}
+			done(rr.ResponseCode >= http.StatusInternalServerError)
+		}()
+		h.ServeHTTP(rr, r)
+	})
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+type limiterSample struct {
+	latency  time.Duration
+	inFlight int
+	failed   bool
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	params := AdaptiveLimiterParams{
+		MinLimit:      2,
+		MaxLimit:      6,
+		InitialLimit:  4,
+		BackoffRatio:  0.5,
+		Tolerance:     2,
+		LatencyWindow: time.Minute,
+	}
+	tests := []struct {
+		name        string
+		samples     []limiterSample
+		wantLimit   int
+		wantUpdates []int
+	}{{
+		name:      "no samples",
+		wantLimit: 4,
+	}, {
+		name: "increase while in use",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 2},
+			{latency: 10 * time.Millisecond, inFlight: 3},
+		},
+		wantLimit:   6,
+		wantUpdates: []int{5, 6},
+	}, {
+		name: "capped at max",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 4},
+			{latency: 10 * time.Millisecond, inFlight: 4},
+			{latency: 10 * time.Millisecond, inFlight: 4},
+		},
+		wantLimit:   6,
+		wantUpdates: []int{5, 6},
+	}, {
+		name: "no increase while mostly idle",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 1},
+		},
+		wantLimit: 4,
+	}, {
+		name: "back off on failure",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 4, failed: true},
+		},
+		wantLimit:   2,
+		wantUpdates: []int{2},
+	}, {
+		name: "back off on latency",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 1},
+			{latency: 30 * time.Millisecond, inFlight: 4},
+		},
+		wantLimit:   2,
+		wantUpdates: []int{2},
+	}, {
+		name: "latency within tolerance",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 1},
+			{latency: 20 * time.Millisecond, inFlight: 4},
+		},
+		wantLimit:   5,
+		wantUpdates: []int{5},
+	}, {
+		name: "capped at min",
+		samples: []limiterSample{
+			{latency: 10 * time.Millisecond, inFlight: 4, failed: true},
+			{latency: 10 * time.Millisecond, inFlight: 4, failed: true},
+		},
+		wantLimit:   2,
+		wantUpdates: []int{2},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var updates []int
+			l := NewAdaptiveLimiter(params, &manualClock{now: time.Now()}, func(limit int) error {
+				updates = append(updates, limit)
+				return nil
+			})
+			for _, s := range test.samples {
+				l.inFlight++
+				l.sample(s.latency, s.inFlight, s.failed)
+			}
+			if got := l.Limit(); got != test.wantLimit {
+				t.Errorf("Limit() = %d, want: %d", got, test.wantLimit)
+			}
+			if !cmp.Equal(updates, test.wantUpdates) {
+				t.Errorf("Updates = %v, want: %v", updates, test.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestAdaptiveLimiterLatencyWindow(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	params := DefaultAdaptiveLimiterParams(10)
+	l := NewAdaptiveLimiter(params, clock, func(int) error { return nil })
+
+	done := l.Start()
+	clock.now = clock.now.Add(10 * time.Millisecond)
+	done(false)
+
+	// Once the window passed, the lowest latency is measured anew, so a slower
+	// container doesn't keep backing off.
+	clock.now = clock.now.Add(params.LatencyWindow + time.Second)
+	done = l.Start()
+	clock.now = clock.now.Add(50 * time.Millisecond)
+	done(false)
+
+	if got, want := l.minLatency, 50*time.Millisecond; got != want {
+		t.Errorf("minLatency = %v, want: %v", got, want)
+	}
+	if got, want := l.Limit(), params.InitialLimit; got != want {
+		t.Errorf("Limit() = %d, want: %d", got, want)
+	}
+	if l.inFlight != 0 {
+		t.Errorf("inFlight = %d, want: 0", l.inFlight)
+	}
+}
+
+func TestAdaptiveLimiterInvalidConstructor(t *testing.T) {
+	valid := DefaultAdaptiveLimiterParams(10)
+	tests := []struct {
+		name   string
+		modify func(*AdaptiveLimiterParams)
+	}{{
+		name:   "min limit 0",
+		modify: func(p *AdaptiveLimiterParams) { p.MinLimit = 0 },
+	}, {
+		name:   "min above max",
+		modify: func(p *AdaptiveLimiterParams) { p.MinLimit = 11 },
+	}, {
+		name:   "initial above max",
+		modify: func(p *AdaptiveLimiterParams) { p.InitialLimit = 11 },
+	}, {
+		name:   "backoff ratio 1",
+		modify: func(p *AdaptiveLimiterParams) { p.BackoffRatio = 1 },
+	}, {
+		name:   "tolerance below 1",
+		modify: func(p *AdaptiveLimiterParams) { p.Tolerance = 0.5 },
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected NewAdaptiveLimiter() to panic")
+				}
+			}()
+			params := valid
+			test.modify(&params)
+			NewAdaptiveLimiter(params, &manualClock{}, func(int) error { return nil })
+		})
+	}
+}
+
+func TestAdaptiveLimitHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantLimit int
+	}{{
+		name:      "success",
+		status:    http.StatusOK,
+		wantLimit: 3,
+	}, {
+		name:      "client error",
+		status:    http.StatusNotFound,
+		wantLimit: 3,
+	}, {
+		name:      "server error",
+		status:    http.StatusServiceUnavailable,
+		wantLimit: 1,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := DefaultAdaptiveLimiterParams(10)
+			params.InitialLimit = 2
+			l := NewAdaptiveLimiter(params, &manualClock{now: time.Now()}, func(int) error { return nil })
+			h := AdaptiveLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}), l)
+
+			// A single request uses half of the limit.
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := l.Limit(); got != test.wantLimit {
+				t.Errorf("Limit() = %d, want: %d", got, test.wantLimit)
+			}
+		})
+	}
+}
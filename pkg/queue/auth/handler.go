@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+	"knative.dev/serving/pkg/queue"
+)
+
+// Handler rejects the requests without a valid bearer token with 401
+// Unauthorized, and those whose token lacks the required claims with 403
+// Forbidden. The claims of the valid tokens are forwarded in the configured
+// headers, which are dropped from all incoming requests so they can't be
+// spoofed. The probes trusted by queue.IsTrustedProbe are not authenticated.
+func Handler(h http.Handler, v *Verifier, livenessProbePath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range v.params.ClaimHeaders {
+			r.Header.Del(header)
+		}
+		if queue.IsTrustedProbe(r, livenessProbePath) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		claims, err := v.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		if !v.Authorize(claims) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			http.Error(w, "insufficient claims", http.StatusForbidden)
+			return
+		}
+
+		for claim, header := range v.params.ClaimHeaders {
+			// Claims that can't be header values are not forwarded.
+			if s, ok := claimString(claims[claim]); ok && httpguts.ValidHeaderFieldValue(s) {
+				r.Header.Set(header, s)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken returns the token of the request's bearer Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(prefix):]), true
+}
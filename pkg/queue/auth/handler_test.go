@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/serving/pkg/network"
+)
+
+func TestHandler(t *testing.T) {
+	path, cleanup := writeJWKS(t)
+	defer cleanup()
+
+	v, err := NewVerifier(Params{
+		JWKSPath:       path,
+		Issuer:         testIssuer,
+		Audience:       testAudience,
+		RequiredClaims: map[string]string{"groups": "admins"},
+		ClaimHeaders:   map[string]string{"sub": "X-User", "groups": "X-Groups", "missing": "X-Missing"},
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier() = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		header      http.Header
+		wantStatus  int
+		wantAuth    string
+		wantHeaders http.Header
+	}{{
+		name: "valid token",
+		header: http.Header{
+			"Authorization": {"Bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims())},
+			"X-Missing":     {"spoofed"},
+		},
+		wantStatus: http.StatusOK,
+		wantHeaders: http.Header{
+			"X-User":   {"alice"},
+			"X-Groups": {`["devs","admins"]`},
+		},
+	}, {
+		name: "lower case scheme",
+		header: http.Header{
+			"Authorization": {"bearer " + sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims())},
+		},
+		wantStatus: http.StatusOK,
+		wantHeaders: http.Header{
+			"X-User":   {"alice"},
+			"X-Groups": {`["devs","admins"]`},
+		},
+	}, {
+		name: "missing token",
+		header: http.Header{
+			"X-User": {"mallory"},
+		},
+		wantStatus: http.StatusUnauthorized,
+		wantAuth:   "Bearer",
+	}, {
+		name: "basic auth",
+		header: http.Header{
+			"Authorization": {"Basic YWxpY2U6c2VjcmV0"},
+		},
+		wantStatus: http.StatusUnauthorized,
+		wantAuth:   "Bearer",
+	}, {
+		name: "invalid token",
+		header: http.Header{
+			"Authorization": {"Bearer not.a.token"},
+		},
+		wantStatus: http.StatusUnauthorized,
+		wantAuth:   `Bearer error="invalid_token"`,
+	}, {
+		name: "missing required claim",
+		header: http.Header{
+			"Authorization": {"Bearer " + func() string {
+				claims := validClaims()
+				claims["groups"] = []string{"devs"}
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+			}()},
+		},
+		wantStatus: http.StatusForbidden,
+		wantAuth:   `Bearer error="insufficient_scope"`,
+	}, {
+		name: "knative probe",
+		header: http.Header{
+			network.ProbeHeaderName: {"queue"},
+			"X-User":                {"mallory"},
+		},
+		wantStatus: http.StatusOK,
+	}, {
+		name: "kubelet probe",
+		path: "/healthz",
+		header: http.Header{
+			network.KubeletProbeHeaderName: {"queue"},
+		},
+		wantStatus: http.StatusOK,
+	}, {
+		name: "spoofed kubelet probe header",
+		path: "/admin",
+		header: http.Header{
+			network.KubeletProbeHeaderName: {"queue"},
+		},
+		wantStatus: http.StatusUnauthorized,
+		wantAuth:   "Bearer",
+	}, {
+		name: "spoofed kube-probe user agent",
+		path: "/admin",
+		header: http.Header{
+			"User-Agent": {network.KubeProbeUAPrefix + "1.15"},
+		},
+		wantStatus: http.StatusUnauthorized,
+		wantAuth:   "Bearer",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotHeaders http.Header
+			h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeaders = http.Header{}
+				for _, header := range []string{"X-User", "X-Groups", "X-Missing"} {
+					if v, ok := r.Header[header]; ok {
+						gotHeaders[header] = v
+					}
+				}
+			}), v, "/healthz")
+
+			path := test.path
+			if path == "" {
+				path = "/"
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header = test.header
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Code; got != test.wantStatus {
+				t.Errorf("Status = %d, want: %d", got, test.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != test.wantAuth {
+				t.Errorf("WWW-Authenticate = %q, want: %q", got, test.wantAuth)
+			}
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			if test.wantHeaders == nil {
+				test.wantHeaders = http.Header{}
+			}
+			if !cmp.Equal(gotHeaders, test.wantHeaders) {
+				t.Errorf("Forwarded headers (-want, +got) = %s", cmp.Diff(test.wantHeaders, gotHeaders))
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// jsonWebKey is the part of a JSON Web Key (RFC 7517) describing RSA and
+// EC public keys.
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signature verification keys of the JSON Web Key Set
+// by their key ID. Keys of other types or uses are skipped.
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var (
+			key interface{}
+			err error
+		)
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %q: %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no signature verification keys")
+	}
+	return keys, nil
+}
+
+func (jwk *jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeInt(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeInt(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk *jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+	x, err := decodeInt(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeInt(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth provides the authentication of requests with JSON Web Tokens
+// for the queue-proxy binary.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// keysReloadInterval is how often the JWKS file is reread, to pick up key
+// rotations of the mounted Secret or ConfigMap.
+const keysReloadInterval = time.Minute
+
+// Params defines how bearer tokens are validated.
+type Params struct {
+	// JWKSPath is the file holding the JSON Web Key Set the tokens are
+	// verified against.
+	JWKSPath string
+	// Issuer is the issuer the tokens have to be issued by, if not empty.
+	Issuer string
+	// Audience is the audience the tokens have to be issued for, if not empty.
+	Audience string
+	// RequiredClaims maps claims the tokens have to carry to their value.
+	// A claim holding a list has to contain the value.
+	RequiredClaims map[string]string
+	// ClaimHeaders maps claims to the request header they are forwarded in.
+	ClaimHeaders map[string]string
+}
+
+// Verifier validates bearer tokens.
+type Verifier struct {
+	params Params
+	parser *jwt.Parser
+
+	mux            sync.Mutex
+	keys           map[string]interface{}
+	loaded         time.Time
+	reloadInterval time.Duration
+}
+
+// NewVerifier returns a Verifier validating tokens with the given parameters.
+// It fails if the JWKS can't be loaded.
+func NewVerifier(params Params) (*Verifier, error) {
+	v := &Verifier{
+		params: params,
+		parser: &jwt.Parser{
+			// Only asymmetric algorithms can be verified with a public JWKS.
+			ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+		},
+		reloadInterval: keysReloadInterval,
+	}
+	keys, err := v.loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	v.keys, v.loaded = keys, time.Now()
+	return v, nil
+}
+
+func (v *Verifier) loadKeys() (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(v.params.JWKSPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %v", err)
+	}
+	return parseJWKS(data)
+}
+
+// key returns the key the token was signed with. The JWKS is reloaded every
+// keysReloadInterval. If that fails, the previous keys are kept.
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	v.mux.Lock()
+	if now := time.Now(); now.Sub(v.loaded) >= v.reloadInterval {
+		if keys, err := v.loadKeys(); err == nil {
+			v.keys = keys
+		}
+		v.loaded = now
+	}
+	keys := v.keys
+	v.mux.Unlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(keys) == 1 {
+		// Tokens may omit the key ID if there is only one key.
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// Verify checks the signature, the time validity, the issuer and the
+// audience of the token and returns its claims.
+func (v *Verifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.key); err != nil {
+		return nil, err
+	}
+	if v.params.Issuer != "" && !claims.VerifyIssuer(v.params.Issuer, true) {
+		return nil, errors.New("token has the wrong issuer")
+	}
+	if v.params.Audience != "" && !hasValue(claims["aud"], v.params.Audience) {
+		return nil, errors.New("token has the wrong audience")
+	}
+	return claims, nil
+}
+
+// Authorize returns whether the claims carry the required claims.
+func (v *Verifier) Authorize(claims jwt.MapClaims) bool {
+	for claim, want := range v.params.RequiredClaims {
+		if !hasValue(claims[claim], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasValue returns whether the value of a claim is, or is a list
+// containing, want.
+func hasValue(claim interface{}, want string) bool {
+	if list, ok := claim.([]interface{}); ok {
+		for _, c := range list {
+			if s, ok := claimString(c); ok && s == want {
+				return true
+			}
+		}
+		return false
+	}
+	s, ok := claimString(claim)
+	return ok && s == want
+}
+
+// claimString returns the value of a claim as a string. Strings are returned
+// as they are, everything else as JSON.
+func claimString(claim interface{}) (string, bool) {
+	switch c := claim.(type) {
+	case nil:
+		return "", false
+	case string:
+		return c, true
+	default:
+		b, err := json.Marshal(c)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "my-service"
+)
+
+var (
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// writeJWKS writes a JWKS with rsaKey as "rsa" and ecKey as "ec" and returns
+// its path and a function removing it.
+func writeJWKS(t *testing.T) (string, func()) {
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []jsonWebKey{{
+			Kid: "rsa",
+			Kty: "RSA",
+			Use: "sig",
+			N:   encodeInt(rsaKey.N),
+			E:   encodeInt(big.NewInt(int64(rsaKey.E))),
+		}, {
+			Kid: "ec",
+			Kty: "EC",
+			Crv: "P-256",
+			X:   encodeInt(ecKey.X),
+			Y:   encodeInt(ecKey.Y),
+		}, {
+			Kid: "encryption",
+			Kty: "RSA",
+			Use: "enc",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, jwks, 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// validClaims returns claims passing the test parameters.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    testIssuer,
+		"aud":    []string{"other", testAudience},
+		"sub":    "alice",
+		"groups": []string{"devs", "admins"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	path, cleanup := writeJWKS(t)
+	defer cleanup()
+
+	v, err := NewVerifier(Params{JWKSPath: path, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("NewVerifier() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{{
+		name: "rsa",
+		token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims())
+		},
+	}, {
+		name: "ec",
+		token: func() string {
+			return sign(t, jwt.SigningMethodES256, "ec", ecKey, validClaims())
+		},
+	}, {
+		name: "single audience",
+		token: func() string {
+			claims := validClaims()
+			claims["aud"] = testAudience
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		},
+	}, {
+		name: "wrong key",
+		token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "ec", rsaKey, validClaims())
+		},
+		wantErr: true,
+	}, {
+		name: "unknown key",
+		token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "other", rsaKey, validClaims())
+		},
+		wantErr: true,
+	}, {
+		name: "key ID required with several keys",
+		token: func() string {
+			return sign(t, jwt.SigningMethodRS256, "", rsaKey, validClaims())
+		},
+		wantErr: true,
+	}, {
+		name: "symmetric algorithm",
+		token: func() string {
+			return sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), validClaims())
+		},
+		wantErr: true,
+	}, {
+		name: "expired",
+		token: func() string {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		},
+		wantErr: true,
+	}, {
+		name: "wrong issuer",
+		token: func() string {
+			claims := validClaims()
+			claims["iss"] = "https://evil.example.com"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		},
+		wantErr: true,
+	}, {
+		name: "wrong audience",
+		token: func() string {
+			claims := validClaims()
+			claims["aud"] = "other"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		},
+		wantErr: true,
+	}, {
+		name:    "malformed",
+		token:   func() string { return "not.a.token" },
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := v.Verify(test.token())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Verify() = %v, want error: %v", err, test.wantErr)
+			}
+			if !test.wantErr && claims["sub"] != "alice" {
+				t.Errorf("sub = %v, want: alice", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestVerifyReloadsKeys(t *testing.T) {
+	path, cleanup := writeJWKS(t)
+	defer cleanup()
+
+	v, err := NewVerifier(Params{JWKSPath: path})
+	if err != nil {
+		t.Fatalf("NewVerifier() = %v", err)
+	}
+	v.reloadInterval = 0
+
+	// Rotate the keys to a single one.
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []jsonWebKey{{
+			Kty: "RSA",
+			N:   encodeInt(newKey.N),
+			E:   encodeInt(big.NewInt(int64(newKey.E))),
+		}},
+	})
+	if err := ioutil.WriteFile(path, jwks, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, "", newKey, validClaims())); err != nil {
+		t.Errorf("Verify() = %v after the keys were rotated", err)
+	}
+
+	// Broken files leave the keys as they are.
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, "", newKey, validClaims())); err != nil {
+		t.Errorf("Verify() = %v after the JWKS broke", err)
+	}
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		jwks string
+	}{{
+		name: "invalid json",
+		jwks: "{",
+	}, {
+		name: "no keys",
+		jwks: `{"keys":[]}`,
+	}, {
+		name: "only unsupported keys",
+		jwks: `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+	}, {
+		name: "invalid rsa key",
+		jwks: `{"keys":[{"kty":"RSA","n":"AQAB"}]}`,
+	}, {
+		name: "point not on curve",
+		jwks: `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, "jwks.json")
+			if err := ioutil.WriteFile(path, []byte(test.jwks), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewVerifier(Params{JWKSPath: path}); err == nil {
+				t.Error("NewVerifier() succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := NewVerifier(Params{JWKSPath: filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("NewVerifier() succeeded with a missing JWKS, want error")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	claims := jwt.MapClaims{
+		"sub":            "alice",
+		"groups":         []interface{}{"devs", "admins"},
+		"email_verified": true,
+		"level":          float64(3),
+	}
+
+	tests := []struct {
+		name     string
+		required map[string]string
+		want     bool
+	}{{
+		name: "nothing required",
+		want: true,
+	}, {
+		name:     "string",
+		required: map[string]string{"sub": "alice"},
+		want:     true,
+	}, {
+		name:     "list",
+		required: map[string]string{"groups": "admins"},
+		want:     true,
+	}, {
+		name:     "bool and number",
+		required: map[string]string{"email_verified": "true", "level": "3"},
+		want:     true,
+	}, {
+		name:     "not in list",
+		required: map[string]string{"groups": "ops"},
+	}, {
+		name:     "missing",
+		required: map[string]string{"tenant": "acme"},
+	}, {
+		name:     "one of several missing",
+		required: map[string]string{"sub": "alice", "tenant": "acme"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &Verifier{params: Params{RequiredClaims: test.required}}
+			if got := v.Authorize(claims); got != test.want {
+				t.Errorf("Authorize() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
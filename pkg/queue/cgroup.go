@@ -0,0 +1,262 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCgroupRoot is where the cgroup hierarchy is usually mounted. In a
+// container it only holds the container's own cgroup.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// ResourceUsage is a snapshot of the resources consumed by a cgroup.
+type ResourceUsage struct {
+	// CPUTime is the cumulative CPU time consumed.
+	CPUTime time.Duration
+	// MemoryWorkingSet is the memory usage in bytes, excluding the
+	// inactive page cache, the same way the kubelet computes it.
+	MemoryWorkingSet float64
+}
+
+// UsageReader reads the current resource usage.
+type UsageReader interface {
+	Read() (ResourceUsage, error)
+}
+
+// CgroupReader reads the resource usage from a cgroup v1 or v2 hierarchy.
+type CgroupReader struct {
+	root string
+	// path is the path of the cgroup relative to the root of the hierarchy,
+	// or of every controller's hierarchy for v1.
+	path string
+	v2   bool
+}
+
+var _ UsageReader = (*CgroupReader)(nil)
+
+// NewCgroupReader creates a CgroupReader for the hierarchy mounted at root.
+// The unified (v2) hierarchy is detected by the presence of cgroup.controllers.
+func NewCgroupReader(root string) *CgroupReader {
+	_, err := os.Stat(filepath.Join(root, "cgroup.controllers"))
+	return &CgroupReader{
+		root: root,
+		v2:   err == nil,
+	}
+}
+
+// Read implements UsageReader.
+func (r *CgroupReader) Read() (ResourceUsage, error) {
+	if r.v2 {
+		return r.readV2()
+	}
+	return r.readV1()
+}
+
+func (r *CgroupReader) readV1() (ResourceUsage, error) {
+	var ret ResourceUsage
+	ns, err := readUint(filepath.Join(r.root, "cpuacct", r.path, "cpuacct.usage"))
+	if err != nil {
+		// Some distributions only mount the combined controller.
+		if ns, err = readUint(filepath.Join(r.root, "cpu,cpuacct", r.path, "cpuacct.usage")); err != nil {
+			return ret, err
+		}
+	}
+	ret.CPUTime = time.Duration(ns)
+
+	usage, err := readUint(filepath.Join(r.root, "memory", r.path, "memory.usage_in_bytes"))
+	if err != nil {
+		return ret, err
+	}
+	inactive, err := readStat(filepath.Join(r.root, "memory", r.path, "memory.stat"), "total_inactive_file")
+	if err != nil {
+		return ret, err
+	}
+	ret.MemoryWorkingSet = workingSet(usage, inactive)
+	return ret, nil
+}
+
+func (r *CgroupReader) readV2() (ResourceUsage, error) {
+	var ret ResourceUsage
+	us, err := readStat(filepath.Join(r.root, r.path, "cpu.stat"), "usage_usec")
+	if err != nil {
+		return ret, err
+	}
+	ret.CPUTime = time.Duration(us) * time.Microsecond
+
+	usage, err := readUint(filepath.Join(r.root, r.path, "memory.current"))
+	if err != nil {
+		return ret, err
+	}
+	inactive, err := readStat(filepath.Join(r.root, r.path, "memory.stat"), "inactive_file")
+	if err != nil {
+		return ret, err
+	}
+	ret.MemoryWorkingSet = workingSet(usage, inactive)
+	return ret, nil
+}
+
+// podCgroupReader reads the resource usage of the containers of a pod other
+// than the queue-proxy, by subtracting the usage of the queue-proxy from the
+// usage of the pod.
+type podCgroupReader struct {
+	pod  UsageReader
+	self UsageReader
+}
+
+var _ UsageReader = (*podCgroupReader)(nil)
+
+// NewPodCgroupReader creates a UsageReader for the containers of the pod with
+// the given UID other than the queue-proxy, i.e. the user container and its
+// sidecars. The cgroup hierarchy of the node has to be mounted at root, while
+// the cgroup of the queue-proxy itself is read at DefaultCgroupRoot.
+func NewPodCgroupReader(root, podUID string) (UsageReader, error) {
+	return newPodCgroupReader(root, DefaultCgroupRoot, podUID)
+}
+
+func newPodCgroupReader(root, selfRoot, podUID string) (UsageReader, error) {
+	if podUID == "" {
+		return nil, errors.New("the pod UID must not be empty")
+	}
+	pod := NewCgroupReader(root)
+	hierarchy := root
+	if !pod.v2 {
+		// The pod has the same path in all the v1 hierarchies.
+		hierarchy = filepath.Join(root, "memory")
+	}
+	path, err := findPodCgroup(hierarchy, podUID)
+	if err != nil {
+		return nil, err
+	}
+	pod.path = path
+	return &podCgroupReader{
+		pod:  pod,
+		self: NewCgroupReader(selfRoot),
+	}, nil
+}
+
+// Read implements UsageReader.
+func (r *podCgroupReader) Read() (ResourceUsage, error) {
+	var ret ResourceUsage
+	pod, err := r.pod.Read()
+	if err != nil {
+		return ret, err
+	}
+	self, err := r.self.Read()
+	if err != nil {
+		return ret, err
+	}
+	if pod.CPUTime > self.CPUTime {
+		ret.CPUTime = pod.CPUTime - self.CPUTime
+	}
+	if pod.MemoryWorkingSet > self.MemoryWorkingSet {
+		ret.MemoryWorkingSet = pod.MemoryWorkingSet - self.MemoryWorkingSet
+	}
+	return ret, nil
+}
+
+// maxPodCgroupDepth bounds the search for the cgroup of a pod. The kubelet
+// creates it up to three levels below its cgroup root, which might not be
+// the root of the hierarchy.
+const maxPodCgroupDepth = 5
+
+// findPodCgroup returns the path of the cgroup of the pod with the given UID
+// relative to the hierarchy.
+func findPodCgroup(hierarchy, podUID string) (string, error) {
+	// The cgroupfs driver names the cgroup of a pod pod<uid>, while the
+	// systemd driver names it kubepods[-<qos>]-pod<uid with underscores>.slice.
+	cgroupfsName := "pod" + podUID
+	systemdSuffix := "-pod" + strings.Replace(podUID, "-", "_", -1) + ".slice"
+
+	dirs := []string{""}
+	for depth := 0; depth < maxPodCgroupDepth && len(dirs) > 0; depth++ {
+		var next []string
+		for _, dir := range dirs {
+			entries, err := ioutil.ReadDir(filepath.Join(hierarchy, dir))
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if !e.IsDir() {
+					continue
+				}
+				path := filepath.Join(dir, e.Name())
+				if e.Name() == cgroupfsName || strings.HasSuffix(e.Name(), systemdSuffix) {
+					return path, nil
+				}
+				next = append(next, path)
+			}
+		}
+		dirs = next
+	}
+	return "", fmt.Errorf("cgroup of pod %s not found in %s", podUID, hierarchy)
+}
+
+func workingSet(usage, inactive uint64) float64 {
+	if inactive > usage {
+		return 0
+	}
+	return float64(usage - inactive)
+}
+
+// readUint reads a file containing a single unsigned integer.
+func readUint(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return v, nil
+}
+
+// readStat reads the value of the given key from a flat keyed file,
+// i.e. a file with a "<key> <value>" pair on every line.
+func readStat(path, key string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != key {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s in %s: %v", key, path, err)
+		}
+		return v, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, path)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatalf("TempDir() = %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll() = %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() = %v", err)
+		}
+	}
+	return root
+}
+
+func TestCgroupReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    ResourceUsage
+		wantErr bool
+	}{{
+		name: "v1",
+		files: map[string]string{
+			"cpuacct/cpuacct.usage":        "1500000000\n",
+			"memory/memory.usage_in_bytes": "4096\n",
+			"memory/memory.stat":           "cache 2048\ntotal_inactive_file 1024\n",
+		},
+		want: ResourceUsage{CPUTime: 1500 * time.Millisecond, MemoryWorkingSet: 3072},
+	}, {
+		name: "v1 with combined cpu controller",
+		files: map[string]string{
+			"cpu,cpuacct/cpuacct.usage":    "1000\n",
+			"memory/memory.usage_in_bytes": "4096\n",
+			"memory/memory.stat":           "total_inactive_file 8192\n",
+		},
+		want: ResourceUsage{CPUTime: time.Microsecond},
+	}, {
+		name: "v1 missing memory",
+		files: map[string]string{
+			"cpuacct/cpuacct.usage": "1000\n",
+		},
+		wantErr: true,
+	}, {
+		name: "v2",
+		files: map[string]string{
+			"cgroup.controllers": "cpu memory\n",
+			"cpu.stat":           "usage_usec 2500000\nuser_usec 2000000\n",
+			"memory.current":     "8192\n",
+			"memory.stat":        "anon 4096\ninactive_file 2048\n",
+		},
+		want: ResourceUsage{CPUTime: 2500 * time.Millisecond, MemoryWorkingSet: 6144},
+	}, {
+		name: "v2 malformed",
+		files: map[string]string{
+			"cgroup.controllers": "cpu memory\n",
+			"cpu.stat":           "usage_usec lots\n",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := writeFiles(t, test.files)
+			defer os.RemoveAll(root)
+
+			got, err := NewCgroupReader(root).Read()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Read() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("Read() = %+v, want: %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPodCgroupReader(t *testing.T) {
+	const uid = "6f2a8b1c-3d4e-4f50-8a9b-0c1d2e3f4a5b"
+	self := map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.stat":           "usage_usec 500000\n",
+		"memory.current":     "2048\n",
+		"memory.stat":        "inactive_file 0\n",
+	}
+	tests := []struct {
+		name    string
+		uid     string
+		files   map[string]string
+		want    ResourceUsage
+		wantErr bool
+	}{{
+		name: "v1 cgroupfs driver",
+		uid:  uid,
+		files: map[string]string{
+			"cpuacct/kubepods/burstable/pod" + uid + "/cpuacct.usage":        "2000000000\n",
+			"memory/kubepods/burstable/pod" + uid + "/memory.usage_in_bytes": "8192\n",
+			"memory/kubepods/burstable/pod" + uid + "/memory.stat":           "total_inactive_file 1024\n",
+			"memory/kubepods/burstable/podother/memory.usage_in_bytes":       "1\n",
+		},
+		want: ResourceUsage{CPUTime: 1500 * time.Millisecond, MemoryWorkingSet: 5120},
+	}, {
+		name: "v2 systemd driver",
+		uid:  uid,
+		files: map[string]string{
+			"cgroup.controllers": "cpu memory\n",
+			"kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod6f2a8b1c_3d4e_4f50_8a9b_0c1d2e3f4a5b.slice/cpu.stat":       "usage_usec 3000000\n",
+			"kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod6f2a8b1c_3d4e_4f50_8a9b_0c1d2e3f4a5b.slice/memory.current": "4096\n",
+			"kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod6f2a8b1c_3d4e_4f50_8a9b_0c1d2e3f4a5b.slice/memory.stat":    "inactive_file 1024\n",
+		},
+		want: ResourceUsage{CPUTime: 2500 * time.Millisecond, MemoryWorkingSet: 1024},
+	}, {
+		name: "pod not found",
+		uid:  uid,
+		files: map[string]string{
+			"cgroup.controllers":              "cpu memory\n",
+			"kubepods/burstable/podother/cpu": "",
+		},
+		wantErr: true,
+	}, {
+		name: "no uid",
+		files: map[string]string{
+			"cgroup.controllers": "cpu memory\n",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := writeFiles(t, test.files)
+			defer os.RemoveAll(root)
+			selfRoot := writeFiles(t, self)
+			defer os.RemoveAll(selfRoot)
+
+			r, err := newPodCgroupReader(root, selfRoot, test.uid)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newPodCgroupReader() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			got, err := r.Read()
+			if err != nil {
+				t.Fatalf("Read() = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("Read() = %+v, want: %+v", got, test.want)
+			}
+		})
+	}
+}
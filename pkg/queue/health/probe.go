@@ -17,6 +17,7 @@ limitations under the License.
 package health
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
@@ -34,19 +35,29 @@ type HTTPProbeConfigOptions struct {
 	*corev1.HTTPGetAction
 	KubeMajor string
 	KubeMinor string
+	// SocketPath is the Unix domain socket to probe instead of the
+	// action's host and port, if set.
+	SocketPath string
 }
 
 // TCPProbeConfigOptions holds the TCP probe config options
 type TCPProbeConfigOptions struct {
 	SocketTimeout time.Duration
 	Address       string
+	// SocketPath is the Unix domain socket to probe instead of the
+	// address, if set.
+	SocketPath string
 }
 
 // TCPProbe checks that a TCP socket to the address can be opened.
 // Did not reuse k8s.io/kubernetes/pkg/probe/tcp to not create a dependency
 // on klog.
 func TCPProbe(config TCPProbeConfigOptions) error {
-	conn, err := net.DialTimeout("tcp", config.Address, config.SocketTimeout)
+	network, address := "tcp", config.Address
+	if config.SocketPath != "" {
+		network, address = "unix", config.SocketPath
+	}
+	conn, err := net.DialTimeout(network, address, config.SocketTimeout)
 	if err != nil {
 		return err
 	}
@@ -56,14 +67,21 @@ func TCPProbe(config TCPProbeConfigOptions) error {
 
 // HTTPProbe checks that HTTP connection can be established to the address.
 func HTTPProbe(config HTTPProbeConfigOptions) error {
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DisableKeepAlives: true,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
 		},
-		Timeout: config.Timeout,
+	}
+	if config.SocketPath != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", config.SocketPath)
+		}
+	}
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
 	}
 	url := url.URL{
 		Scheme: string(config.Scheme),
@@ -17,9 +17,13 @@ limitations under the License.
 package health
 
 import (
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -51,6 +55,44 @@ func TestTCPProbe(t *testing.T) {
 	}
 }
 
+func TestTCPProbeUnixSocket(t *testing.T) {
+	server, path, cleanup := newUnixSocketServer(t)
+	defer cleanup()
+	config := TCPProbeConfigOptions{
+		// The address is ignored in favor of the socket.
+		Address:       "127.0.0.1:0",
+		SocketPath:    path,
+		SocketTimeout: time.Second,
+	}
+	if err := TCPProbe(config); err != nil {
+		t.Errorf("Probe failed with: %v", err)
+	}
+
+	server.Close()
+	if err := TCPProbe(config); err == nil {
+		t.Error("Expected probe to fail but it didn't")
+	}
+}
+
+func TestHTTPProbeUnixSocket(t *testing.T) {
+	server, path, cleanup := newUnixSocketServer(t)
+	defer cleanup()
+	config := HTTPProbeConfigOptions{
+		Timeout: time.Second,
+		// The host and port are ignored in favor of the socket.
+		HTTPGetAction: newHTTPGetAction(t, "http://127.0.0.1:0"),
+		SocketPath:    path,
+	}
+	if err := HTTPProbe(config); err != nil {
+		t.Errorf("Expected probe to succeed but it failed with %v", err)
+	}
+
+	server.Close()
+	if err := HTTPProbe(config); err == nil {
+		t.Error("Expected probe to fail but it didn't")
+	}
+}
+
 func TestHTTPProbeSuccess(t *testing.T) {
 	var gotHeader corev1.HTTPHeader
 	var gotKubeletHeader bool
@@ -189,3 +231,28 @@ func newHTTPGetAction(t *testing.T, serverURL string) *corev1.HTTPGetAction {
 		Scheme: uriScheme,
 	}
 }
+
+// newUnixSocketServer starts a server responding OK on a Unix domain socket
+// and returns it along with the socket's path and a function cleaning both up.
+func newUnixSocketServer(t *testing.T) (*httptest.Server, string, func()) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = l
+	server.Start()
+	return server, path, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
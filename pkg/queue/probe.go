@@ -0,0 +1,36 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+
+	"knative.dev/serving/pkg/network"
+)
+
+// IsTrustedProbe returns whether the request is a probe that may skip the
+// authentication and rate limit of regular requests: a Knative probe, which
+// the queue-proxy answers itself, or a kubelet probe of the user container's
+// liveness probe path. Anyone can set the kubelet probe headers, so kubelet
+// probes of any other path are handled like regular requests. An empty
+// livenessProbePath means the user container has no HTTP liveness probe.
+func IsTrustedProbe(r *http.Request, livenessProbePath string) bool {
+	if network.KnativeProbeHeader(r) != "" {
+		return true
+	}
+	return livenessProbePath != "" && network.IsKubeletProbe(r) && r.URL.Path == livenessProbePath
+}
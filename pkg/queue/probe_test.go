@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"knative.dev/serving/pkg/network"
+)
+
+func TestIsTrustedProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		header    http.Header
+		liveness  string
+		wantProbe bool
+	}{{
+		name: "regular request",
+		path: "/healthz",
+		header: http.Header{
+			"User-Agent": {"curl/7.58.0"},
+		},
+		liveness: "/healthz",
+	}, {
+		name: "knative probe",
+		path: "/",
+		header: http.Header{
+			network.ProbeHeaderName: {Name},
+		},
+		wantProbe: true,
+	}, {
+		name: "kubelet probe of the liveness path",
+		path: "/healthz",
+		header: http.Header{
+			network.KubeletProbeHeaderName: {Name},
+		},
+		liveness:  "/healthz",
+		wantProbe: true,
+	}, {
+		name: "kube-probe user agent on the liveness path",
+		path: "/healthz",
+		header: http.Header{
+			"User-Agent": {network.KubeProbeUAPrefix + "1.15"},
+		},
+		liveness:  "/healthz",
+		wantProbe: true,
+	}, {
+		name: "kubelet probe of another path",
+		path: "/admin",
+		header: http.Header{
+			"User-Agent": {network.KubeProbeUAPrefix + "1.15"},
+		},
+		liveness: "/healthz",
+	}, {
+		name: "kubelet probe without liveness probe",
+		path: "/",
+		header: http.Header{
+			network.KubeletProbeHeaderName: {Name},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.Header = test.header
+			if got := IsTrustedProbe(r, test.liveness); got != test.wantProbe {
+				t.Errorf("IsTrustedProbe() = %v, want: %v", got, test.wantProbe)
+			}
+		})
+	}
+}
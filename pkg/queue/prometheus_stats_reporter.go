@@ -55,6 +55,18 @@ var (
 	averageProxiedConcurrentRequestsGV = newGV(
 		"queue_average_proxied_concurrent_requests",
 		"Number of proxied requests currently being handled by this pod")
+	cpuUsageGV = newGV(
+		"queue_cpu_usage_millicores",
+		"Average CPU usage of this pod in millicores")
+	memoryUsageGV = newGV(
+		"queue_memory_usage_bytes",
+		"Memory working set of this pod in bytes")
+	concurrencyLimitGV = newGV(
+		"queue_concurrency_limit",
+		"Concurrency limit discovered for this pod, or 0 if it is not limited adaptively")
+	averageUpgradedConnectionsGV = newGV(
+		"queue_average_upgraded_connections",
+		"Number of upgraded connections, e.g. WebSockets, currently open on this pod")
 )
 
 func newGV(n, h string) *prometheus.GaugeVec {
@@ -88,7 +100,7 @@ func NewPrometheusStatsReporter(namespace, config, revision, pod string, reporti
 	}
 
 	registry := prometheus.NewRegistry()
-	for _, gv := range []*prometheus.GaugeVec{requestsPerSecondGV, proxiedRequestsPerSecondGV, averageConcurrentRequestsGV, averageProxiedConcurrentRequestsGV} {
+	for _, gv := range []*prometheus.GaugeVec{requestsPerSecondGV, proxiedRequestsPerSecondGV, averageConcurrentRequestsGV, averageProxiedConcurrentRequestsGV, cpuUsageGV, memoryUsageGV, concurrencyLimitGV, averageUpgradedConnectionsGV} {
 		if err := registry.Register(gv); err != nil {
 			return nil, fmt.Errorf("register metric failed: %v", err)
 		}
@@ -118,6 +130,10 @@ func (r *PrometheusStatsReporter) Report(stat *autoscaler.Stat) error {
 	proxiedRequestsPerSecondGV.With(r.labels).Set(stat.ProxiedRequestCount / r.reportingPeriod.Seconds())
 	averageConcurrentRequestsGV.With(r.labels).Set(stat.AverageConcurrentRequests)
 	averageProxiedConcurrentRequestsGV.With(r.labels).Set(stat.AverageProxiedConcurrentRequests)
+	cpuUsageGV.With(r.labels).Set(stat.AverageCPUUsage)
+	memoryUsageGV.With(r.labels).Set(stat.MemoryUsage)
+	concurrencyLimitGV.With(r.labels).Set(stat.ConcurrencyLimit)
+	averageUpgradedConnectionsGV.With(r.labels).Set(stat.AverageUpgradedConnections)
 
 	return nil
 }
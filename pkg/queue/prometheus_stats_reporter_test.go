@@ -101,6 +101,10 @@ func TestReporterReport(t *testing.T) {
 		expectedAverageConcurrentRequests float64
 		expectedProxiedRequestCount       float64
 		expectedProxiedConcurrency        float64
+		expectedCPUUsage                  float64
+		expectedMemoryUsage               float64
+		expectedConcurrencyLimit          float64
+		expectedUpgradedConnections       float64
 	}{{
 		name:                              "no proxy requests",
 		reportingPeriod:                   1 * time.Second,
@@ -133,6 +137,23 @@ func TestReporterReport(t *testing.T) {
 		expectedAverageConcurrentRequests: 3,
 		expectedProxiedRequestCount:       15,
 		expectedProxiedConcurrency:        2,
+	}, {
+		name:                "resource usage",
+		reportingPeriod:     1 * time.Second,
+		autoscalerStat:      &autoscaler.Stat{AverageCPUUsage: 250, MemoryUsage: 1 << 20},
+		expectedCPUUsage:    250,
+		expectedMemoryUsage: 1 << 20,
+	}, {
+		name:                     "concurrency limit",
+		reportingPeriod:          1 * time.Second,
+		autoscalerStat:           &autoscaler.Stat{ConcurrencyLimit: 12},
+		expectedConcurrencyLimit: 12,
+	}, {
+		name:                              "upgraded connections",
+		reportingPeriod:                   1 * time.Second,
+		autoscalerStat:                    &autoscaler.Stat{AverageConcurrentRequests: 3, AverageUpgradedConnections: 2},
+		expectedAverageConcurrentRequests: 3,
+		expectedUpgradedConnections:       2,
 	},
 	}
 
@@ -152,6 +173,10 @@ func TestReporterReport(t *testing.T) {
 			checkData(t, averageConcurrentRequestsGV, test.expectedAverageConcurrentRequests)
 			checkData(t, proxiedRequestsPerSecondGV, test.expectedProxiedRequestCount)
 			checkData(t, averageProxiedConcurrentRequestsGV, test.expectedProxiedConcurrency)
+			checkData(t, cpuUsageGV, test.expectedCPUUsage)
+			checkData(t, memoryUsageGV, test.expectedMemoryUsage)
+			checkData(t, concurrencyLimitGV, test.expectedConcurrencyLimit)
+			checkData(t, averageUpgradedConnectionsGV, test.expectedUpgradedConnections)
 		})
 	}
 }
@@ -0,0 +1,204 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"knative.dev/pkg/system"
+)
+
+// maxRateLimitKeys is the number of keys limited separately. The requests
+// with any further key share a single limit, so that clients sending random
+// keys can neither grow the limiters without bound nor get around the limit.
+const maxRateLimitKeys = 10000
+
+// readyPodsTTL is how long the number of ready pods reported by the
+// autoscaler is trusted. The autoscaler only scrapes a sample of the pods,
+// so a pod that was not scraped for a while might split the limit across
+// far fewer pods than there are by now.
+const readyPodsTTL = 10 * time.Second
+
+// RateLimitParams defines the parameters of the rate limit.
+type RateLimitParams struct {
+	// Rate is the number of requests per second that are let through by all
+	// the pods of the revision together.
+	Rate float64
+	// Burst is the number of requests that are let through at once by all
+	// the pods of the revision together.
+	Burst int
+	// KeyHeader is the request header whose values are limited separately,
+	// e.g. the client ID. If empty, all requests share the limit.
+	KeyHeader string
+	// MaxPods is the most pods the revision scales to. The limit is split
+	// across this many pods until the autoscaler reports the ready pods, and
+	// again once that report is older than readyPodsTTL.
+	MaxPods int
+}
+
+// RateLimiter keeps a token bucket for each key. The rate and burst are
+// split evenly across the ready pods of the revision, so that the revision
+// as a whole sticks to the limit however far it scales out.
+type RateLimiter struct {
+	params RateLimitParams
+	clock  system.Clock
+
+	mux sync.Mutex
+	// pods is the number of ready pods last reported by the autoscaler,
+	// at podsUpdated.
+	pods        int
+	podsUpdated time.Time
+	limiters    map[string]*keyLimiter
+	// overflow is shared by the keys beyond maxRateLimitKeys.
+	overflow *keyLimiter
+	// The time the next idle limiters are dropped.
+	nextSweep time.Time
+}
+
+// keyLimiter is a token bucket. Its rate and burst are not stored, since
+// they change with the number of pods, while the tokens carry over.
+type keyLimiter struct {
+	tokens   float64
+	last     time.Time
+	lastSeen time.Time
+}
+
+// take refills the bucket with the given rate up to the given burst and takes
+// a token if there is one. Otherwise it returns how long until there is one.
+func (kl *keyLimiter) take(now time.Time, r float64, burst int) (bool, time.Duration) {
+	if now.After(kl.last) {
+		kl.tokens += now.Sub(kl.last).Seconds() * r
+		kl.last = now
+	}
+	kl.tokens = math.Min(kl.tokens, float64(burst))
+	if kl.tokens >= 1 {
+		kl.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - kl.tokens) / r * float64(time.Second))
+}
+
+// NewRateLimiter creates a RateLimiter that splits the limit across
+// params.MaxPods pods, until told otherwise by SetPods.
+func NewRateLimiter(params RateLimitParams, clock system.Clock) *RateLimiter {
+	if params.Rate <= 0 {
+		panic(fmt.Sprintf("Rate must be greater than 0. Got %v.", params.Rate))
+	}
+	if params.Burst < 1 {
+		panic(fmt.Sprintf("Burst must be greater than 0. Got %v.", params.Burst))
+	}
+	return &RateLimiter{
+		params:   params,
+		clock:    clock,
+		limiters: make(map[string]*keyLimiter),
+	}
+}
+
+// SetPods splits the limit across the given number of ready pods for the
+// next readyPodsTTL. The buckets keep their tokens, up to the new burst.
+func (l *RateLimiter) SetPods(pods int) {
+	if pods < 1 {
+		pods = 1
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.pods = pods
+	l.podsUpdated = l.clock.Now()
+}
+
+// podRate returns the share of the rate and burst of this pod.
+func (l *RateLimiter) podRate(now time.Time) (float64, int) {
+	pods := l.pods
+	if pods == 0 || now.Sub(l.podsUpdated) > readyPodsTTL {
+		pods = int(math.Max(1, float64(l.params.MaxPods)))
+	}
+	burst := int(math.Ceil(float64(l.params.Burst) / float64(pods)))
+	return l.params.Rate / float64(pods), burst
+}
+
+func newKeyLimiter(now time.Time, burst int) *keyLimiter {
+	return &keyLimiter{tokens: float64(burst), last: now}
+}
+
+// allow returns whether a request with the given key may go through and, if not,
+// how long until it may.
+func (l *RateLimiter) allow(key string) (bool, time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.clock.Now()
+	r, burst := l.podRate(now)
+	l.sweep(now, r, burst)
+	kl, ok := l.limiters[key]
+	if !ok {
+		if len(l.limiters) < maxRateLimitKeys {
+			kl = newKeyLimiter(now, burst)
+			l.limiters[key] = kl
+		} else {
+			if l.overflow == nil {
+				l.overflow = newKeyLimiter(now, burst)
+			}
+			kl = l.overflow
+		}
+	}
+	kl.lastSeen = now
+	return kl.take(now, r, burst)
+}
+
+// sweep drops the limiters that have been idle long enough to refill
+// their bucket, since they are no different from new ones.
+func (l *RateLimiter) sweep(now time.Time, r float64, burst int) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	refill := time.Duration(float64(burst) / r * float64(time.Second))
+	for key, kl := range l.limiters {
+		if now.Sub(kl.lastSeen) > refill {
+			delete(l.limiters, key)
+		}
+	}
+	if l.overflow != nil && now.Sub(l.overflow.lastSeen) > refill {
+		l.overflow = nil
+	}
+	l.nextSweep = now.Add(refill)
+}
+
+// RateLimitHandler rejects the requests in excess of the limiter's rate with
+// 429 Too Many Requests. The probes trusted by IsTrustedProbe are not limited.
+func RateLimitHandler(h http.Handler, l *RateLimiter, livenessProbePath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsTrustedProbe(r, livenessProbePath) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		var key string
+		if l.params.KeyHeader != "" {
+			key = r.Header.Get(l.params.KeyHeader)
+		}
+		if ok, delay := l.allow(key); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"knative.dev/serving/pkg/network"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	type request struct {
+		after      time.Duration
+		key        string
+		probe      bool
+		kubeProbe  bool
+		wantStatus int
+		wantRetry  string
+	}
+	tests := []struct {
+		name     string
+		params   RateLimitParams
+		pods     int
+		requests []request
+	}{{
+		name:   "within burst",
+		params: RateLimitParams{Rate: 1, Burst: 2},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{wantStatus: http.StatusOK},
+		},
+	}, {
+		name:   "over burst",
+		params: RateLimitParams{Rate: 0.5, Burst: 1},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{wantStatus: http.StatusTooManyRequests, wantRetry: "2"},
+		},
+	}, {
+		name:   "refilled",
+		params: RateLimitParams{Rate: 1, Burst: 1},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{after: 500 * time.Millisecond, wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+			{after: 500 * time.Millisecond, wantStatus: http.StatusOK},
+		},
+	}, {
+		name:   "rejected requests don't take tokens",
+		params: RateLimitParams{Rate: 1, Burst: 1},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+			{wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+			{after: time.Second, wantStatus: http.StatusOK},
+		},
+	}, {
+		name:   "probes are not limited",
+		params: RateLimitParams{Rate: 1, Burst: 1},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{probe: true, wantStatus: http.StatusOK},
+			{probe: true, wantStatus: http.StatusOK},
+		},
+	}, {
+		name:   "spoofed kubelet probes are limited",
+		params: RateLimitParams{Rate: 1, Burst: 1},
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{kubeProbe: true, wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+		},
+	}, {
+		name:   "split across pods",
+		params: RateLimitParams{Rate: 4, Burst: 4},
+		pods:   2,
+		requests: []request{
+			{wantStatus: http.StatusOK},
+			{wantStatus: http.StatusOK},
+			{wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+			{after: 500 * time.Millisecond, wantStatus: http.StatusOK},
+		},
+	}, {
+		name:   "shared without key header",
+		params: RateLimitParams{Rate: 1, Burst: 1},
+		requests: []request{
+			{key: "a", wantStatus: http.StatusOK},
+			{key: "b", wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+		},
+	}, {
+		name:   "limited per key",
+		params: RateLimitParams{Rate: 1, Burst: 1, KeyHeader: "X-Client"},
+		requests: []request{
+			{key: "a", wantStatus: http.StatusOK},
+			{key: "b", wantStatus: http.StatusOK},
+			{key: "a", wantStatus: http.StatusTooManyRequests, wantRetry: "1"},
+			{wantStatus: http.StatusOK},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &manualClock{now: time.Now()}
+			l := NewRateLimiter(test.params, clock)
+			l.SetPods(test.pods)
+			h := RateLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+				l, "/healthz")
+
+			for i, req := range test.requests {
+				clock.now = clock.now.Add(req.after)
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if req.key != "" {
+					r.Header.Set("X-Client", req.key)
+				}
+				if req.probe {
+					r.Header.Set(network.ProbeHeaderName, Name)
+				}
+				if req.kubeProbe {
+					r.Header.Set("User-Agent", network.KubeProbeUAPrefix+"1.15")
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if got := w.Code; got != req.wantStatus {
+					t.Errorf("Request %d: status = %d, want: %d", i, got, req.wantStatus)
+				}
+				if got := w.Header().Get("Retry-After"); got != req.wantRetry {
+					t.Errorf("Request %d: Retry-After = %q, want: %q", i, got, req.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	l := NewRateLimiter(RateLimitParams{Rate: 1, Burst: 2}, clock)
+
+	l.allow("a")
+	clock.now = clock.now.Add(time.Second)
+	l.allow("b")
+	if got, want := len(l.limiters), 2; got != want {
+		t.Fatalf("len(limiters) = %d, want: %d", got, want)
+	}
+
+	// a refilled its bucket, b did not yet.
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	l.allow("b")
+	if _, ok := l.limiters["a"]; ok {
+		t.Error("Idle limiter of a was not dropped")
+	}
+	if _, ok := l.limiters["b"]; !ok {
+		t.Error("Limiter of b was dropped")
+	}
+}
+
+func TestRateLimiterSetPods(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	l := NewRateLimiter(RateLimitParams{Rate: 10, Burst: 10, MaxPods: 5}, clock)
+
+	for _, test := range []struct {
+		name      string
+		report    bool
+		pods      int
+		after     time.Duration
+		wantRate  float64
+		wantBurst int
+	}{{
+		name:      "not reported yet",
+		wantRate:  2,
+		wantBurst: 2,
+	}, {
+		name:      "no pods",
+		report:    true,
+		pods:      0,
+		wantRate:  10,
+		wantBurst: 10,
+	}, {
+		name:      "split",
+		report:    true,
+		pods:      3,
+		wantRate:  10.0 / 3,
+		wantBurst: 4,
+	}, {
+		name:      "more pods than burst",
+		report:    true,
+		pods:      20,
+		wantRate:  0.5,
+		wantBurst: 1,
+	}, {
+		name:      "outdated report",
+		report:    true,
+		pods:      1,
+		after:     readyPodsTTL + time.Second,
+		wantRate:  2,
+		wantBurst: 2,
+	}} {
+		if test.report {
+			l.SetPods(test.pods)
+		}
+		clock.now = clock.now.Add(test.after)
+		r, burst := l.podRate(clock.now)
+		if r != test.wantRate {
+			t.Errorf("%s: rate = %v, want: %v", test.name, r, test.wantRate)
+		}
+		if burst != test.wantBurst {
+			t.Errorf("%s: burst = %d, want: %d", test.name, burst, test.wantBurst)
+		}
+	}
+}
+
+func TestRateLimiterSetPodsKeepsTokens(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	l := NewRateLimiter(RateLimitParams{Rate: 4, Burst: 4}, clock)
+	l.SetPods(2)
+
+	// Drain the burst of 2.
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("allow(a) #%d = false, want: true", i)
+		}
+	}
+	// Scaling in raises the burst, but does not hand it out at once.
+	l.SetPods(1)
+	if ok, delay := l.allow("a"); ok || delay != 250*time.Millisecond {
+		t.Errorf("allow(a) = %v, %v, want: false, 250ms", ok, delay)
+	}
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("allow(a) #%d after refill = false, want: true", i)
+		}
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Error("allow(a) after refill = true, want: false")
+	}
+}
+
+func TestRateLimiterMaxKeys(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	l := NewRateLimiter(RateLimitParams{Rate: 1, Burst: 1}, clock)
+
+	for i := 0; i < maxRateLimitKeys; i++ {
+		if ok, _ := l.allow(strconv.Itoa(i)); !ok {
+			t.Fatalf("allow(%d) = false, want: true", i)
+		}
+	}
+	// The keys beyond the maximum share a single limit.
+	if ok, _ := l.allow("new"); !ok {
+		t.Error("allow(new) = false, want: true")
+	}
+	if ok, _ := l.allow("newer"); ok {
+		t.Error("allow(newer) = true, want: false")
+	}
+	if got := len(l.limiters); got != maxRateLimitKeys {
+		t.Errorf("len(limiters) = %d, want: %d", got, maxRateLimitKeys)
+	}
+}
+
+func TestRateLimiterInvalidConstructor(t *testing.T) {
+	tests := []struct {
+		name   string
+		params RateLimitParams
+	}{{
+		name:   "rate 0",
+		params: RateLimitParams{Burst: 1},
+	}, {
+		name:   "burst 0",
+		params: RateLimitParams{Rate: 1},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected NewRateLimiter() to panic")
+				}
+			}()
+			NewRateLimiter(test.params, &manualClock{})
+		})
+	}
+}
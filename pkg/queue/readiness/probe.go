@@ -38,6 +38,9 @@ const (
 type Probe struct {
 	*corev1.Probe
 	count int32
+	// SocketPath is the Unix domain socket the user-container listens on, if
+	// any. HTTP and TCP probes dial it instead of the probe's host and port.
+	SocketPath string
 }
 
 // NewProbe returns a pointer a new Probe
@@ -106,7 +109,8 @@ func (p *Probe) doProbe(probe func(time.Duration) error) error {
 // if the probe count is greater than success threshold and false if TCP probe fails
 func (p *Probe) tcpProbe() error {
 	config := health.TCPProbeConfigOptions{
-		Address: p.TCPSocket.Host + ":" + p.TCPSocket.Port.String(),
+		Address:    p.TCPSocket.Host + ":" + p.TCPSocket.Port.String(),
+		SocketPath: p.SocketPath,
 	}
 
 	return p.doProbe(func(to time.Duration) error {
@@ -121,6 +125,7 @@ func (p *Probe) tcpProbe() error {
 func (p *Probe) httpProbe() error {
 	config := health.HTTPProbeConfigOptions{
 		HTTPGetAction: p.HTTPGet,
+		SocketPath:    p.SocketPath,
 	}
 
 	return p.doProbe(func(to time.Duration) error {
@@ -18,10 +18,13 @@ package readiness
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -137,6 +140,66 @@ func TestTCPSuccess(t *testing.T) {
 	}
 }
 
+func TestUnixSocketProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Listener = l
+	ts.Start()
+	defer ts.Close()
+
+	// Nothing listens on the probes' ports, only on the socket.
+	tests := []struct {
+		name    string
+		handler corev1.Handler
+	}{{
+		name: "tcp",
+		handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Host: "127.0.0.1",
+				Port: intstr.FromInt(12345),
+			},
+		},
+	}, {
+		name: "http",
+		handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Host:   "127.0.0.1",
+				Port:   intstr.FromInt(12345),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pb := NewProbe(&corev1.Probe{
+				PeriodSeconds:    1,
+				TimeoutSeconds:   2,
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+				Handler:          test.handler,
+			})
+			pb.SocketPath = path
+
+			if !pb.ProbeContainer() {
+				t.Error("Probe report failure. Expected success.")
+			}
+		})
+	}
+}
+
 func TestHTTPFailureToConnect(t *testing.T) {
 	pb := NewProbe(&corev1.Probe{
 		PeriodSeconds:    1,
@@ -65,6 +65,12 @@ func (h *requestMetricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 			h.sendRequestMetrics(http.StatusInternalServerError, latency)
 			panic(err)
 		}
+		if rr.Hijacked() {
+			// The lifetime of an upgraded connection is no response time.
+			h.statsReporter.ReportRequestCount(rr.ResponseCode)
+			h.statsReporter.ReportUpgradedConnectionDuration(latency)
+			return
+		}
 		h.sendRequestMetrics(rr.ResponseCode, latency)
 	}()
 
@@ -17,7 +17,9 @@ limitations under the License.
 package queue
 
 import (
+	"bufio"
 	"bytes"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -91,6 +93,46 @@ func TestRequestMetricHandler(t *testing.T) {
 	}
 }
 
+func TestRequestMetricHandlerUpgradedConnection(t *testing.T) {
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+		time.Sleep(10 * time.Millisecond)
+	})
+	r := &fakeStatsReporter{}
+	handler, err := NewRequestMetricHandler(baseHandler, r, nil)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	handler.ServeHTTP(&fakeHijackableWriter{httptest.NewRecorder()}, req)
+
+	if got, want := r.reqCountReportTimes, 1; got != want {
+		t.Errorf("ReportRequestCount was triggered %v times, want %v", got, want)
+	}
+	if got, want := r.lastRespCode, http.StatusSwitchingProtocols; got != want {
+		t.Errorf("Response code got %v, want %v", got, want)
+	}
+	if got, want := r.respTimeReportTimes, 0; got != want {
+		t.Errorf("ReportResponseTime was triggered %v times, want %v", got, want)
+	}
+	if got, want := r.connDurReportTimes, 1; got != want {
+		t.Errorf("ReportUpgradedConnectionDuration was triggered %v times, want %v", got, want)
+	}
+	if r.lastConnDuration < 10*time.Millisecond {
+		t.Errorf("Connection duration got %v, want at least %v", r.lastConnDuration, 10*time.Millisecond)
+	}
+}
+
+// fakeHijackableWriter is a ResponseRecorder whose connection can be hijacked.
+type fakeHijackableWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *fakeHijackableWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
 func TestRequestMetricHandlerPanickingHandler(t *testing.T) {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		panic("no!")
@@ -129,9 +171,11 @@ type fakeStatsReporter struct {
 	reqCountReportTimes int
 	respTimeReportTimes int
 	queueDepthTimes     int
+	connDurReportTimes  int
 	lastRespCode        int
 	lastReqCount        int64
 	lastReqLatency      time.Duration
+	lastConnDuration    time.Duration
 }
 
 func (r *fakeStatsReporter) ReportQueueDepth(qd int) error {
@@ -152,3 +196,9 @@ func (r *fakeStatsReporter) ReportResponseTime(responseCode int, d time.Duration
 	r.lastReqLatency = d
 	return nil
 }
+
+func (r *fakeStatsReporter) ReportUpgradedConnectionDuration(d time.Duration) error {
+	r.connDurReportTimes++
+	r.lastConnDuration = d
+	return nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pkghttp "knative.dev/serving/pkg/http"
+	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/queue/stats"
+)
+
+// UnmatchedRoute is the route the requests whose path matches no route
+// template are reported under.
+const UnmatchedRoute = "unmatched"
+
+// RouteMatcher normalizes request paths to the route templates they match.
+type RouteMatcher struct {
+	routes []routeTemplate
+}
+
+type routeTemplate struct {
+	template string
+	// segments are the path segments of the template, with parameters
+	// represented by paramSegment.
+	segments []string
+}
+
+// paramSegment stands for the parameters of route templates. Literal segments
+// can't contain braces, so it never clashes with them.
+const paramSegment = "{}"
+
+// NewRouteMatcher creates a RouteMatcher for templates such as /users/{id},
+// as parsed by serving.ParseRouteTemplates. Templates are matched in order.
+func NewRouteMatcher(templates []string) *RouteMatcher {
+	m := &RouteMatcher{routes: make([]routeTemplate, 0, len(templates))}
+	for _, t := range templates {
+		segments := strings.Split(strings.TrimPrefix(t, "/"), "/")
+		for i, s := range segments {
+			if strings.HasPrefix(s, "{") {
+				segments[i] = paramSegment
+			}
+		}
+		m.routes = append(m.routes, routeTemplate{template: t, segments: segments})
+	}
+	return m
+}
+
+// Match returns the first route template the path matches, or UnmatchedRoute.
+// A parameter matches any single non-empty path segment.
+func (m *RouteMatcher) Match(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, r := range m.routes {
+		if r.matches(segments) {
+			return r.template
+		}
+	}
+	return UnmatchedRoute
+}
+
+func (r *routeTemplate) matches(segments []string) bool {
+	if len(segments) != len(r.segments) {
+		return false
+	}
+	for i, s := range r.segments {
+		if s == paramSegment && segments[i] == "" || s != paramSegment && s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type routeMetricHandler struct {
+	handler       http.Handler
+	statsReporter stats.RouteStatsReporter
+	matcher       *RouteMatcher
+
+	inFlightMux sync.Mutex
+	inFlight    map[string]int64
+}
+
+// NewRouteMetricHandler creates an http.Handler that emits request metrics
+// per route, as normalized by the RouteMatcher.
+func NewRouteMetricHandler(h http.Handler, r stats.RouteStatsReporter, m *RouteMatcher) (http.Handler, error) {
+	if r == nil {
+		return nil, errors.New("RouteStatsReporter must not be nil")
+	}
+	if m == nil {
+		return nil, errors.New("RouteMatcher must not be nil")
+	}
+
+	return &routeMetricHandler{
+		handler:       h,
+		statsReporter: r,
+		matcher:       m,
+		inFlight:      make(map[string]int64, len(m.routes)+1),
+	}, nil
+}
+
+func (h *routeMetricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Filter probe requests for route metrics.
+	if network.IsProbe(r) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	route := h.matcher.Match(r.URL.Path)
+	h.addInFlight(route, 1)
+	body := &countingReadCloser{ReadCloser: r.Body}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = body
+	}
+	rr := pkghttp.NewResponseRecorder(w, http.StatusOK)
+	startTime := time.Now()
+
+	defer func() {
+		h.addInFlight(route, -1)
+
+		// If ServeHTTP panics, recover, record the failure and panic again.
+		err := recover()
+		latency := time.Since(startTime)
+		if err != nil {
+			h.statsReporter.ReportRouteRequest(route, http.StatusInternalServerError, latency, body.count(), 0)
+			panic(err)
+		}
+		if rr.Hijacked() {
+			// The lifetime of an upgraded connection is no response time.
+			return
+		}
+		h.statsReporter.ReportRouteRequest(route, rr.ResponseCode, latency, body.count(), int64(atomic.LoadInt32(&rr.ResponseSize)))
+	}()
+
+	h.handler.ServeHTTP(rr, r)
+}
+
+// addInFlight changes the number of in-flight requests of the route by delta
+// and reports it. The report happens under the lock, so the last reported
+// value is always the current one.
+func (h *routeMetricHandler) addInFlight(route string, delta int64) {
+	h.inFlightMux.Lock()
+	defer h.inFlightMux.Unlock()
+	h.inFlight[route] += delta
+	h.statsReporter.ReportRouteInFlight(route, h.inFlight[route])
+}
+
+// countingReadCloser counts the bytes read from the request body. The body may
+// be read by the proxy's transport on another goroutine.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+func (c *countingReadCloser) count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
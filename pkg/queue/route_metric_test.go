@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"knative.dev/serving/pkg/network"
+)
+
+func TestRouteMatcher(t *testing.T) {
+	m := NewRouteMatcher([]string{"/", "/users/{id}/orders", "/users/me", "/users/{id}", "/{tenant}/users/{id}"})
+
+	tests := []struct {
+		path string
+		want string
+	}{{
+		path: "/",
+		want: "/",
+	}, {
+		path: "/users/42",
+		want: "/users/{id}",
+	}, {
+		path: "/users/me",
+		want: "/users/me",
+	}, {
+		path: "/users/42/orders",
+		want: "/users/{id}/orders",
+	}, {
+		path: "/acme/users/42",
+		want: "/{tenant}/users/{id}",
+	}, {
+		path: "/users/",
+		want: UnmatchedRoute,
+	}, {
+		path: "/users/42/",
+		want: UnmatchedRoute,
+	}, {
+		path: "/orders",
+		want: UnmatchedRoute,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := m.Match(test.path); got != test.want {
+				t.Errorf("Match(%q) = %q, want: %q", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewRouteMetricHandlerFailure(t *testing.T) {
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := NewRouteMetricHandler(baseHandler, nil, NewRouteMatcher(nil)); err == nil {
+		t.Error("should get error when RouteStatsReporter is empty")
+	}
+	if _, err := NewRouteMetricHandler(baseHandler, &fakeRouteStatsReporter{}, nil); err == nil {
+		t.Error("should get error when RouteMatcher is empty")
+	}
+}
+
+func TestRouteMetricHandler(t *testing.T) {
+	r := &fakeRouteStatsReporter{}
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := r.lastInFlight, int64(1); got != want && !network.IsProbe(req) {
+			t.Errorf("In-flight requests while serving = %d, want: %d", got, want)
+		}
+		ioutil.ReadAll(req.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	handler, err := NewRouteMetricHandler(baseHandler, r, NewRouteMatcher([]string{"/users/{id}"}))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/users/42", bytes.NewBufferString("alice"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got, want := r.requestReportTimes, 1; got != want {
+		t.Errorf("ReportRouteRequest was triggered %v times, want %v", got, want)
+	}
+	if got, want := r.lastRoute, "/users/{id}"; got != want {
+		t.Errorf("Route = %q, want: %q", got, want)
+	}
+	if got, want := r.lastRespCode, http.StatusCreated; got != want {
+		t.Errorf("Response code = %v, want: %v", got, want)
+	}
+	if got, want := r.lastReqSize, int64(len("alice")); got != want {
+		t.Errorf("Request size = %d, want: %d", got, want)
+	}
+	if got, want := r.lastRespSize, int64(len("created")); got != want {
+		t.Errorf("Response size = %d, want: %d", got, want)
+	}
+	if r.lastLatency == 0 {
+		t.Errorf("Latency = %v, want larger than 0", r.lastLatency)
+	}
+	if got, want := r.lastInFlight, int64(0); got != want {
+		t.Errorf("In-flight requests after serving = %d, want: %d", got, want)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/orders", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got, want := r.lastRoute, UnmatchedRoute; got != want {
+		t.Errorf("Route = %q, want: %q", got, want)
+	}
+
+	// A probe request should not be recorded.
+	req.Header.Set(network.ProbeHeaderName, "activator")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got, want := r.requestReportTimes, 2; got != want {
+		t.Errorf("ReportRouteRequest was triggered %v times, want %v", got, want)
+	}
+}
+
+func TestRouteMetricHandlerUpgradedConnection(t *testing.T) {
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+	})
+	r := &fakeRouteStatsReporter{}
+	handler, err := NewRouteMetricHandler(baseHandler, r, NewRouteMatcher(nil))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	handler.ServeHTTP(&fakeHijackableWriter{httptest.NewRecorder()}, req)
+
+	if got, want := r.requestReportTimes, 0; got != want {
+		t.Errorf("ReportRouteRequest was triggered %v times, want %v", got, want)
+	}
+	if got, want := r.lastInFlight, int64(0); got != want {
+		t.Errorf("In-flight requests after serving = %d, want: %d", got, want)
+	}
+}
+
+func TestRouteMetricHandlerPanickingHandler(t *testing.T) {
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("no!")
+	})
+	r := &fakeRouteStatsReporter{}
+	handler, err := NewRouteMetricHandler(baseHandler, r, NewRouteMatcher(nil))
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	defer func() {
+		if err := recover(); err == nil {
+			t.Error("Want ServeHTTP to panic, got nothing.")
+		}
+		if got, want := r.lastRespCode, http.StatusInternalServerError; got != want {
+			t.Errorf("Response code = %v, want: %v", got, want)
+		}
+		if got, want := r.lastInFlight, int64(0); got != want {
+			t.Errorf("In-flight requests after serving = %d, want: %d", got, want)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// fakeRouteStatsReporter just records the last stats it received and the
+// times ReportRouteRequest was called.
+type fakeRouteStatsReporter struct {
+	requestReportTimes int
+	lastRoute          string
+	lastRespCode       int
+	lastLatency        time.Duration
+	lastReqSize        int64
+	lastRespSize       int64
+	lastInFlight       int64
+}
+
+func (r *fakeRouteStatsReporter) ReportRouteRequest(route string, responseCode int, d time.Duration, requestSize, responseSize int64) error {
+	r.requestReportTimes++
+	r.lastRoute = route
+	r.lastRespCode = responseCode
+	r.lastLatency = d
+	r.lastReqSize = requestSize
+	r.lastRespSize = responseSize
+	return nil
+}
+
+func (r *fakeRouteStatsReporter) ReportRouteInFlight(route string, inFlight int64) error {
+	r.lastInFlight = inFlight
+	return nil
+}
@@ -40,6 +40,11 @@ const (
 	ProxiedIn
 	// ProxiedOut represents a finished proxied request.
 	ProxiedOut
+	// UpgradeIn represents the upgrade of the connection of a request, e.g.
+	// to WebSocket. The request itself is counted by ReqIn or ProxiedIn.
+	UpgradeIn
+	// UpgradeOut represents the close of an upgraded connection.
+	UpgradeOut
 )
 
 // Channels is a structure for holding the channels for driving Stats.
@@ -57,13 +62,24 @@ type Channels struct {
 type Stats struct {
 	podName string
 	ch      Channels
+	usage   UsageReader
+	limiter ConcurrencyLimiter
 }
 
-// NewStats instantiates a new instance of Stats.
-func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
+// ConcurrencyLimiter reports the concurrency limit of a container.
+type ConcurrencyLimiter interface {
+	Limit() int
+}
+
+// NewStats instantiates a new instance of Stats. If usage is not nil the
+// reported stats include the CPU and memory usage it reads. If limiter is
+// not nil they include the concurrency limit it discovered.
+func NewStats(podName string, channels Channels, startedAt time.Time, usage UsageReader, limiter ConcurrencyLimiter) *Stats {
 	s := &Stats{
 		podName: podName,
 		ch:      channels,
+		usage:   usage,
+		limiter: limiter,
 	}
 
 	go func() {
@@ -72,11 +88,21 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 			proxiedCount       float64
 			concurrency        int32
 			proxiedConcurrency int32
+			upgraded           int32
 		)
 
 		lastChange := startedAt
+		// The CPU time is cumulative, so keep the last reading to compute the rate.
+		lastRead := startedAt
+		var lastCPUTime time.Duration
+		if s.usage != nil {
+			if u, err := s.usage.Read(); err == nil {
+				lastCPUTime = u.CPUTime
+			}
+		}
 		timeOnConcurrency := make(map[int32]time.Duration)
 		timeOnProxiedConcurrency := make(map[int32]time.Duration)
+		timeOnUpgraded := make(map[int32]time.Duration)
 
 		// Updates the lastChanged/timeOnConcurrency state
 		// Note: Due to nature of the channels used below, the ReportChan
@@ -88,6 +114,7 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 				durationSinceChange := time.Sub(lastChange)
 				timeOnConcurrency[concurrency] += durationSinceChange
 				timeOnProxiedConcurrency[proxiedConcurrency] += durationSinceChange
+				timeOnUpgraded[upgraded] += durationSinceChange
 				lastChange = time
 			}
 		}
@@ -110,6 +137,10 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 					fallthrough
 				case ReqOut:
 					concurrency--
+				case UpgradeIn:
+					upgraded++
+				case UpgradeOut:
+					upgraded--
 				}
 			case now := <-s.ch.ReportChan:
 				updateState(now)
@@ -121,6 +152,21 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 					AverageProxiedConcurrentRequests: weightedAverage(timeOnProxiedConcurrency),
 					RequestCount:                     requestCount,
 					ProxiedRequestCount:              proxiedCount,
+					AverageUpgradedConnections:       weightedAverage(timeOnUpgraded),
+				}
+				if s.usage != nil {
+					// Failing to read the usage leaves the values at zero,
+					// which does not prevent scaling on requests.
+					if u, err := s.usage.Read(); err == nil {
+						if elapsed := now.Sub(lastRead); elapsed > 0 && u.CPUTime >= lastCPUTime {
+							stat.AverageCPUUsage = 1000 * float64(u.CPUTime-lastCPUTime) / float64(elapsed)
+						}
+						stat.MemoryUsage = u.MemoryWorkingSet
+						lastRead, lastCPUTime = now, u.CPUTime
+					}
+				}
+				if s.limiter != nil {
+					stat.ConcurrencyLimit = float64(s.limiter.Limit())
 				}
 				// Send the stat to another goroutine to transmit
 				// so we can continue bucketing stats.
@@ -129,6 +175,7 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 				// Reset the stat counts which have been reported.
 				timeOnConcurrency = make(map[int32]time.Duration)
 				timeOnProxiedConcurrency = make(map[int32]time.Duration)
+				timeOnUpgraded = make(map[int32]time.Duration)
 				requestCount = 0
 				proxiedCount = 0
 			}
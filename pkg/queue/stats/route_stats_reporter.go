@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/metrics/metricskey"
+)
+
+// Message sizes in bytes.
+var sizeDistribution = view.Distribution(64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216)
+
+var (
+	routeRequestCountM = stats.Int64(
+		"route_request_count",
+		"The number of requests per route",
+		stats.UnitDimensionless)
+	routeResponseTimeInMsecM = stats.Float64(
+		"route_request_latencies",
+		"The response time per route in millisecond",
+		stats.UnitMilliseconds)
+	routeRequestSizeM = stats.Int64(
+		"route_request_sizes",
+		"The size of the request bodies per route in bytes",
+		stats.UnitBytes)
+	routeResponseSizeM = stats.Int64(
+		"route_response_sizes",
+		"The size of the response bodies per route in bytes",
+		stats.UnitBytes)
+	routeInFlightM = stats.Int64(
+		"route_requests_in_flight",
+		"The number of requests per route being served",
+		stats.UnitDimensionless)
+)
+
+// RouteStatsReporter defines the interface for sending per-route queue proxy metrics.
+type RouteStatsReporter interface {
+	ReportRouteRequest(route string, responseCode int, d time.Duration, requestSize, responseSize int64) error
+	ReportRouteInFlight(route string, inFlight int64) error
+}
+
+// RouteReporter holds cached metric objects to report per-route queue proxy metrics.
+// The response codes are only reported by class to bound the cardinality.
+type RouteReporter struct {
+	initialized          bool
+	ctx                  context.Context
+	routeKey             tag.Key
+	responseCodeClassKey tag.Key
+}
+
+// NewRouteStatsReporter creates a reporter that collects and reports per-route
+// queue proxy metrics.
+func NewRouteStatsReporter(ns, service, config, rev string) (*RouteReporter, error) {
+	if ns == "" {
+		return nil, errors.New("namespace must not be empty")
+	}
+	if config == "" {
+		return nil, errors.New("config must not be empty")
+	}
+	if rev == "" {
+		return nil, errors.New("revision must not be empty")
+	}
+
+	nsTag, err := tag.NewKey(metricskey.LabelNamespaceName)
+	if err != nil {
+		return nil, err
+	}
+	svcTag, err := tag.NewKey(metricskey.LabelServiceName)
+	if err != nil {
+		return nil, err
+	}
+	configTag, err := tag.NewKey(metricskey.LabelConfigurationName)
+	if err != nil {
+		return nil, err
+	}
+	revTag, err := tag.NewKey(metricskey.LabelRevisionName)
+	if err != nil {
+		return nil, err
+	}
+	routeTag, err := tag.NewKey("route")
+	if err != nil {
+		return nil, err
+	}
+	responseCodeClassTag, err := tag.NewKey("response_code_class")
+	if err != nil {
+		return nil, err
+	}
+
+	requestTags := []tag.Key{nsTag, svcTag, configTag, revTag, routeTag, responseCodeClassTag}
+	if err = view.Register(
+		&view.View{
+			Description: routeRequestCountM.Description(),
+			Measure:     routeRequestCountM,
+			Aggregation: view.Count(),
+			TagKeys:     requestTags,
+		},
+		&view.View{
+			Description: routeResponseTimeInMsecM.Description(),
+			Measure:     routeResponseTimeInMsecM,
+			Aggregation: defaultLatencyDistribution,
+			TagKeys:     requestTags,
+		},
+		&view.View{
+			Description: routeRequestSizeM.Description(),
+			Measure:     routeRequestSizeM,
+			Aggregation: sizeDistribution,
+			TagKeys:     requestTags,
+		},
+		&view.View{
+			Description: routeResponseSizeM.Description(),
+			Measure:     routeResponseSizeM,
+			Aggregation: sizeDistribution,
+			TagKeys:     requestTags,
+		},
+		&view.View{
+			Description: routeInFlightM.Description(),
+			Measure:     routeInFlightM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{nsTag, svcTag, configTag, revTag, routeTag},
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	// Note that service name can be an empty string, so it needs a special treatment.
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(nsTag, ns),
+		tag.Insert(svcTag, valueOrUnknown(service)),
+		tag.Insert(configTag, config),
+		tag.Insert(revTag, rev),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteReporter{
+		initialized:          true,
+		ctx:                  ctx,
+		routeKey:             routeTag,
+		responseCodeClassKey: responseCodeClassTag,
+	}, nil
+}
+
+// ReportRouteRequest captures the count, response time and sizes of a request to the route.
+func (r *RouteReporter) ReportRouteRequest(route string, responseCode int, d time.Duration, requestSize, responseSize int64) error {
+	if !r.initialized {
+		return errors.New("RouteStatsReporter is not initialized yet")
+	}
+
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(r.routeKey, route),
+		tag.Insert(r.responseCodeClassKey, responseCodeClass(responseCode)))
+	if err != nil {
+		return err
+	}
+
+	// convert time.Duration in nanoseconds to milliseconds
+	metrics.Record(ctx, routeRequestCountM.M(1))
+	metrics.Record(ctx, routeResponseTimeInMsecM.M(float64(d/time.Millisecond)))
+	metrics.Record(ctx, routeRequestSizeM.M(requestSize))
+	metrics.Record(ctx, routeResponseSizeM.M(responseSize))
+	return nil
+}
+
+// ReportRouteInFlight captures the number of requests to the route being served.
+func (r *RouteReporter) ReportRouteInFlight(route string, inFlight int64) error {
+	if !r.initialized {
+		return errors.New("RouteStatsReporter is not initialized yet")
+	}
+
+	ctx, err := tag.New(r.ctx, tag.Insert(r.routeKey, route))
+	if err != nil {
+		return err
+	}
+
+	metrics.Record(ctx, routeInFlightM.M(inFlight))
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+)
+
+func TestNewRouteStatsReporterNegative(t *testing.T) {
+	for _, args := range [][]string{
+		{"", testSvc, testConf, testRev},
+		{testNs, testSvc, "", testRev},
+		{testNs, testSvc, testConf, ""},
+	} {
+		if _, err := NewRouteStatsReporter(args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("NewRouteStatsReporter(%v) succeeded, want error", args)
+		}
+	}
+}
+
+func TestRouteReporterReport(t *testing.T) {
+	r := &RouteReporter{}
+	if err := r.ReportRouteRequest("/users/{id}", 200, time.Second, 1, 1); err == nil {
+		t.Error("RouteReporter.ReportRouteRequest() expected an error for Report call before init. Got success.")
+	}
+	if err := r.ReportRouteInFlight("/users/{id}", 1); err == nil {
+		t.Error("RouteReporter.ReportRouteInFlight() expected an error for Report call before init. Got success.")
+	}
+
+	r, err := NewRouteStatsReporter(testNs, "" /*service name*/, testConf, testRev)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewRouteStatsReporter() = %v", err)
+	}
+	defer metricstest.Unregister("route_request_count", "route_request_latencies",
+		"route_request_sizes", "route_response_sizes", "route_requests_in_flight")
+
+	wantTags := map[string]string{
+		metricskey.LabelNamespaceName:     testNs,
+		metricskey.LabelServiceName:       "unknown",
+		metricskey.LabelConfigurationName: testConf,
+		metricskey.LabelRevisionName:      testRev,
+		"route":                           "/users/{id}",
+		"response_code_class":             "2xx",
+	}
+	expectSuccess(t, "ReportRouteRequest", func() error {
+		return r.ReportRouteRequest("/users/{id}", 200, 100*time.Millisecond, 10, 1000)
+	})
+	expectSuccess(t, "ReportRouteRequest", func() error {
+		return r.ReportRouteRequest("/users/{id}", 201, 300*time.Millisecond, 30, 3000)
+	})
+	metricstest.CheckCountData(t, "route_request_count", wantTags, 2)
+	metricstest.CheckDistributionData(t, "route_request_latencies", wantTags, 2, 100, 300)
+	metricstest.CheckDistributionData(t, "route_request_sizes", wantTags, 2, 10, 30)
+	metricstest.CheckDistributionData(t, "route_response_sizes", wantTags, 2, 1000, 3000)
+
+	inFlightTags := map[string]string{
+		metricskey.LabelNamespaceName:     testNs,
+		metricskey.LabelServiceName:       "unknown",
+		metricskey.LabelConfigurationName: testConf,
+		metricskey.LabelRevisionName:      testRev,
+		"route":                           "/users/{id}",
+	}
+	expectSuccess(t, "ReportRouteInFlight", func() error { return r.ReportRouteInFlight("/users/{id}", 2) })
+	expectSuccess(t, "ReportRouteInFlight", func() error { return r.ReportRouteInFlight("/users/{id}", 1) })
+	metricstest.CheckLastValueData(t, "route_requests_in_flight", inFlightTags, 1)
+}
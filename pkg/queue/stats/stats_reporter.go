@@ -33,11 +33,15 @@ import (
 // https://github.com/census-ecosystem/opencensus-go-exporter-stackdriver/issues/98
 var defaultLatencyDistribution = view.Distribution(5, 10, 20, 40, 60, 80, 100, 150, 200, 250, 300, 350, 400, 450, 500, 600, 700, 800, 900, 1000, 2000, 5000, 10000, 20000, 50000, 100000)
 
+// Upgraded connections live from seconds to days, in milliseconds.
+var connectionDurationDistribution = view.Distribution(1000, 5000, 10000, 30000, 60000, 300000, 600000, 1800000, 3600000, 7200000, 21600000, 86400000)
+
 // StatsReporter defines the interface for sending queue proxy metrics.
 type StatsReporter interface {
 	ReportRequestCount(responseCode int) error
 	ReportResponseTime(responseCode int, d time.Duration) error
 	ReportQueueDepth(depth int) error
+	ReportUpgradedConnectionDuration(d time.Duration) error
 }
 
 // Reporter holds cached metric objects to report queue proxy metrics.
@@ -52,12 +56,14 @@ type Reporter struct {
 	responseCodeClassKey tag.Key
 	countMetric          *stats.Int64Measure
 	latencyMetric        *stats.Float64Measure
-	queueSizeMetric      *stats.Int64Measure // NB: this can be nil, depending on the reporter.
+	queueSizeMetric      *stats.Int64Measure   // NB: this can be nil, depending on the reporter.
+	connDurationMetric   *stats.Float64Measure // NB: this can be nil, depending on the reporter.
 }
 
 // NewStatsReporter creates a reporter that collects and reports queue proxy metrics.
 func NewStatsReporter(ns, service, config, rev string, countMetric *stats.Int64Measure,
-	latencyMetric *stats.Float64Measure, queueSizeMetric *stats.Int64Measure) (*Reporter, error) {
+	latencyMetric *stats.Float64Measure, queueSizeMetric *stats.Int64Measure,
+	connDurationMetric *stats.Float64Measure) (*Reporter, error) {
 	if ns == "" {
 		return nil, errors.New("namespace must not be empty")
 	}
@@ -123,6 +129,18 @@ func NewStatsReporter(ns, service, config, rev string, countMetric *stats.Int64M
 			return nil, err
 		}
 	}
+	// If connection duration reporter is provided register the view for it too.
+	if connDurationMetric != nil {
+		if err = view.Register(
+			&view.View{
+				Description: "The duration of upgraded connections in millisecond",
+				Measure:     connDurationMetric,
+				Aggregation: connectionDurationDistribution,
+				TagKeys:     []tag.Key{nsTag, svcTag, configTag, revTag},
+			}); err != nil {
+			return nil, err
+		}
+	}
 
 	// Note that service name can be an empty string, so it needs a special treatment.
 	ctx, err := tag.New(
@@ -148,6 +166,7 @@ func NewStatsReporter(ns, service, config, rev string, countMetric *stats.Int64M
 		countMetric:          countMetric,
 		latencyMetric:        latencyMetric,
 		queueSizeMetric:      queueSizeMetric,
+		connDurationMetric:   connDurationMetric,
 	}, nil
 }
 
@@ -207,6 +226,21 @@ func (r *Reporter) ReportResponseTime(responseCode int, d time.Duration) error {
 	return nil
 }
 
+// ReportUpgradedConnectionDuration captures the duration of upgraded connections,
+// if the reporter is provided with the metric.
+func (r *Reporter) ReportUpgradedConnectionDuration(d time.Duration) error {
+	if !r.initialized {
+		return errors.New("StatsReporter is not initialized yet")
+	}
+	if r.connDurationMetric == nil {
+		return nil
+	}
+
+	// convert time.Duration in nanoseconds to milliseconds
+	metrics.Record(r.ctx, r.connDurationMetric.M(float64(d/time.Millisecond)))
+	return nil
+}
+
 // responseCodeClass converts response code to a string of response code class.
 // e.g. The response code class is "5xx" for response code 503.
 func responseCodeClass(responseCode int) string {
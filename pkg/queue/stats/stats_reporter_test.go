@@ -35,6 +35,7 @@ const (
 	countName   = "request_count"
 	qdepthName  = "queue_depth"
 	latencyName = "request_latencies"
+	connDurName = "upgraded_connection_durations"
 )
 
 var (
@@ -50,6 +51,10 @@ var (
 		latencyName,
 		"The response time in millisecond",
 		stats.UnitMilliseconds)
+	connDurationMetric = stats.Float64(
+		connDurName,
+		"The duration of upgraded connections in millisecond",
+		stats.UnitMilliseconds)
 )
 
 func TestNewStatsReporterNegative(t *testing.T) {
@@ -86,7 +91,7 @@ func TestNewStatsReporterNegative(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if _, err := NewStatsReporter(test.namespace, testSvc, test.config, test.revision,
-				countMetric, latencyMetric, queueSizeMetric); err.Error() != test.result.Error() {
+				countMetric, latencyMetric, queueSizeMetric, connDurationMetric); err.Error() != test.result.Error() {
 				t.Errorf("%+v, got: '%+v'", test.errorMsg, err)
 			}
 		})
@@ -104,8 +109,11 @@ func TestReporterReport(t *testing.T) {
 	if err := r.ReportResponseTime(200, time.Second); err == nil {
 		t.Error("Reporter.ReportRequestCount() expected an error for Report call before init. Got success.")
 	}
+	if err := r.ReportUpgradedConnectionDuration(time.Second); err == nil {
+		t.Error("Reporter.ReportUpgradedConnectionDuration() expected an error for Report call before init. Got success.")
+	}
 
-	r, err := NewStatsReporter(testNs, testSvc, testConf, testRev, countMetric, latencyMetric, queueSizeMetric)
+	r, err := NewStatsReporter(testNs, testSvc, testConf, testRev, countMetric, latencyMetric, queueSizeMetric, connDurationMetric)
 	if err != nil {
 		t.Fatalf("Unexpected error from NewStatsReporter() = %v", err)
 	}
@@ -140,10 +148,20 @@ func TestReporterReport(t *testing.T) {
 	expectSuccess(t, "QueueDepth", func() error { return r.ReportQueueDepth(2) })
 	metricstest.CheckLastValueData(t, "queue_depth", wantTags, 2)
 
+	connTags := map[string]string{
+		metricskey.LabelNamespaceName:     testNs,
+		metricskey.LabelServiceName:       testSvc,
+		metricskey.LabelConfigurationName: testConf,
+		metricskey.LabelRevisionName:      testRev,
+	}
+	expectSuccess(t, "ReportUpgradedConnectionDuration", func() error { return r.ReportUpgradedConnectionDuration(time.Minute) })
+	expectSuccess(t, "ReportUpgradedConnectionDuration", func() error { return r.ReportUpgradedConnectionDuration(time.Hour) })
+	metricstest.CheckDistributionData(t, connDurName, connTags, 2, 60000, 3600000)
+
 	unregisterViews(r)
 
 	// Test reporter with empty service name
-	r, err = NewStatsReporter(testNs, "" /*service name*/, testConf, testRev, countMetric, latencyMetric, queueSizeMetric)
+	r, err = NewStatsReporter(testNs, "" /*service name*/, testConf, testRev, countMetric, latencyMetric, queueSizeMetric, nil)
 	if err != nil {
 		t.Fatalf("Unexpected error from NewStatsReporter() = %v", err)
 	}
@@ -160,6 +178,9 @@ func TestReporterReport(t *testing.T) {
 	expectSuccess(t, "ReportRequestCount", func() error { return r.ReportRequestCount(200) })
 	metricstest.CheckCountData(t, "request_count", wantTags, 1)
 
+	// Without the metric, connection durations are dropped.
+	expectSuccess(t, "ReportUpgradedConnectionDuration", func() error { return r.ReportUpgradedConnectionDuration(time.Minute) })
+
 	unregisterViews(r)
 }
 
@@ -174,7 +195,7 @@ func unregisterViews(r *Reporter) error {
 	if !r.initialized {
 		return errors.New("reporter is not initialized")
 	}
-	metricstest.Unregister(countName, latencyName, qdepthName)
+	metricstest.Unregister(countName, latencyName, qdepthName, connDurName)
 	r.initialized = false
 	return nil
 }
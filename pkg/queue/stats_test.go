@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"knative.dev/serving/pkg/autoscaler"
 )
 
@@ -257,6 +258,125 @@ func TestTwoRequestsOneProxied(t *testing.T) {
 	}
 }
 
+type fakeUsageReader struct {
+	usage []ResourceUsage
+}
+
+func (f *fakeUsageReader) Read() (ResourceUsage, error) {
+	ret := f.usage[0]
+	if len(f.usage) > 1 {
+		f.usage = f.usage[1:]
+	}
+	return ret, nil
+}
+
+func TestResourceUsage(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsWithUsage(now, &fakeUsageReader{usage: []ResourceUsage{{
+		CPUTime: time.Second,
+	}, {
+		CPUTime:          time.Second + 500*time.Millisecond,
+		MemoryWorkingSet: 1 << 20,
+	}, {
+		CPUTime:          time.Second + 600*time.Millisecond,
+		MemoryWorkingSet: 2 << 20,
+	}}})
+
+	// Half a core over the first second.
+	now = now.Add(time.Second)
+	got := s.report(now)
+	want := &autoscaler.Stat{
+		Time:            &now,
+		PodName:         podName,
+		AverageCPUUsage: 500,
+		MemoryUsage:     1 << 20,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+
+	now = now.Add(time.Second)
+	got = s.report(now)
+	want = &autoscaler.Stat{
+		Time:            &now,
+		PodName:         podName,
+		AverageCPUUsage: 100,
+		MemoryUsage:     2 << 20,
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
+type fakeLimiter int
+
+func (f fakeLimiter) Limit() int {
+	return int(f)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsWithLimiter(now, nil, fakeLimiter(7))
+
+	now = now.Add(time.Second)
+	got := s.report(now)
+	want := &autoscaler.Stat{
+		Time:             &now,
+		PodName:          podName,
+		ConcurrencyLimit: 7,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
+func TestUpgradedConnection(t *testing.T) {
+	now := time.Now()
+	s := newTestStats(now)
+
+	s.requestStart(now)
+	now = now.Add(500 * time.Millisecond)
+	s.upgradeStart(now)
+	now = now.Add(1 * time.Second)
+	got := s.report(now)
+
+	want := &autoscaler.Stat{
+		Time:                       &now,
+		PodName:                    podName,
+		AverageConcurrentRequests:  1.0,
+		RequestCount:               1,
+		AverageUpgradedConnections: float64(1) / float64(1.5),
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+
+	// The connection stays open over the whole next period.
+	now = now.Add(1 * time.Second)
+	got = s.report(now)
+	want = &autoscaler.Stat{
+		Time:                       &now,
+		PodName:                    podName,
+		AverageConcurrentRequests:  1.0,
+		AverageUpgradedConnections: 1.0,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+
+	s.upgradeEnd(now)
+	s.requestEnd(now)
+	now = now.Add(1 * time.Second)
+	got = s.report(now)
+	want = &autoscaler.Stat{
+		Time:    &now,
+		PodName: podName,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
 // Test type to hold the bi-directional time channels
 type testStats struct {
 	Stats
@@ -264,13 +384,21 @@ type testStats struct {
 }
 
 func newTestStats(now time.Time) *testStats {
+	return newTestStatsWithUsage(now, nil)
+}
+
+func newTestStatsWithUsage(now time.Time, usage UsageReader) *testStats {
+	return newTestStatsWithLimiter(now, usage, nil)
+}
+
+func newTestStatsWithLimiter(now time.Time, usage UsageReader, limiter ConcurrencyLimiter) *testStats {
 	reportBiChan := make(chan time.Time)
 	ch := Channels{
 		ReqChan:    make(chan ReqEvent),
 		ReportChan: (<-chan time.Time)(reportBiChan),
 		StatChan:   make(chan *autoscaler.Stat),
 	}
-	s := NewStats(podName, ch, now)
+	s := NewStats(podName, ch, now, usage, limiter)
 	t := &testStats{
 		Stats:        *s,
 		reportBiChan: reportBiChan,
@@ -294,6 +422,14 @@ func (s *testStats) proxiedEnd(now time.Time) {
 	s.ch.ReqChan <- ReqEvent{Time: now, EventType: ProxiedOut}
 }
 
+func (s *testStats) upgradeStart(now time.Time) {
+	s.ch.ReqChan <- ReqEvent{Time: now, EventType: UpgradeIn}
+}
+
+func (s *testStats) upgradeEnd(now time.Time) {
+	s.ch.ReqChan <- ReqEvent{Time: now, EventType: UpgradeOut}
+}
+
 func (s *testStats) report(now time.Time) *autoscaler.Stat {
 	s.reportBiChan <- now
 	return <-s.ch.StatChan
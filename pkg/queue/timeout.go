@@ -44,17 +44,37 @@ import (
 //
 // The implementation is largely inspired by http.TimeoutHandler.
 func TimeToFirstByteTimeoutHandler(h http.Handler, dt time.Duration, msg string) http.Handler {
+	return TimeoutHandler(h, TimeoutParams{FirstByte: dt}, msg)
+}
+
+// TimeoutParams defines the time limits of a request. A zero value
+// disables the respective limit.
+type TimeoutParams struct {
+	// FirstByte limits the time until the first byte of the response is written.
+	FirstByte time.Duration
+	// Idle limits the time a response that started may go without writes.
+	Idle time.Duration
+	// Max limits the overall duration of the request.
+	Max time.Duration
+}
+
+// TimeoutHandler returns a Handler that runs `h` with the given time limits.
+// It behaves like TimeToFirstByteTimeoutHandler until the first byte of the
+// response is written. After that, exceeding the idle or the max limit cancels
+// the request and aborts the response, which can't be answered with an error
+// anymore, by panicking with http.ErrAbortHandler.
+func TimeoutHandler(h http.Handler, params TimeoutParams, msg string) http.Handler {
 	return &timeoutHandler{
 		handler: h,
 		body:    msg,
-		dt:      dt,
+		params:  params,
 	}
 }
 
 type timeoutHandler struct {
 	handler http.Handler
 	body    string
-	dt      time.Duration
+	params  TimeoutParams
 }
 
 func (h *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -80,22 +100,65 @@ func (h *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handler.ServeHTTP(tw, r.WithContext(ctx))
 	}()
 
-	timeout := time.NewTimer(h.dt)
-	defer timeout.Stop()
+	firstByteTimeout := newTimer(h.params.FirstByte)
+	defer firstByteTimeout.Stop()
+	idleTimeout := newTimer(h.params.Idle)
+	defer idleTimeout.Stop()
+	maxTimeout := newTimer(h.params.Max)
+	defer maxTimeout.Stop()
 	for {
 		select {
 		case p := <-panicChan:
 			panic(p)
 		case <-done:
 			return
-		case <-timeout.C:
+		case <-firstByteTimeout.C:
+			if tw.TimeoutAndWriteError(h.body) {
+				return
+			}
+		case now := <-idleTimeout.C:
+			// Responses that didn't start yet are not idle but waiting for
+			// their first byte.
+			idle, known := tw.idleSince(now)
+			if !known || idle < h.params.Idle {
+				idleTimeout.Reset(h.params.Idle - idle)
+				continue
+			}
+			h.abort(tw, cancelCtx, done, panicChan)
+		case <-maxTimeout.C:
 			if tw.TimeoutAndWriteError(h.body) {
 				return
 			}
+			h.abort(tw, cancelCtx, done, panicChan)
 		}
 	}
 }
 
+// abort cancels a request whose response already started, waits for the
+// handler to give up on it and aborts the response.
+func (h *timeoutHandler) abort(tw *timeoutWriter, cancelCtx context.CancelFunc, done chan struct{}, panicChan chan interface{}) {
+	tw.timeout()
+	cancelCtx()
+	// Handlers like httputil.ReverseProxy panic when their writes fail, so
+	// wait for the handler not to leave a panic behind.
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// newTimer returns a timer firing after d, or never if d is 0.
+func newTimer(d time.Duration) *time.Timer {
+	if d <= 0 {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	}
+	return time.NewTimer(d)
+}
+
 // timeoutWriter is a wrapper around an http.ResponseWriter. It guards
 // writing an error response to whether or not the underlying writer has
 // already been written to.
@@ -109,6 +172,9 @@ type timeoutWriter struct {
 	mu        sync.Mutex
 	timedOut  bool
 	wroteOnce bool
+	lastWrite time.Time
+	// conn is the hijacked connection, if any.
+	conn net.Conn
 }
 
 var _ http.Flusher = (*timeoutWriter)(nil)
@@ -122,8 +188,22 @@ func (tw *timeoutWriter) Flush() {
 // Hijack calls Hijack() on the wrapped http.ResponseWriter if it implements
 // http.Hijacker interface, which is required for net/http/httputil/reverseproxy
 // to handle connection upgrade/switching protocol.  Otherwise returns an error.
+//
+// Hijacking counts as writing the response, so that upgraded connections are
+// not timed out waiting for their first byte.
 func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return websocket.HijackIfPossible(tw.w)
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+
+	c, rw, err := websocket.HijackIfPossible(tw.w)
+	if err == nil {
+		tw.wroteOnce = true
+		tw.conn = c
+	}
+	return c, rw, err
 }
 
 func (tw *timeoutWriter) Header() http.Header { return tw.w.Header() }
@@ -136,6 +216,7 @@ func (tw *timeoutWriter) Write(p []byte) (int, error) {
 	}
 
 	tw.wroteOnce = true
+	tw.lastWrite = time.Now()
 	return tw.w.Write(p)
 }
 
@@ -147,9 +228,33 @@ func (tw *timeoutWriter) WriteHeader(code int) {
 	}
 
 	tw.wroteOnce = true
+	tw.lastWrite = time.Now()
 	tw.w.WriteHeader(code)
 }
 
+// idleSince returns how long before now the writer was last written to,
+// and whether that is known. It is not for responses that didn't start
+// yet, nor for hijacked connections, whose writes bypass the writer.
+func (tw *timeoutWriter) idleSince(now time.Time) (time.Duration, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.wroteOnce || tw.conn != nil {
+		return 0, false
+	}
+	return now.Sub(tw.lastWrite), true
+}
+
+// timeout makes all subsequent writes result in http.ErrHandlerTimeout
+// and closes the hijacked connection, if any.
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if tw.conn != nil {
+		tw.conn.Close()
+	}
+}
+
 // TimeoutAndError writes an error to the response write if
 // nothing has been written on the writer before. Returns whether
 // an error was written or not.
@@ -16,8 +16,11 @@ limitations under the License.
 package queue
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -128,3 +131,164 @@ func TestTimeToFirstByteTimeoutHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeoutHandler(t *testing.T) {
+	// stream writes to w until it fails or the request is canceled.
+	stream := func(w http.ResponseWriter, r *http.Request) {
+		for {
+			if _, err := w.Write([]byte("hi")); err != nil {
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		params     TimeoutParams
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+		wantAbort  bool
+	}{{
+		name:   "steady stream",
+		params: TimeoutParams{Idle: 50 * time.Millisecond},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 5; i++ {
+				time.Sleep(20 * time.Millisecond)
+				w.Write([]byte("hi"))
+			}
+		},
+		wantStatus: http.StatusOK,
+		wantBody:   "hihihihihi",
+	}, {
+		name:   "idle before first byte",
+		params: TimeoutParams{FirstByte: time.Second, Idle: 10 * time.Millisecond},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("hi"))
+		},
+		wantStatus: http.StatusOK,
+		wantBody:   "hi",
+	}, {
+		name:   "stalled stream",
+		params: TimeoutParams{Idle: 50 * time.Millisecond},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hi"))
+			<-r.Context().Done()
+		},
+		wantStatus: http.StatusOK,
+		wantBody:   "hi",
+		wantAbort:  true,
+	}, {
+		name:   "max duration before first byte",
+		params: TimeoutParams{Max: 10 * time.Millisecond},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		},
+		wantStatus: http.StatusServiceUnavailable,
+		wantBody:   "request timeout",
+	}, {
+		name:       "max duration of stream",
+		params:     TimeoutParams{Idle: 50 * time.Millisecond, Max: 100 * time.Millisecond},
+		handler:    stream,
+		wantStatus: http.StatusOK,
+		wantBody:   "hi",
+		wantAbort:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler := TimeoutHandler(test.handler, test.params, "request timeout")
+
+			func() {
+				defer func() {
+					recovered := recover()
+					if test.wantAbort && recovered != http.ErrAbortHandler {
+						t.Errorf("Expected the handler to abort, got: %v", recovered)
+					} else if !test.wantAbort && recovered != nil {
+						t.Errorf("Unexpected panic: %v", recovered)
+					}
+				}()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+
+			if got := rr.Code; got != test.wantStatus {
+				t.Errorf("Status = %d, want: %d", got, test.wantStatus)
+			}
+			if got := rr.Body.String(); !strings.HasPrefix(got, test.wantBody) {
+				t.Errorf("Body = %q, want prefix: %q", got, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestTimeoutHandlerHijacked(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    TimeoutParams
+		wantAbort bool
+	}{{
+		name:   "no first byte timeout",
+		params: TimeoutParams{FirstByte: 10 * time.Millisecond, Idle: 10 * time.Millisecond},
+	}, {
+		name:      "max duration",
+		params:    TimeoutParams{Max: 50 * time.Millisecond},
+		wantAbort: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			rr := httptest.NewRecorder()
+			w := &pipeHijackableWriter{ResponseRecorder: rr, conn: server}
+
+			handler := TimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Errorf("Hijack() = %v", err)
+					return
+				}
+				// Read until the connection is closed or a second passed.
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				conn.Read(make([]byte, 1))
+			}), test.params, "request timeout")
+
+			start := time.Now()
+			func() {
+				defer func() {
+					recovered := recover()
+					if test.wantAbort && recovered != http.ErrAbortHandler {
+						t.Errorf("Expected the handler to abort, got: %v", recovered)
+					} else if !test.wantAbort && recovered != nil {
+						t.Errorf("Unexpected panic: %v", recovered)
+					}
+				}()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+
+			if rr.Code == http.StatusServiceUnavailable {
+				t.Error("The upgraded connection was timed out waiting for its first byte")
+			}
+			if elapsed := time.Since(start); test.wantAbort && elapsed >= time.Second {
+				t.Errorf("The connection was not closed at the max duration, took %v", elapsed)
+			}
+		})
+	}
+}
+
+// pipeHijackableWriter hands out one end of a pipe when hijacked.
+type pipeHijackableWriter struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (w *pipeHijackableWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"knative.dev/pkg/websocket"
+)
+
+// TrackUpgrade wraps w so that hijacking the connection of r to upgrade it,
+// e.g. to WebSocket, sends an UpgradeIn event to reqChan. The returned function
+// sends the matching UpgradeOut event, if any, and must be called once the
+// upgraded connection is closed, i.e. the handler of r returned.
+// Requests that don't ask for an upgrade are not tracked.
+func TrackUpgrade(w http.ResponseWriter, r *http.Request, reqChan chan ReqEvent) (http.ResponseWriter, func()) {
+	if r.Header.Get("Upgrade") == "" {
+		return w, func() {}
+	}
+
+	uw := &upgradeWriter{ResponseWriter: w, reqChan: reqChan}
+	return uw, func() {
+		if atomic.LoadInt32(&uw.upgraded) == 1 {
+			reqChan <- ReqEvent{Time: time.Now(), EventType: UpgradeOut}
+		}
+	}
+}
+
+type upgradeWriter struct {
+	http.ResponseWriter
+	reqChan chan ReqEvent
+	// upgraded is whether the connection has been hijacked. Like in
+	// ResponseRecorder, access to it is only through atomic calls.
+	upgraded int32
+}
+
+var (
+	_ http.Flusher  = (*upgradeWriter)(nil)
+	_ http.Hijacker = (*upgradeWriter)(nil)
+)
+
+// Flush flushes the buffer to the client.
+func (uw *upgradeWriter) Flush() {
+	uw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack hijacks the connection of the wrapped http.ResponseWriter, if it
+// supports it, and records the upgrade.
+func (uw *upgradeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, rw, err := websocket.HijackIfPossible(uw.ResponseWriter)
+	if err == nil && atomic.CompareAndSwapInt32(&uw.upgraded, 0, 1) {
+		uw.reqChan <- ReqEvent{Time: time.Now(), EventType: UpgradeIn}
+	}
+	return c, rw, err
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrackUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		upgrade    string
+		writer     http.ResponseWriter
+		hijack     bool
+		wantEvents []ReqEventType
+	}{{
+		name:   "no upgrade requested",
+		writer: &fakeHijackableWriter{httptest.NewRecorder()},
+		hijack: true,
+	}, {
+		name:    "upgrade requested, not hijacked",
+		upgrade: "websocket",
+		writer:  &fakeHijackableWriter{httptest.NewRecorder()},
+	}, {
+		name:    "upgrade fails",
+		upgrade: "websocket",
+		writer:  httptest.NewRecorder(),
+		hijack:  true,
+	}, {
+		name:       "upgraded",
+		upgrade:    "websocket",
+		writer:     &fakeHijackableWriter{httptest.NewRecorder()},
+		hijack:     true,
+		wantEvents: []ReqEventType{UpgradeIn, UpgradeOut},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqChan := make(chan ReqEvent, 10)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.upgrade != "" {
+				r.Header.Set("Connection", "Upgrade")
+				r.Header.Set("Upgrade", test.upgrade)
+			}
+
+			w, closeUpgrade := TrackUpgrade(test.writer, r, reqChan)
+			if test.hijack {
+				if hj, ok := w.(http.Hijacker); ok {
+					hj.Hijack()
+				}
+			}
+			closeUpgrade()
+			close(reqChan)
+
+			var got []ReqEventType
+			for e := range reqChan {
+				got = append(got, e.EventType)
+			}
+			if len(got) != len(test.wantEvents) {
+				t.Fatalf("Events = %v, want: %v", got, test.wantEvents)
+			}
+			for i := range got {
+				if got[i] != test.wantEvents[i] {
+					t.Errorf("Events = %v, want: %v", got, test.wantEvents)
+				}
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package kpa
 import (
 	"context"
 	"fmt"
+	"time"
 
 	perrors "github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -282,7 +283,7 @@ func computeActiveCondition(pa *pav1alpha1.PodAutoscaler, want int32, got int) {
 
 // activeThreshold returns the scale required for the pa to be marked Active
 func activeThreshold(pa *pav1alpha1.PodAutoscaler) int {
-	min, _ := pa.ScaleBounds()
+	min, _ := pa.ScaleBoundsAt(time.Now())
 	if min < 1 {
 		min = 1
 	}
@@ -18,6 +18,7 @@ package kpa
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -1399,6 +1400,32 @@ func TestControllerUpdateError(t *testing.T) {
 	}
 }
 
+func TestControllerUpdateDeciderError(t *testing.T) {
+	defer logtesting.ClearAll()
+	ctx, _ := SetupFakeContext(t)
+
+	key := testNamespace + "/" + testRevision
+	// E.g. the algorithm of the updated spec isn't registered.
+	want := errors.New(`unknown scaling algorithm "magic"`)
+
+	ctl := NewController(ctx, newConfigWatcher(),
+		&failingDeciders{
+			decider:   &autoscaler.Decider{},
+			updateErr: want,
+		})
+
+	kpa := revisionresources.MakePA(newTestRevision(testNamespace, testRevision))
+	fakeservingclient.Get(ctx).AutoscalingV1alpha1().PodAutoscalers(testNamespace).Create(kpa)
+	fakepainformer.Get(ctx).Informer().GetIndexer().Add(kpa)
+
+	newDeployment(t, fakedynamicclient.Get(ctx), testRevision+"-deployment", 3)
+
+	got := perrors.Cause(ctl.Reconciler.Reconcile(context.Background(), key))
+	if got != want {
+		t.Errorf("Reconcile() = %v, wanted %v", got, want)
+	}
+}
+
 func TestControllerGetError(t *testing.T) {
 	defer logtesting.ClearAll()
 	ctx, _ := SetupFakeContext(t)
@@ -1516,13 +1543,15 @@ func (km *testDeciders) Update(ctx context.Context, decider *autoscaler.Decider)
 func (km *testDeciders) Watch(fn func(string)) {}
 
 type failingDeciders struct {
+	decider   *autoscaler.Decider
 	getErr    error
 	createErr error
 	deleteErr error
+	updateErr error
 }
 
 func (km *failingDeciders) Get(ctx context.Context, namespace, name string) (*autoscaler.Decider, error) {
-	return nil, km.getErr
+	return km.decider, km.getErr
 }
 
 func (km *failingDeciders) Create(ctx context.Context, decider *autoscaler.Decider) (*autoscaler.Decider, error) {
@@ -1537,6 +1566,9 @@ func (km *failingDeciders) Watch(fn func(string)) {
 }
 
 func (km *failingDeciders) Update(ctx context.Context, decider *autoscaler.Decider) (*autoscaler.Decider, error) {
+	if km.updateErr != nil {
+		return nil, km.updateErr
+	}
 	return decider, nil
 }
 
@@ -1621,3 +1653,39 @@ func (t *testConfigStore) ToContext(ctx context.Context) context.Context {
 }
 
 var _ reconciler.ConfigStore = (*testConfigStore)(nil)
+
+func TestActiveThreshold(t *testing.T) {
+	tests := []struct {
+		name string
+		pa   *asv1a1.PodAutoscaler
+		want int
+	}{{
+		name: "no bounds",
+		pa:   kpa(testNamespace, testRevision),
+		want: 1,
+	}, {
+		name: "min scale",
+		pa:   kpa(testNamespace, testRevision, WithReachabilityReachable, WithLowerScaleBound(2)),
+		want: 2,
+	}, {
+		name: "scheduled min scale",
+		pa: kpa(testNamespace, testRevision, WithReachabilityReachable, WithLowerScaleBound(2), func(pa *asv1a1.PodAutoscaler) {
+			pa.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=5"
+		}),
+		want: 5,
+	}, {
+		name: "unreachable",
+		pa: kpa(testNamespace, testRevision, WithReachabilityUnreachable, WithLowerScaleBound(2), func(pa *asv1a1.PodAutoscaler) {
+			pa.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=5"
+		}),
+		want: 1,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := activeThreshold(test.pa); got != test.want {
+				t.Errorf("activeThreshold() = %d, want: %d", got, test.want)
+			}
+		})
+	}
+}
@@ -58,18 +58,31 @@ func MakeDecider(ctx context.Context, pa *v1alpha1.PodAutoscaler, config *autosc
 	if x, ok := pa.TargetBC(); ok {
 		tbc = x
 	}
+
+	maxScaleDownRate := config.MaxScaleDownRate
+	if x, ok := pa.MaxScaleDownRate(); ok {
+		maxScaleDownRate = x
+	}
+
+	stabilizationWindow := config.ScaleDownStabilizationWindow
+	if x, ok := pa.ScaleDownStabilizationWindow(); ok {
+		stabilizationWindow = x
+	}
 	return &autoscaler.Decider{
 		ObjectMeta: *pa.ObjectMeta.DeepCopy(),
 		Spec: autoscaler.DeciderSpec{
-			TickInterval:        config.TickInterval,
-			MaxScaleUpRate:      config.MaxScaleUpRate,
-			ScalingMetric:       pa.Metric(),
-			TargetValue:         target,
-			TotalValue:          total,
-			TargetBurstCapacity: tbc,
-			PanicThreshold:      panicThreshold,
-			StableWindow:        resources.StableWindow(pa, config),
-			ServiceName:         svc,
+			TickInterval:                 config.TickInterval,
+			MaxScaleUpRate:               config.MaxScaleUpRate,
+			MaxScaleDownRate:             maxScaleDownRate,
+			ScaleDownStabilizationWindow: stabilizationWindow,
+			ScalingMetric:                pa.Metric(),
+			Algorithm:                    pa.Algorithm(),
+			TargetValue:                  target,
+			TotalValue:                   total,
+			TargetBurstCapacity:          tbc,
+			PanicThreshold:               panicThreshold,
+			StableWindow:                 resources.StableWindow(pa, config),
+			ServiceName:                  svc,
 		},
 	}
 }
@@ -109,6 +109,35 @@ func TestMakeDecider(t *testing.T) {
 		name: "with metric annotation",
 		pa:   pa(WithMetricAnnotation("rps")),
 		want: decider(withTarget(100.0), withPanicThreshold(200.0), withTotal(100), withMetric("rps"), withMetricAnnotation("rps")),
+	}, {
+		name: "with scale down annotations",
+		pa: pa(withPAAnnotation(autoscaling.MaxScaleDownRateAnnotationKey, "1.5"),
+			withPAAnnotation(autoscaling.ScaleDownStabilizationWindowAnnotationKey, "5m")),
+		want: decider(withTarget(100.0), withPanicThreshold(200.0), withTotal(100),
+			withDeciderAnnotation(autoscaling.MaxScaleDownRateAnnotationKey, "1.5"),
+			withDeciderAnnotation(autoscaling.ScaleDownStabilizationWindowAnnotationKey, "5m"),
+			func(d *autoscaler.Decider) {
+				d.Spec.MaxScaleDownRate = 1.5
+				d.Spec.ScaleDownStabilizationWindow = 5 * time.Minute
+			}),
+	}, {
+		name: "with scale down config",
+		pa:   pa(),
+		want: decider(withTarget(100.0), withPanicThreshold(200.0), withTotal(100),
+			func(d *autoscaler.Decider) {
+				d.Spec.MaxScaleDownRate = 3
+				d.Spec.ScaleDownStabilizationWindow = time.Minute
+			}),
+		cfgOpt: func(c autoscaler.Config) *autoscaler.Config {
+			c.MaxScaleDownRate = 3
+			c.ScaleDownStabilizationWindow = time.Minute
+			return &c
+		},
+	}, {
+		name: "with algorithm annotation",
+		pa:   pa(withAlgorithmAnnotation(autoscaling.PIDAlgorithm)),
+		want: decider(withTarget(100.0), withPanicThreshold(200.0), withTotal(100),
+			withAlgorithm(autoscaling.PIDAlgorithm), withDeciderAlgorithmAnnotation(autoscaling.PIDAlgorithm)),
 	}}
 
 	for _, tc := range cases {
@@ -170,8 +199,10 @@ func decider(options ...DeciderOption) *autoscaler.Decider {
 		},
 		Spec: autoscaler.DeciderSpec{
 			MaxScaleUpRate:      config.MaxScaleUpRate,
+			MaxScaleDownRate:    config.MaxScaleDownRate,
 			TickInterval:        config.TickInterval,
 			ScalingMetric:       "concurrency",
+			Algorithm:           autoscaling.KPADefaultAlgorithm,
 			TargetValue:         100,
 			TotalValue:          100,
 			TargetBurstCapacity: 211,
@@ -187,6 +218,36 @@ func decider(options ...DeciderOption) *autoscaler.Decider {
 
 type DeciderOption func(*autoscaler.Decider)
 
+func withPAAnnotation(k, v string) PodAutoscalerOption {
+	return func(pa *v1alpha1.PodAutoscaler) {
+		pa.Annotations[k] = v
+	}
+}
+
+func withDeciderAnnotation(k, v string) DeciderOption {
+	return func(d *autoscaler.Decider) {
+		d.Annotations[k] = v
+	}
+}
+
+func withAlgorithmAnnotation(algorithm string) PodAutoscalerOption {
+	return func(pa *v1alpha1.PodAutoscaler) {
+		pa.Annotations[autoscaling.AlgorithmAnnotationKey] = algorithm
+	}
+}
+
+func withDeciderAlgorithmAnnotation(algorithm string) DeciderOption {
+	return func(d *autoscaler.Decider) {
+		d.Annotations[autoscaling.AlgorithmAnnotationKey] = algorithm
+	}
+}
+
+func withAlgorithm(algorithm string) DeciderOption {
+	return func(decider *autoscaler.Decider) {
+		decider.Spec.Algorithm = algorithm
+	}
+}
+
 func withMetric(metric string) DeciderOption {
 	return func(decider *autoscaler.Decider) {
 		decider.Spec.ScalingMetric = metric
@@ -247,6 +308,7 @@ var config = &autoscaler.Config{
 	ContainerConcurrencyTargetDefault:  100.0,
 	TargetBurstCapacity:                211.0,
 	MaxScaleUpRate:                     10.0,
+	MaxScaleDownRate:                   2.0,
 	RPSTargetDefault:                   100,
 	TargetUtilization:                  1.0,
 	StableWindow:                       60 * time.Second,
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -88,6 +89,11 @@ type scaler struct {
 	// For async probes.
 	probeManager asyncProber
 	enqueueCB    func(interface{}, time.Duration)
+
+	// boundaries are the next boundaries of the min scale schedules the
+	// PAs were enqueued for. Guarded by boundariesMux.
+	boundariesMux sync.Mutex
+	boundaries    map[types.NamespacedName]time.Time
 }
 
 // newScaler creates a scaler.
@@ -261,12 +267,20 @@ func (ks *scaler) applyScale(ctx context.Context, pa *pav1alpha1.PodAutoscaler,
 func (ks *scaler) Scale(ctx context.Context, pa *pav1alpha1.PodAutoscaler, sks *nv1a1.ServerlessService, desiredScale int32) (int32, error) {
 	logger := logging.FromContext(ctx)
 
+	now := time.Now()
+	min, max := pa.ScaleBoundsAt(now)
+	ks.enqueueAtNextBoundary(pa, now)
+
 	if desiredScale < 0 && !pa.Status.IsActivating() {
+		// The scheduled min scale has to be in place when the window opens,
+		// whether or not there are metrics yet.
+		if staticMin, _ := pa.ScaleBounds(); min > staticMin {
+			return ks.raiseToMin(ctx, pa, desiredScale, min)
+		}
 		logger.Debug("Metrics are not yet being collected.")
 		return desiredScale, nil
 	}
 
-	min, max := pa.ScaleBounds()
 	if newScale := applyBounds(min, max, desiredScale); newScale != desiredScale {
 		logger.Debugf("Adjusting desiredScale to meet the min and max bounds before applying: %d -> %d", desiredScale, newScale)
 		desiredScale = newScale
@@ -294,3 +308,60 @@ func (ks *scaler) Scale(ctx context.Context, pa *pav1alpha1.PodAutoscaler, sks *
 	logger.Infof("Scaling from %d to %d", currentScale, desiredScale)
 	return ks.applyScale(ctx, pa, desiredScale, ps)
 }
+
+// raiseToMin scales the target up to the scheduled min scale, if it is
+// below it. Without metrics the target is never scaled down.
+func (ks *scaler) raiseToMin(ctx context.Context, pa *pav1alpha1.PodAutoscaler, desiredScale, min int32) (int32, error) {
+	logger := logging.FromContext(ctx)
+
+	ps, err := resources.GetScaleResource(pa.Namespace, pa.Spec.ScaleTargetRef, ks.psInformerFactory)
+	if err != nil {
+		logger.Errorw(fmt.Sprintf("Resource %v not found", pa.Spec.ScaleTargetRef), zap.Error(err))
+		return desiredScale, err
+	}
+	currentScale := int32(1)
+	if ps.Spec.Replicas != nil {
+		currentScale = *ps.Spec.Replicas
+	}
+	if currentScale >= min {
+		logger.Debug("Metrics are not yet being collected.")
+		return desiredScale, nil
+	}
+
+	logger.Infof("Scaling up to the scheduled minScale %d", min)
+	return ks.applyScale(ctx, pa, min, ps)
+}
+
+// enqueueAtNextBoundary enqueues the PA for when the next window of its min
+// scale schedule opens or closes. It enqueues the PA only once per boundary.
+func (ks *scaler) enqueueAtNextBoundary(pa *pav1alpha1.PodAutoscaler, now time.Time) {
+	if pa.Spec.Reachability == pav1alpha1.ReachabilityUnreachable {
+		return
+	}
+	schedule, ok := pa.MinScaleSchedule()
+	if !ok {
+		return
+	}
+	next := schedule.NextBoundary(now)
+	if next.IsZero() {
+		return
+	}
+
+	key := types.NamespacedName{Namespace: pa.Namespace, Name: pa.Name}
+	ks.boundariesMux.Lock()
+	defer ks.boundariesMux.Unlock()
+	if ks.boundaries[key].Equal(next) {
+		return
+	}
+	if ks.boundaries == nil {
+		ks.boundaries = make(map[types.NamespacedName]time.Time)
+	}
+	// Forget the boundaries that passed, so deleted PAs don't leak.
+	for k, b := range ks.boundaries {
+		if !b.After(now) {
+			delete(ks.boundaries, k)
+		}
+	}
+	ks.boundaries[key] = next
+	ks.enqueueCB(pa, next.Sub(now))
+}
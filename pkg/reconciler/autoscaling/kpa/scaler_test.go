@@ -272,6 +272,68 @@ func TestScaler(t *testing.T) {
 			paMarkInactive(k, time.Now().Add(-gracePeriod))
 			WithReachabilityUnknown(k)
 		},
+	}, {
+		label:         "scale down to scheduled minScale",
+		startReplicas: 10,
+		scaleTo:       0,
+		minScale:      2,
+		wantReplicas:  3,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=3"
+			paMarkInactive(k, time.Now().Add(-gracePeriod))
+			WithReachabilityReachable(k)
+		},
+		wantCBCount: 1,
+	}, {
+		label:         "scheduled minScale is bound by maxScale",
+		startReplicas: 10,
+		scaleTo:       0,
+		minScale:      2,
+		maxScale:      4,
+		wantReplicas:  4,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=30"
+			paMarkInactive(k, time.Now().Add(-gracePeriod))
+			WithReachabilityReachable(k)
+		},
+		wantCBCount: 1,
+	}, {
+		label:         "scales up to scheduled minScale with no metrics",
+		startReplicas: 0,
+		scaleTo:       -1, // no metrics
+		wantReplicas:  3,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=3"
+			paMarkInactive(k, time.Now())
+			WithReachabilityReachable(k)
+		},
+		wantCBCount: 1,
+	}, {
+		label:         "does not scale down to scheduled minScale with no metrics",
+		startReplicas: 10,
+		scaleTo:       -1, // no metrics
+		wantReplicas:  -1,
+		wantScaling:   false,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=3"
+			paMarkActive(k, time.Now())
+			WithReachabilityReachable(k)
+		},
+		wantCBCount: 1,
+	}, {
+		label:         "ignore scheduled minScale if unreachable",
+		startReplicas: 10,
+		scaleTo:       0,
+		wantReplicas:  0,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=3"
+			paMarkInactive(k, time.Now().Add(-gracePeriod))
+			WithReachabilityUnreachable(k)
+		},
 	}, {
 		label:         "scales up",
 		startReplicas: 1,
@@ -393,6 +455,41 @@ func TestScaler(t *testing.T) {
 	}
 }
 
+func TestScalerEnqueuesOncePerBoundary(t *testing.T) {
+	defer logtesting.ClearAll()
+	ctx, _ := SetupFakeContext(t)
+	dynamicClient := fakedynamicclient.Get(ctx)
+
+	revision := newRevision(t, fakeservingclient.Get(ctx), 0, 0)
+	newDeployment(t, dynamicClient, names.Deployment(revision), 3)
+	var delays []time.Duration
+	revisionScaler := newScaler(ctx, presources.NewPodScalableInformerFactory(ctx), func(_ interface{}, d time.Duration) {
+		delays = append(delays, d)
+	})
+	dynamicClient.PrependReactor("patch", "deployments",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			return true, nil, nil
+		})
+
+	pa := newKPA(t, fakeservingclient.Get(ctx), revision)
+	pa.Annotations[autoscaling.MinScaleScheduleAnnotationKey] = "daily 00:00-24:00 minScale=3"
+	paMarkActive(pa, time.Now())
+	WithReachabilityReachable(pa)
+
+	ctx = config.ToContext(ctx, defaultConfig())
+	for i := 0; i < 3; i++ {
+		if _, err := revisionScaler.Scale(ctx, pa, sks("ns", "name"), 3); err != nil {
+			t.Fatal("Scale got an unexpected error: ", err)
+		}
+	}
+	if got, want := len(delays), 1; got != want {
+		t.Fatalf("Enqueue callback invoked = %d times, want: %d", got, want)
+	}
+	if delays[0] <= 0 || delays[0] > 24*time.Hour {
+		t.Errorf("Enqueued after %v, want until the next midnight", delays[0])
+	}
+}
+
 func TestDisableScaleToZero(t *testing.T) {
 	defer logtesting.ClearAll()
 	tests := []struct {
@@ -167,6 +167,9 @@ var config = &autoscaler.Config{
 	ContainerConcurrencyTargetFraction: 1.0,
 	ContainerConcurrencyTargetDefault:  100.0,
 	RPSTargetDefault:                   200.0,
+	CPUTargetDefault:                   1000.0,
+	MemoryTargetDefault:                1024.0,
+	ConnectionsTargetDefault:           1000.0,
 	TargetUtilization:                  0.7,
 	MaxScaleUpRate:                     10.0,
 	StableWindow:                       60 * time.Second,
@@ -28,13 +28,36 @@ import (
 // and resolves them to the final value to be used by the autoscaler.
 // `target` is the target value of scaling metric that we autoscaler will aim for;
 // `total` is the maximum possible value of scaling metric that is permitted on the pod.
+// CPU is measured in millicores and memory in bytes, but memory targets are
+// specified in mebibytes. The CPU target is annotated with targetMillicores,
+// since the target annotation is a percentage of the requested cpu for the HPA.
 func ResolveMetricTarget(pa *v1alpha1.PodAutoscaler, config *autoscaler.Config) (target float64, total float64) {
 	var tu float64
+	// unit converts the configured and annotated targets to the unit the metric is collected in.
+	unit := 1.0
+	annotatedTarget := pa.Target
 
 	switch pa.Metric() {
 	case autoscaling.RPS:
 		total = config.RPSTargetDefault
 		tu = config.TargetUtilization
+	case autoscaling.CPU:
+		total = config.CPUTargetDefault
+		tu = config.TargetUtilization
+		annotatedTarget = pa.TargetMillicores
+	case autoscaling.Memory:
+		unit = 1 << 20
+		total = config.MemoryTargetDefault * unit
+		tu = config.TargetUtilization
+	case autoscaling.Connections:
+		total = config.ConnectionsTargetDefault
+		tu = config.TargetUtilization
+	case autoscaling.Custom:
+		// The target of a custom metric is required and, unlike the defaults
+		// of the other metrics, it is not a capacity, so it is used as is,
+		// for the total too.
+		total, _ = pa.Target()
+		tu = 1
 	default:
 		// Concurrency is used by default
 		total = float64(pa.Spec.ContainerConcurrency)
@@ -51,10 +74,11 @@ func ResolveMetricTarget(pa *v1alpha1.PodAutoscaler, config *autoscaler.Config)
 	target = math.Max(1, total*tu)
 
 	// Use the target provided via annotation, if applicable.
-	if annotationTarget, ok := pa.Target(); ok {
+	if annotationTarget, ok := annotatedTarget(); ok {
 		// We pick the smaller value between the calculated target and the annotationTarget
 		// to make sure the autoscaler does not aim for a higher concurrency than the application
 		// can handle per containerConcurrency.
+		annotationTarget *= unit
 		target = math.Max(1, math.Min(target, annotationTarget*tu))
 		total = math.Min(annotationTarget, total)
 	}
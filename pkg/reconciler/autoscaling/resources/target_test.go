@@ -156,6 +156,51 @@ func TestResolveMetricTarget(t *testing.T) {
 		pa:      pa(WithMetricAnnotation(autoscaling.RPS), WithTargetAnnotation("300")),
 		wantTgt: 140,
 		wantTot: 200,
+	}, {
+		name:    "CPU: defaults",
+		pa:      pa(WithMetricAnnotation(autoscaling.CPU)),
+		wantTgt: 700,
+		wantTot: 1000,
+	}, {
+		name:    "CPU: with targetMillicores annotation 500",
+		pa:      pa(WithMetricAnnotation(autoscaling.CPU), WithTargetMillicoresAnnotation("500")),
+		wantTgt: 350,
+		wantTot: 500,
+	}, {
+		name:    "CPU: the target annotation is a percentage for the HPA",
+		pa:      pa(WithMetricAnnotation(autoscaling.CPU), WithTargetAnnotation("80")),
+		wantTgt: 700,
+		wantTot: 1000,
+	}, {
+		name:    "Memory: defaults",
+		pa:      pa(WithMetricAnnotation(autoscaling.Memory), WithTUAnnotation("50")),
+		wantTgt: 512 << 20,
+		wantTot: 1024 << 20,
+	}, {
+		name:    "Memory: with target annotation 256",
+		pa:      pa(WithMetricAnnotation(autoscaling.Memory), WithTargetAnnotation("256"), WithTUAnnotation("50")),
+		wantTgt: 128 << 20,
+		wantTot: 256 << 20,
+	}, {
+		name:    "Connections: defaults",
+		pa:      pa(WithMetricAnnotation(autoscaling.Connections)),
+		wantTgt: 700,
+		wantTot: 1000,
+	}, {
+		name:    "Connections: with target annotation 200",
+		pa:      pa(WithMetricAnnotation(autoscaling.Connections), WithTargetAnnotation("200")),
+		wantTgt: 140,
+		wantTot: 200,
+	}, {
+		name:    "Custom: target is used as is",
+		pa:      pa(WithMetricAnnotation(autoscaling.Custom), WithTargetAnnotation("10")),
+		wantTgt: 10,
+		wantTot: 10,
+	}, {
+		name:    "Custom: without target annotation",
+		pa:      pa(WithMetricAnnotation(autoscaling.Custom)),
+		wantTgt: 1,
+		wantTot: 0,
 	}}
 
 	for _, tc := range cases {
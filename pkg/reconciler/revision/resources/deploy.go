@@ -24,6 +24,7 @@ import (
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	tracingconfig "knative.dev/pkg/tracing/config"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/networking"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
@@ -42,10 +43,19 @@ import (
 )
 
 const (
-	varLogVolumeName   = "knative-var-log"
-	varLogVolumePath   = "/var/log"
-	internalVolumeName = "knative-internal"
-	internalVolumePath = "/var/knative-internal"
+	varLogVolumeName     = "knative-var-log"
+	varLogVolumePath     = "/var/log"
+	internalVolumeName   = "knative-internal"
+	internalVolumePath   = "/var/knative-internal"
+	userSocketVolumeName = "knative-user-socket"
+	userSocketVolumePath = "/var/run/knative"
+	userSocketPath       = userSocketVolumePath + "/user.sock"
+	authJWKSVolumeName   = "knative-auth-jwks"
+	authJWKSVolumePath   = "/var/knative-auth"
+	authJWKSKey          = "jwks.json"
+	authJWKSPath         = authJWKSVolumePath + "/" + authJWKSKey
+	nodeCgroupVolumeName = "knative-node-cgroup"
+	nodeCgroupVolumePath = "/var/knative-node-cgroup"
 )
 
 var (
@@ -73,6 +83,45 @@ var (
 		MountPath: internalVolumePath,
 	}
 
+	userSocketVolume = corev1.Volume{
+		Name: userSocketVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+
+	userSocketVolumeMount = corev1.VolumeMount{
+		Name:      userSocketVolumeName,
+		MountPath: userSocketVolumePath,
+	}
+
+	authJWKSVolumeMount = corev1.VolumeMount{
+		Name:      authJWKSVolumeName,
+		MountPath: authJWKSVolumePath,
+		ReadOnly:  true,
+	}
+
+	// The queue-proxy reads the CPU and memory usage of the user container
+	// from the cgroup of the pod, which is only visible in the hierarchy of
+	// the node.
+	nodeCgroupVolume = corev1.Volume{
+		Name: nodeCgroupVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: queue.DefaultCgroupRoot,
+				Type: &hostPathDirectory,
+			},
+		},
+	}
+
+	nodeCgroupVolumeMount = corev1.VolumeMount{
+		Name:      nodeCgroupVolumeName,
+		MountPath: nodeCgroupVolumePath,
+		ReadOnly:  true,
+	}
+
+	hostPathDirectory = corev1.HostPathDirectory
+
 	// This PreStop hook is actually calling an endpoint on the queue-proxy
 	// because of the way PreStop hooks are called by kubelet. We use this
 	// to block the user-container from exiting before the queue-proxy is ready
@@ -129,6 +178,13 @@ func makePodSpec(rev *v1alpha1.Revision, loggingConfig *logging.Config, tracingC
 	userContainer.Ports = buildContainerPorts(userPort)
 	userContainer.Env = append(userContainer.Env, buildUserPortEnv(userPortStr))
 	userContainer.Env = append(userContainer.Env, getKnativeEnvVar(rev)...)
+	if usesUserSocket(rev) {
+		userContainer.VolumeMounts = append(userContainer.VolumeMounts, userSocketVolumeMount)
+		userContainer.Env = append(userContainer.Env, corev1.EnvVar{
+			Name:  knativeSocketPathEnvVariableKey,
+			Value: userSocketPath,
+		})
+	}
 	// Explicitly disable stdin and tty allocation
 	userContainer.Stdin = false
 	userContainer.TTY = false
@@ -154,13 +210,18 @@ func makePodSpec(rev *v1alpha1.Revision, loggingConfig *logging.Config, tracingC
 	rewriteUserProbe(userContainer.LivenessProbe, userPortInt)
 
 	podSpec := &corev1.PodSpec{
-		Containers: []corev1.Container{
-			*userContainer,
-			*queueContainer,
-		},
+		Containers:                    append(makeUserContainers(rev, userContainer), *queueContainer),
 		Volumes:                       append([]corev1.Volume{varLogVolume}, rev.Spec.Volumes...),
 		ServiceAccountName:            rev.Spec.ServiceAccountName,
 		TerminationGracePeriodSeconds: rev.Spec.TimeoutSeconds,
+		// The optional fields are only set when allowed by config-features.
+		Affinity:          rev.Spec.Affinity,
+		Tolerations:       rev.Spec.Tolerations,
+		NodeSelector:      rev.Spec.NodeSelector,
+		SecurityContext:   rev.Spec.SecurityContext,
+		RuntimeClassName:  rev.Spec.RuntimeClassName,
+		ImagePullSecrets:  rev.Spec.ImagePullSecrets,
+		PriorityClassName: rev.Spec.PriorityClassName,
 	}
 
 	// Add the Knative internal volume only if /var/log collection is enabled
@@ -168,9 +229,78 @@ func makePodSpec(rev *v1alpha1.Revision, loggingConfig *logging.Config, tracingC
 		podSpec.Volumes = append(podSpec.Volumes, internalVolume)
 	}
 
+	// Add the volume sharing the user container's socket with the queue-proxy
+	if usesUserSocket(rev) {
+		podSpec.Volumes = append(podSpec.Volumes, userSocketVolume)
+	}
+
+	// Add the volume with the keys the queue-proxy authenticates requests with
+	if jwksVolume := makeAuthJWKSVolume(rev); jwksVolume != nil {
+		podSpec.Volumes = append(podSpec.Volumes, *jwksVolume)
+	}
+
+	// Add the cgroups of the node for the queue-proxy to read the resource usage
+	if scalesOnResourceUsage(rev) {
+		podSpec.Volumes = append(podSpec.Volumes, nodeCgroupVolume)
+	}
+
 	return podSpec, nil
 }
 
+// makeUserContainers returns the serving container in its place among the
+// sidecars of the revision.
+func makeUserContainers(rev *v1alpha1.Revision, userContainer *corev1.Container) []corev1.Container {
+	if len(rev.Spec.Containers) < 2 {
+		return []corev1.Container{*userContainer}
+	}
+	servingIdx := serving.ServingContainerIndex(rev.Spec.Containers)
+	containers := make([]corev1.Container, 0, len(rev.Spec.Containers))
+	for i := range rev.Spec.Containers {
+		if i == servingIdx {
+			containers = append(containers, *userContainer)
+		} else {
+			containers = append(containers, *makeSidecarContainer(rev, &rev.Spec.Containers[i]))
+		}
+	}
+	return containers
+}
+
+// makeSidecarContainer returns a sidecar of the revision. Unlike the serving
+// container, it doesn't get requests from the queue-proxy and its probes are
+// run by the kubelet as they are.
+func makeSidecarContainer(rev *v1alpha1.Revision, sidecar *corev1.Container) *corev1.Container {
+	container := sidecar.DeepCopy()
+	// Adding or removing an overwritten corev1.Container field here? Don't forget to
+	// update the fieldmasks / validations in pkg/apis/serving
+
+	// Prefer the imageDigest of the sidecar if available
+	if digest := imageDigest(rev, container.Name); digest != "" {
+		container.Image = digest
+	}
+	// Sidecars outlive the requests in flight like the serving container.
+	container.Lifecycle = userLifecycle
+	container.Env = append(container.Env, getKnativeEnvVar(rev)...)
+	// Explicitly disable stdin and tty allocation
+	container.Stdin = false
+	container.TTY = false
+
+	if container.TerminationMessagePolicy == "" {
+		container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	}
+	return container
+}
+
+// imageDigest returns the resolved digest of the image of the named
+// container, or "" if it isn't resolved.
+func imageDigest(rev *v1alpha1.Revision, name string) string {
+	for _, status := range rev.Status.ContainerStatuses {
+		if status.Name == name {
+			return status.ImageDigest
+		}
+	}
+	return ""
+}
+
 func getUserPort(rev *v1alpha1.Revision) int32 {
 	ports := rev.Spec.GetContainer().Ports
 
@@ -183,6 +313,59 @@ func getUserPort(rev *v1alpha1.Revision) int32 {
 	return v1alpha1.DefaultUserPort
 }
 
+// usesUserSocket returns whether the user container of the revision listens
+// on a Unix domain socket. The annotation is validated, so anything but true
+// means it listens on its TCP port.
+func usesUserSocket(rev *v1alpha1.Revision) bool {
+	b, _ := strconv.ParseBool(rev.GetAnnotations()[serving.QueueSideCarUserSocketAnnotation])
+	return b
+}
+
+// scalesOnResourceUsage returns whether the KPA scales the revision on the
+// CPU or memory usage the queue-proxy reports.
+func scalesOnResourceUsage(rev *v1alpha1.Revision) bool {
+	annotations := rev.GetAnnotations()
+	if class := annotations[autoscaling.ClassAnnotationKey]; class != "" && class != autoscaling.KPA {
+		return false
+	}
+	switch annotations[autoscaling.MetricAnnotationKey] {
+	case autoscaling.CPU, autoscaling.Memory:
+		return true
+	}
+	return false
+}
+
+// makeAuthJWKSVolume returns the volume of the Secret or ConfigMap holding the
+// JWKS the queue-proxy validates bearer tokens against, or nil if the revision
+// doesn't authenticate requests.
+func makeAuthJWKSVolume(rev *v1alpha1.Revision) *corev1.Volume {
+	items := []corev1.KeyToPath{{Key: authJWKSKey, Path: authJWKSKey}}
+	annotations := rev.GetAnnotations()
+	if name := annotations[serving.QueueSideCarAuthJWKSSecretAnnotation]; name != "" {
+		return &corev1.Volume{
+			Name: authJWKSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: name,
+					Items:      items,
+				},
+			},
+		}
+	}
+	if name := annotations[serving.QueueSideCarAuthJWKSConfigMapAnnotation]; name != "" {
+		return &corev1.Volume{
+			Name: authJWKSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Items:                items,
+				},
+			},
+		}
+	}
+	return nil
+}
+
 func buildContainerPorts(userPort int32) []corev1.ContainerPort {
 	return []corev1.ContainerPort{{
 		Name:          v1alpha1.UserPortName,
@@ -34,6 +34,7 @@ import (
 	"knative.dev/pkg/system"
 	_ "knative.dev/pkg/system/testing"
 	tracingconfig "knative.dev/pkg/tracing/config"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/networking"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
@@ -102,9 +103,45 @@ var (
 		}, {
 			Name:  "CONTAINER_CONCURRENCY",
 			Value: "0",
+		}, {
+			Name:  "CONTAINER_CONCURRENCY_ADAPTIVE",
+			Value: "false",
+		}, {
+			Name:  "RATE_LIMIT",
+			Value: "0",
+		}, {
+			Name:  "RATE_LIMIT_BURST",
+			Value: "0",
+		}, {
+			Name:  "RATE_LIMIT_KEY_HEADER",
+			Value: "",
+		}, {
+			Name:  "RATE_LIMIT_MAX_PODS",
+			Value: "0",
+		}, {
+			Name:  "AUTH_JWKS_PATH",
+			Value: "",
+		}, {
+			Name:  "AUTH_ISSUER",
+			Value: "",
+		}, {
+			Name:  "AUTH_AUDIENCE",
+			Value: "",
+		}, {
+			Name:  "AUTH_REQUIRED_CLAIMS",
+			Value: "",
+		}, {
+			Name:  "AUTH_CLAIM_HEADERS",
+			Value: "",
 		}, {
 			Name:  "REVISION_TIMEOUT_SECONDS",
 			Value: "45",
+		}, {
+			Name:  "REVISION_IDLE_TIMEOUT_SECONDS",
+			Value: "0",
+		}, {
+			Name:  "REVISION_MAX_DURATION_SECONDS",
+			Value: "0",
 		}, {
 			Name: "SERVING_POD",
 			ValueFrom: &corev1.EnvVarSource{
@@ -115,6 +152,14 @@ var (
 			ValueFrom: &corev1.EnvVarSource{
 				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
 			},
+		}, {
+			Name: "SERVING_POD_UID",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.uid"},
+			},
+		}, {
+			Name:  "CGROUP_ROOT",
+			Value: "",
 		}, {
 			Name: "SERVING_LOGGING_CONFIG",
 			// No logging configuration
@@ -124,9 +169,33 @@ var (
 		}, {
 			Name:  "SERVING_REQUEST_LOG_TEMPLATE",
 			Value: "",
+		}, {
+			Name:  "SERVING_REQUEST_LOG_FORMAT",
+			Value: "",
+		}, {
+			Name:  "SERVING_REQUEST_LOG_FIELDS",
+			Value: "",
+		}, {
+			Name:  "SERVING_REQUEST_LOG_SAMPLE_RATE",
+			Value: "0",
+		}, {
+			Name:  "SERVING_REQUEST_LOG_ALWAYS_LOG_ERRORS",
+			Value: "false",
+		}, {
+			Name:  "SERVING_REQUEST_LOG_SLOW_THRESHOLD",
+			Value: "0s",
 		}, {
 			Name:  "SERVING_REQUEST_METRICS_BACKEND",
 			Value: "",
+		}, {
+			Name:  "OTLP_ENDPOINT",
+			Value: "",
+		}, {
+			Name:  "OTLP_PROTOCOL",
+			Value: "",
+		}, {
+			Name:  "ROUTE_TEMPLATES",
+			Value: "",
 		}, {
 			Name:  "TRACING_CONFIG_BACKEND",
 			Value: "",
@@ -145,6 +214,12 @@ var (
 		}, {
 			Name:  "USER_PORT",
 			Value: "8080",
+		}, {
+			Name:  "USER_SOCKET_PATH",
+			Value: "",
+		}, {
+			Name:  "USER_LIVENESS_PROBE_PATH",
+			Value: "",
 		}, {
 			Name:  "SYSTEM_NAMESPACE",
 			Value: system.Namespace(),
@@ -286,6 +361,12 @@ func withInternalVolumeMount() containerOption {
 	}
 }
 
+func withUserSocketVolumeMount() containerOption {
+	return func(container *corev1.Container) {
+		container.VolumeMounts = append(container.VolumeMounts, userSocketVolumeMount)
+	}
+}
+
 func withReadinessProbe(handler corev1.Handler) containerOption {
 	return func(container *corev1.Container) {
 		container.ReadinessProbe = &corev1.Probe{Handler: handler}
@@ -654,6 +735,7 @@ func TestMakePodSpec(t *testing.T) {
 				),
 				queueContainer(
 					withEnvVar("CONTAINER_CONCURRENCY", "0"),
+					withEnvVar("USER_LIVENESS_PROBE_PATH", "/"),
 				),
 			}),
 	}, {
@@ -712,6 +794,262 @@ func TestMakePodSpec(t *testing.T) {
 				podSpec.Volumes = append(podSpec.Volumes, internalVolume)
 			},
 		),
+	}, {
+		name: "with user socket",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSideCarUserSocketAnnotation: "true",
+				}
+				container(revision.Spec.GetContainer(),
+					withTCPReadinessProbe(),
+				)
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{
+				userContainer(
+					withEnvVar("K_SOCKET_PATH", "/var/run/knative/user.sock"),
+					withUserSocketVolumeMount(),
+				),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+					withEnvVar("USER_SOCKET_PATH", "/var/run/knative/user.sock"),
+					withUserSocketVolumeMount(),
+				),
+			},
+			withAppendedVolumes(userSocketVolume),
+		),
+	}, {
+		name: "with auth",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSideCarAuthJWKSSecretAnnotation: "jwks",
+					serving.QueueSideCarAuthIssuerAnnotation:     "https://issuer.example.com",
+				}
+				container(revision.Spec.GetContainer(),
+					withTCPReadinessProbe(),
+				)
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{
+				userContainer(),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+					withEnvVar("AUTH_JWKS_PATH", "/var/knative-auth/jwks.json"),
+					withEnvVar("AUTH_ISSUER", "https://issuer.example.com"),
+					func(container *corev1.Container) {
+						container.VolumeMounts = append(container.VolumeMounts, authJWKSVolumeMount)
+					},
+				),
+			},
+			withAppendedVolumes(corev1.Volume{
+				Name: "knative-auth-jwks",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: "jwks",
+						Items:      []corev1.KeyToPath{{Key: "jwks.json", Path: "jwks.json"}},
+					},
+				},
+			}),
+		),
+	}, {
+		name: "with cpu metric",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				revision.Annotations = map[string]string{
+					autoscaling.ClassAnnotationKey:  autoscaling.KPA,
+					autoscaling.MetricAnnotationKey: autoscaling.CPU,
+				}
+				container(revision.Spec.GetContainer(),
+					withTCPReadinessProbe(),
+				)
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{
+				userContainer(),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+					withEnvVar("CGROUP_ROOT", "/var/knative-node-cgroup"),
+					func(container *corev1.Container) {
+						container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+							Name:      "knative-node-cgroup",
+							MountPath: "/var/knative-node-cgroup",
+							ReadOnly:  true,
+						})
+					},
+				),
+			},
+			withAppendedVolumes(corev1.Volume{
+				Name: "knative-node-cgroup",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{
+						Path: "/sys/fs/cgroup",
+						Type: &hostPathDirectory,
+					},
+				},
+			}),
+		),
+	}, {
+		name: "with cpu metric on the hpa",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				revision.Annotations = map[string]string{
+					autoscaling.ClassAnnotationKey:  autoscaling.HPA,
+					autoscaling.MetricAnnotationKey: autoscaling.CPU,
+				}
+				container(revision.Spec.GetContainer(),
+					withTCPReadinessProbe(),
+				)
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{
+				userContainer(),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+				),
+			},
+		),
+	}, {
+		name: "with feature flagged fields",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				container(revision.Spec.GetContainer(),
+					withTCPReadinessProbe(),
+				)
+				revision.Spec.NodeSelector = map[string]string{"pool": "gpu"}
+				revision.Spec.Tolerations = []corev1.Toleration{{
+					Key:    "gpu",
+					Effect: corev1.TaintEffectNoSchedule,
+				}}
+				revision.Spec.SecurityContext = &corev1.PodSecurityContext{
+					FSGroup: ptr.Int64(1000),
+				}
+				revision.Spec.RuntimeClassName = ptr.String("gvisor")
+				revision.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{
+					Name: "registry",
+				}}
+				revision.Spec.PriorityClassName = "high"
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{
+				userContainer(),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+				),
+			},
+			func(podSpec *corev1.PodSpec) {
+				podSpec.NodeSelector = map[string]string{"pool": "gpu"}
+				podSpec.Tolerations = []corev1.Toleration{{
+					Key:    "gpu",
+					Effect: corev1.TaintEffectNoSchedule,
+				}}
+				podSpec.SecurityContext = &corev1.PodSecurityContext{
+					FSGroup: ptr.Int64(1000),
+				}
+				podSpec.RuntimeClassName = ptr.String("gvisor")
+				podSpec.ImagePullSecrets = []corev1.LocalObjectReference{{
+					Name: "registry",
+				}}
+				podSpec.PriorityClassName = "high"
+			},
+		),
+	}, {
+		name: "with sidecars",
+		rev: revision(withContainerConcurrency(1),
+			func(revision *v1alpha1.Revision) {
+				serving := revision.Spec.DeprecatedContainer
+				serving.Ports = []corev1.ContainerPort{{
+					ContainerPort: v1alpha1.DefaultUserPort,
+				}}
+				container(serving, withTCPReadinessProbe())
+				revision.Spec.DeprecatedContainer = nil
+				revision.Spec.Containers = []corev1.Container{{
+					Name:  "logger",
+					Image: "fluentd",
+					Env:   []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+					LivenessProbe: &corev1.Probe{
+						Handler: corev1.Handler{
+							HTTPGet: &corev1.HTTPGetAction{
+								Path: "/health",
+								Port: intstr.FromInt(8081),
+							},
+						},
+					},
+				}, *serving, {
+					Name:                     "proxy",
+					Image:                    "cloudsql",
+					TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				}}
+				revision.Status.ContainerStatuses = []v1alpha1.ContainerStatuses{{
+					Name:        "proxy",
+					ImageDigest: "cloudsql@sha256:deadbeef",
+				}}
+			}),
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: podSpec(
+			[]corev1.Container{{
+				Name:  "logger",
+				Image: "fluentd",
+				Env: []corev1.EnvVar{
+					{Name: "FOO", Value: "bar"},
+					{Name: "K_REVISION", Value: "bar"},
+					{Name: "K_CONFIGURATION", Value: "cfg"},
+					{Name: "K_SERVICE", Value: "svc"},
+				},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/health",
+							Port: intstr.FromInt(8081),
+						},
+					},
+				},
+				Lifecycle:                userLifecycle,
+				TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+			}, userContainer(), {
+				Name:  "proxy",
+				Image: "cloudsql@sha256:deadbeef",
+				Env: []corev1.EnvVar{
+					{Name: "K_REVISION", Value: "bar"},
+					{Name: "K_CONFIGURATION", Value: "cfg"},
+					{Name: "K_SERVICE", Value: "svc"},
+				},
+				Lifecycle:                userLifecycle,
+				TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			}, queueContainer(
+				withEnvVar("CONTAINER_CONCURRENCY", "1"),
+			)},
+		),
 	}, {
 		name: "complex pod spec",
 		rev: revision(
@@ -792,6 +1130,11 @@ func TestMakePodSpec(t *testing.T) {
 			}
 		})
 
+		// Revisions with sidecars are in podspec already.
+		if test.rev.Spec.DeprecatedContainer == nil {
+			continue
+		}
+
 		t.Run(test.name+"(podspec)", func(t *testing.T) {
 			quantityComparer := cmp.Comparer(func(x, y resource.Quantity) bool {
 				return x.Cmp(y) == 0
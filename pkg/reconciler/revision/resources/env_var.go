@@ -26,6 +26,7 @@ const (
 	knativeRevisionEnvVariableKey      = "K_REVISION"
 	knativeConfigurationEnvVariableKey = "K_CONFIGURATION"
 	knativeServiceEnvVariableKey       = "K_SERVICE"
+	knativeSocketPathEnvVariableKey    = "K_SOCKET_PATH"
 )
 
 func getKnativeEnvVar(rev *v1alpha1.Revision) []corev1.EnvVar {
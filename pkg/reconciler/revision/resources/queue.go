@@ -19,7 +19,9 @@ package resources
 import (
 	"fmt"
 	"math"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +33,7 @@ import (
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/system"
 	tracingconfig "knative.dev/pkg/tracing/config"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/networking"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
@@ -135,6 +138,14 @@ func fractionFromPercentage(m map[string]string, k string) (float64, bool) {
 	return float64(value / 100), err == nil
 }
 
+// annotationOrDefault returns the value of the annotation k, or def if it is not set.
+func annotationOrDefault(m map[string]string, k, def string) string {
+	if v, ok := m[k]; ok {
+		return v
+	}
+	return def
+}
+
 func makeQueueProbe(in *corev1.Probe) *corev1.Probe {
 	if in == nil || in.PeriodSeconds == 0 {
 		out := &corev1.Probe{
@@ -200,6 +211,13 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 	if rev.Spec.TimeoutSeconds != nil {
 		ts = *rev.Spec.TimeoutSeconds
 	}
+	var idleTimeout, maxDuration int64
+	if rev.Spec.IdleTimeoutSeconds != nil {
+		idleTimeout = *rev.Spec.IdleTimeoutSeconds
+	}
+	if rev.Spec.MaxDurationSeconds != nil {
+		maxDuration = *rev.Spec.MaxDurationSeconds
+	}
 
 	// We need to configure only one serving port for the Queue proxy, since
 	// we know the protocol that is being used by this application.
@@ -214,6 +232,29 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 	if observabilityConfig.EnableVarLogCollection {
 		volumeMounts = append(volumeMounts, internalVolumeMount)
 	}
+	var socketPath string
+	if usesUserSocket(rev) {
+		volumeMounts = append(volumeMounts, userSocketVolumeMount)
+		socketPath = userSocketPath
+	}
+	var jwksPath string
+	if makeAuthJWKSVolume(rev) != nil {
+		volumeMounts = append(volumeMounts, authJWKSVolumeMount)
+		jwksPath = authJWKSPath
+	}
+	var cgroupRoot string
+	if scalesOnResourceUsage(rev) {
+		volumeMounts = append(volumeMounts, nodeCgroupVolumeMount)
+		cgroupRoot = nodeCgroupVolumePath
+	}
+
+	// The annotation is validated, so anything but true turns adaptive concurrency off.
+	adaptiveConcurrency, _ := strconv.ParseBool(rev.GetAnnotations()[serving.QueueSideCarAdaptiveConcurrencyAnnotation])
+	// A rate limit of 0 turns rate limiting off and a burst of 0 defaults it to the rate limit.
+	rateLimit := annotationOrDefault(rev.GetAnnotations(), serving.QueueSideCarRateLimitAnnotation, "0")
+	rateLimitBurst := annotationOrDefault(rev.GetAnnotations(), serving.QueueSideCarRateLimitBurstAnnotation, "0")
+	// The limit is split across maxScale pods until the autoscaler reports the ready pods.
+	rateLimitMaxPods := annotationOrDefault(rev.GetAnnotations(), autoscaling.MaxScaleAnnotationKey, "0")
 
 	rp := rev.Spec.GetContainer().ReadinessProbe.DeepCopy()
 
@@ -250,9 +291,45 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 		}, {
 			Name:  "CONTAINER_CONCURRENCY",
 			Value: strconv.Itoa(int(rev.Spec.GetContainerConcurrency())),
+		}, {
+			Name:  "CONTAINER_CONCURRENCY_ADAPTIVE",
+			Value: strconv.FormatBool(adaptiveConcurrency),
+		}, {
+			Name:  "RATE_LIMIT",
+			Value: rateLimit,
+		}, {
+			Name:  "RATE_LIMIT_BURST",
+			Value: rateLimitBurst,
+		}, {
+			Name:  "RATE_LIMIT_KEY_HEADER",
+			Value: rev.GetAnnotations()[serving.QueueSideCarRateLimitKeyHeaderAnnotation],
+		}, {
+			Name:  "RATE_LIMIT_MAX_PODS",
+			Value: rateLimitMaxPods,
+		}, {
+			Name:  "AUTH_JWKS_PATH",
+			Value: jwksPath,
+		}, {
+			Name:  "AUTH_ISSUER",
+			Value: rev.GetAnnotations()[serving.QueueSideCarAuthIssuerAnnotation],
+		}, {
+			Name:  "AUTH_AUDIENCE",
+			Value: rev.GetAnnotations()[serving.QueueSideCarAuthAudienceAnnotation],
+		}, {
+			Name:  "AUTH_REQUIRED_CLAIMS",
+			Value: rev.GetAnnotations()[serving.QueueSideCarAuthRequiredClaimsAnnotation],
+		}, {
+			Name:  "AUTH_CLAIM_HEADERS",
+			Value: rev.GetAnnotations()[serving.QueueSideCarAuthClaimHeadersAnnotation],
 		}, {
 			Name:  "REVISION_TIMEOUT_SECONDS",
 			Value: strconv.Itoa(int(ts)),
+		}, {
+			Name:  "REVISION_IDLE_TIMEOUT_SECONDS",
+			Value: strconv.Itoa(int(idleTimeout)),
+		}, {
+			Name:  "REVISION_MAX_DURATION_SECONDS",
+			Value: strconv.Itoa(int(maxDuration)),
 		}, {
 			Name: "SERVING_POD",
 			ValueFrom: &corev1.EnvVarSource{
@@ -267,6 +344,16 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 					FieldPath: "status.podIP",
 				},
 			},
+		}, {
+			Name: "SERVING_POD_UID",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.uid",
+				},
+			},
+		}, {
+			Name:  "CGROUP_ROOT",
+			Value: cgroupRoot,
 		}, {
 			Name:  "SERVING_LOGGING_CONFIG",
 			Value: loggingConfig.LoggingConfig,
@@ -276,9 +363,33 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 		}, {
 			Name:  "SERVING_REQUEST_LOG_TEMPLATE",
 			Value: observabilityConfig.RequestLogTemplate,
+		}, {
+			Name:  "SERVING_REQUEST_LOG_FORMAT",
+			Value: observabilityConfig.RequestLogFormat,
+		}, {
+			Name:  "SERVING_REQUEST_LOG_FIELDS",
+			Value: strings.Join(observabilityConfig.RequestLogFields, ","),
+		}, {
+			Name:  "SERVING_REQUEST_LOG_SAMPLE_RATE",
+			Value: strconv.FormatFloat(observabilityConfig.RequestLogSampleRate, 'f', -1, 64),
+		}, {
+			Name:  "SERVING_REQUEST_LOG_ALWAYS_LOG_ERRORS",
+			Value: strconv.FormatBool(observabilityConfig.RequestLogAlwaysLogErrors),
+		}, {
+			Name:  "SERVING_REQUEST_LOG_SLOW_THRESHOLD",
+			Value: observabilityConfig.RequestLogSlowThreshold.String(),
 		}, {
 			Name:  "SERVING_REQUEST_METRICS_BACKEND",
 			Value: observabilityConfig.RequestMetricsBackend,
+		}, {
+			Name:  "OTLP_ENDPOINT",
+			Value: observabilityConfig.OTLPEndpoint,
+		}, {
+			Name:  "OTLP_PROTOCOL",
+			Value: string(observabilityConfig.OTLPProtocol),
+		}, {
+			Name:  "ROUTE_TEMPLATES",
+			Value: rev.GetAnnotations()[serving.QueueSideCarRouteTemplatesAnnotation],
 		}, {
 			Name:  "TRACING_CONFIG_BACKEND",
 			Value: string(tracingConfig.Backend),
@@ -297,6 +408,12 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 		}, {
 			Name:  "USER_PORT",
 			Value: strconv.Itoa(int(userPort)),
+		}, {
+			Name:  "USER_SOCKET_PATH",
+			Value: socketPath,
+		}, {
+			Name:  "USER_LIVENESS_PROBE_PATH",
+			Value: livenessProbePath(rev.Spec.GetContainer().LivenessProbe),
 		}, {
 			Name:  system.NamespaceEnvKey,
 			Value: system.Namespace(),
@@ -321,6 +438,24 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, t
 		}},
 	}, nil
 }
+
+// livenessProbePath returns the request path of the HTTP liveness probe, which
+// the kubelet sends through the queue-proxy, or "" if there is no such probe.
+func livenessProbePath(p *corev1.Probe) string {
+	if p == nil || p.HTTPGet == nil {
+		return ""
+	}
+	// Like the kubelet, drop the query and make the path absolute.
+	path := p.HTTPGet.Path
+	if u, err := url.Parse(path); err == nil {
+		path = u.Path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
 func applyReadinessProbeDefaults(p *corev1.Probe, port int32) {
 	switch {
 	case p == nil:
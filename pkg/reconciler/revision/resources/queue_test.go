@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -37,6 +38,7 @@ import (
 	"knative.dev/pkg/system"
 	_ "knative.dev/pkg/system/testing"
 	tracingconfig "knative.dev/pkg/tracing/config"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/networking"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/apis/serving/v1alpha1"
@@ -314,6 +316,285 @@ func TestMakeQueueContainer(t *testing.T) {
 				"CONTAINER_CONCURRENCY": "10",
 			}),
 		},
+	}, {
+		name: "adaptive container concurrency",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.QueueSideCarAdaptiveConcurrencyAnnotation: "true",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(10),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY":          "10",
+				"CONTAINER_CONCURRENCY_ADAPTIVE": "true",
+			}),
+		},
+	}, {
+		name: "user socket",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.QueueSideCarUserSocketAnnotation: "true",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(10),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			VolumeMounts:    []corev1.VolumeMount{userSocketVolumeMount},
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY": "10",
+				"USER_SOCKET_PATH":      "/var/run/knative/user.sock",
+			}),
+		},
+	}, {
+		name: "http liveness probe",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(1),
+					TimeoutSeconds:       ptr.Int64(45),
+					PodSpec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:           containerName,
+							ReadinessProbe: testProbe,
+							LivenessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "healthz?verbose=true",
+									},
+								},
+							},
+						}},
+					},
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"USER_LIVENESS_PROBE_PATH": "/healthz",
+			}),
+		},
+	}, {
+		name: "auth",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.QueueSideCarAuthJWKSConfigMapAnnotation:  "jwks",
+					serving.QueueSideCarAuthIssuerAnnotation:         "https://issuer.example.com",
+					serving.QueueSideCarAuthAudienceAnnotation:       "my-service",
+					serving.QueueSideCarAuthRequiredClaimsAnnotation: "groups=admins",
+					serving.QueueSideCarAuthClaimHeadersAnnotation:   "sub=X-User",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(10),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			VolumeMounts:    []corev1.VolumeMount{authJWKSVolumeMount},
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY": "10",
+				"AUTH_JWKS_PATH":        "/var/knative-auth/jwks.json",
+				"AUTH_ISSUER":           "https://issuer.example.com",
+				"AUTH_AUDIENCE":         "my-service",
+				"AUTH_REQUIRED_CLAIMS":  "groups=admins",
+				"AUTH_CLAIM_HEADERS":    "sub=X-User",
+			}),
+		},
+	}, {
+		name: "rate limit",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.QueueSideCarRateLimitAnnotation:          "2.5",
+					serving.QueueSideCarRateLimitBurstAnnotation:     "5",
+					serving.QueueSideCarRateLimitKeyHeaderAnnotation: "X-Client-Id",
+					autoscaling.MaxScaleAnnotationKey:                "5",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(1),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"RATE_LIMIT":            "2.5",
+				"RATE_LIMIT_BURST":      "5",
+				"RATE_LIMIT_KEY_HEADER": "X-Client-Id",
+				"RATE_LIMIT_MAX_PODS":   "5",
+			}),
+		},
+	}, {
+		name: "memory metric",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					autoscaling.MetricAnnotationKey: autoscaling.Memory,
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(1),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			VolumeMounts: []corev1.VolumeMount{{
+				Name:      "knative-node-cgroup",
+				MountPath: "/var/knative-node-cgroup",
+				ReadOnly:  true,
+			}},
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CGROUP_ROOT": "/var/knative-node-cgroup",
+			}),
+		},
+	}, {
+		name: "streaming timeouts",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(1),
+					TimeoutSeconds:       ptr.Int64(45),
+					IdleTimeoutSeconds:   ptr.Int64(30),
+					MaxDurationSeconds:   ptr.Int64(3600),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"REVISION_IDLE_TIMEOUT_SECONDS": "30",
+				"REVISION_MAX_DURATION_SECONDS": "3600",
+			}),
+		},
 	}, {
 		name: "request log as env var",
 		rev: &v1alpha1.Revision{
@@ -347,6 +628,49 @@ func TestMakeQueueContainer(t *testing.T) {
 				"SERVING_REQUEST_LOG_TEMPLATE": "test template",
 			}),
 		},
+	}, {
+		name: "structured request log as env var",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(0),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{
+			RequestLogFormat:          "json",
+			RequestLogFields:          []string{"method", "status"},
+			RequestLogSampleRate:      0.05,
+			RequestLogAlwaysLogErrors: true,
+			RequestLogSlowThreshold:   1500 * time.Millisecond,
+		},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY":                 "0",
+				"SERVING_REQUEST_LOG_FORMAT":            "json",
+				"SERVING_REQUEST_LOG_FIELDS":            "method,status",
+				"SERVING_REQUEST_LOG_SAMPLE_RATE":       "0.05",
+				"SERVING_REQUEST_LOG_ALWAYS_LOG_ERRORS": "true",
+				"SERVING_REQUEST_LOG_SLOW_THRESHOLD":    "1.5s",
+			}),
+		},
 	}, {
 		name: "request metrics backend as env var",
 		rev: &v1alpha1.Revision{
@@ -382,6 +706,80 @@ func TestMakeQueueContainer(t *testing.T) {
 				"SERVING_REQUEST_METRICS_BACKEND": "prometheus",
 			}),
 		},
+	}, {
+		name: "otlp endpoint as env var",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(0),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{
+			OTLPEndpoint: "http://otel-collector.observability:4318",
+		},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY": "0",
+				"OTLP_ENDPOINT":         "http://otel-collector.observability:4318",
+			}),
+		},
+	}, {
+		name: "route templates",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.QueueSideCarRouteTemplatesAnnotation: "/users/{id},/orders",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				RevisionSpec: v1beta1.RevisionSpec{
+					ContainerConcurrency: ptr.Int64(0),
+					TimeoutSeconds:       ptr.Int64(45),
+				},
+			},
+		},
+		lc: &logging.Config{},
+		tc: &tracingconfig.Config{},
+		oc: &metrics.ObservabilityConfig{
+			RequestMetricsBackend: "prometheus",
+		},
+		ac: &autoscaler.Config{},
+		cc: &deployment.Config{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:            QueueContainerName,
+			Resources:       createQueueResources(make(map[string]string), &corev1.Container{}),
+			Ports:           append(queueNonServingPorts, queueHTTPPort),
+			ReadinessProbe:  defaultKnativeQReadinessProbe,
+			SecurityContext: queueSecurityContext,
+			// These changed based on the Revision and configs passed in.
+			Env: env(map[string]string{
+				"CONTAINER_CONCURRENCY":           "0",
+				"SERVING_REQUEST_METRICS_BACKEND": "prometheus",
+				"ROUTE_TEMPLATES":                 "/users/{id},/orders",
+			}),
+		},
 	}}
 
 	for _, test := range tests {
@@ -1077,7 +1475,19 @@ var defaultEnv = map[string]string{
 	"SERVING_CONFIGURATION":                 "",
 	"SERVING_REVISION":                      "bar",
 	"CONTAINER_CONCURRENCY":                 "1",
+	"CONTAINER_CONCURRENCY_ADAPTIVE":        "false",
+	"RATE_LIMIT":                            "0",
+	"RATE_LIMIT_BURST":                      "0",
+	"RATE_LIMIT_KEY_HEADER":                 "",
+	"RATE_LIMIT_MAX_PODS":                   "0",
+	"AUTH_JWKS_PATH":                        "",
+	"AUTH_ISSUER":                           "",
+	"AUTH_AUDIENCE":                         "",
+	"AUTH_REQUIRED_CLAIMS":                  "",
+	"AUTH_CLAIM_HEADERS":                    "",
 	"REVISION_TIMEOUT_SECONDS":              "45",
+	"REVISION_IDLE_TIMEOUT_SECONDS":         "0",
+	"REVISION_MAX_DURATION_SECONDS":         "0",
 	"SERVING_LOGGING_CONFIG":                "",
 	"SERVING_LOGGING_LEVEL":                 "",
 	"TRACING_CONFIG_BACKEND":                "",
@@ -1086,8 +1496,18 @@ var defaultEnv = map[string]string{
 	"TRACING_CONFIG_SAMPLE_RATE":            "0.000000",
 	"TRACING_CONFIG_DEBUG":                  "false",
 	"SERVING_REQUEST_LOG_TEMPLATE":          "",
+	"SERVING_REQUEST_LOG_FORMAT":            "",
+	"SERVING_REQUEST_LOG_FIELDS":            "",
+	"SERVING_REQUEST_LOG_SAMPLE_RATE":       "0",
+	"SERVING_REQUEST_LOG_ALWAYS_LOG_ERRORS": "false",
+	"SERVING_REQUEST_LOG_SLOW_THRESHOLD":    "0s",
 	"SERVING_REQUEST_METRICS_BACKEND":       "",
+	"OTLP_ENDPOINT":                         "",
+	"OTLP_PROTOCOL":                         "",
+	"ROUTE_TEMPLATES":                       "",
 	"USER_PORT":                             strconv.Itoa(v1alpha1.DefaultUserPort),
+	"USER_SOCKET_PATH":                      "",
+	"USER_LIVENESS_PROBE_PATH":              "",
 	"SYSTEM_NAMESPACE":                      system.Namespace(),
 	"METRICS_DOMAIN":                        pkgmetrics.Domain(),
 	"QUEUE_SERVING_PORT":                    "8012",
@@ -1095,6 +1515,7 @@ var defaultEnv = map[string]string{
 	"ENABLE_VAR_LOG_COLLECTION":             "false",
 	"VAR_LOG_VOLUME_NAME":                   varLogVolumeName,
 	"INTERNAL_VOLUME_PATH":                  internalVolumePath,
+	"CGROUP_ROOT":                           "",
 }
 
 func probeJSON(container *corev1.Container) string {
@@ -1130,6 +1551,11 @@ func env(overrides map[string]string) []corev1.EnvVar {
 		ValueFrom: &corev1.EnvVarSource{
 			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
 		},
+	}, {
+		Name: "SERVING_POD_UID",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.uid"},
+		},
 	}}...)
 
 	sortEnv(env)
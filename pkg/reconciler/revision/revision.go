@@ -123,29 +123,57 @@ func (c *Reconciler) Reconcile(ctx context.Context, key string) error {
 	return nil
 }
 
+// reconcileDigest resolves the images of all the containers to digests. The
+// digests already resolved are kept, so that the pods of the revision keep
+// running the same images even if the tags move.
 func (c *Reconciler) reconcileDigest(ctx context.Context, rev *v1alpha1.Revision) error {
-	// The image digest has already been resolved.
+	containers := rev.Spec.Containers
+	if len(containers) == 0 {
+		containers = []corev1.Container{*rev.Spec.GetContainer()}
+	}
+	servingName := rev.Spec.GetContainer().Name
+
+	digests := make(map[string]string, len(containers))
+	for _, status := range rev.Status.ContainerStatuses {
+		digests[status.Name] = status.ImageDigest
+	}
+	// Revisions from before the sidecars only recorded the serving container's digest.
 	if rev.Status.ImageDigest != "" {
-		return nil
+		digests[servingName] = rev.Status.ImageDigest
 	}
 
 	cfgs := config.FromContext(ctx)
 	opt := k8schain.Options{
 		Namespace:          rev.Namespace,
 		ServiceAccountName: rev.Spec.ServiceAccountName,
-		// ImagePullSecrets: Not possible via RevisionSpec, since we
-		// don't expose such a field.
 	}
-	digest, err := c.resolver.Resolve(rev.Spec.GetContainer().Image,
-		opt, cfgs.Deployment.RegistriesSkippingTagResolving)
-	if err != nil {
-		rev.Status.MarkContainerMissing(
-			v1alpha1.RevisionContainerMissingMessage(
-				rev.Spec.GetContainer().Image, err.Error()))
-		return err
+	for _, s := range rev.Spec.ImagePullSecrets {
+		opt.ImagePullSecrets = append(opt.ImagePullSecrets, s.Name)
 	}
-
-	rev.Status.ImageDigest = digest
+	var statuses []v1alpha1.ContainerStatuses
+	for _, container := range containers {
+		digest := digests[container.Name]
+		if digest == "" {
+			var err error
+			digest, err = c.resolver.Resolve(container.Image, opt, cfgs.Deployment.RegistriesSkippingTagResolving)
+			if err != nil {
+				rev.Status.MarkContainerMissing(
+					v1alpha1.RevisionContainerMissingMessage(container.Image, err.Error()))
+				return err
+			}
+		}
+		if container.Name == servingName {
+			rev.Status.ImageDigest = digest
+		}
+		if digest == "" {
+			continue
+		}
+		statuses = append(statuses, v1alpha1.ContainerStatuses{
+			Name:        container.Name,
+			ImageDigest: digest,
+		})
+	}
+	rev.Status.ContainerStatuses = statuses
 
 	return nil
 }
@@ -63,6 +63,7 @@ import (
 	"knative.dev/serving/pkg/autoscaler"
 	"knative.dev/serving/pkg/deployment"
 	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/reconciler/revision/config"
 	"knative.dev/serving/pkg/reconciler/revision/resources"
 	resourcenames "knative.dev/serving/pkg/reconciler/revision/resources/names"
 
@@ -269,6 +270,71 @@ func (r *errorResolver) Resolve(_ string, _ k8schain.Options, _ sets.String) (st
 	return "", errors.New(r.error)
 }
 
+// imageResolver resolves the images in its map, and counts the resolutions.
+type imageResolver struct {
+	digests  map[string]string
+	resolved []string
+}
+
+func (r *imageResolver) Resolve(image string, _ k8schain.Options, _ sets.String) (string, error) {
+	r.resolved = append(r.resolved, image)
+	return r.digests[image], nil
+}
+
+func TestReconcileDigestSidecars(t *testing.T) {
+	resolver := &imageResolver{digests: map[string]string{
+		"gcr.io/repo/image":   "gcr.io/repo/image@sha256:serving",
+		"gcr.io/repo/sidecar": "gcr.io/repo/sidecar@sha256:sidecar",
+	}}
+	c := &Reconciler{resolver: resolver}
+	ctx := config.ToContext(context.Background(), &config.Config{Deployment: getTestDeploymentConfig()})
+
+	rev := testRevision()
+	rev.Spec.Containers = []corev1.Container{{
+		Name:  "sidecar",
+		Image: "gcr.io/repo/sidecar",
+	}, {
+		Name:  "user-container",
+		Image: "gcr.io/repo/image",
+		Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+	}, {
+		Name:  "skipped",
+		Image: "ko.local/skipped",
+	}}
+	if err := c.reconcileDigest(ctx, rev); err != nil {
+		t.Fatalf("reconcileDigest() = %v", err)
+	}
+	want := []v1alpha1.ContainerStatuses{{
+		Name:        "sidecar",
+		ImageDigest: "gcr.io/repo/sidecar@sha256:sidecar",
+	}, {
+		Name:        "user-container",
+		ImageDigest: "gcr.io/repo/image@sha256:serving",
+	}}
+	if !cmp.Equal(rev.Status.ContainerStatuses, want) {
+		t.Errorf("ContainerStatuses (-want, +got) = %s", cmp.Diff(want, rev.Status.ContainerStatuses))
+	}
+	if got, want := rev.Status.ImageDigest, "gcr.io/repo/image@sha256:serving"; got != want {
+		t.Errorf("ImageDigest = %q, want: %q", got, want)
+	}
+
+	// The digests are not resolved again, even if the tags moved.
+	resolver.digests = map[string]string{
+		"gcr.io/repo/image":   "gcr.io/repo/image@sha256:moved",
+		"gcr.io/repo/sidecar": "gcr.io/repo/sidecar@sha256:moved",
+	}
+	resolver.resolved = nil
+	if err := c.reconcileDigest(ctx, rev); err != nil {
+		t.Fatalf("reconcileDigest() = %v", err)
+	}
+	if !cmp.Equal(rev.Status.ContainerStatuses, want) {
+		t.Errorf("ContainerStatuses (-want, +got) = %s", cmp.Diff(want, rev.Status.ContainerStatuses))
+	}
+	if got, want := resolver.resolved, []string{"ko.local/skipped"}; !cmp.Equal(got, want) {
+		t.Errorf("Resolved %v, want only the image without a digest: %v", got, want)
+	}
+}
+
 func TestResolutionFailed(t *testing.T) {
 	ctx, _, controller, _ := newTestController(t)
 
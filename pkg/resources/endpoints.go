@@ -30,6 +30,17 @@ func ReadyAddressCount(endpoints *corev1.Endpoints) int {
 	return total
 }
 
+// ReadyAddresses returns the IPs of the addresses ready for the given endpoint.
+func ReadyAddresses(endpoints *corev1.Endpoints) []string {
+	ret := make([]string, 0, ReadyAddressCount(endpoints))
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			ret = append(ret, address.IP)
+		}
+	}
+	return ret
+}
+
 // ReadyPodCounter provides a count of currently ready pods. This
 // information is used by UniScaler implementations to make scaling
 // decisions. The interface prevents the UniScaler from needing to
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeinformers "k8s.io/client-go/informers"
@@ -109,6 +111,30 @@ func TestReadyAddressCount(t *testing.T) {
 	}
 }
 
+func TestReadyAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints *corev1.Endpoints
+		want      []string
+	}{{
+		name:      "no ready addresses",
+		endpoints: endpoints(0),
+		want:      []string{},
+	}, {
+		name:      "two ready addresses",
+		endpoints: endpoints(2),
+		want:      []string{"127.0.0.1", "127.0.0.2"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ReadyAddresses(test.endpoints); !cmp.Equal(got, test.want) {
+				t.Errorf("ReadyAddresses() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
 func endpoints(ipCount int) *corev1.Endpoints {
 	ep := &corev1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
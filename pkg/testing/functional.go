@@ -152,6 +152,13 @@ func WithTargetAnnotation(target string) PodAutoscalerOption {
 	return withAnnotationValue(autoscaling.TargetAnnotationKey, target)
 }
 
+// WithTargetMillicoresAnnotation returns a PodAutoscalerOption which sets
+// the PodAutoscaler autoscaling.knative.dev/targetMillicores annotation to
+// the provided value.
+func WithTargetMillicoresAnnotation(target string) PodAutoscalerOption {
+	return withAnnotationValue(autoscaling.TargetMillicoresAnnotationKey, target)
+}
+
 // WithTUAnnotation returns a PodAutoscalerOption which sets
 // the PodAutoscaler autoscaling.knative.dev/targetUtilizationPercentage
 // annotation to the provided value.
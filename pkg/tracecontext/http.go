@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracecontext
+
+import (
+	"net/http"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// Propagation extracts span contexts from the W3C Trace Context headers, or
+// the B3 headers if there are none, and injects them in both formats, so
+// traces are continued whichever format the clients and applications use.
+var Propagation propagation.HTTPFormat = &multiFormat{
+	formats: []propagation.HTTPFormat{&HTTPFormat{}, &b3.HTTPFormat{}},
+}
+
+type multiFormat struct {
+	formats []propagation.HTTPFormat
+}
+
+// SpanContextFromRequest returns the span context of the first format found
+// in the request.
+func (f *multiFormat) SpanContextFromRequest(req *http.Request) (trace.SpanContext, bool) {
+	for _, format := range f.formats {
+		if sc, ok := format.SpanContextFromRequest(req); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// SpanContextToRequest sets the span context in all the formats.
+func (f *multiFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	for _, format := range f.formats {
+		format.SpanContextToRequest(sc, req)
+	}
+}
+
+// HTTPSpanMiddleware is a http.Handler middleware to create spans for the
+// HTTP endpoint, continuing the traces propagated with Propagation.
+func HTTPSpanMiddleware(next http.Handler) http.Handler {
+	return &ochttp.Handler{Handler: next, Propagation: Propagation}
+}
+
+// NewTransport returns a http.RoundTripper creating spans for the requests
+// sent with base and propagating them with Propagation.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return &ochttp.Transport{Base: base, Propagation: Propagation}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracecontext propagates OpenCensus spans over HTTP in the W3C
+// Trace Context format, alongside the B3 format.
+package tracecontext
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"go.opencensus.io/trace/tracestate"
+)
+
+// W3C Trace Context headers, see https://www.w3.org/TR/trace-context/.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+const (
+	supportedVersion = 0
+	maxVersion       = 254
+	// traceparentLen is the length of a version 00 traceparent.
+	traceparentLen = 55
+	// maxTracestateEntries is the number of list members a tracestate may have.
+	maxTracestateEntries = 32
+)
+
+// HTTPFormat implements propagation.HTTPFormat to propagate traces in the
+// traceparent and tracestate headers.
+type HTTPFormat struct{}
+
+var _ propagation.HTTPFormat = (*HTTPFormat)(nil)
+
+// SpanContextFromRequest extracts a span context from the traceparent and
+// tracestate headers of incoming requests. A malformed tracestate is dropped.
+func (f *HTTPFormat) SpanContextFromRequest(req *http.Request) (trace.SpanContext, bool) {
+	sc, ok := ParseTraceparent(req.Header.Get(TraceparentHeader))
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	sc.Tracestate = parseTracestate(req.Header[http.CanonicalHeaderKey(TracestateHeader)])
+	return sc, true
+}
+
+// SpanContextToRequest sets the traceparent and tracestate headers of
+// outgoing requests.
+func (f *HTTPFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	req.Header.Set(TraceparentHeader, fmt.Sprintf("%02x-%s-%s-%02x",
+		supportedVersion, sc.TraceID, sc.SpanID, byte(sc.TraceOptions)&1))
+	if sc.Tracestate == nil || len(sc.Tracestate.Entries()) == 0 {
+		req.Header.Del(TracestateHeader)
+		return
+	}
+	entries := make([]string, 0, len(sc.Tracestate.Entries()))
+	for _, e := range sc.Tracestate.Entries() {
+		entries = append(entries, e.Key+"="+e.Value)
+	}
+	req.Header.Set(TracestateHeader, strings.Join(entries, ","))
+}
+
+// ParseTraceparent parses the value of the traceparent header. Later versions
+// are parsed as version 00, as the specification requires.
+func ParseTraceparent(h string) (trace.SpanContext, bool) {
+	h = strings.TrimSpace(h)
+	if len(h) < traceparentLen {
+		return trace.SpanContext{}, false
+	}
+	version, ok := decodeHex(h[0:2], 1)
+	if !ok || version[0] > maxVersion ||
+		version[0] == supportedVersion && len(h) != traceparentLen ||
+		len(h) > traceparentLen && h[traceparentLen] != '-' ||
+		h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return trace.SpanContext{}, false
+	}
+
+	var sc trace.SpanContext
+	traceID, ok := decodeHex(h[3:35], len(sc.TraceID))
+	if !ok || isZero(traceID) {
+		return trace.SpanContext{}, false
+	}
+	spanID, ok := decodeHex(h[36:52], len(sc.SpanID))
+	if !ok || isZero(spanID) {
+		return trace.SpanContext{}, false
+	}
+	flags, ok := decodeHex(h[53:55], 1)
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.TraceOptions = trace.TraceOptions(flags[0] & 1)
+	return sc, true
+}
+
+// parseTracestate parses the values of the tracestate headers. Invalid
+// values are dropped altogether.
+func parseTracestate(headers []string) *tracestate.Tracestate {
+	var entries []tracestate.Entry
+	for _, h := range headers {
+		for _, member := range strings.Split(h, ",") {
+			member = strings.TrimSpace(member)
+			if member == "" {
+				continue
+			}
+			kv := strings.SplitN(member, "=", 2)
+			if len(kv) != 2 {
+				return nil
+			}
+			entries = append(entries, tracestate.Entry{Key: kv[0], Value: kv[1]})
+		}
+	}
+	if len(entries) == 0 || len(entries) > maxTracestateEntries {
+		return nil
+	}
+	ts, err := tracestate.New(nil, entries...)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// decodeHex decodes n bytes from lower case hex.
+func decodeHex(s string, n int) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != n {
+		return nil, false
+	}
+	return b, true
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
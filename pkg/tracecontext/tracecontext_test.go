@@ -0,0 +1,181 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracecontext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/tracestate"
+)
+
+var (
+	testTraceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	testSpanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   trace.SpanContext
+		wantOK bool
+	}{{
+		name:   "sampled",
+		header: testTraceparent,
+		want:   trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID, TraceOptions: 1},
+		wantOK: true,
+	}, {
+		name:   "not sampled",
+		header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		want:   trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID},
+		wantOK: true,
+	}, {
+		name:   "unknown flags",
+		header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09",
+		want:   trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID, TraceOptions: 1},
+		wantOK: true,
+	}, {
+		name:   "future version with more fields",
+		header: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-holds",
+		want:   trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID, TraceOptions: 1},
+		wantOK: true,
+	}, {
+		name:   "version 00 with more fields",
+		header: testTraceparent + "-more",
+	}, {
+		name:   "invalid version",
+		header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}, {
+		name:   "upper case",
+		header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	}, {
+		name:   "zero trace ID",
+		header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	}, {
+		name:   "zero span ID",
+		header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	}, {
+		name:   "wrong separator",
+		header: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}, {
+		name:   "too short",
+		header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	}, {
+		name: "empty",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseTraceparent(test.header)
+			if ok != test.wantOK {
+				t.Fatalf("ParseTraceparent(%q) = %v, want: %v", test.header, ok, test.wantOK)
+			}
+			if got != test.want {
+				t.Errorf("ParseTraceparent(%q) = %v, want: %v", test.header, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHTTPFormatTracestate(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    string
+	}{{
+		name:    "single header",
+		headers: []string{"congo=t61rcWkgMzE, rojo=00f067aa0ba902b7"},
+		want:    "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+	}, {
+		name:    "several headers",
+		headers: []string{"congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7"},
+		want:    "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+	}, {
+		name:    "invalid member",
+		headers: []string{"congo=t61rcWkgMzE,rojo"},
+	}, {
+		name:    "invalid key",
+		headers: []string{"Congo=t61rcWkgMzE"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(TraceparentHeader, testTraceparent)
+			for _, h := range test.headers {
+				req.Header.Add(TracestateHeader, h)
+			}
+
+			f := &HTTPFormat{}
+			sc, ok := f.SpanContextFromRequest(req)
+			if !ok {
+				t.Fatal("SpanContextFromRequest() = false, want: true")
+			}
+			out := httptest.NewRequest(http.MethodGet, "/", nil)
+			f.SpanContextToRequest(sc, out)
+			if got, want := out.Header.Get(TraceparentHeader), testTraceparent; got != want {
+				t.Errorf("traceparent = %q, want: %q", got, want)
+			}
+			if got := out.Header.Get(TracestateHeader); got != test.want {
+				t.Errorf("tracestate = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	b3Req := httptest.NewRequest(http.MethodGet, "/", nil)
+	b3Req.Header.Set(b3.TraceIDHeader, testTraceID.String())
+	b3Req.Header.Set(b3.SpanIDHeader, testSpanID.String())
+	b3Req.Header.Set(b3.SampledHeader, "1")
+	want := trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID, TraceOptions: 1}
+	if got, ok := Propagation.SpanContextFromRequest(b3Req); !ok || got != want {
+		t.Errorf("SpanContextFromRequest(B3) = %v, %v, want: %v", got, ok, want)
+	}
+
+	// The W3C headers win.
+	b3Req.Header.Set(TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	got, ok := Propagation.SpanContextFromRequest(b3Req)
+	if !ok || got.TraceID.String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("SpanContextFromRequest(both) = %v, %v, want the traceparent", got, ok)
+	}
+
+	if _, ok := Propagation.SpanContextFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Error("SpanContextFromRequest(none) = true, want: false")
+	}
+
+	ts, _ := tracestate.New(nil, tracestate.Entry{Key: "rojo", Value: "00f067aa0ba902b7"})
+	out := httptest.NewRequest(http.MethodGet, "/", nil)
+	Propagation.SpanContextToRequest(trace.SpanContext{TraceID: testTraceID, SpanID: testSpanID, TraceOptions: 1, Tracestate: ts}, out)
+	for header, want := range map[string]string{
+		TraceparentHeader: testTraceparent,
+		TracestateHeader:  "rojo=00f067aa0ba902b7",
+		b3.TraceIDHeader:  testTraceID.String(),
+		b3.SpanIDHeader:   testSpanID.String(),
+		b3.SampledHeader:  "1",
+	} {
+		if got := out.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want: %q", header, got, want)
+		}
+	}
+}